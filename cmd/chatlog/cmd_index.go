@@ -0,0 +1,127 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/ysy950803/chatlog/internal/chatlog"
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.PersistentFlags().StringVarP(&indexPlatform, "platform", "p", "", "platform")
+	indexCmd.PersistentFlags().IntVarP(&indexVer, "version", "v", 0, "version")
+	indexCmd.PersistentFlags().StringVarP(&indexWorkDir, "work-dir", "w", "", "work dir")
+
+	indexCmd.AddCommand(indexStatusCmd)
+	indexCmd.AddCommand(indexRebuildCmd)
+	for _, action := range []string{"verify", "optimize", "vacuum"} {
+		indexCmd.AddCommand(newIndexCheckCmd(action))
+	}
+	for _, cmd := range indexCmd.Commands() {
+		if cmd != indexStatusCmd {
+			cmd.Flags().StringVarP(&indexStore, "store", "s", "", "only operate on the store with this id or file name")
+		}
+	}
+}
+
+var (
+	indexPlatform string
+	indexVer      int
+	indexWorkDir  string
+	indexStore    string
+)
+
+var indexCheckShort = map[string]string{
+	"verify":   "Check SQLite and FTS5 integrity of the index",
+	"optimize": "Merge FTS5 segments of the index",
+	"vacuum":   "Reclaim free pages of the index files",
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the full-text search index",
+	Long: `Manage the full-text search index under <work dir>/indexes/messages.
+
+  chatlog index status -w <work dir>
+  chatlog index rebuild -w <work dir> [--store <id>]
+  chatlog index verify|optimize|vacuum -w <work dir> [--store <id>]`,
+}
+
+var indexStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show index version, fingerprints and per-store document counts",
+	Run: func(cmd *cobra.Command, args []string) {
+		m := chatlog.New()
+		report, err := m.CommandIndexStatus("", indexCmdConf())
+		if err != nil {
+			log.Err(err).Msg("failed to get index status")
+			return
+		}
+		fmt.Print(report.PlainText())
+	},
+}
+
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuild the whole index or a single store",
+	Run: func(cmd *cobra.Command, args []string) {
+		m := chatlog.New()
+		report, err := m.CommandIndexRebuild("", indexCmdConf(), indexStore)
+		if err != nil {
+			log.Err(err).Msg("failed to rebuild index")
+			return
+		}
+		fmt.Print(report.PlainText())
+	},
+}
+
+func newIndexCheckCmd(action string) *cobra.Command {
+	return &cobra.Command{
+		Use:   action,
+		Short: indexCheckShort[action],
+		Run: func(cmd *cobra.Command, args []string) {
+			m := chatlog.New()
+			results, err := m.CommandIndexCheck("", indexCmdConf(), action, indexStore)
+			if err != nil {
+				log.Err(err).Msgf("failed to %s index", action)
+				return
+			}
+			printIndexCheckResults(results)
+		},
+	}
+}
+
+func printIndexCheckResults(results []*model.IndexCheckResult) {
+	failed := 0
+	for _, r := range results {
+		if r.OK {
+			fmt.Printf("%s: ok\n", r.ID)
+			continue
+		}
+		failed++
+		fmt.Printf("%s: %s\n", r.ID, r.Error)
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d stores failed, run `chatlog index rebuild --store <id>` to rebuild them\n", failed, len(results))
+		os.Exit(1)
+	}
+}
+
+func indexCmdConf() map[string]any {
+	cmdConf := make(map[string]any)
+	if len(indexWorkDir) != 0 {
+		cmdConf["work_dir"] = indexWorkDir
+	}
+	if len(indexPlatform) != 0 {
+		cmdConf["platform"] = indexPlatform
+	}
+	if indexVer != 0 {
+		cmdConf["version"] = indexVer
+	}
+	return cmdConf
+}
@@ -0,0 +1,84 @@
+package chatlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/ysy950803/chatlog/internal/chatlog"
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().StringVarP(&searchPlatform, "platform", "p", "", "platform")
+	searchCmd.Flags().IntVarP(&searchVer, "version", "v", 0, "version")
+	searchCmd.Flags().StringVarP(&searchWorkDir, "work-dir", "w", "", "work dir")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "max hits")
+	searchCmd.Flags().IntVarP(&searchOffset, "offset", "o", 0, "offset")
+	searchCmd.Flags().IntVarP(&searchContext, "context", "c", 0, "context messages before/after each hit")
+	searchCmd.Flags().StringVar(&searchCursor, "cursor", "", "continue from the next cursor of a previous search")
+}
+
+var (
+	searchPlatform string
+	searchVer      int
+	searchWorkDir  string
+	searchLimit    int
+	searchOffset   int
+	searchContext  int
+	searchCursor   string
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search chat history",
+	Long: `Search decrypted chat history with the full-text index.
+
+The query supports operators, e.g.
+  chatlog search 'from:张三 in:工作群 type:file after:2024-03-01 合同'`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cmdConf := make(map[string]any)
+		if len(searchWorkDir) != 0 {
+			cmdConf["work_dir"] = searchWorkDir
+		}
+		if len(searchPlatform) != 0 {
+			cmdConf["platform"] = searchPlatform
+		}
+		if searchVer != 0 {
+			cmdConf["version"] = searchVer
+		}
+
+		req := &model.SearchRequest{
+			Query:   strings.Join(args, " "),
+			Limit:   searchLimit,
+			Offset:  searchOffset,
+			Context: searchContext,
+			Cursor:  searchCursor,
+		}
+
+		m := chatlog.New()
+		resp, err := m.CommandSearch("", cmdConf, req)
+		if err != nil {
+			log.Err(err).Msg("failed to search")
+			return
+		}
+
+		fmt.Printf("总命中: %d, 本页: %d\n", resp.Total, len(resp.Hits))
+		for idx, hit := range resp.Hits {
+			if hit == nil || hit.Message == nil {
+				continue
+			}
+			fmt.Println(strings.Repeat("-", 60))
+			fmt.Print(hit.PlainText(idx, ""))
+		}
+		if resp.NextCursor != "" {
+			fmt.Println(strings.Repeat("-", 60))
+			fmt.Printf("next cursor: %s\n", resp.NextCursor)
+		}
+	},
+}
@@ -12,6 +12,7 @@ func init() {
 	serverCmd.PersistentPreRun = initLog
 	serverCmd.PersistentFlags().BoolVar(&Debug, "debug", false, "debug")
 	serverCmd.Flags().StringVarP(&serverAddr, "addr", "a", "", "server address")
+	serverCmd.Flags().StringVarP(&serverGRPCAddr, "grpc-addr", "", "", "grpc server address")
 	serverCmd.Flags().StringVarP(&serverPlatform, "platform", "p", "", "platform")
 	serverCmd.Flags().IntVarP(&serverVer, "version", "v", 0, "version")
 	serverCmd.Flags().StringVarP(&serverDataDir, "data-dir", "d", "", "data dir")
@@ -19,10 +20,12 @@ func init() {
 	serverCmd.Flags().StringVarP(&serverImgKey, "img-key", "i", "", "img key")
 	serverCmd.Flags().StringVarP(&serverWorkDir, "work-dir", "w", "", "work dir")
 	serverCmd.Flags().BoolVarP(&serverAutoDecrypt, "auto-decrypt", "", false, "auto decrypt")
+	serverCmd.Flags().StringSliceVarP(&serverCORSOrigins, "cors-origin", "", nil, "allowed CORS origins, any origin when empty")
 }
 
 var (
 	serverAddr        string
+	serverGRPCAddr    string
 	serverDataDir     string
 	serverDataKey     string
 	serverImgKey      string
@@ -30,6 +33,7 @@ var (
 	serverPlatform    string
 	serverVer         int
 	serverAutoDecrypt bool
+	serverCORSOrigins []string
 )
 
 var serverCmd = &cobra.Command{
@@ -53,6 +57,9 @@ func getServerConfig() map[string]any {
 	if len(serverAddr) != 0 {
 		cmdConf["http_addr"] = serverAddr
 	}
+	if len(serverGRPCAddr) != 0 {
+		cmdConf["grpc_addr"] = serverGRPCAddr
+	}
 	if len(serverDataDir) != 0 {
 		cmdConf["data_dir"] = serverDataDir
 	}
@@ -74,5 +81,8 @@ func getServerConfig() map[string]any {
 	if serverAutoDecrypt {
 		cmdConf["auto_decrypt"] = true
 	}
+	if len(serverCORSOrigins) != 0 {
+		cmdConf["auth.cors_origins"] = serverCORSOrigins
+	}
 	return cmdConf
 }
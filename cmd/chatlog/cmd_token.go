@@ -0,0 +1,101 @@
+package chatlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/ysy950803/chatlog/internal/chatlog"
+	"github.com/ysy950803/chatlog/internal/chatlog/conf"
+)
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.PersistentFlags().BoolVar(&tokenServer, "server", false, "manage tokens of the server command (chatlog-server.json)")
+
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenAddCmd)
+	tokenCmd.AddCommand(tokenRemoveCmd)
+	tokenAddCmd.Flags().StringVarP(&tokenScopes, "scope", "s", strings.Join(conf.DefaultTokenScopes, ","), "comma separated scopes: "+strings.Join(conf.Scopes, ",")+" or all")
+}
+
+var (
+	tokenServer bool
+	tokenScopes string
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens of the HTTP server",
+	Long: `Manage API tokens of the HTTP server. Tokens are stored hashed in the config file,
+once any token exists every request must carry one with the required scope:
+
+  read      /api/v1 data endpoints
+  media     /image, /video, /file, /voice, /data, /avatar
+  mcp       /mcp, /sse, /message
+  settings  /api/v1/setting
+  actions   /api/v1/actions
+
+Send it as "Authorization: Bearer <token>" or the "token" query parameter.
+
+  chatlog token add <name> [--scope read,media,mcp]
+  chatlog token list
+  chatlog token remove <name>`,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Run: func(cmd *cobra.Command, args []string) {
+		m := chatlog.New()
+		tokens, err := m.CommandTokenList("", tokenServer)
+		if err != nil {
+			log.Err(err).Msg("failed to list tokens")
+			return
+		}
+		if len(tokens) == 0 {
+			fmt.Println("no tokens, the HTTP server accepts anonymous requests")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSCOPES\tCREATED")
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, strings.Join(t.Scopes, ","), t.CreatedAt)
+		}
+		w.Flush()
+	},
+}
+
+var tokenAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Create an API token, the token is only shown once",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		m := chatlog.New()
+		token, err := m.CommandTokenAdd("", tokenServer, args[0], conf.ParseScopes(tokenScopes))
+		if err != nil {
+			log.Err(err).Msg("failed to add token")
+			return
+		}
+		fmt.Println(token)
+		fmt.Fprintln(os.Stderr, "save the token now, it can not be shown again; restart chatlog to apply")
+	},
+}
+
+var tokenRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove an API token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		m := chatlog.New()
+		if err := m.CommandTokenRemove("", tokenServer, args[0]); err != nil {
+			log.Err(err).Msg("failed to remove token")
+			return
+		}
+		fmt.Println("token removed, restart chatlog to apply")
+	},
+}
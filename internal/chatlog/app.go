@@ -44,6 +44,8 @@ const (
 	settingKeyOpenAITimeout   settingsKey = "openai_timeout"
 	settingKeyWhisperModel    settingsKey = "whisper_model"
 	settingKeyWhisperThreads  settingsKey = "whisper_threads"
+	settingKeyAPITokens       settingsKey = "api_tokens"
+	settingKeyCORSOrigins     settingsKey = "cors_origins"
 )
 
 type App struct {
@@ -552,6 +554,8 @@ func (a *App) initSettingsTab() {
 		a.newSettingsItem(12, "设置 OpenAI Base URL", settingKeyOpenAIBaseURL, a.settingOpenAIBaseURL),
 		a.newSettingsItem(13, "设置 OpenAI 代理", settingKeyOpenAIProxy, a.settingOpenAIProxy),
 		a.newSettingsItem(14, "设置 OpenAI 请求超时", settingKeyOpenAITimeout, a.settingOpenAITimeout),
+		a.newSettingsItem(15, "管理 API 令牌", settingKeyAPITokens, a.settingAPITokens),
+		a.newSettingsItem(16, "设置跨域来源", settingKeyCORSOrigins, a.settingCORSOrigins),
 	}
 
 	a.settingsMenu.SetItems(a.settingsItems)
@@ -704,6 +708,22 @@ func (a *App) refreshSettingsMenu() {
 		item.Description = fmt.Sprintf("当前请求超时: %s", formatTimeoutSummary(timeoutValue))
 	}
 
+	if item := a.settingsItemMap[settingKeyAPITokens]; item != nil {
+		if auth := a.ctx.GetAuth(); auth.Enabled() {
+			item.Description = fmt.Sprintf("已配置 %d 个令牌", len(auth.Tokens))
+		} else {
+			item.Description = "未配置，接口可匿名访问"
+		}
+	}
+
+	if item := a.settingsItemMap[settingKeyCORSOrigins]; item != nil {
+		if auth := a.ctx.GetAuth(); auth.AllowAnyOrigin() {
+			item.Description = "允许任意来源"
+		} else {
+			item.Description = strings.Join(auth.CORSOrigins, ", ")
+		}
+	}
+
 	a.settingsMenu.SetItems(a.settingsItems)
 }
 
@@ -1244,6 +1264,160 @@ func (a *App) settingDataDir() {
 	a.SetFocus(formView)
 }
 
+// updateAuthConfig 修改访问控制配置的副本并保存，HTTP 服务从下一个请求起生效
+func (a *App) updateAuthConfig(mutator func(*conf.AuthConfig) error) error {
+	auth := a.ctx.GetAuth().Clone()
+	if err := mutator(auth); err != nil {
+		return err
+	}
+	return a.ctx.SaveAuthConfig(auth)
+}
+
+// settingAPITokens 列出 API 令牌，选择令牌可删除
+func (a *App) settingAPITokens() {
+	subMenu := menu.NewSubMenu("API 令牌")
+	subMenu.AddItem(&menu.Item{
+		Index:       0,
+		Name:        "新建令牌",
+		Description: "令牌明文只在创建时显示一次",
+		Selected:    func(*menu.Item) { a.addAPIToken() },
+	})
+
+	auth := a.ctx.GetAuth()
+	if !auth.Enabled() {
+		subMenu.AddItem(&menu.Item{
+			Index:       1,
+			Name:        "无令牌",
+			Description: "所有接口可匿名访问",
+		})
+	} else {
+		for i, t := range auth.Tokens {
+			subMenu.AddItem(&menu.Item{
+				Index:       i + 1,
+				Name:        t.Name,
+				Description: fmt.Sprintf("权限: %s 创建于: %s", strings.Join(t.Scopes, ","), t.CreatedAt),
+				Selected: func(*menu.Item) {
+					a.showModal(fmt.Sprintf("删除令牌 %s？", t.Name), []string{"删除", "取消"}, func(buttonIndex int, buttonLabel string) {
+						a.mainPages.RemovePage("modal")
+						if buttonIndex != 0 {
+							return
+						}
+						err := a.updateAuthConfig(func(auth *conf.AuthConfig) error {
+							auth.RemoveToken(t.Name)
+							return nil
+						})
+						a.mainPages.RemovePage("submenu")
+						a.refreshSettingsMenu()
+						if err != nil {
+							a.showError(fmt.Errorf("删除令牌失败: %v", err))
+							return
+						}
+						a.showInfo("令牌已删除")
+					})
+				},
+			})
+		}
+	}
+
+	a.mainPages.AddPage("submenu", subMenu, true, true)
+	a.SetFocus(subMenu)
+}
+
+// addAPIToken 新建 API 令牌
+func (a *App) addAPIToken() {
+	formView := form.NewForm("新建 API 令牌")
+
+	var name string
+	formView.AddInputField("名称", "", 0, nil, func(text string) {
+		name = text
+	})
+
+	scopes := make(map[string]bool)
+	for _, scope := range conf.DefaultTokenScopes {
+		scopes[scope] = true
+	}
+	for _, scope := range conf.Scopes {
+		formView.AddCheckbox(scope, scopes[scope], func(checked bool) {
+			scopes[scope] = checked
+		})
+	}
+
+	formView.AddButton("保存", func() {
+		var selected []string
+		for _, scope := range conf.Scopes {
+			if scopes[scope] {
+				selected = append(selected, scope)
+			}
+		}
+		if len(selected) == 0 {
+			a.showError(fmt.Errorf("请至少选择一个权限"))
+			return
+		}
+
+		var token string
+		err := a.updateAuthConfig(func(auth *conf.AuthConfig) error {
+			var err error
+			token, err = auth.AddToken(name, selected)
+			return err
+		})
+		if err != nil {
+			a.showError(fmt.Errorf("新建令牌失败: %v", err))
+			return
+		}
+		a.mainPages.RemovePage("submenu2")
+		a.mainPages.RemovePage("submenu")
+		a.refreshSettingsMenu()
+		a.showInfo("令牌只显示这一次，请立即保存:\n\n" + token)
+	})
+
+	formView.AddButton("取消", func() {
+		a.mainPages.RemovePage("submenu2")
+	})
+
+	a.mainPages.AddPage("submenu2", formView, true, true)
+	a.SetFocus(formView)
+}
+
+// settingCORSOrigins 设置允许跨域访问的来源，多个来源用逗号分隔，留空允许任意来源
+func (a *App) settingCORSOrigins() {
+	formView := form.NewForm("设置跨域来源")
+
+	var tempOrigins string
+	if auth := a.ctx.GetAuth(); auth != nil {
+		tempOrigins = strings.Join(auth.CORSOrigins, ",")
+	}
+	formView.AddInputField("来源", tempOrigins, 0, nil, func(text string) {
+		tempOrigins = text
+	})
+
+	formView.AddButton("保存", func() {
+		var origins []string
+		for _, origin := range strings.Split(tempOrigins, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+		err := a.updateAuthConfig(func(auth *conf.AuthConfig) error {
+			auth.CORSOrigins = origins
+			return nil
+		})
+		a.mainPages.RemovePage("submenu2")
+		a.refreshSettingsMenu()
+		if err != nil {
+			a.showError(fmt.Errorf("保存失败: %v", err))
+			return
+		}
+		a.showInfo("跨域来源已保存")
+	})
+
+	formView.AddButton("取消", func() {
+		a.mainPages.RemovePage("submenu2")
+	})
+
+	a.mainPages.AddPage("submenu2", formView, true, true)
+	a.SetFocus(formView)
+}
+
 // selectAccountSelected 处理切换账号菜单项的选择事件
 func (a *App) selectAccountSelected(i *menu.Item) {
 	// 创建子菜单
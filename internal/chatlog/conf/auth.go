@@ -0,0 +1,267 @@
+package conf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/pkg/config"
+)
+
+// API 令牌的权限范围
+const (
+	ScopeRead     = "read"     // /api/v1 下的数据接口
+	ScopeMedia    = "media"    // 图片、视频、文件、语音、头像
+	ScopeMCP      = "mcp"      // /mcp、/sse、/message
+	ScopeSettings = "settings" // /api/v1/setting
+	ScopeActions  = "actions"  // /api/v1/actions，以及语音转写、常用搜索的修改接口
+)
+
+var Scopes = []string{ScopeRead, ScopeMedia, ScopeMCP, ScopeSettings, ScopeActions}
+
+// DefaultTokenScopes 为未指定权限范围时令牌的默认权限，只读
+var DefaultTokenScopes = []string{ScopeRead, ScopeMedia, ScopeMCP}
+
+const tokenPrefix = "chatlog_"
+
+// AuthConfig 为 HTTP 服务的访问控制配置
+// 未配置令牌时所有接口均可匿名访问，CORSOrigins 为空时允许任意来源
+type AuthConfig struct {
+	Tokens      []*APIToken `mapstructure:"tokens" json:"tokens"`
+	CORSOrigins []string    `mapstructure:"cors_origins" json:"cors_origins"`
+}
+
+// APIToken 为配置中保存的令牌，只保存令牌的 SHA-256，明文只在创建时显示一次
+type APIToken struct {
+	Name      string   `mapstructure:"name" json:"name"`
+	Hash      string   `mapstructure:"hash" json:"hash"`
+	Scopes    []string `mapstructure:"scopes" json:"scopes"`
+	CreatedAt string   `mapstructure:"created_at" json:"created_at"`
+}
+
+// Enabled 返回是否需要校验令牌
+func (c *AuthConfig) Enabled() bool {
+	return c != nil && len(c.Tokens) > 0
+}
+
+// Verify 返回与明文令牌匹配的配置项，不匹配时返回 nil
+func (c *AuthConfig) Verify(token string) *APIToken {
+	if c == nil || token == "" {
+		return nil
+	}
+	hash := []byte(HashToken(token))
+	var found *APIToken
+	for _, t := range c.Tokens {
+		// 遍历所有令牌，比较耗时与匹配位置无关
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			found = t
+		}
+	}
+	return found
+}
+
+// Authorize 校验令牌是否拥有 scope 权限，未配置令牌时不校验
+func (c *AuthConfig) Authorize(token, scope string) error {
+	if !c.Enabled() {
+		return nil
+	}
+	t := c.Verify(token)
+	if t == nil {
+		return errors.Unauthorized()
+	}
+	if !t.HasScope(scope) {
+		return errors.ScopeDenied(scope)
+	}
+	return nil
+}
+
+// mediaKey 为媒体链接签名的密钥，每次启动时随机生成，重启后已签发的签名失效
+var mediaKey = func() []byte {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+}()
+
+// SignMedia 签发 ttl 内有效的媒体链接签名，形如 <过期时间>.<HMAC>，只能用于访问 media 权限的接口
+// 用于在启用令牌校验时为输出中的媒体链接附加访问凭据，避免在链接中暴露令牌明文
+func SignMedia(ttl time.Duration) string {
+	exp := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return exp + "." + mediaMAC(exp)
+}
+
+// VerifyMedia 校验 SignMedia 签发的签名且未过期
+func VerifyMedia(sig string) bool {
+	exp, mac, ok := strings.Cut(sig, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(mediaMAC(exp)))
+}
+
+func mediaMAC(exp string) string {
+	h := hmac.New(sha256.New, mediaKey)
+	h.Write([]byte("media:" + exp))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Clone 返回副本，修改副本后整体替换，避免与正在处理请求的读取方竞争
+func (c *AuthConfig) Clone() *AuthConfig {
+	if c == nil {
+		return &AuthConfig{}
+	}
+	return &AuthConfig{
+		Tokens:      slices.Clone(c.Tokens),
+		CORSOrigins: slices.Clone(c.CORSOrigins),
+	}
+}
+
+// AllowOrigin 返回是否允许来自 origin 的跨域请求
+func (c *AuthConfig) AllowOrigin(origin string) bool {
+	if c == nil || len(c.CORSOrigins) == 0 {
+		return true
+	}
+	return slices.Contains(c.CORSOrigins, "*") || slices.Contains(c.CORSOrigins, origin)
+}
+
+// AllowAnyOrigin 返回是否允许任意来源
+func (c *AuthConfig) AllowAnyOrigin() bool {
+	return c == nil || len(c.CORSOrigins) == 0 || slices.Contains(c.CORSOrigins, "*")
+}
+
+// Token 按名称查找令牌
+func (c *AuthConfig) Token(name string) *APIToken {
+	if c == nil {
+		return nil
+	}
+	for _, t := range c.Tokens {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// AddToken 生成新令牌并加入配置，返回令牌明文
+func (c *AuthConfig) AddToken(name string, scopes []string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("token name is required")
+	}
+	if c.Token(name) != nil {
+		return "", fmt.Errorf("token %q already exists", name)
+	}
+	if len(scopes) == 0 {
+		scopes = DefaultTokenScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return "", fmt.Errorf("unknown scope %q, available: %s", scope, strings.Join(Scopes, ","))
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	c.Tokens = append(c.Tokens, &APIToken{
+		Name:      name,
+		Hash:      HashToken(token),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedAt: time.Now().Format(time.RFC3339),
+	})
+	return token, nil
+}
+
+// RemoveToken 按名称删除令牌，返回是否存在
+func (c *AuthConfig) RemoveToken(name string) bool {
+	if c == nil {
+		return false
+	}
+	n := len(c.Tokens)
+	c.Tokens = slices.DeleteFunc(c.Tokens, func(t *APIToken) bool { return t.Name == name })
+	return len(c.Tokens) != n
+}
+
+// HasScope 返回令牌是否拥有指定权限
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// HashToken 返回令牌明文的 SHA-256 十六进制串
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseScopes 解析逗号分隔的权限范围，all 表示全部权限
+func ParseScopes(s string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		switch scope {
+		case "":
+		case "all":
+			scopes = append(scopes, Scopes...)
+		default:
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// LoadAuthConfig 只读取配置文件中的 auth 配置，不受环境变量影响，便于修改后写回
+// server 为 true 时读取 server 模式的配置文件
+func LoadAuthConfig(configPath string, server bool) (*AuthConfig, *config.Manager, error) {
+	if configPath == "" {
+		configPath = os.Getenv(EnvConfigDir)
+	}
+	name := ""
+	if server {
+		name = ServerConfigName
+	}
+	cm, err := config.New(AppName, configPath, name, "", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	var c struct {
+		Auth *AuthConfig `mapstructure:"auth"`
+	}
+	if err := cm.Load(&c); err != nil {
+		return nil, nil, err
+	}
+	if c.Auth == nil {
+		c.Auth = &AuthConfig{}
+	}
+	return c.Auth, cm, nil
+}
+
+// SaveAuthConfig 将 auth 配置写回配置文件
+func SaveAuthConfig(cm *config.Manager, c *AuthConfig) error {
+	// 转为 map 后再写入，避免结构体值与文件中已有的 auth.* 键混在一起
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return cm.SetConfig("auth", m)
+}
@@ -0,0 +1,66 @@
+package conf
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/embedding"
+)
+
+// EmbeddingConfig 控制语义检索使用的向量模型，未启用时 /api/v1/search 只支持关键词检索
+type EmbeddingConfig struct {
+	Enabled               bool   `mapstructure:"enabled" json:"enabled"`
+	Provider              string `mapstructure:"provider" json:"provider"`
+	Model                 string `mapstructure:"model" json:"model"`
+	Dimensions            int    `mapstructure:"dimensions" json:"dimensions"`
+	APIKey                string `mapstructure:"api_key" json:"api_key"`
+	BaseURL               string `mapstructure:"base_url" json:"base_url"`
+	ServiceURL            string `mapstructure:"service_url" json:"service_url"`
+	BatchSize             int    `mapstructure:"batch_size" json:"batch_size"`
+	RequestTimeoutSeconds int    `mapstructure:"request_timeout_seconds" json:"request_timeout_seconds"`
+}
+
+// Normalize 统一 provider 名称并补全默认值
+func (c *EmbeddingConfig) Normalize() {
+	if c == nil {
+		return
+	}
+	c.Provider = strings.ToLower(strings.TrimSpace(c.Provider))
+	c.Model = strings.TrimSpace(c.Model)
+	c.APIKey = strings.TrimSpace(c.APIKey)
+	c.BaseURL = strings.TrimSpace(c.BaseURL)
+	c.ServiceURL = strings.TrimSpace(c.ServiceURL)
+
+	switch c.Provider {
+	case "http", "local", "webservice":
+		c.Provider = embedding.ProviderHTTP
+		if c.ServiceURL == "" {
+			c.ServiceURL = "http://127.0.0.1:9100/embed"
+		}
+	default:
+		c.Provider = embedding.ProviderOpenAI
+		if c.Model == "" {
+			c.Model = "text-embedding-3-small"
+		}
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 64
+	}
+}
+
+// ToConfig 转换为创建向量模型客户端的参数
+func (c *EmbeddingConfig) ToConfig() embedding.Config {
+	if c == nil {
+		return embedding.Config{}
+	}
+	return embedding.Config{
+		Provider:       c.Provider,
+		Model:          c.Model,
+		Dimensions:     c.Dimensions,
+		APIKey:         c.APIKey,
+		BaseURL:        c.BaseURL,
+		ServiceURL:     c.ServiceURL,
+		BatchSize:      c.BatchSize,
+		RequestTimeout: time.Duration(c.RequestTimeoutSeconds) * time.Second,
+	}
+}
@@ -5,18 +5,21 @@ const (
 )
 
 type ServerConfig struct {
-	Type        string        `mapstructure:"type"`
-	Platform    string        `mapstructure:"platform"`
-	Version     int           `mapstructure:"version"`
-	FullVersion string        `mapstructure:"full_version"`
-	DataDir     string        `mapstructure:"data_dir"`
-	DataKey     string        `mapstructure:"data_key"`
-	ImgKey      string        `mapstructure:"img_key"`
-	WorkDir     string        `mapstructure:"work_dir"`
-	HTTPAddr    string        `mapstructure:"http_addr"`
-	AutoDecrypt bool          `mapstructure:"auto_decrypt"`
-	Webhook     *Webhook      `mapstructure:"webhook"`
-	Speech      *SpeechConfig `mapstructure:"speech"`
+	Type        string           `mapstructure:"type"`
+	Platform    string           `mapstructure:"platform"`
+	Version     int              `mapstructure:"version"`
+	FullVersion string           `mapstructure:"full_version"`
+	DataDir     string           `mapstructure:"data_dir"`
+	DataKey     string           `mapstructure:"data_key"`
+	ImgKey      string           `mapstructure:"img_key"`
+	WorkDir     string           `mapstructure:"work_dir"`
+	HTTPAddr    string           `mapstructure:"http_addr"`
+	GRPCAddr    string           `mapstructure:"grpc_addr"`
+	AutoDecrypt bool             `mapstructure:"auto_decrypt"`
+	Webhook     *Webhook         `mapstructure:"webhook"`
+	Speech      *SpeechConfig    `mapstructure:"speech"`
+	Embedding   *EmbeddingConfig `mapstructure:"embedding"`
+	Auth        *AuthConfig      `mapstructure:"auth"`
 }
 
 var ServerDefaults = map[string]any{}
@@ -56,6 +59,11 @@ func (c *ServerConfig) GetHTTPAddr() string {
 	return c.HTTPAddr
 }
 
+// GetGRPCAddr 返回 gRPC 服务地址，为空时不启动 gRPC 服务
+func (c *ServerConfig) GetGRPCAddr() string {
+	return c.GRPCAddr
+}
+
 func (c *ServerConfig) GetWebhook() *Webhook {
 	return c.Webhook
 }
@@ -64,6 +72,14 @@ func (c *ServerConfig) GetSpeech() *SpeechConfig {
 	return c.Speech
 }
 
+func (c *ServerConfig) GetEmbedding() *EmbeddingConfig {
+	return c.Embedding
+}
+
+func (c *ServerConfig) GetAuth() *AuthConfig {
+	return c.Auth
+}
+
 func (c *ServerConfig) SetHTTPAddr(addr string) {
 	c.HTTPAddr = addr
 }
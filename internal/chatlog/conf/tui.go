@@ -1,10 +1,12 @@
 package conf
 
 type TUIConfig struct {
-	ConfigDir   string          `mapstructure:"-" json:"config_dir"`
-	LastAccount string          `mapstructure:"last_account" json:"last_account"`
-	History     []ProcessConfig `mapstructure:"history" json:"history"`
-	Webhook     *Webhook        `mapstructure:"webhook" json:"webhook"`
+	ConfigDir   string           `mapstructure:"-" json:"config_dir"`
+	LastAccount string           `mapstructure:"last_account" json:"last_account"`
+	History     []ProcessConfig  `mapstructure:"history" json:"history"`
+	Webhook     *Webhook         `mapstructure:"webhook" json:"webhook"`
+	Embedding   *EmbeddingConfig `mapstructure:"embedding" json:"embedding"`
+	Auth        *AuthConfig      `mapstructure:"auth" json:"auth"`
 }
 
 var TUIDefaults = map[string]any{}
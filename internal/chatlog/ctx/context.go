@@ -271,6 +271,31 @@ func (c *Context) GetSpeech() *conf.SpeechConfig {
 	return c.speech
 }
 
+func (c *Context) GetEmbedding() *conf.EmbeddingConfig {
+	return c.conf.Embedding
+}
+
+func (c *Context) GetAuth() *conf.AuthConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conf.Auth
+}
+
+// SaveAuthConfig 保存令牌与跨域配置，HTTP 服务从下一个请求起生效
+func (c *Context) SaveAuthConfig(auth *conf.AuthConfig) error {
+	if c.cm == nil {
+		return errors.New("config manager unavailable")
+	}
+	if err := conf.SaveAuthConfig(c.cm, auth); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conf.Auth = auth
+	return nil
+}
+
 func (c *Context) SetHTTPEnabled(enabled bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
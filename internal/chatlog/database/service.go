@@ -7,7 +7,9 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/ysy950803/chatlog/internal/chatlog/conf"
+	"github.com/ysy950803/chatlog/internal/chatlog/events"
 	"github.com/ysy950803/chatlog/internal/chatlog/webhook"
+	"github.com/ysy950803/chatlog/internal/embedding"
 	"github.com/ysy950803/chatlog/internal/errors"
 	"github.com/ysy950803/chatlog/internal/model"
 	"github.com/ysy950803/chatlog/internal/wechatdb"
@@ -27,6 +29,9 @@ type Service struct {
 	db            *wechatdb.DB
 	webhook       *webhook.Service
 	webhookCancel context.CancelFunc
+
+	// 新消息推送，与 webhook 共用消息库的回调
+	events *events.Hub
 }
 
 type Config interface {
@@ -34,6 +39,7 @@ type Config interface {
 	GetPlatform() string
 	GetVersion() int
 	GetWebhook() *conf.Webhook
+	GetEmbedding() *conf.EmbeddingConfig
 }
 
 func NewService(conf Config) *Service {
@@ -44,16 +50,35 @@ func NewService(conf Config) *Service {
 }
 
 func (s *Service) Start() error {
-	db, err := wechatdb.New(s.conf.GetWorkDir(), s.conf.GetPlatform(), s.conf.GetVersion())
+	db, err := wechatdb.NewWithOptions(s.conf.GetWorkDir(), s.conf.GetPlatform(), s.conf.GetVersion(), wechatdb.Options{
+		Embedder: s.newEmbedder(),
+	})
 	if err != nil {
 		return err
 	}
 	s.SetReady()
 	s.db = db
+	s.events = events.NewHub(db)
 	s.initWebhook()
 	return nil
 }
 
+// newEmbedder 按配置创建语义检索的向量模型，未启用或配置有误时返回 nil
+func (s *Service) newEmbedder() embedding.Provider {
+	cfg := s.conf.GetEmbedding()
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	cfg.Normalize()
+	provider, err := embedding.New(cfg.ToConfig())
+	if err != nil {
+		log.Err(err).Msg("initialise embedding provider failed")
+		return nil
+	}
+	log.Info().Str("model", provider.Name()).Msg("semantic search enabled")
+	return provider
+}
+
 func (s *Service) Stop() error {
 	if s.db != nil {
 		s.db.Close()
@@ -64,6 +89,10 @@ func (s *Service) Stop() error {
 		s.webhookCancel()
 		s.webhookCancel = nil
 	}
+	if s.events != nil {
+		s.events.Close()
+		s.events = nil
+	}
 	return nil
 }
 
@@ -88,6 +117,11 @@ func (s *Service) GetDB() *wechatdb.DB {
 	return s.db
 }
 
+// Events 返回新消息推送，数据库未就绪时返回 nil
+func (s *Service) Events() *events.Hub {
+	return s.events
+}
+
 // GetWorkDir exposes the underlying work directory where decrypted DB files are stored.
 // This is useful for higher layers (HTTP) to compute DB sizes for summary statistics.
 func (s *Service) GetWorkDir() string {
@@ -97,8 +131,20 @@ func (s *Service) GetWorkDir() string {
 	return s.conf.GetWorkDir()
 }
 
-func (s *Service) GetMessages(start, end time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
-	return s.db.GetMessages(start, end, talker, sender, keyword, limit, offset)
+func (s *Service) GetMessages(start, end time.Time, talker string, sender string, keyword string, msgType string, limit, offset int) ([]*model.Message, error) {
+	return s.db.GetMessages(start, end, talker, sender, keyword, msgType, limit, offset)
+}
+
+func (s *Service) StreamMessages(ctx context.Context, start, end time.Time, talker, sender, keyword, msgType string, cursor model.MessageCursor, limit int, handler func(*model.Message) error) (*model.MessageCursor, error) {
+	return s.db.StreamMessages(ctx, start, end, talker, sender, keyword, msgType, cursor, limit, handler)
+}
+
+func (s *Service) GetMessageContext(talker string, seq int64, before, after int) (*model.MessageContext, error) {
+	return s.db.GetMessageContext(talker, seq, before, after)
+}
+
+func (s *Service) GetReplyThread(talker string, seq int64, window time.Duration) (*model.ReplyThread, error) {
+	return s.db.GetReplyThread(talker, seq, window)
 }
 
 func (s *Service) SearchMessages(req *model.SearchRequest) (*model.SearchResponse, error) {
@@ -112,10 +158,22 @@ func (s *Service) GetContacts(key string, limit, offset int) (*wechatdb.GetConta
 	return s.db.GetContacts(key, limit, offset)
 }
 
+func (s *Service) GetContact(key string) (*model.Contact, error) {
+	return s.db.GetContact(key)
+}
+
+func (s *Service) GetChatRoom(key string) (*model.ChatRoom, error) {
+	return s.db.GetChatRoom(key)
+}
+
 func (s *Service) GetChatRooms(key string, limit, offset int) (*wechatdb.GetChatRoomsResp, error) {
 	return s.db.GetChatRooms(key, limit, offset)
 }
 
+func (s *Service) ResolveTalker(name string, limit int, all bool) (*model.TalkerResolution, error) {
+	return s.db.ResolveTalker(name, limit, all)
+}
+
 // GetSession retrieves session information
 func (s *Service) GetSessions(key string, limit, offset int) (*wechatdb.GetSessionsResp, error) {
 	return s.db.GetSessions(key, limit, offset)
@@ -136,6 +194,13 @@ func (s *Service) initWebhook() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	s.webhookCancel = cancel
 	hooks := s.webhook.GetHooks(ctx, s.db)
+	if s.events != nil {
+		var delayMs int64
+		if cfg := s.conf.GetWebhook(); cfg != nil {
+			delayMs = cfg.DelayMs
+		}
+		hooks = append(hooks, webhook.NewGroup(ctx, "message", []webhook.Webhook{s.events}, delayMs))
+	}
 	for _, hook := range hooks {
 		log.Info().Msgf("set callback %#v", hook)
 		if err := s.db.SetCallback(hook.Group(), hook.Callback); err != nil {
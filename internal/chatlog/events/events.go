@@ -0,0 +1,319 @@
+// Package events 检测新到达的消息并推送给订阅者，供 SSE、WebSocket 等长连接使用
+//
+// Hub 实现 webhook.Webhook，与 webhook 共用消息库的 fsnotify 回调：
+// 每次回调按会话从上次推送的序号之后读取新消息，按 (time, seq) 排序后分发给各订阅者
+package events
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+
+	cerrors "github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb"
+	"github.com/ysy950803/chatlog/pkg/util"
+)
+
+// subscriptionBuffer 为每个订阅者缓存的消息条数，写满时断开该订阅者，由客户端带上最后的事件 ID 重连补齐
+const subscriptionBuffer = 256
+
+// lookahead 为读取新消息时结束时间相对当前时间的余量，与 webhook 一致
+const lookahead = 10 * time.Minute
+
+// activeWindow 为检测新消息时查看的会话范围：最近会话时间在此之内的会话
+// 会话库可能晚于消息库更新，不能只看最近会话时间晚于上次检测的会话
+const activeWindow = 24 * time.Hour
+
+// Backfill 的限制：只补发 maxBackfillAge 之内、不超过 maxBackfill 条的消息
+const (
+	maxBackfillAge = 24 * time.Hour
+	maxBackfill    = 1000
+)
+
+// errBackfillFull 在补发消息超过 maxBackfill 时中止读取
+var errBackfillFull = errors.New("backfill limit exceeded")
+
+// Filter 为订阅条件，各字段为空时不过滤
+type Filter struct {
+	Talkers map[string]struct{}
+	Senders map[string]struct{}
+	Types   *model.MessageTypeFilter
+	Keyword *regexp.Regexp
+}
+
+// NewFilter 创建订阅条件，talker/sender 为逗号分隔的 userName（名称需先经 ResolveTalkerAndSender 解析）
+func NewFilter(talker, sender, msgType, keyword string) (*Filter, error) {
+	f := &Filter{
+		Talkers: toSet(util.Str2List(talker, ",")),
+		Senders: toSet(util.Str2List(sender, ",")),
+	}
+	types, err := model.ParseMessageTypeFilter(msgType)
+	if err != nil {
+		return nil, cerrors.InvalidMessageType(err)
+	}
+	f.Types = types
+	if keyword != "" {
+		if f.Keyword, err = regexp.Compile(keyword); err != nil {
+			return nil, cerrors.InvalidArg("keyword")
+		}
+	}
+	return f, nil
+}
+
+func toSet(items []string) map[string]struct{} {
+	if len(items) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		set[item] = struct{}{}
+	}
+	return set
+}
+
+// Match 判断消息是否满足订阅条件
+func (f *Filter) Match(m *model.Message) bool {
+	if f == nil {
+		return true
+	}
+	if f.Talkers != nil {
+		if _, ok := f.Talkers[m.Talker]; !ok {
+			return false
+		}
+	}
+	if f.Senders != nil {
+		if _, ok := f.Senders[m.Sender]; !ok {
+			return false
+		}
+	}
+	if f.Types != nil && !f.Types.Match(m.Type, m.SubType) {
+		return false
+	}
+	return f.Keyword == nil || f.Keyword.MatchString(m.PlainTextContent())
+}
+
+// Subscription 为一个订阅者，C 关闭表示订阅已结束（Hub 关闭或消息积压）
+type Subscription struct {
+	hub    *Hub
+	filter *Filter
+	ch     chan *model.Message
+	once   sync.Once
+}
+
+// C 返回新消息通道
+func (s *Subscription) C() <-chan *model.Message {
+	return s.ch
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub 检测新消息并分发给订阅者
+type Hub struct {
+	db *wechatdb.DB
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+
+	// detectMu 保证同一时间只有一次检测，since/seen 只在检测中读写
+	detectMu sync.Mutex
+	since    time.Time
+	seen     map[string]model.MessageMarker
+}
+
+// NewHub 创建 Hub，只推送创建之后到达的消息
+func NewHub(db *wechatdb.DB) *Hub {
+	return &Hub{
+		db:    db,
+		subs:  make(map[*Subscription]struct{}),
+		since: time.Now(),
+		seen:  make(map[string]model.MessageMarker),
+	}
+}
+
+// Subscribe 添加订阅者
+func (h *Hub) Subscribe(filter *Filter) *Subscription {
+	s := &Subscription{hub: h, filter: filter, ch: make(chan *model.Message, subscriptionBuffer)}
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, s)
+	h.mu.Unlock()
+	s.once.Do(func() { close(s.ch) })
+}
+
+// Close 结束全部订阅
+func (h *Hub) Close() {
+	h.mu.Lock()
+	subs := make([]*Subscription, 0, len(h.subs))
+	for s := range h.subs {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+	for _, s := range subs {
+		s.Close()
+	}
+}
+
+func (h *Hub) subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Do 实现 webhook.Webhook，在消息库变化时读取新消息并分发
+func (h *Hub) Do(event fsnotify.Event) {
+	h.detectMu.Lock()
+	defer h.detectMu.Unlock()
+
+	// 没有订阅者时不读取，之后订阅的客户端需要更早的消息时通过 Backfill 读取
+	if h.subscribers() == 0 {
+		h.since = time.Now()
+		h.seen = make(map[string]model.MessageMarker)
+		return
+	}
+
+	messages, err := h.detect()
+	if err != nil {
+		log.Debug().Err(err).Msg("detect new messages failed")
+	}
+	if len(messages) == 0 {
+		return
+	}
+	sortMessages(messages)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		for _, m := range messages {
+			if !s.filter.Match(m) {
+				continue
+			}
+			select {
+			case s.ch <- m:
+			default:
+				log.Debug().Msg("event subscriber lagging behind, disconnect")
+				delete(h.subs, s)
+				s.once.Do(func() { close(s.ch) })
+			}
+			if _, ok := h.subs[s]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// detect 读取各会话上次推送之后的消息
+// 每个会话从上次推送的最后一条消息的序号之后流式读取，没有新消息的会话只需一次按序号的索引查询
+func (h *Hub) detect() ([]*model.Message, error) {
+	sessions, err := h.db.GetSessions("", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*model.Message
+	end := time.Now().Add(lookahead)
+	for _, sess := range sessions.Items {
+		last, ok := h.seen[sess.UserName]
+		if !ok && sess.NTime.Before(h.since.Add(-activeWindow)) {
+			continue
+		}
+		start, cursor := h.since, model.MessageCursor{}
+		if ok {
+			start, cursor = time.Unix(last.Unix, 0), model.MessageCursor{Seq: last.Seq}
+		}
+		_, err := h.db.StreamMessages(context.Background(), start, end, sess.UserName, "", "", "", cursor, 0, func(m *model.Message) error {
+			marker := model.MarkerOf(m)
+			if ok && !last.Before(marker) {
+				return nil
+			}
+			ret = append(ret, m)
+			last, ok = marker, true
+			return nil
+		})
+		if err != nil {
+			log.Debug().Err(err).Str("talker", sess.UserName).Msg("read new messages failed")
+		}
+		if ok {
+			h.seen[sess.UserName] = last
+		}
+	}
+	return ret, nil
+}
+
+// Backfill 读取位置 after 之后满足订阅条件的消息，用于客户端断线后按最后的事件 ID 补齐
+// 位置早于 maxBackfillAge 或之后的消息超过 maxBackfill 条时返回 ResyncRequired，由客户端重新拉取历史后不带事件 ID 订阅
+func (h *Hub) Backfill(ctx context.Context, after model.MessageMarker, filter *Filter) ([]*model.Message, error) {
+	start := time.Unix(after.Unix, 0)
+	if start.Before(time.Now().Add(-maxBackfillAge)) {
+		return nil, cerrors.ResyncRequired()
+	}
+
+	var talkers []string
+	if filter != nil && filter.Talkers != nil {
+		for talker := range filter.Talkers {
+			talkers = append(talkers, talker)
+		}
+	} else {
+		sessions, err := h.db.GetSessions("", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, sess := range sessions.Items {
+			if !sess.NTime.Before(start.Add(-activeWindow)) {
+				talkers = append(talkers, sess.UserName)
+			}
+		}
+	}
+
+	// 逐个会话按序号流式读取，只保留满足条件的消息，超过上限即停止
+	var ret []*model.Message
+	end := time.Now().Add(lookahead)
+	for _, talker := range talkers {
+		_, err := h.db.StreamMessages(ctx, start, end, talker, "", "", "", model.MessageCursor{}, 0, func(m *model.Message) error {
+			if !after.Before(model.MarkerOf(m)) || !filter.Match(m) {
+				return nil
+			}
+			if len(ret) >= maxBackfill {
+				return errBackfillFull
+			}
+			ret = append(ret, m)
+			return nil
+		})
+		if errors.Is(err, errBackfillFull) {
+			return nil, cerrors.ResyncRequired()
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Debug().Err(err).Str("talker", talker).Msg("backfill messages failed")
+		}
+	}
+	sortMessages(ret)
+	return ret, nil
+}
+
+// sortMessages 按 (time, seq, talker) 排序
+func sortMessages(messages []*model.Message) {
+	sort.SliceStable(messages, func(i, j int) bool {
+		a, b := model.MarkerOf(messages[i]), model.MarkerOf(messages[j])
+		if a != b {
+			return a.Before(b)
+		}
+		return messages[i].Talker < messages[j].Talker
+	})
+}
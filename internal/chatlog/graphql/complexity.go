@@ -0,0 +1,121 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// MaxComplexity 为单次查询允许的最大代价
+// 每个字段计 1，列表字段按 limit 参数（未指定时为默认条数）乘以子字段代价，例如
+// sessions(limit: 20) { contact { nickName } messages(limit: 10) { content } } 的代价为 20 × (1 + 2 + 10 × 2) = 460
+const MaxComplexity = 5000
+
+// Complexity 估算文档中要执行的操作的代价，operationName 为空时取唯一的操作
+// 文档须已通过校验；内省字段（__schema、__type 等）不计入代价
+func Complexity(doc *ast.Document, operationName string, variables map[string]any) int {
+	var op *ast.OperationDefinition
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				op = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if op == nil {
+		return 0
+	}
+
+	vars := make(map[string]any, len(op.VariableDefinitions))
+	for _, def := range op.VariableDefinitions {
+		if def.DefaultValue != nil {
+			vars[def.Variable.Name.Value] = def.DefaultValue
+		}
+	}
+	for k, v := range variables {
+		vars[k] = v
+	}
+
+	c := &complexity{fragments: fragments, vars: vars, visiting: make(map[string]bool)}
+	return c.selectionSet(op.SelectionSet)
+}
+
+type complexity struct {
+	fragments map[string]*ast.FragmentDefinition
+	vars      map[string]any
+	visiting  map[string]bool
+}
+
+func (c *complexity) selectionSet(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	total := 0
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			total += c.field(sel)
+		case *ast.InlineFragment:
+			total += c.selectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			frag, ok := c.fragments[name]
+			if !ok || c.visiting[name] {
+				continue
+			}
+			c.visiting[name] = true
+			total += c.selectionSet(frag.SelectionSet)
+			delete(c.visiting, name)
+		}
+		// 超过上限后不再精确累加，避免大 limit 嵌套时溢出
+		if total > MaxComplexity {
+			return MaxComplexity + 1
+		}
+	}
+	return total
+}
+
+func (c *complexity) field(f *ast.Field) int {
+	name := f.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return 0
+	}
+	cost := 1 + c.selectionSet(f.SelectionSet)
+	bound, ok := listLimits[name]
+	if !ok {
+		return cost
+	}
+	limit := bound.def
+	for _, arg := range f.Arguments {
+		if arg.Name.Value == "limit" {
+			if n, ok := c.intValue(arg.Value); ok && n > 0 {
+				limit = n
+			}
+		}
+	}
+	return min(limit, bound.max) * cost
+}
+
+// intValue 读取整数参数，参数可以是字面量或变量
+func (c *complexity) intValue(v ast.Value) (int, bool) {
+	switch v := v.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch val := c.vars[v.Name.Value].(type) {
+		case ast.Value:
+			return c.intValue(val)
+		case int:
+			return val, true
+		case float64:
+			return int(val), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,37 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestComplexity(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]any
+		want  int
+	}{
+		{"scalar", `{ contact(key: "a") { nickName } }`, nil, 2},
+		{"default limit", `{ sessions { userName } }`, nil, 20 * 2},
+		{"nested", `{ sessions(limit: 20) { contact { nickName } messages(limit: 10) { content } } }`, nil, 20 * (1 + 2 + 10*2)},
+		{"clamped", `{ contacts(limit: 100000) { userName } }`, nil, 200 * 2},
+		{"variable", `query q($n: Int) { contacts(limit: $n) { userName } }`, map[string]any{"n": float64(5)}, 5 * 2},
+		{"variable default", `query q($n: Int = 3) { contacts(limit: $n) { userName } }`, nil, 3 * 2},
+		{"fragment", `{ chatRooms(limit: 2) { ...room } } fragment room on ChatRoom { name users(limit: 4) { userName } }`, nil, 2 * (1 + 1 + 4*2)},
+		{"introspection", `{ __schema { types { name } } }`, nil, 0},
+		{"over limit", `{ sessions(limit: 200) { messages(limit: 500) { content } } }`, nil, MaxComplexity + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := Complexity(doc, "", tt.vars); got != tt.want {
+				t.Errorf("Complexity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
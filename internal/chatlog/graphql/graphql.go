@@ -0,0 +1,65 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request 为 GraphQL 请求
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Executor 执行 GraphQL 查询
+type Executor struct {
+	schema graphql.Schema
+}
+
+func NewExecutor(db DB) (*Executor, error) {
+	schema, err := NewSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{schema: schema}, nil
+}
+
+// Execute 解析、校验并执行查询，代价超过 MaxComplexity 的查询不执行
+// 结果的 extensions.complexity 为估算的查询代价
+func (e *Executor) Execute(ctx context.Context, req *Request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if vr := graphql.ValidateDocument(&e.schema, doc, nil); !vr.IsValid {
+		return &graphql.Result{Errors: vr.Errors}
+	}
+
+	complexity := Complexity(doc, req.OperationName, req.Variables)
+	if complexity > MaxComplexity {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{
+			gqlerrors.NewFormattedError(fmt.Sprintf("query complexity exceeds limit %d, reduce limit arguments or nested list fields", MaxComplexity)),
+		}}
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	if result.Extensions == nil {
+		result.Extensions = make(map[string]any)
+	}
+	result.Extensions["complexity"] = complexity
+	return result
+}
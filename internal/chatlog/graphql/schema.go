@@ -0,0 +1,370 @@
+// Package graphql 提供联系人、群聊、会话与消息的 GraphQL 查询
+//
+// 会话、联系人、群聊之间的关联字段从仓库的联系人/群聊缓存中解析，一次请求即可取得会话、对方信息、群成员与最近消息；
+// 执行前按 complexity 估算查询代价，超过上限的查询直接拒绝
+package graphql
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb"
+	"github.com/ysy950803/chatlog/pkg/util"
+)
+
+// DB 为解析字段所需的数据接口，由 database.Service 实现
+type DB interface {
+	GetContact(key string) (*model.Contact, error)
+	GetContacts(key string, limit, offset int) (*wechatdb.GetContactsResp, error)
+	GetChatRoom(key string) (*model.ChatRoom, error)
+	GetChatRooms(key string, limit, offset int) (*wechatdb.GetChatRoomsResp, error)
+	GetSessions(key string, limit, offset int) (*wechatdb.GetSessionsResp, error)
+	GetMessages(start, end time.Time, talker string, sender string, keyword string, msgType string, limit, offset int) ([]*model.Message, error)
+}
+
+// 列表字段的默认条数，列表字段的 limit 参数同时用于估算查询代价
+const (
+	defaultListLimit     = 20
+	defaultMessageLimit  = 20
+	defaultUserLimit     = 50
+	defaultMessagesRange = "last-7d"
+)
+
+// listLimits 为列表字段的默认条数与最大条数，按字段名区分
+var listLimits = map[string]struct{ def, max int }{
+	"sessions":  {defaultListLimit, 200},
+	"contacts":  {defaultListLimit, 200},
+	"chatRooms": {defaultListLimit, 200},
+	"messages":  {defaultMessageLimit, 500},
+	"users":     {defaultUserLimit, 500},
+}
+
+func listArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"keyword": {Type: graphql.String, DefaultValue: ""},
+		"limit":   {Type: graphql.Int, DefaultValue: defaultListLimit},
+		"offset":  {Type: graphql.Int, DefaultValue: 0},
+	}
+}
+
+func messagesArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"range":   {Type: graphql.String, DefaultValue: defaultMessagesRange, Description: "时间范围，写法与 chatlog 接口的 time 参数相同"},
+		"sender":  {Type: graphql.String, DefaultValue: ""},
+		"keyword": {Type: graphql.String, DefaultValue: ""},
+		"type":    {Type: graphql.String, DefaultValue: ""},
+		"limit":   {Type: graphql.Int, DefaultValue: defaultMessageLimit},
+		"offset":  {Type: graphql.Int, DefaultValue: 0},
+	}
+}
+
+// limitArg 读取 limit 参数并限制在字段允许的范围内
+func limitArg(p graphql.ResolveParams) int {
+	bound := listLimits[p.Info.FieldName]
+	limit, _ := p.Args["limit"].(int)
+	if limit <= 0 {
+		limit = bound.def
+	}
+	return min(limit, bound.max)
+}
+
+func offsetArg(p graphql.ResolveParams) int {
+	offset, _ := p.Args["offset"].(int)
+	return max(offset, 0)
+}
+
+func stringArg(p graphql.ResolveParams, name string) string {
+	s, _ := p.Args[name].(string)
+	return strings.TrimSpace(s)
+}
+
+// NewSchema 创建 GraphQL schema
+func NewSchema(db DB) (graphql.Schema, error) {
+	r := &resolver{db: db}
+
+	contact := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Contact",
+		Fields: graphql.Fields{
+			"userName":  {Type: graphql.NewNonNull(graphql.String)},
+			"alias":     {Type: graphql.String},
+			"remark":    {Type: graphql.String},
+			"nickName":  {Type: graphql.String},
+			"isFriend":  {Type: graphql.Boolean},
+			"avatarUrl": {Type: graphql.String},
+		},
+	})
+	chatRoomUser := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ChatRoomUser",
+		Fields: graphql.Fields{
+			"userName":    {Type: graphql.NewNonNull(graphql.String)},
+			"displayName": {Type: graphql.String},
+			"contact":     {Type: contact, Resolve: r.chatRoomUserContact},
+		},
+	})
+	chatRoom := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ChatRoom",
+		Fields: graphql.Fields{
+			"name":     {Type: graphql.NewNonNull(graphql.String)},
+			"owner":    {Type: graphql.String},
+			"remark":   {Type: graphql.String},
+			"nickName": {Type: graphql.String},
+			"users": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(chatRoomUser))),
+				Args: graphql.FieldConfigArgument{
+					"limit":  {Type: graphql.Int, DefaultValue: defaultUserLimit},
+					"offset": {Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: r.chatRoomUsers,
+			},
+		},
+	})
+	message := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Message",
+		Fields: graphql.Fields{
+			"seq":        {Type: graphql.NewNonNull(graphql.Float), Description: "消息序号，13 位"},
+			"time":       {Type: graphql.NewNonNull(graphql.String), Resolve: fieldOf(func(m *model.Message) any { return m.Time.Format(time.RFC3339) })},
+			"talker":     {Type: graphql.NewNonNull(graphql.String)},
+			"talkerName": {Type: graphql.String},
+			"isChatRoom": {Type: graphql.Boolean},
+			"sender":     {Type: contact, Resolve: r.messageSender},
+			"senderName": {Type: graphql.String},
+			"isSelf":     {Type: graphql.Boolean},
+			"type":       {Type: graphql.Int},
+			"subType":    {Type: graphql.Int},
+			"content":    {Type: graphql.String},
+			"contents":   {Type: graphql.String, Description: "结构化内容的 JSON，与 HTTP 接口中的 contents 相同", Resolve: fieldOf(messageContents)},
+			"permalink":  {Type: graphql.String},
+		},
+	})
+	messages := &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(message))),
+		Args:    messagesArgs(),
+		Resolve: r.talkerMessages,
+	}
+	contact.AddFieldConfig("messages", messages)
+	chatRoom.AddFieldConfig("messages", messages)
+
+	session := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Session",
+		Fields: graphql.Fields{
+			"userName": {Type: graphql.NewNonNull(graphql.String)},
+			"nOrder":   {Type: graphql.Int},
+			"nickName": {Type: graphql.String},
+			"content":  {Type: graphql.String},
+			"nTime":    {Type: graphql.String, Resolve: fieldOf(func(s *model.Session) any { return s.NTime.Format(time.RFC3339) })},
+			"contact":  {Type: contact, Resolve: r.sessionContact},
+			"chatRoom": {Type: chatRoom, Resolve: r.sessionChatRoom},
+			"messages": messages,
+		},
+	})
+
+	queryMessagesArgs := messagesArgs()
+	queryMessagesArgs["talker"] = &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"sessions": {
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(session))),
+				Args:    listArgs(),
+				Resolve: r.sessions,
+			},
+			"contacts": {
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(contact))),
+				Args:    listArgs(),
+				Resolve: r.contacts,
+			},
+			"chatRooms": {
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(chatRoom))),
+				Args:    listArgs(),
+				Resolve: r.chatRooms,
+			},
+			"contact": {
+				Type:    contact,
+				Args:    graphql.FieldConfigArgument{"key": {Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: r.contact,
+			},
+			"chatRoom": {
+				Type:    chatRoom,
+				Args:    graphql.FieldConfigArgument{"key": {Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: r.chatRoom,
+			},
+			"messages": {
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(message))),
+				Args:    queryMessagesArgs,
+				Resolve: r.talkerMessages,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// fieldOf 将字段值的计算包装为 resolve 函数
+func fieldOf[T any](fn func(T) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		v, ok := p.Source.(T)
+		if !ok {
+			return nil, nil
+		}
+		return fn(v), nil
+	}
+}
+
+func messageContents(m *model.Message) any {
+	if len(m.Contents) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(m.Contents)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+type resolver struct {
+	db DB
+}
+
+func (r *resolver) sessions(p graphql.ResolveParams) (any, error) {
+	resp, err := r.db.GetSessions(stringArg(p, "keyword"), limitArg(p), offsetArg(p))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+func (r *resolver) contacts(p graphql.ResolveParams) (any, error) {
+	resp, err := r.db.GetContacts(stringArg(p, "keyword"), limitArg(p), offsetArg(p))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+func (r *resolver) chatRooms(p graphql.ResolveParams) (any, error) {
+	resp, err := r.db.GetChatRooms(stringArg(p, "keyword"), limitArg(p), offsetArg(p))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+func (r *resolver) contact(p graphql.ResolveParams) (any, error) {
+	return nilIfMissing(r.findContact(stringArg(p, "key"))), nil
+}
+
+func (r *resolver) chatRoom(p graphql.ResolveParams) (any, error) {
+	return nilIfMissing(r.findChatRoom(stringArg(p, "key"))), nil
+}
+
+// nilIfMissing 避免将 nil 指针作为非空 interface 返回，使字段解析为 null
+func nilIfMissing[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return v
+}
+
+// findContact 从联系人缓存中查找，找不到时返回 nil，字段解析为 null
+func (r *resolver) findContact(key string) *model.Contact {
+	if key == "" {
+		return nil
+	}
+	c, err := r.db.GetContact(key)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+func (r *resolver) findChatRoom(key string) *model.ChatRoom {
+	if key == "" {
+		return nil
+	}
+	c, err := r.db.GetChatRoom(key)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+func (r *resolver) sessionContact(p graphql.ResolveParams) (any, error) {
+	sess, _ := p.Source.(*model.Session)
+	if sess == nil {
+		return nil, nil
+	}
+	return nilIfMissing(r.findContact(sess.UserName)), nil
+}
+
+func (r *resolver) sessionChatRoom(p graphql.ResolveParams) (any, error) {
+	sess, _ := p.Source.(*model.Session)
+	if sess == nil || !strings.HasSuffix(sess.UserName, "@chatroom") {
+		return nil, nil
+	}
+	return nilIfMissing(r.findChatRoom(sess.UserName)), nil
+}
+
+func (r *resolver) chatRoomUsers(p graphql.ResolveParams) (any, error) {
+	room, _ := p.Source.(*model.ChatRoom)
+	if room == nil {
+		return []*model.ChatRoomUser{}, nil
+	}
+	offset := min(offsetArg(p), len(room.Users))
+	end := min(offset+limitArg(p), len(room.Users))
+	users := make([]*model.ChatRoomUser, 0, end-offset)
+	for i := offset; i < end; i++ {
+		users = append(users, &room.Users[i])
+	}
+	return users, nil
+}
+
+// chatRoomUserContact 群成员不一定是好友，不在联系人缓存中时以群昵称补全
+func (r *resolver) chatRoomUserContact(p graphql.ResolveParams) (any, error) {
+	u, _ := p.Source.(*model.ChatRoomUser)
+	if u == nil {
+		return nil, nil
+	}
+	if c := r.findContact(u.UserName); c != nil {
+		return c, nil
+	}
+	return &model.Contact{UserName: u.UserName, NickName: u.DisplayName}, nil
+}
+
+// messageSender 发送者不在联系人缓存中时（如非好友的群成员）以消息中的发送者名称补全
+func (r *resolver) messageSender(p graphql.ResolveParams) (any, error) {
+	m, _ := p.Source.(*model.Message)
+	if m == nil || m.Sender == "" {
+		return nil, nil
+	}
+	if c := r.findContact(m.Sender); c != nil {
+		return c, nil
+	}
+	return &model.Contact{UserName: m.Sender, NickName: m.SenderName}, nil
+}
+
+// talkerMessages 解析 Query.messages 以及 Contact、ChatRoom、Session 上的 messages 字段
+func (r *resolver) talkerMessages(p graphql.ResolveParams) (any, error) {
+	talker := stringArg(p, "talker")
+	switch src := p.Source.(type) {
+	case *model.Contact:
+		talker = src.UserName
+	case *model.ChatRoom:
+		talker = src.Name
+	case *model.Session:
+		talker = src.UserName
+	}
+	if talker == "" {
+		return []*model.Message{}, nil
+	}
+	start, end, ok := util.TimeRangeOf(stringArg(p, "range"))
+	if !ok {
+		return nil, errors.InvalidArg("range")
+	}
+	return r.db.GetMessages(start, end, talker, stringArg(p, "sender"), stringArg(p, "keyword"), stringArg(p, "type"), limitArg(p), offsetArg(p))
+}
@@ -0,0 +1,253 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/pkg/chatlogpb"
+	"github.com/ysy950803/chatlog/pkg/util"
+	"github.com/ysy950803/chatlog/pkg/util/dat2img"
+	"github.com/ysy950803/chatlog/pkg/util/silk"
+)
+
+// mediaChunkSize 为 GetMedia 每块的大小，小于 gRPC 默认的 4MB 消息上限
+const mediaChunkSize = 1 << 20
+
+func (s *Service) GetContacts(ctx context.Context, req *chatlogpb.ListRequest) (*chatlogpb.ContactList, error) {
+	resp, err := s.db.GetContacts(strings.TrimSpace(req.Keyword), int(req.Limit), int(req.Offset))
+	if err != nil {
+		return nil, err
+	}
+	ret := &chatlogpb.ContactList{Items: make([]*chatlogpb.Contact, 0, len(resp.Items))}
+	for _, c := range resp.Items {
+		ret.Items = append(ret.Items, &chatlogpb.Contact{
+			UserName: c.UserName,
+			Alias:    c.Alias,
+			Remark:   c.Remark,
+			NickName: c.NickName,
+			IsFriend: c.IsFriend,
+		})
+	}
+	return ret, nil
+}
+
+func (s *Service) GetChatRooms(ctx context.Context, req *chatlogpb.ListRequest) (*chatlogpb.ChatRoomList, error) {
+	resp, err := s.db.GetChatRooms(strings.TrimSpace(req.Keyword), int(req.Limit), int(req.Offset))
+	if err != nil {
+		return nil, err
+	}
+	ret := &chatlogpb.ChatRoomList{Items: make([]*chatlogpb.ChatRoom, 0, len(resp.Items))}
+	for _, c := range resp.Items {
+		room := &chatlogpb.ChatRoom{
+			Name:     c.Name,
+			Owner:    c.Owner,
+			Remark:   c.Remark,
+			NickName: c.NickName,
+			Users:    make([]*chatlogpb.ChatRoomUser, 0, len(c.Users)),
+		}
+		for _, u := range c.Users {
+			room.Users = append(room.Users, &chatlogpb.ChatRoomUser{UserName: u.UserName, DisplayName: u.DisplayName})
+		}
+		ret.Items = append(ret.Items, room)
+	}
+	return ret, nil
+}
+
+func (s *Service) GetSessions(ctx context.Context, req *chatlogpb.ListRequest) (*chatlogpb.SessionList, error) {
+	resp, err := s.db.GetSessions(strings.TrimSpace(req.Keyword), int(req.Limit), int(req.Offset))
+	if err != nil {
+		return nil, err
+	}
+	ret := &chatlogpb.SessionList{Items: make([]*chatlogpb.Session, 0, len(resp.Items))}
+	for _, sess := range resp.Items {
+		ret.Items = append(ret.Items, &chatlogpb.Session{
+			UserName: sess.UserName,
+			NOrder:   int32(sess.NOrder),
+			NickName: sess.NickName,
+			Content:  sess.Content,
+			NTime:    sess.NTime.Unix(),
+		})
+	}
+	return ret, nil
+}
+
+// GetMessages 与 NDJSON 格式的 chatlog 接口一样按序号顺序读取，边读边发送
+func (s *Service) GetMessages(req *chatlogpb.MessagesRequest, stream chatlogpb.Chatlog_GetMessagesServer) error {
+	if req.Talker == "" {
+		return errors.ErrTalkerEmpty
+	}
+	timeRange := req.Time
+	if timeRange == "" {
+		timeRange = "all"
+	}
+	start, end, ok := util.TimeRangeOf(timeRange)
+	if !ok {
+		return errors.InvalidArg("time")
+	}
+	if _, err := model.ParseMessageTypeFilter(req.Type); err != nil {
+		return errors.InvalidMessageType(err)
+	}
+	cursor, err := model.ParseMessageCursor(req.Cursor)
+	if err != nil {
+		return errors.InvalidArg("cursor")
+	}
+
+	next, err := s.db.StreamMessages(stream.Context(), start, end, req.Talker, req.Sender, req.Keyword, req.Type, cursor, max(int(req.Limit), 0), func(m *model.Message) error {
+		return stream.Send(toMessage(m))
+	})
+	if err != nil {
+		return err
+	}
+	if next != nil {
+		stream.SetTrailer(metadata.Pairs("next-cursor", next.String()))
+	}
+	return nil
+}
+
+func (s *Service) SearchMessages(ctx context.Context, req *chatlogpb.SearchRequest) (*chatlogpb.SearchResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 20
+	}
+	sReq := &model.SearchRequest{
+		Query:   strings.TrimSpace(req.Query),
+		Talker:  strings.TrimSpace(req.Talker),
+		Sender:  strings.TrimSpace(req.Sender),
+		Types:   strings.TrimSpace(req.Type),
+		Limit:   min(limit, 200),
+		Offset:  max(int(req.Offset), 0),
+		Context: min(max(int(req.Context), 0), model.MaxSearchContext),
+		Cursor:  strings.TrimSpace(req.Cursor),
+		Mode:    strings.TrimSpace(req.Mode),
+	}
+	if req.Time != "" {
+		start, end, ok := util.TimeRangeOf(req.Time)
+		if !ok {
+			return nil, errors.InvalidArg("time")
+		}
+		sReq.Start, sReq.End = start, end
+	}
+
+	resp, err := s.db.SearchMessages(sReq)
+	if err != nil {
+		return nil, err
+	}
+	ret := &chatlogpb.SearchResponse{Hits: []*chatlogpb.SearchHit{}}
+	if resp == nil {
+		return ret, nil
+	}
+	ret.Total = int32(resp.Total)
+	ret.DurationMs = resp.DurationMs
+	ret.NextCursor = resp.NextCursor
+	for _, hit := range resp.Hits {
+		ret.Hits = append(ret.Hits, &chatlogpb.SearchHit{
+			Message: toMessage(hit.Message),
+			Snippet: hit.Snippet,
+			Score:   hit.Score,
+			Before:  toMessages(hit.Before),
+			After:   toMessages(hit.After),
+		})
+	}
+	return ret, nil
+}
+
+// GetMedia 读取媒体文件并分块发送，处理方式与 HTTP 媒体接口一致：.dat 图片解码，语音转为 mp3
+func (s *Service) GetMedia(req *chatlogpb.MediaRequest, stream chatlogpb.Chatlog_GetMediaServer) error {
+	if req.Type == "" || req.Key == "" {
+		return errors.InvalidArg("key")
+	}
+	media, err := s.db.GetMedia(req.Type, req.Key)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	var contentType string
+	switch media.Type {
+	case "voice":
+		data, contentType = media.Data, "audio/silk"
+		if out, err := silk.Silk2MP3(media.Data); err == nil {
+			data, contentType = out, "audio/mp3"
+		}
+	default:
+		path := filepath.Join(s.conf.GetDataDir(), filepath.Clean(media.Path))
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return errors.ErrMediaNotFound
+		}
+		data, contentType = b, mime.TypeByExtension(filepath.Ext(path))
+		if strings.ToLower(filepath.Ext(path)) == ".dat" {
+			if out, ext, err := dat2img.Dat2Image(b); err == nil {
+				data, contentType = out, mime.TypeByExtension("."+ext)
+			}
+		}
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+	}
+
+	first := &chatlogpb.MediaChunk{
+		Type:        media.Type,
+		Key:         media.Key,
+		Name:        media.Name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	for offset := 0; offset < len(data) || first != nil; offset += mediaChunkSize {
+		chunk := &chatlogpb.MediaChunk{}
+		if first != nil {
+			chunk, first = first, nil
+		}
+		chunk.Data = data[offset:min(offset+mediaChunkSize, len(data))]
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toMessages(messages []*model.Message) []*chatlogpb.Message {
+	if len(messages) == 0 {
+		return nil
+	}
+	ret := make([]*chatlogpb.Message, 0, len(messages))
+	for _, m := range messages {
+		ret = append(ret, toMessage(m))
+	}
+	return ret
+}
+
+func toMessage(m *model.Message) *chatlogpb.Message {
+	if m == nil {
+		return nil
+	}
+	ret := &chatlogpb.Message{
+		Seq:        m.Seq,
+		Time:       m.Time.Unix(),
+		Talker:     m.Talker,
+		TalkerName: m.TalkerName,
+		IsChatRoom: m.IsChatRoom,
+		Sender:     m.Sender,
+		SenderName: m.SenderName,
+		IsSelf:     m.IsSelf,
+		Type:       m.Type,
+		SubType:    m.SubType,
+		Content:    m.Content,
+		Permalink:  m.Permalink,
+	}
+	if len(m.Contents) > 0 {
+		if b, err := json.Marshal(m.Contents); err == nil {
+			ret.ContentsJson = string(b)
+		}
+	}
+	return ret
+}
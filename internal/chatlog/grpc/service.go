@@ -0,0 +1,157 @@
+// Package grpc 提供与 HTTP 数据接口对应的 gRPC 服务，接口定义见 pkg/chatlogpb/chatlog.proto
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ysy950803/chatlog/internal/chatlog/conf"
+	"github.com/ysy950803/chatlog/internal/chatlog/database"
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/pkg/chatlogpb"
+)
+
+type Service struct {
+	chatlogpb.UnimplementedChatlogServer
+
+	conf   Config
+	db     *database.Service
+	server *grpc.Server
+}
+
+type Config interface {
+	GetGRPCAddr() string
+	GetDataDir() string
+	GetAuth() *conf.AuthConfig
+}
+
+func NewService(conf Config, db *database.Service) *Service {
+	s := &Service{
+		conf: conf,
+		db:   db,
+	}
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	chatlogpb.RegisterChatlogServer(s.server, s)
+	return s
+}
+
+// Start 在配置的地址上启动 gRPC 服务，未配置地址时不启动
+func (s *Service) Start() error {
+	addr := s.conf.GetGRPCAddr()
+	if addr == "" {
+		return nil
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.server.Serve(lis); err != nil {
+			log.Err(err).Msg("Failed to start gRPC server")
+		}
+	}()
+
+	log.Info().Msg("Starting gRPC server on " + addr)
+	return nil
+}
+
+func (s *Service) Stop() error {
+	s.server.GracefulStop()
+	log.Info().Msg("gRPC server stopped")
+	return nil
+}
+
+func (s *Service) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorize(ctx, conf.ScopeRead); err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.checkDBState(); err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	return resp, toStatus(err)
+}
+
+func (s *Service) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	scope := conf.ScopeRead
+	if info.FullMethod == chatlogpb.Chatlog_GetMedia_FullMethodName {
+		scope = conf.ScopeMedia
+	}
+	if err := s.authorize(ss.Context(), scope); err != nil {
+		return toStatus(err)
+	}
+	if err := s.checkDBState(); err != nil {
+		return err
+	}
+	return toStatus(handler(srv, ss))
+}
+
+// authorize 与 HTTP 接口的 authMiddleware 一致，令牌通过 authorization: Bearer <token> 元数据传递
+func (s *Service) authorize(ctx context.Context, scope string) error {
+	var token string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("authorization"); len(v) > 0 && len(v[0]) > 7 && strings.EqualFold(v[0][:7], "Bearer ") {
+		token = strings.TrimSpace(v[0][7:])
+	}
+	return s.conf.GetAuth().Authorize(token, scope)
+}
+
+// checkDBState 与 HTTP 接口的 checkDBStateMiddleware 一致，数据库未就绪时返回 Unavailable
+func (s *Service) checkDBState() error {
+	switch s.db.State {
+	case database.StateInit:
+		return status.Error(codes.Unavailable, "database is not ready")
+	case database.StateDecrypting:
+		return status.Error(codes.Unavailable, "database is decrypting, please wait")
+	case database.StateError:
+		return status.Error(codes.Unavailable, "database is error: "+s.db.StateMsg)
+	}
+	return nil
+}
+
+// toStatus 将 errors.Error 中的 HTTP 状态码转换为对应的 gRPC 状态码
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	code := codes.Internal
+	switch errors.GetCode(err) {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.Aborted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	}
+	return status.Error(code, err.Error())
+}
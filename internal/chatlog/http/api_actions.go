@@ -2,9 +2,13 @@ package http
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
 )
 
 func (s *Service) handleActionGetDataKey(c *gin.Context) {
@@ -88,3 +92,59 @@ func (s *Service) handleActionStopAutoDecrypt(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
+
+// GET /api/v1/index
+func (s *Service) handleIndexStatus(c *gin.Context) {
+	report, err := s.db.GetDB().IndexReport()
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /api/v1/actions/index/rebuild?store=<id>
+// 重建耗时较长，在后台执行，进度通过 GET /api/v1/index 查看
+func (s *Service) handleActionIndexRebuild(c *gin.Context) {
+	db := s.db.GetDB()
+	if status := db.IndexStatus(); status != nil && status.InProgress {
+		errors.Err(c, errors.IndexBusy())
+		return
+	}
+	storeID := strings.TrimSpace(c.Query("store"))
+	go func() {
+		if err := db.RebuildIndex(storeID); err != nil {
+			log.Err(err).Str("store", storeID).Msg("failed to rebuild index via api")
+		}
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"status": "rebuilding", "store": storeID})
+}
+
+// POST /api/v1/actions/index/{verify,optimize,vacuum}?store=<id>
+func (s *Service) handleActionIndexCheck(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := s.db.GetDB()
+		storeID := strings.TrimSpace(c.Query("store"))
+
+		var results []*model.IndexCheckResult
+		var err error
+		switch action {
+		case "verify":
+			results, err = db.VerifyIndex(storeID)
+		case "optimize":
+			results, err = db.OptimizeIndex(storeID)
+		case "vacuum":
+			results, err = db.VacuumIndex(storeID)
+		}
+		if err != nil {
+			errors.Err(c, err)
+			return
+		}
+
+		ok := true
+		for _, r := range results {
+			ok = ok && r.OK
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": ok, "stores": results})
+	}
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// ndjsonFlushEvery 为 NDJSON 输出时每写出多少条消息刷新一次
+const ndjsonFlushEvery = 100
+
+// chatlogPage 为按游标分页时 JSON 格式的响应，next_cursor 为空表示已读完
+type chatlogPage struct {
+	Items      []*model.Message `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// chatlogCursor 解析 cursor 或 after_seq 参数，两者都未指定时返回 false
+func chatlogCursor(cursor string, afterSeq *int64) (model.MessageCursor, bool, error) {
+	if cursor != "" {
+		ret, err := model.ParseMessageCursor(cursor)
+		if err != nil {
+			return ret, true, errors.InvalidArg("cursor")
+		}
+		return ret, true, nil
+	}
+	if afterSeq != nil {
+		if *afterSeq < 0 {
+			return model.MessageCursor{}, true, errors.InvalidArg("after_seq")
+		}
+		return model.MessageCursor{Seq: *afterSeq}, true, nil
+	}
+	return model.MessageCursor{}, false, nil
+}
+
+// streamChatlogNDJSON 边读边写出消息，每行一条；因 limit 截断时最后一行为 {"next_cursor": "..."}
+// 开始输出后出错时无法再修改状态码，最后一行为 {"error": "..."}
+func (s *Service) streamChatlogNDJSON(c *gin.Context, start, end time.Time, talker, sender, keyword, msgType string, cursor model.MessageCursor, limit int) {
+	enc := json.NewEncoder(c.Writer)
+	enc.SetEscapeHTML(false)
+	written := 0
+	next, err := s.db.StreamMessages(c.Request.Context(), start, end, talker, sender, keyword, msgType, cursor, limit, func(m *model.Message) error {
+		if written == 0 {
+			c.Writer.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+			c.Writer.Header().Set("Cache-Control", "no-cache")
+			c.Writer.WriteHeader(http.StatusOK)
+		}
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+		written++
+		if written%ndjsonFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if written == 0 {
+			errors.Err(c, err)
+			return
+		}
+		enc.Encode(gin.H{"error": err.Error()})
+		c.Writer.Flush()
+		return
+	}
+	if written == 0 {
+		c.Writer.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+		c.Writer.WriteHeader(http.StatusOK)
+	}
+	if next != nil {
+		enc.Encode(gin.H{"next_cursor": next.String()})
+	}
+	c.Writer.Flush()
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ysy950803/chatlog/internal/chatlog/events"
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// 心跳间隔的默认值与取值范围（秒）
+const (
+	defaultHeartbeat = 15
+	minHeartbeat     = 5
+	maxHeartbeat     = 300
+)
+
+// GET /api/v1/events?talker=&sender=&type=&keyword=&heartbeat=15
+// 以 Server-Sent Events 推送新到达的消息，事件 ID 为消息的 "<unix>-<seq>"
+// 带 Last-Event-ID 请求头（或 last_event_id 参数）重连时先补发该位置之后的消息
+func (s *Service) handleEvents(c *gin.Context) {
+	q := struct {
+		Talker      string `form:"talker"`
+		Sender      string `form:"sender"`
+		Type        string `form:"type"`
+		Keyword     string `form:"keyword"`
+		Heartbeat   int    `form:"heartbeat"`
+		LastEventID string `form:"last_event_id"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	hub := s.db.Events()
+	if hub == nil {
+		errors.Err(c, errors.EventsUnavailable())
+		return
+	}
+	filter, err := s.eventFilter(q.Talker, q.Sender, q.Type, q.Keyword)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	lastID := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if lastID == "" {
+		lastID = strings.TrimSpace(q.LastEventID)
+	}
+	var resume *model.MessageMarker
+	if lastID != "" {
+		marker, err := model.ParseMessageMarker(lastID)
+		if err != nil {
+			errors.Err(c, errors.InvalidArg("Last-Event-ID"))
+			return
+		}
+		resume = &marker
+	}
+
+	heartbeat := q.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+	heartbeat = max(minHeartbeat, min(heartbeat, maxHeartbeat))
+
+	// 先订阅再补发，补发期间到达的消息留在订阅通道中，按 talker/seq 去重
+	// 补发在写出响应头之前完成，位置过旧时返回 410，由客户端重新拉取历史
+	sub := hub.Subscribe(filter)
+	defer sub.Close()
+
+	var backlog []*model.Message
+	if resume != nil {
+		backlog, err = hub.Backfill(c.Request.Context(), *resume, filter)
+		if err != nil {
+			errors.Err(c, err)
+			return
+		}
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", 3000)
+	c.Writer.Flush()
+
+	sent := make(map[string]struct{})
+	for _, m := range backlog {
+		sent[eventKey(m)] = struct{}{}
+		if err := writeSSE(c, "message", model.MarkerOf(m).String(), m); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(time.Duration(heartbeat) * time.Second)
+	defer ticker.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprintf(c.Writer, ": ping %d\n\n", time.Now().Unix()); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case m, ok := <-sub.C():
+			if !ok {
+				// 推送积压或服务停止，客户端会带上最后的事件 ID 重连
+				return
+			}
+			if _, dup := sent[eventKey(m)]; dup {
+				continue
+			}
+			if err := writeSSE(c, "message", model.MarkerOf(m).String(), m); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventFilter 解析订阅条件，talker/sender 支持名称，与 chatlog 接口一致
+func (s *Service) eventFilter(talker, sender, msgType, keyword string) (*events.Filter, error) {
+	if talker != "" || sender != "" {
+		var err error
+		talker, sender, err = s.db.GetDB().ResolveTalkerAndSender(talker, sender)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events.NewFilter(talker, sender, msgType, keyword)
+}
+
+func eventKey(m *model.Message) string {
+	return m.Talker + "/" + strconv.FormatInt(m.Seq, 10) + "/" + strconv.FormatInt(m.Time.Unix(), 10)
+}
+
+// writeSSE 写出一个事件，data 编码为单行 JSON
+func writeSSE(c *gin.Context, event, id string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	buf := strings.Builder{}
+	if id != "" {
+		buf.WriteString("id: " + id + "\n")
+	}
+	buf.WriteString("event: " + event + "\n")
+	buf.WriteString("data: ")
+	buf.Write(b)
+	buf.WriteString("\n\n")
+	if _, err := c.Writer.WriteString(buf.String()); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
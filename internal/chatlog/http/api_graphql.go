@@ -0,0 +1,55 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/chatlog/graphql"
+	"github.com/ysy950803/chatlog/internal/errors"
+)
+
+func (s *Service) initGraphQL() {
+	executor, err := graphql.NewExecutor(s.db)
+	if err != nil {
+		log.Err(err).Msg("init graphql schema failed")
+		return
+	}
+	s.graphql = executor
+}
+
+// GET  /api/v1/graphql?query=&operationName=&variables=
+// POST /api/v1/graphql {"query": "...", "operationName": "...", "variables": {...}}
+// 查询代价超过 graphql.MaxComplexity 时不执行，结果中的 errors 说明原因
+func (s *Service) handleGraphQL(c *gin.Context) {
+	if s.graphql == nil {
+		errors.Err(c, errors.New(nil, http.StatusServiceUnavailable, "graphql unavailable"))
+		return
+	}
+
+	req := &graphql.Request{}
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(req); err != nil {
+			errors.Err(c, errors.InvalidArg("body"))
+			return
+		}
+	} else {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				errors.Err(c, errors.InvalidArg("variables"))
+				return
+			}
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		errors.Err(c, errors.InvalidArg("query"))
+		return
+	}
+
+	c.JSON(http.StatusOK, s.graphql.Execute(c.Request.Context(), req))
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// defaultMessageContext 为未指定 before/after 时前后各附带的消息条数
+const defaultMessageContext = 10
+
+// 查找引用回复时默认与最多向后查找的天数
+const (
+	defaultThreadDays = 7
+	maxThreadDays     = 365
+)
+
+// GET /api/v1/message/:talker/:seq?before=10&after=10&format=json
+// 按会话与序号读取单条消息及其前后的消息，即消息的固定链接
+func (s *Service) handleMessage(c *gin.Context) {
+	q := struct {
+		Before *int   `form:"before"`
+		After  *int   `form:"after"`
+		Format string `form:"format"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	talker := strings.TrimSpace(c.Param("talker"))
+	if talker == "" {
+		errors.Err(c, errors.ErrTalkerEmpty)
+		return
+	}
+	seq, err := strconv.ParseInt(c.Param("seq"), 10, 64)
+	if err != nil || seq <= 0 {
+		errors.Err(c, errors.InvalidArg("seq"))
+		return
+	}
+	before, after := defaultMessageContext, defaultMessageContext
+	if q.Before != nil {
+		before = *q.Before
+	}
+	if q.After != nil {
+		after = *q.After
+	}
+
+	mc, err := s.db.GetMessageContext(talker, seq, before, after)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	host, query := c.Request.Host, s.mediaQuery(c.Request)
+	messages := make([]*model.Message, 0, 1+len(mc.Before)+len(mc.After))
+	messages = append(messages, mc.Before...)
+	messages = append(messages, mc.Message)
+	messages = append(messages, mc.After...)
+
+	switch strings.ToLower(strings.TrimSpace(q.Format)) {
+	case "html":
+		c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		msg := mc.Message
+		title := msg.Talker
+		if msg.TalkerName != "" {
+			title = fmt.Sprintf("%s (%s)", msg.TalkerName, msg.Talker)
+		}
+		writeChatlogHTMLHeader(c.Writer, title)
+		c.Writer.WriteString("<h2>" + template.HTMLEscapeString(title) + "</h2>")
+		// 以首尾消息为中心翻看更早或更晚的消息
+		nav := url.Values{"format": {"html"}, "before": {strconv.Itoa(before)}, "after": {strconv.Itoa(after)}}
+		if len(mc.Before) > 0 {
+			c.Writer.WriteString("<div class=\"pager\"><a href=\"" + template.HTMLEscapeString(model.PermalinkPath(msg.Talker, mc.Before[0].Seq)+"?"+nav.Encode()) + "\">« 更早</a></div>")
+		}
+		for _, m := range mc.Before {
+			s.writeSearchMessageHTML(c.Writer, m, host, query, "", 0, true)
+		}
+		s.writeSearchMessageHTML(c.Writer, msg, host, query, "", 0, false)
+		for _, m := range mc.After {
+			s.writeSearchMessageHTML(c.Writer, m, host, query, "", 0, true)
+		}
+		if len(mc.After) > 0 {
+			c.Writer.WriteString("<div class=\"pager\"><a href=\"" + template.HTMLEscapeString(model.PermalinkPath(msg.Talker, mc.After[len(mc.After)-1].Seq)+"?"+nav.Encode()) + "\">更晚 »</a></div>")
+		}
+		c.Writer.WriteString(previewHTMLSnippet)
+		c.Writer.WriteString("</body></html>")
+	case "csv":
+		c.Writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%d.csv", mc.Message.Talker, seq))
+		csvWriter := csv.NewWriter(c.Writer)
+		csvWriter.Write([]string{"Time", "SenderName", "Sender", "TalkerName", "Talker", "Content"})
+		for _, m := range messages {
+			m.SetMediaQuery(query)
+			csvWriter.Write(m.CSV(host))
+		}
+		csvWriter.Flush()
+	case "text", "plain":
+		c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		mc.Message.SetMediaQuery(query)
+		c.Writer.WriteString(mc.PlainText(host))
+	default:
+		c.JSON(http.StatusOK, mc)
+	}
+}
+
+// GET /api/v1/thread/:talker/:seq?days=7&format=json
+// 返回消息逐级引用的原消息，以及该消息之后 days 天内直接或间接引用它的回复
+func (s *Service) handleThread(c *gin.Context) {
+	q := struct {
+		Days   int    `form:"days"`
+		Format string `form:"format"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	talker := strings.TrimSpace(c.Param("talker"))
+	if talker == "" {
+		errors.Err(c, errors.ErrTalkerEmpty)
+		return
+	}
+	seq, err := strconv.ParseInt(c.Param("seq"), 10, 64)
+	if err != nil || seq <= 0 {
+		errors.Err(c, errors.InvalidArg("seq"))
+		return
+	}
+	if q.Days <= 0 {
+		q.Days = defaultThreadDays
+	}
+	q.Days = min(q.Days, maxThreadDays)
+
+	thread, err := s.db.GetReplyThread(talker, seq, time.Duration(q.Days)*24*time.Hour)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	host, query := c.Request.Host, s.mediaQuery(c.Request)
+	switch strings.ToLower(strings.TrimSpace(q.Format)) {
+	case "html":
+		c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		msg := thread.Message
+		title := msg.Talker
+		if msg.TalkerName != "" {
+			title = fmt.Sprintf("%s (%s)", msg.TalkerName, msg.Talker)
+		}
+		writeChatlogHTMLHeader(c.Writer, title)
+		c.Writer.WriteString("<h2>" + template.HTMLEscapeString(title) + " - 回复链</h2>")
+		for _, m := range thread.Ancestors {
+			s.writeSearchMessageHTML(c.Writer, m, host, query, "", 0, true)
+		}
+		s.writeSearchMessageHTML(c.Writer, msg, host, query, "", 0, false)
+		s.writeReplyNodesHTML(c.Writer, thread.Replies, host, query)
+		c.Writer.WriteString(previewHTMLSnippet)
+		c.Writer.WriteString("</body></html>")
+	case "text", "plain":
+		c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		thread.Message.SetMediaQuery(query)
+		c.Writer.WriteString(thread.PlainText(host))
+	default:
+		c.JSON(http.StatusOK, thread)
+	}
+}
+
+// writeReplyNodesHTML 按层级缩进输出回复
+func (s *Service) writeReplyNodesHTML(w io.Writer, nodes []*model.ReplyNode, host, query string) {
+	if len(nodes) == 0 {
+		return
+	}
+	io.WriteString(w, "<div class=\"replies\">")
+	for _, n := range nodes {
+		s.writeSearchMessageHTML(w, n.Message, host, query, "", 0, false)
+		s.writeReplyNodesHTML(w, n.Replies, host, query)
+	}
+	io.WriteString(w, "</div>")
+}
+
+// messageTimeHTML 输出消息时间，消息有固定链接时链接到该消息及其上下文
+func messageTimeHTML(m *model.Message) string {
+	text := template.HTMLEscapeString(m.Time.Format("2006-01-02 15:04:05"))
+	if m.Permalink == "" {
+		return "<span class=\"time\">" + text + "</span>"
+	}
+	return "<a class=\"time\" href=\"" + template.HTMLEscapeString(m.Permalink+"?format=html") + "\" title=\"消息链接\">" + text + "</a>"
+}
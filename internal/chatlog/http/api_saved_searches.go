@@ -0,0 +1,157 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/savedsearch"
+)
+
+type savedSearchRequest struct {
+	Name     string               `json:"name"`
+	Request  *model.SearchRequest `json:"request"`
+	Notify   *model.SearchNotify  `json:"notify"`
+	Disabled bool                 `json:"disabled"`
+}
+
+func (r *savedSearchRequest) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.InvalidArg("name")
+	}
+	if r.Request == nil {
+		return errors.InvalidArg("request")
+	}
+	// 翻页与分面参数对常用搜索没有意义，保存前清除
+	r.Request.Offset = 0
+	r.Request.Cursor = ""
+	r.Request.Facets = false
+	if err := r.Request.Clone().ExpandOperators(); err != nil {
+		return errors.InvalidQuery(err)
+	}
+	if _, err := model.ParseMessageTypeFilter(r.Request.Types); err != nil {
+		return errors.InvalidQuery(err)
+	}
+	if r.Notify != nil {
+		r.Notify.URL = strings.TrimSpace(r.Notify.URL)
+		if r.Notify.URL != "" && !strings.HasPrefix(r.Notify.URL, "http://") && !strings.HasPrefix(r.Notify.URL, "https://") {
+			return errors.InvalidArg("notify.url")
+		}
+	}
+	return nil
+}
+
+func (s *Service) savedSearches(c *gin.Context) *savedsearch.Store {
+	db := s.db.GetDB()
+	if db == nil || db.SavedSearches() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database is not ready"})
+		return nil
+	}
+	return db.SavedSearches()
+}
+
+// GET /api/v1/saved-searches
+func (s *Service) handleListSavedSearches(c *gin.Context) {
+	store := s.savedSearches(c)
+	if store == nil {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": store.List()})
+}
+
+// GET /api/v1/saved-searches/:id
+func (s *Service) handleGetSavedSearch(c *gin.Context) {
+	store := s.savedSearches(c)
+	if store == nil {
+		return
+	}
+	item, err := store.Get(c.Param("id"))
+	if err != nil {
+		errors.Err(c, savedSearchError(c.Param("id"), err))
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// POST /api/v1/saved-searches
+func (s *Service) handleCreateSavedSearch(c *gin.Context) {
+	store := s.savedSearches(c)
+	if store == nil {
+		return
+	}
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "detail": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	item, err := store.Create(&model.SavedSearch{
+		Name:     req.Name,
+		Request:  req.Request,
+		Notify:   req.Notify,
+		Disabled: req.Disabled,
+	})
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// PUT /api/v1/saved-searches/:id
+func (s *Service) handleUpdateSavedSearch(c *gin.Context) {
+	store := s.savedSearches(c)
+	if store == nil {
+		return
+	}
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "detail": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	// LastSeen、Seen 保持不变，修改条件后只对之后的新消息提醒
+	item, err := store.Update(c.Param("id"), func(item *model.SavedSearch) {
+		item.Name = req.Name
+		item.Request = req.Request
+		item.Notify = req.Notify
+		item.Disabled = req.Disabled
+	})
+	if err != nil {
+		errors.Err(c, savedSearchError(c.Param("id"), err))
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// DELETE /api/v1/saved-searches/:id
+func (s *Service) handleDeleteSavedSearch(c *gin.Context) {
+	store := s.savedSearches(c)
+	if store == nil {
+		return
+	}
+	if err := store.Delete(c.Param("id")); err != nil {
+		errors.Err(c, savedSearchError(c.Param("id"), err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func savedSearchError(id string, err error) error {
+	if errors.Is(err, savedsearch.ErrNotFound) {
+		return errors.SavedSearchNotFound(id)
+	}
+	return err
+}
@@ -0,0 +1,344 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/ysy950803/chatlog/internal/chatlog/events"
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/pkg/util"
+)
+
+// WebSocket 连接的限制
+const (
+	wsMaxFrameBytes     = 1 << 20
+	wsMaxSubscriptions  = 32
+	wsDefaultLimit      = 100
+	wsMaxLimit          = 1000
+	wsHeartbeatInterval = 30 * time.Second
+)
+
+// wsRequest 为客户端发送的帧，op 决定使用哪些字段
+//
+//	subscribe:   talker/sender/keyword/type，可选 last_event_id 补发该位置之后的消息
+//	unsubscribe: subscription
+//	messages:    time/talker/sender/keyword/type/limit/offset，对应 GET /api/v1/chatlog
+//	search:      query/talker/sender/type/time/limit/offset/cursor/mode，对应 GET /api/v1/search
+//	contacts:    keyword/limit/offset
+//	sessions:    keyword/limit/offset
+//	ping
+type wsRequest struct {
+	ID           string `json:"id,omitempty"`
+	Op           string `json:"op"`
+	Subscription string `json:"subscription,omitempty"`
+	Talker       string `json:"talker,omitempty"`
+	Sender       string `json:"sender,omitempty"`
+	Keyword      string `json:"keyword,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Time         string `json:"time,omitempty"`
+	Query        string `json:"query,omitempty"`
+	Cursor       string `json:"cursor,omitempty"`
+	Mode         string `json:"mode,omitempty"`
+	Limit        int    `json:"limit,omitempty"`
+	Offset       int    `json:"offset,omitempty"`
+	LastEventID  string `json:"last_event_id,omitempty"`
+}
+
+// wsResponse 为服务端发送的帧
+// 请求的响应带有请求的 id 与 op；新消息推送的 op 为 message，带有订阅 ID 与事件 ID
+// 订阅因消息积压或服务停止结束时推送 op 为 unsubscribed 的帧
+type wsResponse struct {
+	ID           string      `json:"id,omitempty"`
+	Op           string      `json:"op"`
+	Subscription string      `json:"subscription,omitempty"`
+	EventID      string      `json:"event_id,omitempty"`
+	Result       interface{} `json:"result,omitempty"`
+	Message      interface{} `json:"message,omitempty"`
+	Error        *wsError    `json:"error,omitempty"`
+}
+
+type wsError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// GET /api/v1/ws
+// 在一条 WebSocket 连接上订阅新消息并执行查询，协议见 wsRequest 与 wsResponse
+func (s *Service) handleWebSocket(c *gin.Context) {
+	if s.db.Events() == nil {
+		errors.Err(c, errors.EventsUnavailable())
+		return
+	}
+	server := websocket.Server{
+		Handshake: s.checkWebSocketOrigin,
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = wsMaxFrameBytes
+			newWSSession(s, ws).run()
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkWebSocketOrigin 接受没有 Origin（非浏览器客户端）、与请求同源或 auth.cors_origins 允许的连接，与 HTTP 接口的跨域规则一致
+func (s *Service) checkWebSocketOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	if origin != nil && !strings.EqualFold(origin.Host, req.Host) && !s.conf.GetAuth().AllowOrigin(req.Header.Get("Origin")) {
+		return errors.InvalidArg("origin")
+	}
+	config.Origin = origin
+	return nil
+}
+
+// wsSession 为一条 WebSocket 连接的状态
+type wsSession struct {
+	svc *Service
+	ws  *websocket.Conn
+
+	// ctx 在连接关闭时取消，用于中止补发
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	nextID int
+	subs   map[string]*events.Subscription
+}
+
+func newWSSession(svc *Service, ws *websocket.Conn) *wsSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &wsSession{svc: svc, ws: ws, ctx: ctx, cancel: cancel, subs: make(map[string]*events.Subscription)}
+}
+
+func (w *wsSession) run() {
+	defer w.close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go w.heartbeat(done)
+
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(w.ws, &data); err != nil {
+			return
+		}
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			w.send(&wsResponse{Op: "error", Error: &wsError{Code: http.StatusBadRequest, Message: "invalid frame"}})
+			continue
+		}
+		w.handle(&req)
+	}
+}
+
+func (w *wsSession) heartbeat(done <-chan struct{}) {
+	ticker := time.NewTicker(wsHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := w.send(&wsResponse{Op: "ping"}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (w *wsSession) close() {
+	w.cancel()
+	w.mu.Lock()
+	subs := w.subs
+	w.subs = make(map[string]*events.Subscription)
+	w.mu.Unlock()
+	for _, sub := range subs {
+		sub.Close()
+	}
+	w.ws.Close()
+}
+
+func (w *wsSession) send(resp *wsResponse) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	return websocket.JSON.Send(w.ws, resp)
+}
+
+func (w *wsSession) handle(req *wsRequest) {
+	var (
+		result interface{}
+		err    error
+	)
+	switch strings.ToLower(strings.TrimSpace(req.Op)) {
+	case "ping":
+		w.send(&wsResponse{ID: req.ID, Op: "pong"})
+		return
+	case "subscribe":
+		result, err = w.subscribe(req)
+	case "unsubscribe":
+		result, err = w.unsubscribe(req)
+	case "messages":
+		result, err = w.messages(req)
+	case "search":
+		result, err = w.search(req)
+	case "contacts":
+		result, err = w.svc.db.GetContacts(strings.TrimSpace(req.Keyword), req.Limit, req.Offset)
+	case "sessions":
+		result, err = w.svc.db.GetSessions(strings.TrimSpace(req.Keyword), req.Limit, req.Offset)
+	default:
+		err = errors.InvalidArg("op")
+	}
+
+	resp := &wsResponse{ID: req.ID, Op: req.Op}
+	if err != nil {
+		resp.Error = &wsError{Code: errors.GetCode(err), Message: err.Error()}
+	} else {
+		resp.Result = result
+	}
+	w.send(resp)
+}
+
+func (w *wsSession) subscribe(req *wsRequest) (interface{}, error) {
+	hub := w.svc.db.Events()
+	if hub == nil {
+		return nil, errors.EventsUnavailable()
+	}
+	filter, err := w.svc.eventFilter(req.Talker, req.Sender, req.Type, req.Keyword)
+	if err != nil {
+		return nil, err
+	}
+	var resume *model.MessageMarker
+	if id := strings.TrimSpace(req.LastEventID); id != "" {
+		marker, err := model.ParseMessageMarker(id)
+		if err != nil {
+			return nil, errors.InvalidArg("last_event_id")
+		}
+		resume = &marker
+	}
+
+	w.mu.Lock()
+	if len(w.subs) >= wsMaxSubscriptions {
+		w.mu.Unlock()
+		return nil, errors.InvalidArg("subscription")
+	}
+	w.nextID++
+	id := "s" + strconv.Itoa(w.nextID)
+	w.mu.Unlock()
+
+	// 先订阅再补发，补发失败（如位置过旧）时订阅失败，由客户端重新拉取历史
+	sub := hub.Subscribe(filter)
+	var backlog []*model.Message
+	if resume != nil {
+		backlog, err = hub.Backfill(w.ctx, *resume, filter)
+		if err != nil {
+			sub.Close()
+			return nil, err
+		}
+	}
+
+	w.mu.Lock()
+	w.subs[id] = sub
+	w.mu.Unlock()
+
+	go w.forward(id, sub, backlog)
+	return gin.H{"subscription": id}, nil
+}
+
+// forward 将订阅到的新消息推送给客户端，先推送补发的消息
+func (w *wsSession) forward(id string, sub *events.Subscription, backlog []*model.Message) {
+	sent := make(map[string]struct{})
+	for _, m := range backlog {
+		sent[eventKey(m)] = struct{}{}
+		if err := w.push(id, m); err != nil {
+			return
+		}
+	}
+	for m := range sub.C() {
+		if _, dup := sent[eventKey(m)]; dup {
+			continue
+		}
+		if err := w.push(id, m); err != nil {
+			return
+		}
+	}
+
+	// 订阅通道关闭：客户端取消订阅时已从 subs 中移除，否则为积压或服务停止
+	w.mu.Lock()
+	_, active := w.subs[id]
+	delete(w.subs, id)
+	w.mu.Unlock()
+	if active {
+		w.send(&wsResponse{Op: "unsubscribed", Subscription: id})
+	}
+}
+
+func (w *wsSession) push(id string, m *model.Message) error {
+	return w.send(&wsResponse{Op: "message", Subscription: id, EventID: model.MarkerOf(m).String(), Message: m})
+}
+
+func (w *wsSession) unsubscribe(req *wsRequest) (interface{}, error) {
+	w.mu.Lock()
+	sub, ok := w.subs[req.Subscription]
+	delete(w.subs, req.Subscription)
+	w.mu.Unlock()
+	if !ok {
+		return nil, errors.InvalidArg("subscription")
+	}
+	sub.Close()
+	return gin.H{"subscription": req.Subscription}, nil
+}
+
+func (w *wsSession) messages(req *wsRequest) (interface{}, error) {
+	start, end, ok := util.TimeRangeOf(req.Time)
+	if !ok {
+		return nil, errors.InvalidArg("time")
+	}
+	if req.Talker == "" {
+		return nil, errors.ErrTalkerEmpty
+	}
+	if _, err := model.ParseMessageTypeFilter(req.Type); err != nil {
+		return nil, errors.InvalidMessageType(err)
+	}
+	return w.svc.db.GetMessages(start, end, req.Talker, req.Sender, req.Keyword, req.Type, wsLimit(req.Limit), max(req.Offset, 0))
+}
+
+func (w *wsSession) search(req *wsRequest) (interface{}, error) {
+	sReq := &model.SearchRequest{
+		Query:  strings.TrimSpace(req.Query),
+		Talker: strings.TrimSpace(req.Talker),
+		Sender: strings.TrimSpace(req.Sender),
+		Types:  strings.TrimSpace(req.Type),
+		Limit:  min(wsLimit(req.Limit), 200),
+		Offset: max(req.Offset, 0),
+		Cursor: strings.TrimSpace(req.Cursor),
+		Mode:   strings.TrimSpace(req.Mode),
+	}
+	if req.Time != "" {
+		start, end, ok := util.TimeRangeOf(req.Time)
+		if !ok {
+			return nil, errors.InvalidArg("time")
+		}
+		sReq.Start, sReq.End = start, end
+	}
+	return w.svc.db.SearchMessages(sReq)
+}
+
+// wsLimit 限制单次查询返回的条数，连接上的响应为单个帧
+func wsLimit(limit int) int {
+	if limit <= 0 {
+		return wsDefaultLimit
+	}
+	return min(limit, wsMaxLimit)
+}
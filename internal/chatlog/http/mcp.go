@@ -24,11 +24,21 @@ func (s *Service) initMCPServer() {
 	s.mcpServer.AddTool(ContactTool, s.handleMCPContact)
 	s.mcpServer.AddTool(ChatRoomTool, s.handleMCPChatRoom)
 	s.mcpServer.AddTool(RecentChatTool, s.handleMCPRecentChat)
+	s.mcpServer.AddTool(ResolveTalkerTool, s.handleMCPResolveTalker)
 	s.mcpServer.AddTool(ChatLogTool, s.handleMCPChatLog)
+	s.mcpServer.AddTool(SearchTool, s.handleMCPSearch)
+	s.mcpServer.AddTool(SemanticSearchTool, s.handleMCPSemanticSearch)
 	s.mcpServer.AddTool(CurrentTimeTool, s.handleMCPCurrentTime)
 	s.mcpServer.AddTool(DiaryTool, s.handleMCPDiary)
-	s.mcpSSEServer = server.NewSSEServer(s.mcpServer)
-	s.mcpStreamableServer = server.NewStreamableHTTPServer(s.mcpServer)
+	s.mcpSSEServer = server.NewSSEServer(s.mcpServer,
+		// 令牌通过 /sse?token= 传递时，/message 端点带上相同的查询参数
+		server.WithAppendQueryToMessageEndpoint(),
+		// 工具结果中的媒体链接按调用请求的令牌附加签名
+		server.WithSSEContextFunc(s.withMediaQuery),
+	)
+	s.mcpStreamableServer = server.NewStreamableHTTPServer(s.mcpServer,
+		server.WithHTTPContextFunc(s.withMediaQuery),
+	)
 }
 
 var ContactTool = mcp.NewTool(
@@ -48,55 +58,20 @@ var RecentChatTool = mcp.NewTool(
 	mcp.WithDescription(`查询最近会话列表，包括个人聊天和群聊。当用户想了解最近的聊天记录、查看最近联系过的人或群组时使用此工具。不需要参数，直接返回最近的会话列表。`),
 )
 
+var ResolveTalkerTool = mcp.NewTool(
+	"resolve_talker",
+	mcp.WithDescription(`将人名或群名解析为唯一的会话ID（UserName）。支持备注名、昵称、微信号，以及全拼或首字母（如"zhangsan"、"zs"）。
+返回按匹配程度排序的候选项及最近会话时间；ambiguous 为 true 时说明有多个同等匹配的联系人或群聊，请结合上下文选择或向用户确认，再用选中的 UserName 作为 talker 查询。
+其他工具的 talker 参数有歧义时会返回 ambiguous talker 错误并附带同样的候选项。`),
+	mcp.WithString("name", mcp.Description("要解析的人名或群名"), mcp.Required()),
+)
+
 var ChatLogTool = mcp.NewTool(
 	"query_chat_log",
 	mcp.WithDescription(`检索历史聊天记录，可根据时间、对话方、发送者和关键词等条件进行精确查询。当用户需要查找特定信息或想了解与某人/某群的历史交流时使用此工具。
 
-【强制多步查询流程!】
-当查询特定话题或特定发送者发言时，必须严格按照以下流程使用，任何偏离都会导致错误的结果：
-
-步骤1: 初步定位相关消息
-- 使用keyword参数查找特定话题
-- 使用sender参数查找特定发送者的消息
-- 使用较宽时间范围初步查询
-
-步骤2: 【必须执行】针对每个关键结果点分别获取上下文
-- 必须对步骤1返回的每个时间点T1, T2, T3...分别执行独立查询（时间范围接近的消息可以合并为一个查询）
-- 每次独立查询必须移除keyword参数
-- 每次独立查询必须移除sender参数
-- 每次独立查询使用"Tn前后15-30分钟"的窄范围
-- 每次独立查询仅保留talker参数
-
-步骤3: 【必须执行】综合分析所有上下文
-- 必须等待所有步骤2的查询结果返回后再进行分析
-- 必须综合考虑所有上下文信息后再回答用户
-
-【严格执行规则！】
-- 禁止仅凭步骤1的结果直接回答用户
-- 禁止在步骤2使用过大的时间范围一次性查询所有上下文
-- 禁止跳过步骤2或步骤3
-- 必须对每个关键结果点分别执行独立的上下文查询
-
-【执行示例】
-正确流程示例:
-1. 步骤1: chatlog(time="2023-04-01~2023-04-30", talker="工作群", keyword="项目进度")
-返回结果: 4月5日、4月12日、4月20日有相关消息
-2. 步骤2:
-- 查询1: chatlog(time="2023-04-05/09:30~2023-04-05/10:30", talker="工作群") // 注意没有keyword
-- 查询2: chatlog(time="2023-04-12/14:00~2023-04-12/15:00", talker="工作群") // 注意没有keyword
-- 查询3: chatlog(time="2023-04-20/16:00~2023-04-20/17:00", talker="工作群") // 注意没有keyword
-3. 步骤3: 综合分析所有上下文后回答用户
-
-错误流程示例:
-- 仅执行步骤1后直接回答
-- 步骤2使用time="2023-04-01~2023-04-30"一次性查询
-- 步骤2仍然保留keyword或sender参数
-
-【自我检查】回答用户前必须自问:
-- 我是否对每个关键时间点都执行了独立的上下文查询?
-- 我是否在上下文查询中移除了keyword和sender参数?
-- 我是否分析了所有上下文后再回答?
-- 如果上述任一问题答案为"否"，则必须纠正流程
+如需查找某个话题或关键词出现的位置并查看前后对话，优先使用 search_chat_log 工具并设置 context 参数，它会直接返回每条命中及其上下文，无需再逐个时间点重新查询。
+本工具适合按时间范围浏览完整对话。
 
 返回格式："昵称(ID) 时间\n消息内容\n昵称(ID) 时间\n消息内容"
 当查询多个Talker时，返回格式为："昵称(ID)\n[TalkerName(Talker)] 时间\n消息内容"
@@ -127,23 +102,63 @@ var ChatLogTool = mcp.NewTool(
 	mcp.WithString("talker", mcp.Description(`指定对话方（联系人或群组）
 - 可使用ID、昵称或备注名
 - 多个对话方用","分隔，如："张三,李四,工作群"
-- 【重要】这是多步查询中唯一应保留的参数`), mcp.Required()),
+- 名称匹配到多个会话时返回 ambiguous talker 错误并列出候选项，请改用其中的 UserName`), mcp.Required()),
 	mcp.WithString("sender", mcp.Description(`指定群聊中的发送者
 - 仅在查询群聊记录时有效
 - 多个发送者用","分隔，如："张三,李四"
-- 可使用ID、昵称或备注名
-【重要】查询特定发送者的消息时：
-1. 第一步：使用sender参数初步定位多个相关消息时间点
-2. 后续步骤：必须移除sender参数，分别查询每个时间点前后的完整对话
-3. 错误示例：对所有找到的消息一次性查询大范围上下文
-4. 正确示例：对每个时间点T分别执行查询"T前后15-30分钟"（不带sender）`)),
+- 可使用ID、昵称或备注名`)),
 	mcp.WithString("keyword", mcp.Description(`搜索内容中的关键词
 - 支持正则表达式匹配
-- 【重要】查询特定话题时：
-1. 第一步：使用keyword参数初步定位多个相关消息时间点
-2. 后续步骤：必须移除keyword参数，分别查询每个时间点前后的完整对话
-3. 错误示例：对所有找到的关键词消息一次性查询大范围上下文
-4. 正确示例：对每个时间点T分别执行查询"T前后15-30分钟"（不带keyword）`)),
+- 需要查看命中消息的上下文时，请改用 search_chat_log 工具`)),
+	mcp.WithString("type", mcp.Description(`按消息类型筛选
+- 可选值：text、image、voice、video、file、link、emoji、location、card、forward、quote、miniprogram、system 等，也可使用数字形式如 49:6
+- 多个类型用","分隔表示任一匹配，如："file,link"
+- 以"-"开头表示排除，如："-system,-pat"`)),
+)
+
+var SearchTool = mcp.NewTool(
+	"search_chat_log",
+	mcp.WithDescription(`基于全文索引搜索聊天记录，返回按相关度排序的命中消息，并可附带每条命中在同一会话中的前后上下文。
+当用户询问"某人/某群有没有聊过某件事"、"上次提到某个话题是什么时候"等需要定位话题并理解前后语境的问题时使用此工具。
+
+返回格式：
+[序号] 时间 @ 会话显示名(ID)
+链接: 命中消息的固定链接，可直接提供给用户定位到该消息
+  时间 发送者: 上文消息
+> 时间 发送者: 命中消息
+  时间 发送者: 下文消息
+
+提示：
+1. query 支持中英文关键词，多个关键词用空格分隔表示同时包含，可使用 OR 连接表示任一包含，使用双引号表示精确短语
+2. query 中可直接使用操作符缩小范围：from:发送者、in:会话、type:类别（file/link/image/voice/video 等）、has:类别、-type:排除的类别、after:日期、before:日期
+例如："from:张三 in:工作群 type:file after:2024-03-01 合同"
+   关键词可加字段前缀限定匹配范围：title:标题、desc:描述、url:域名、file:文件名、location:位置、quote:引用原文、forward:合并转发标题，例如"file:报价单"、"url:github.com"
+3. 默认附带前后各 5 条上下文，如上下文不足以理解语境，可增大 context 参数`),
+	mcp.WithString("query", mcp.Description("搜索关键词，可包含 from:/in:/type:/has:/after:/before: 操作符"), mcp.Required()),
+	mcp.WithString("talker", mcp.Description(`可选，限定对话方（联系人或群组），可使用ID、昵称或备注名，多个用","分隔`)),
+	mcp.WithString("sender", mcp.Description(`可选，限定发送者，多个用","分隔`)),
+	mcp.WithString("time", mcp.Description(`可选，限定时间范围，格式与 query_chat_log 的 time 参数一致，如"2023-04-01~2023-04-30"`)),
+	mcp.WithString("type", mcp.Description(`可选，按消息类型筛选，取值与 query_chat_log 的 type 参数一致，如"file,link"或"-system"`)),
+	mcp.WithNumber("context", mcp.Description("每条命中前后各附带的上下文消息条数，默认 5，最大 50，设为 0 表示不附带")),
+	mcp.WithNumber("limit", mcp.Description("返回的命中条数，默认 20")),
+	mcp.WithString("cursor", mcp.Description("可选，翻页游标。上次结果末尾给出的 next_cursor，传入后返回后续命中，其余参数需保持不变")),
+)
+
+var SemanticSearchTool = mcp.NewTool(
+	"semantic_search_chat_log",
+	mcp.WithDescription(`按语义检索聊天记录，用于关键词难以命中、只知道大意的问题，例如"有人推荐过适合带娃去的餐厅吗"。
+默认使用 hybrid 模式，将向量相似度与全文检索的排序融合；mode 为 semantic 时只按语义相似度排序。
+需要服务端配置向量模型，未配置时返回错误，此时请改用 search_chat_log。
+返回格式与 search_chat_log 一致，翻页使用 offset 参数。`),
+	mcp.WithString("query", mcp.Description("用自然语言描述要找的内容，可包含 from:/in:/type:/after:/before: 操作符"), mcp.Required()),
+	mcp.WithString("mode", mcp.Description("检索模式：hybrid（默认）或 semantic")),
+	mcp.WithString("talker", mcp.Description(`可选，限定对话方（联系人或群组），可使用ID、昵称或备注名，多个用","分隔`)),
+	mcp.WithString("sender", mcp.Description(`可选，限定发送者，多个用","分隔`)),
+	mcp.WithString("time", mcp.Description(`可选，限定时间范围，格式与 query_chat_log 的 time 参数一致，如"2023-04-01~2023-04-30"`)),
+	mcp.WithString("type", mcp.Description(`可选，按消息类型筛选，取值与 query_chat_log 的 type 参数一致`)),
+	mcp.WithNumber("context", mcp.Description("每条命中前后各附带的上下文消息条数，默认 5，最大 50，设为 0 表示不附带")),
+	mcp.WithNumber("limit", mcp.Description("返回的命中条数，默认 20")),
+	mcp.WithNumber("offset", mcp.Description("跳过的命中条数，用于翻页")),
 )
 
 var CurrentTimeTool = mcp.NewTool(
@@ -251,6 +266,51 @@ func (s *Service) handleMCPChatRoom(ctx context.Context, request mcp.CallToolReq
 	}, nil
 }
 
+type ResolveTalkerRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *Service) handleMCPResolveTalker(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var req ResolveTalkerRequest
+	if err := request.BindArguments(&req); err != nil {
+		log.Error().Err(err).Msg("Failed to bind arguments")
+		return errors.ErrMCPTool(err), nil
+	}
+
+	resolution, err := s.db.ResolveTalker(req.Name, 10, false)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve talker")
+		return errors.ErrMCPTool(err), nil
+	}
+	buf := &bytes.Buffer{}
+	switch {
+	case resolution.UserName != "":
+		buf.WriteString(fmt.Sprintf("resolved: %s\n", resolution.UserName))
+	case resolution.Ambiguous:
+		buf.WriteString("ambiguous: true\n")
+	default:
+		buf.WriteString("未找到匹配的联系人或群聊\n")
+	}
+	if len(resolution.Candidates) > 0 {
+		buf.WriteString("UserName,Kind,DisplayName,Remark,NickName,Alias,LastActive,MatchType\n")
+	}
+	for _, c := range resolution.Candidates {
+		lastActive := ""
+		if c.LastActive != nil {
+			lastActive = c.LastActive.Format("2006-01-02 15:04")
+		}
+		buf.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%s\n", c.UserName, c.Kind, c.DisplayName, c.Remark, c.NickName, c.Alias, lastActive, c.MatchType))
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: buf.String(),
+			},
+		},
+	}, nil
+}
+
 type RecentChatRequest struct {
 	Keyword string `json:"keyword"`
 	Limit   int    `json:"limit"`
@@ -291,6 +351,7 @@ type ChatLogRequest struct {
 	Talker  string `form:"talker"`
 	Sender  string `form:"sender"`
 	Keyword string `form:"keyword"`
+	Type    string `form:"type"`
 	Limit   int    `form:"limit"`
 	Offset  int    `form:"offset"`
 	Format  string `form:"format"`
@@ -319,7 +380,7 @@ func (s *Service) handleMCPChatLog(ctx context.Context, request mcp.CallToolRequ
 		req.Offset = 0
 	}
 
-	messages, err := s.db.GetMessages(start, end, req.Talker, req.Sender, req.Keyword, req.Limit, req.Offset)
+	messages, err := s.db.GetMessages(start, end, req.Talker, req.Sender, req.Keyword, req.Type, req.Limit, req.Offset)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get messages")
 		return errors.ErrMCPTool(err), nil
@@ -329,7 +390,9 @@ func (s *Service) handleMCPChatLog(ctx context.Context, request mcp.CallToolRequ
 	if len(messages) == 0 {
 		buf.WriteString("未找到符合查询条件的聊天记录")
 	}
+	query := mediaQueryOf(ctx)
 	for _, m := range messages {
+		m.SetMediaQuery(query)
 		buf.WriteString(m.PlainText(strings.Contains(req.Talker, ","), util.PerfectTimeFormat(start, end), ""))
 		buf.WriteString("\n")
 	}
@@ -344,6 +407,113 @@ func (s *Service) handleMCPChatLog(ctx context.Context, request mcp.CallToolRequ
 	}, nil
 }
 
+type SearchRequest struct {
+	Query   string `json:"query"`
+	Talker  string `json:"talker"`
+	Sender  string `json:"sender"`
+	Time    string `json:"time"`
+	Type    string `json:"type"`
+	Context *int   `json:"context"`
+	Limit   int    `json:"limit"`
+	Offset  int    `json:"offset"`
+	Cursor  string `json:"cursor"`
+	Mode    string `json:"mode"`
+}
+
+func (s *Service) handleMCPSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+
+	var req SearchRequest
+	if err := request.BindArguments(&req); err != nil {
+		log.Error().Err(err).Msg("Failed to bind arguments")
+		log.Error().Interface("request", request.GetRawArguments()).Msg("Failed to bind arguments")
+		return errors.ErrMCPTool(err), nil
+	}
+	req.Mode = ""
+	return s.searchMCP(req, mediaQueryOf(ctx))
+}
+
+func (s *Service) handleMCPSemanticSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+
+	var req SearchRequest
+	if err := request.BindArguments(&req); err != nil {
+		log.Error().Err(err).Msg("Failed to bind arguments")
+		log.Error().Interface("request", request.GetRawArguments()).Msg("Failed to bind arguments")
+		return errors.ErrMCPTool(err), nil
+	}
+	if strings.TrimSpace(req.Mode) == "" {
+		req.Mode = model.SearchModeHybrid
+	}
+	req.Cursor = ""
+	return s.searchMCP(req, mediaQueryOf(ctx))
+}
+
+// searchMCP 执行搜索并输出为文本，query 为媒体链接附加的查询参数
+func (s *Service) searchMCP(req SearchRequest, query string) (*mcp.CallToolResult, error) {
+
+	contextSize := 5
+	if req.Context != nil {
+		contextSize = *req.Context
+	}
+
+	sReq := &model.SearchRequest{
+		Query:   strings.TrimSpace(req.Query),
+		Talker:  strings.TrimSpace(req.Talker),
+		Sender:  strings.TrimSpace(req.Sender),
+		Types:   strings.TrimSpace(req.Type),
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+		Context: contextSize,
+		Cursor:  strings.TrimSpace(req.Cursor),
+		Mode:    strings.TrimSpace(req.Mode),
+	}
+	if strings.TrimSpace(req.Time) != "" {
+		start, end, ok := util.TimeRangeOf(req.Time)
+		if !ok {
+			return errors.ErrMCPTool(errors.InvalidArg("time")), nil
+		}
+		sReq.Start = start
+		sReq.End = end
+	}
+
+	resp, err := s.db.SearchMessages(sReq)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to search messages")
+		return errors.ErrMCPTool(err), nil
+	}
+
+	buf := &bytes.Buffer{}
+	if resp == nil || len(resp.Hits) == 0 {
+		buf.WriteString("未找到符合查询条件的聊天记录")
+		if resp != nil && resp.Index != nil && !resp.Index.Ready {
+			buf.WriteString("（全文索引尚未就绪，请稍后重试）")
+		}
+	} else {
+		fmt.Fprintf(buf, "共命中 %d 条，本次返回 %d 条\n", resp.Total, len(resp.Hits))
+		for idx, hit := range resp.Hits {
+			if hit == nil || hit.Message == nil {
+				continue
+			}
+			hit.Message.SetMediaQuery(query)
+			buf.WriteString(hit.PlainText(idx, ""))
+			buf.WriteString("-----------------------------\n")
+		}
+		if resp.NextCursor != "" {
+			fmt.Fprintf(buf, "还有更多结果，next_cursor: %s\n", resp.NextCursor)
+		} else if sReq.Mode != "" && resp.Offset+len(resp.Hits) < resp.Total {
+			fmt.Fprintf(buf, "还有更多结果，下一页 offset: %d\n", resp.Offset+len(resp.Hits))
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: buf.String(),
+			},
+		},
+	}, nil
+}
+
 func (s *Service) handleMCPCurrentTime(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
@@ -388,7 +558,7 @@ func (s *Service) handleMCPDiary(ctx context.Context, request mcp.CallToolReques
 	groups := make([]*grouped, 0)
 
 	for _, sess := range sessionsResp.Items {
-		msgs, err := s.db.GetMessages(start, end, sess.UserName, "", "", 0, 0)
+		msgs, err := s.db.GetMessages(start, end, sess.UserName, "", "", "", 0, 0)
 		if err != nil || len(msgs) == 0 {
 			continue
 		}
@@ -405,6 +575,7 @@ func (s *Service) handleMCPDiary(ctx context.Context, request mcp.CallToolReques
 		groups = append(groups, &grouped{Talker: sess.UserName, TalkerName: sess.NickName, Messages: msgs})
 	}
 
+	query := mediaQueryOf(ctx)
 	buf := &bytes.Buffer{}
 	if len(groups) == 0 {
 		buf.WriteString(fmt.Sprintf("最近%dh没有我参与的会话", hours))
@@ -427,6 +598,7 @@ func (s *Service) handleMCPDiary(ctx context.Context, request mcp.CallToolReques
 				buf.WriteString(" ")
 				buf.WriteString(sender)
 				buf.WriteString(" ")
+				m.SetMediaQuery(query)
 				buf.WriteString(m.PlainTextContent())
 				buf.WriteString("\n")
 			}
@@ -1,15 +1,74 @@
 package http
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/chatlog/conf"
 	"github.com/ysy950803/chatlog/internal/chatlog/database"
+	"github.com/ysy950803/chatlog/internal/errors"
 )
 
-func corsMiddleware() gin.HandlerFunc {
+// redactedParams 为请求日志中需要隐去值的查询参数
+var redactedParams = map[string]bool{"token": true, mediaSigParam: true}
+
+// requestLogger 记录请求日志，格式与 gin 默认日志一致，查询参数中的令牌替换为 REDACTED
+func requestLogger() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Output:    log.Logger,
+		SkipPaths: []string{"/health"},
+		Formatter: func(p gin.LogFormatterParams) string {
+			if p.Latency > time.Minute {
+				p.Latency = p.Latency.Truncate(time.Second)
+			}
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+				p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				p.StatusCode,
+				p.Latency,
+				p.ClientIP,
+				p.Method,
+				redactQuery(p.Path),
+				p.ErrorMessage,
+			)
+		},
+	})
+}
+
+// redactQuery 隐去 path 查询串中 redactedParams 的值，其余参数保持原样
+func redactQuery(path string) string {
+	base, query, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(key); err == nil && redactedParams[name] {
+			params[i] = key + "=REDACTED"
+		}
+	}
+	return base + "?" + strings.Join(params, "&")
+}
+
+// corsMiddleware 按 auth.cors_origins 设置允许的来源，未配置时允许任意来源
+func corsMiddleware(conf Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		auth := conf.GetAuth()
+		if auth.AllowAnyOrigin() {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Writer.Header().Add("Vary", "Origin")
+			if origin := c.GetHeader("Origin"); origin != "" && auth.AllowOrigin(origin) {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
@@ -23,6 +82,70 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// authMiddleware 校验请求携带的 API 令牌拥有 scope 权限，未配置令牌时不校验
+// 令牌通过 Authorization: Bearer <token> 请求头或 token 查询参数传递，media 权限的接口也接受有效的媒体签名
+func (s *Service) authMiddleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scope == conf.ScopeMedia && conf.VerifyMedia(c.Query(mediaSigParam)) {
+			c.Next()
+			return
+		}
+		if err := s.conf.GetAuth().Authorize(requestToken(c.Request), scope); err != nil {
+			errors.Err(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func requestToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return r.URL.Query().Get("token")
+}
+
+// 媒体链接签名的查询参数与有效期
+const (
+	mediaSigParam = "sig"
+	mediaLinkTTL  = 6 * time.Hour
+)
+
+// mediaQuery 返回输出中的媒体链接需要附加的查询参数：启用令牌校验且请求的令牌拥有 media 权限时为短期有效的签名，否则为空
+func (s *Service) mediaQuery(r *http.Request) string {
+	auth := s.conf.GetAuth()
+	if !auth.Enabled() || auth.Authorize(requestToken(r), conf.ScopeMedia) != nil {
+		return ""
+	}
+	return mediaSigParam + "=" + conf.SignMedia(mediaLinkTTL)
+}
+
+// withQuery 在链接后附加查询参数 query，query 为空时原样返回
+func withQuery(link, query string) string {
+	if query == "" {
+		return link
+	}
+	if strings.Contains(link, "?") {
+		return link + "&" + query
+	}
+	return link + "?" + query
+}
+
+// mediaQueryKey 为 MCP 请求 context 中媒体链接查询参数的键
+type mediaQueryKey struct{}
+
+// withMediaQuery 将请求对应的媒体链接查询参数放入 MCP 工具调用的 context
+func (s *Service) withMediaQuery(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, mediaQueryKey{}, s.mediaQuery(r))
+}
+
+// mediaQueryOf 返回 withMediaQuery 放入 context 的查询参数
+func mediaQueryOf(ctx context.Context) string {
+	query, _ := ctx.Value(mediaQueryKey{}).(string)
+	return query
+}
+
 func (s *Service) checkDBStateMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		switch s.db.State {
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ysy950803/chatlog/internal/chatlog/conf"
+	"github.com/ysy950803/chatlog/internal/errors"
+)
+
+type authTestConfig struct {
+	Config
+	auth *conf.AuthConfig
+}
+
+func (c authTestConfig) GetAuth() *conf.AuthConfig { return c.auth }
+
+// TestRouteScopes 保证除公开页面外的每个路由都校验令牌，且所需权限与 OpenAPI 文档中的 x-token-scope 一致
+func TestRouteScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := &conf.AuthConfig{}
+	tokens := make(map[string]string)
+	for _, scope := range conf.Scopes {
+		others := slices.DeleteFunc(slices.Clone(conf.Scopes), func(s string) bool { return s == scope })
+		token, err := auth.AddToken("without-"+scope, others)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[scope] = token
+	}
+	s := &Service{router: gin.New(), conf: authTestConfig{auth: auth}}
+	// 漏掉令牌校验的路由会调用到未初始化的依赖
+	s.router.Use(errors.RecoveryMiddleware())
+	s.initRouter()
+
+	// 修改数据的接口不能只凭只读令牌访问
+	for _, route := range []string{"POST /api/v1/transcribe", "DELETE /api/v1/transcribe", "POST /api/v1/saved-searches", "PUT /api/v1/saved-searches/:id", "DELETE /api/v1/saved-searches/:id"} {
+		method, path, _ := strings.Cut(route, " ")
+		if scope := apiScope(method, path); scope != conf.ScopeActions {
+			t.Errorf("%s scope = %q, want %q", route, scope, conf.ScopeActions)
+		}
+	}
+
+	public := map[string]bool{"/": true, "/favicon.ico": true, "/static/*filepath": true, "/health": true, "/api/v1/openapi.json": true}
+	mcpRoutes := map[string]bool{"/mcp": true, "/sse": true, "/message": true}
+	for _, r := range s.router.Routes() {
+		if public[r.Path] {
+			continue
+		}
+		scope := apiScope(r.Method, r.Path)
+		if mcpRoutes[r.Path] {
+			scope = conf.ScopeMCP
+		}
+		path := r.Path
+		for _, seg := range strings.Split(r.Path, "/") {
+			if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+				path = strings.Replace(path, seg, "x", 1)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(r.Method, path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without token: status %d, want 401", r.Method, r.Path, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(r.Method, path+"?token="+tokens[scope], nil)
+		s.router.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s without scope %s: status %d, want 403", r.Method, r.Path, scope, w.Code)
+		}
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := map[string]string{
+		"/api/v1/chatlog":                            "/api/v1/chatlog",
+		"/api/v1/chatlog?talker=a&token=secret":      "/api/v1/chatlog?talker=a&token=REDACTED",
+		"/image/abc?%74oken=secret&size=big":         "/image/abc?%74oken=REDACTED&size=big",
+		"/api/v1/search?query=token%3Dx&tokens=keep": "/api/v1/search?query=token%3Dx&tokens=keep",
+	}
+	for path, want := range tests {
+		if got := redactQuery(path); got != want {
+			t.Errorf("redactQuery(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// 启用令牌后，拥有 media 权限的请求输出的媒体链接带有签名，签名只能访问媒体接口
+func TestMediaQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := &conf.AuthConfig{}
+	reader, err := auth.AddToken("reader", []string{conf.ScopeRead, conf.ScopeMedia})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noMedia, err := auth.AddToken("no-media", []string{conf.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{router: gin.New(), conf: authTestConfig{auth: auth}}
+	s.router.Use(errors.RecoveryMiddleware())
+	s.initRouter()
+
+	if q := s.mediaQuery(httptest.NewRequest("GET", "/api/v1/chatlog?token="+noMedia, nil)); q != "" {
+		t.Errorf("media query without media scope = %q, want empty", q)
+	}
+	query := s.mediaQuery(httptest.NewRequest("GET", "/api/v1/chatlog?token="+reader, nil))
+	if !strings.HasPrefix(query, mediaSigParam+"=") {
+		t.Fatalf("media query = %q, want signature", query)
+	}
+
+	for path, want := range map[string]bool{
+		"/avatar/x?" + query:                           true,
+		"/image/x?" + query:                            true,
+		"/image/x?" + query + "0":                      false,
+		"/api/v1/contact?" + query:                     false,
+		"/image/x?sig=1.forged":                        false,
+		"/image/x?sig=" + conf.SignMedia(-time.Minute): false,
+	} {
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if got := w.Code != http.StatusUnauthorized; got != want {
+			t.Errorf("GET %s: status %d, want authorized %v", path, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,658 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql/gqlerrors"
+
+	"github.com/ysy950803/chatlog/internal/chatlog/conf"
+	"github.com/ysy950803/chatlog/internal/chatlog/graphql"
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb"
+	"github.com/ysy950803/chatlog/internal/whisper"
+	"github.com/ysy950803/chatlog/pkg/version"
+)
+
+// apiDoc 描述一个 HTTP 接口，Method 与 Path 和 gin 中注册的路由相同
+// 请求体与响应的 schema 由 Body、Response 的值的类型按 json tag 生成
+type apiDoc struct {
+	Method      string
+	Path        string
+	ID          string
+	Tag         string
+	Summary     string
+	Description string
+	Params      []apiParam
+	Formats     []string // format 参数的取值，第一个为默认值
+	Body        any
+	Status      int // 成功时的状态码，默认为 200
+	Response    any
+	Content     []string       // 非 JSON 的响应类型，如图片、音频
+	Responses   map[int]string // 其他状态码的说明，如 302、409
+}
+
+// apiParam 为 query 参数，路径参数由 Path 生成，也可以在 Params 中用同名的项说明
+type apiParam struct {
+	Name     string
+	Type     string // string、integer、boolean
+	Desc     string
+	Required bool
+	Enum     []string
+}
+
+// oneOf 表示按请求参数返回不同结构的 JSON 响应
+type oneOf []any
+
+func strParam(name, desc string) apiParam  { return apiParam{Name: name, Type: "string", Desc: desc} }
+func intParam(name, desc string) apiParam  { return apiParam{Name: name, Type: "integer", Desc: desc} }
+func boolParam(name, desc string) apiParam { return apiParam{Name: name, Type: "boolean", Desc: desc} }
+
+func (p apiParam) required() apiParam {
+	p.Required = true
+	return p
+}
+
+func (p apiParam) enum(values ...string) apiParam {
+	p.Enum = values
+	return p
+}
+
+var (
+	listParams = []apiParam{
+		strParam("keyword", "按备注、昵称、微信号及其拼音过滤，为空时返回全部"),
+		intParam("limit", "返回条数，0 表示不限"),
+		intParam("offset", "跳过的条数"),
+	}
+	messageFilterParams = []apiParam{
+		strParam("talker", "会话，微信 ID、群 ID 或名称，多个以英文逗号分隔"),
+		strParam("sender", "发送者，多个以英文逗号分隔"),
+		strParam("keyword", "消息内容关键词"),
+		strParam("type", "消息类别，如 file,link 或 49:6，以 - 开头表示排除"),
+	}
+	mediaParams = []apiParam{
+		boolParam("info", "返回媒体信息而不是内容"),
+	}
+)
+
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+type transcriptionResponse struct {
+	Key      string            `json:"key"`
+	Text     string            `json:"text"`
+	Language string            `json:"language"`
+	Duration float64           `json:"duration"`
+	Cached   bool              `json:"cached,omitempty"`
+	Segments []whisper.Segment `json:"segments,omitempty"`
+}
+
+// ambiguousTalker 为按名称解析 talker 的接口的 409 响应
+var ambiguousTalker = map[int]string{http.StatusConflict: "talker 匹配到多个联系人或群聊，candidates 为候选项"}
+
+// apiDocs 列出全部 HTTP 接口，新增路由时需要在此补充，见 TestOpenAPICoversRoutes
+var apiDocs = []apiDoc{
+	{Method: "GET", Path: "/health", ID: "health", Tag: "system", Summary: "健康检查", Response: statusResponse{}},
+	{Method: "GET", Path: "/api/v1/openapi.json", ID: "getOpenAPI", Tag: "system", Summary: "OpenAPI 描述文档", Response: map[string]any{}},
+
+	{Method: "GET", Path: "/api/v1/setting", ID: "getSetting", Tag: "settings", Summary: "读取配置", Response: settingResponse{}},
+	{Method: "POST", Path: "/api/v1/setting", ID: "updateSetting", Tag: "settings", Summary: "修改配置", Description: "只修改请求体中出现的字段",
+		Body: settingRequest{}, Response: settingResponse{}},
+
+	{Method: "POST", Path: "/api/v1/actions/get-data-key", ID: "getDataKey", Tag: "actions", Summary: "获取数据密钥", Response: statusResponse{}},
+	{Method: "POST", Path: "/api/v1/actions/decrypt", ID: "decrypt", Tag: "actions", Summary: "解密数据库", Response: statusResponse{}},
+	{Method: "POST", Path: "/api/v1/actions/http/start", ID: "startHTTP", Tag: "actions", Summary: "启动 HTTP 服务",
+		Status: http.StatusAccepted, Response: statusResponse{}, Responses: map[int]string{http.StatusOK: "服务已在运行"}},
+	{Method: "POST", Path: "/api/v1/actions/http/stop", ID: "stopHTTP", Tag: "actions", Summary: "停止 HTTP 服务",
+		Status: http.StatusAccepted, Response: statusResponse{}, Responses: map[int]string{http.StatusOK: "服务已停止"}},
+	{Method: "POST", Path: "/api/v1/actions/auto-decrypt/start", ID: "startAutoDecrypt", Tag: "actions", Summary: "开启自动解密", Response: statusResponse{}},
+	{Method: "POST", Path: "/api/v1/actions/auto-decrypt/stop", ID: "stopAutoDecrypt", Tag: "actions", Summary: "关闭自动解密", Response: statusResponse{}},
+	{Method: "POST", Path: "/api/v1/actions/index/rebuild", ID: "rebuildIndex", Tag: "index", Summary: "重建全文索引", Description: "在后台执行，进度通过 GET /api/v1/index 查看",
+		Params: []apiParam{strParam("store", "只处理指定的消息库")}, Status: http.StatusAccepted,
+		Response: struct {
+			Status string `json:"status"`
+			Store  string `json:"store"`
+		}{}},
+	{Method: "POST", Path: "/api/v1/actions/index/verify", ID: "verifyIndex", Tag: "index", Summary: "校验全文索引完整性",
+		Params: []apiParam{strParam("store", "只处理指定的消息库")}, Response: indexCheckResponse{}},
+	{Method: "POST", Path: "/api/v1/actions/index/optimize", ID: "optimizeIndex", Tag: "index", Summary: "FTS5 optimize",
+		Params: []apiParam{strParam("store", "只处理指定的消息库")}, Response: indexCheckResponse{}},
+	{Method: "POST", Path: "/api/v1/actions/index/vacuum", ID: "vacuumIndex", Tag: "index", Summary: "VACUUM 索引文件",
+		Params: []apiParam{strParam("store", "只处理指定的消息库")}, Response: indexCheckResponse{}},
+	{Method: "GET", Path: "/api/v1/index", ID: "getIndexStatus", Tag: "index", Summary: "索引状态", Response: model.IndexReport{}},
+
+	{Method: "GET", Path: "/api/v1/chatlog", ID: "getChatlog", Tag: "messages", Summary: "聊天记录",
+		Description: "未指定 talker 时按会话分组返回；指定 cursor 或 after_seq 时按序号分页返回 {items, next_cursor}，响应头 X-Next-Cursor 同 next_cursor；" +
+			"ndjson 格式每行一条消息，因 limit 截断时最后一行为 {\"next_cursor\": \"...\"}。cursor、after_seq 与 ndjson 格式需要指定 talker",
+		Params: append([]apiParam{
+			strParam("time", "时间范围，如 2024-01-01、2024-01-01~2024-01-31、last-7d"),
+		}, append(messageFilterParams,
+			intParam("limit", "返回条数，0 表示不限"),
+			intParam("offset", "跳过的条数"),
+			strParam("cursor", "上一页返回的 next_cursor"),
+			intParam("after_seq", "从该序号之后开始读取"),
+		)...),
+		Formats:   []string{"json", "html", "csv", "text", "ndjson"},
+		Response:  oneOf{[]*model.Message{}, []*talkerMessages{}, chatlogPage{}},
+		Responses: ambiguousTalker},
+	{Method: "GET", Path: "/api/v1/message/:talker/:seq", ID: "getMessage", Tag: "messages", Summary: "单条消息及其上下文",
+		Params: []apiParam{
+			strParam("talker", "会话，微信 ID、群 ID 或名称"),
+			intParam("seq", "消息序号"),
+			intParam("before", "之前的消息条数，默认 10"),
+			intParam("after", "之后的消息条数，默认 10"),
+		},
+		Formats: []string{"json", "html", "csv", "text"}, Response: model.MessageContext{}, Responses: ambiguousTalker},
+	{Method: "GET", Path: "/api/v1/thread/:talker/:seq", ID: "getThread", Tag: "messages", Summary: "引用回复链",
+		Description: "返回消息逐级引用的原消息，以及之后 days 天内直接或间接引用它的回复",
+		Params: []apiParam{
+			strParam("talker", "会话，微信 ID、群 ID 或名称"),
+			intParam("seq", "消息序号"),
+			intParam("days", "向后查找回复的天数，默认 7，最多 365"),
+		},
+		Formats: []string{"json", "html", "text"}, Response: model.ReplyThread{}, Responses: ambiguousTalker},
+	{Method: "GET", Path: "/api/v1/events", ID: "streamEvents", Tag: "messages", Summary: "新消息推送（SSE）",
+		Description: "事件名为 message，data 为 Message 的 JSON，id 为 \"<unix>-<seq>\"；带 Last-Event-ID 请求头或 last_event_id 参数时先补发该位置之后的消息",
+		Params: append(messageFilterParams,
+			intParam("heartbeat", "心跳间隔（秒），默认 15，取值 5~300"),
+			strParam("last_event_id", "从该事件之后继续推送"),
+		),
+		Content: []string{"text/event-stream"}, Responses: map[int]string{
+			http.StatusConflict: ambiguousTalker[http.StatusConflict],
+			http.StatusGone:     "Last-Event-ID 早于 24 小时或之后的消息超过 1000 条，需重新拉取聊天记录后不带事件 ID 订阅",
+		}},
+	{Method: "GET", Path: "/api/v1/ws", ID: "openWebSocket", Tag: "messages", Summary: "WebSocket 接口",
+		Description: "在一条连接上订阅新消息并执行查询，协议见 README",
+		Status:      http.StatusSwitchingProtocols},
+	{Method: "GET", Path: "/api/v1/graphql", ID: "queryGraphQL", Tag: "messages", Summary: "GraphQL 查询",
+		Params: []apiParam{
+			strParam("query", "GraphQL 查询").required(),
+			strParam("operationName", "要执行的操作名"),
+			strParam("variables", "JSON 格式的变量"),
+		},
+		Response: graphqlResponse{}},
+	{Method: "POST", Path: "/api/v1/graphql", ID: "postGraphQL", Tag: "messages", Summary: "GraphQL 查询",
+		Body: graphql.Request{}, Response: graphqlResponse{}},
+
+	{Method: "GET", Path: "/api/v1/contact", ID: "listContacts", Tag: "contacts", Summary: "联系人列表",
+		Params: listParams, Formats: []string{"json", "html", "csv", "text"}, Response: wechatdb.GetContactsResp{}},
+	{Method: "GET", Path: "/api/v1/chatroom", ID: "listChatRooms", Tag: "contacts", Summary: "群聊列表",
+		Params: listParams, Formats: []string{"json", "csv", "text"}, Response: wechatdb.GetChatRoomsResp{}},
+	{Method: "GET", Path: "/api/v1/resolve", ID: "resolveTalker", Tag: "contacts", Summary: "会话名称解析",
+		Params: []apiParam{
+			strParam("name", "会话名称，支持备注、昵称、微信号或其全拼、首字母").required(),
+			intParam("limit", "返回条数，默认 10"),
+			boolParam("all", "只列出候选项，不解析出唯一会话"),
+		},
+		Response: model.TalkerResolution{}},
+	{Method: "GET", Path: "/api/v1/session", ID: "listSessions", Tag: "contacts", Summary: "最近会话",
+		Params: listParams, Formats: []string{"json", "html", "csv", "text"}, Response: wechatdb.GetSessionsResp{}},
+
+	{Method: "GET", Path: "/api/v1/diary", ID: "getDiary", Tag: "messages", Summary: "日记",
+		Description: "返回指定日期内“我”参与的会话的消息，按会话分组",
+		Params: []apiParam{
+			strParam("date", "日期 YYYY-MM-DD，默认为今天"),
+			strParam("talker", "只看指定会话"),
+		},
+		Formats: []string{"json", "html", "csv", "text"}, Response: []*talkerMessages{}},
+	{Method: "GET", Path: "/api/v1/dashboard", ID: "getDashboard", Tag: "stats", Summary: "总结",
+		Params:   []apiParam{strParam("download", "为 1 时作为 dashboard.json 附件下载").enum("1")},
+		Response: Dashboard{}},
+	{Method: "GET", Path: "/api/v1/search", ID: "search", Tag: "search", Summary: "搜索",
+		Description: "q 支持 from:、in:、type:、has:、after:、before:、title:、desc:、url:、file:、location:、quote:、forward: 等操作符；" +
+			"next_cursor 非空时作为下一次请求的 cursor 翻页，响应头 X-Next-Cursor 同 next_cursor",
+		Params: []apiParam{
+			strParam("q", "关键词及操作符"),
+			strParam("talker", "会话，多个以英文逗号分隔"),
+			strParam("sender", "发送者，多个以英文逗号分隔"),
+			strParam("type", "消息类别，以 - 开头表示排除"),
+			strParam("time", "时间范围，与聊天记录接口相同"),
+			strParam("start", "开始时间，未指定 time 时有效"),
+			strParam("end", "结束时间，未指定 time 时有效"),
+			intParam("limit", "返回条数，默认 20，最多 200"),
+			intParam("offset", "跳过的条数，连续翻页建议使用 cursor；mode 为 semantic、hybrid 时 offset+limit 最多 1000"),
+			intParam("context", "每条命中前后附带的上下文条数"),
+			strParam("cursor", "上一页返回的 next_cursor"),
+			strParam("search_after", "cursor 的别名"),
+			boolParam("facets", "返回全部命中按会话、发送者、类型、月份的分布"),
+			strParam("mode", "检索方式").enum(model.SearchModeKeyword, model.SearchModeSemantic, model.SearchModeHybrid),
+		},
+		Formats: []string{"json", "html", "text", "csv"}, Response: model.SearchResponse{}, Responses: ambiguousTalker},
+
+	{Method: "GET", Path: "/api/v1/transcribe", ID: "getTranscribeStatus", Tag: "transcribe", Summary: "语音批量转写进度", Response: model.TranscribeStatus{}},
+	{Method: "POST", Path: "/api/v1/transcribe", ID: "startTranscribe", Tag: "transcribe", Summary: "开始语音批量转写",
+		Params: []apiParam{
+			strParam("talker", "只转写指定会话，为空时处理全部会话"),
+			strParam("time", "时间范围"),
+			boolParam("force", "重新转写已有结果的语音"),
+			boolParam("restart", "从头开始，不从断点继续"),
+		},
+		Status: http.StatusAccepted, Response: model.TranscribeStatus{}},
+	{Method: "DELETE", Path: "/api/v1/transcribe", ID: "stopTranscribe", Tag: "transcribe", Summary: "停止语音批量转写",
+		Response: oneOf{model.TranscribeStatus{}, statusResponse{}}},
+
+	{Method: "GET", Path: "/api/v1/saved-searches", ID: "listSavedSearches", Tag: "search", Summary: "常用搜索列表",
+		Response: struct {
+			Items []*model.SavedSearch `json:"items"`
+		}{}},
+	{Method: "POST", Path: "/api/v1/saved-searches", ID: "createSavedSearch", Tag: "search", Summary: "新建常用搜索",
+		Body: savedSearchRequest{}, Status: http.StatusCreated, Response: model.SavedSearch{}},
+	{Method: "GET", Path: "/api/v1/saved-searches/:id", ID: "getSavedSearch", Tag: "search", Summary: "读取常用搜索", Response: model.SavedSearch{}},
+	{Method: "PUT", Path: "/api/v1/saved-searches/:id", ID: "updateSavedSearch", Tag: "search", Summary: "修改常用搜索",
+		Body: savedSearchRequest{}, Response: model.SavedSearch{}},
+	{Method: "DELETE", Path: "/api/v1/saved-searches/:id", ID: "deleteSavedSearch", Tag: "search", Summary: "删除常用搜索", Response: statusResponse{}},
+
+	{Method: "GET", Path: "/image/*key", ID: "getImage", Tag: "media", Summary: "图片",
+		Description: "key 为消息中的图片 key 或数据目录下的相对路径，多个以英文逗号分隔时返回第一个能找到的",
+		Params:      mediaParams, Response: model.Media{}, Responses: map[int]string{http.StatusFound: "跳转到 /data/ 下的文件"}},
+	{Method: "GET", Path: "/video/*key", ID: "getVideo", Tag: "media", Summary: "视频",
+		Params: mediaParams, Response: model.Media{}, Responses: map[int]string{http.StatusFound: "跳转到 /data/ 下的文件"}},
+	{Method: "GET", Path: "/file/*key", ID: "getFile", Tag: "media", Summary: "文件",
+		Params: mediaParams, Response: model.Media{}, Responses: map[int]string{http.StatusFound: "跳转到 /data/ 下的文件"}},
+	{Method: "GET", Path: "/voice/*key", ID: "getVoice", Tag: "media", Summary: "语音",
+		Description: "返回转码后的 mp3，转码失败时返回原始 SILK；transcribe=1 时返回转写结果",
+		Params: append(mediaParams,
+			boolParam("transcribe", "返回语音转写结果"),
+			strParam("lang", "转写语言"),
+			boolParam("translate", "翻译为英文"),
+		),
+		Response: oneOf{transcriptionResponse{}, model.Media{}}, Content: []string{"audio/mp3", "audio/silk"}},
+	{Method: "GET", Path: "/data/*path", ID: "getData", Tag: "media", Summary: "数据目录下的文件",
+		Description: "加密的 .dat 图片解密后返回", Content: []string{"application/octet-stream"}},
+	{Method: "GET", Path: "/avatar/:username", ID: "getAvatar", Tag: "media", Summary: "头像",
+		Params: []apiParam{
+			strParam("username", "微信 ID 或群 ID"),
+			strParam("size", "尺寸").enum("small", "big"),
+		},
+		Content: []string{"image/jpeg"}, Responses: map[int]string{http.StatusFound: "跳转到头像地址"}},
+}
+
+// undocumentedRoutes 为不在描述文档中的路由：页面、静态文件与 MCP（由 MCP 协议描述）
+var undocumentedRoutes = map[string]bool{
+	"/":                 true,
+	"/favicon.ico":      true,
+	"/static/*filepath": true,
+	"/mcp":              true,
+	"/sse":              true,
+	"/message":          true,
+}
+
+type indexCheckResponse struct {
+	OK     bool                      `json:"ok"`
+	Stores []*model.IndexCheckResult `json:"stores"`
+}
+
+type graphqlResponse struct {
+	Data       any                        `json:"data"`
+	Errors     []gqlerrors.FormattedError `json:"errors,omitempty"`
+	Extensions map[string]any             `json:"extensions,omitempty"`
+}
+
+// formatContentTypes 为 format 参数各取值对应的响应类型
+var formatContentTypes = map[string]string{
+	"json":   "application/json",
+	"html":   "text/html",
+	"csv":    "text/csv",
+	"text":   "text/plain",
+	"ndjson": "application/x-ndjson",
+}
+
+var openAPISpec = sync.OnceValue(buildOpenAPISpec)
+
+// GET /api/v1/openapi.json
+func (s *Service) handleOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPISpec())
+}
+
+func buildOpenAPISpec() map[string]any {
+	g := &openAPISchemas{
+		schemas: map[string]any{
+			"Error": map[string]any{
+				"description": "错误信息，多数接口为 JSON 字符串，部分接口为 {error, detail}",
+				"oneOf": []any{
+					map[string]any{"type": "string"},
+					map[string]any{"type": "object", "properties": map[string]any{
+						"error":  map[string]any{"type": "string"},
+						"detail": map[string]any{"type": "string"},
+					}},
+				},
+			},
+		},
+		types: make(map[string]reflect.Type),
+	}
+	paths := make(map[string]map[string]any)
+	for i := range apiDocs {
+		d := &apiDocs[i]
+		path := openAPIPath(d.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(d.Method)] = g.operation(d)
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "chatlog HTTP API",
+			"version":     version.Version,
+			"description": "聊天记录、联系人、搜索等数据接口，以及配置、后台任务与多媒体内容。MCP 接口（/mcp、/sse）不在此文档中。配置了 API 令牌时，除 /health 与本文档外的接口都需要携带令牌，x-token-scope 为所需的权限",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+				"tokenQuery": map[string]any{"type": "apiKey", "in": "query", "name": "token"},
+			},
+		},
+	}
+}
+
+// openAPIPath 将 gin 路由中的 :name 与 *name 转为 {name}
+func openAPIPath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+type openAPISchemas struct {
+	schemas map[string]any
+	types   map[string]reflect.Type
+}
+
+func (g *openAPISchemas) operation(d *apiDoc) map[string]any {
+	op := map[string]any{
+		"operationId": d.ID,
+		"tags":        []string{d.Tag},
+		"summary":     d.Summary,
+	}
+	if d.Description != "" {
+		op["description"] = d.Description
+	}
+
+	params := make([]any, 0, len(d.Params)+2)
+	inPath := make(map[string]bool)
+	for _, seg := range strings.Split(d.Path, "/") {
+		if !strings.HasPrefix(seg, ":") && !strings.HasPrefix(seg, "*") {
+			continue
+		}
+		p := apiParam{Name: seg[1:], Type: "string"}
+		if seg[0] == '*' {
+			p.Desc = "可包含 /"
+		}
+		// Params 中与路径参数同名的项用于说明路径参数
+		for _, o := range d.Params {
+			if o.Name == p.Name {
+				p = o
+			}
+		}
+		p.Required = true
+		inPath[p.Name] = true
+		params = append(params, openAPIParam(p, "path"))
+	}
+	for _, p := range d.Params {
+		if !inPath[p.Name] {
+			params = append(params, openAPIParam(p, "query"))
+		}
+	}
+	if len(d.Formats) > 0 {
+		params = append(params, map[string]any{
+			"name":        "format",
+			"in":          "query",
+			"description": "输出格式，默认为 " + d.Formats[0],
+			"schema":      map[string]any{"type": "string", "enum": d.Formats, "default": d.Formats[0]},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if d.Body != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": g.schemaOf(d.Body)}},
+		}
+	}
+
+	content := make(map[string]any)
+	if d.Response != nil {
+		content["application/json"] = map[string]any{"schema": g.schemaOf(d.Response)}
+	}
+	for _, f := range d.Formats {
+		switch f {
+		case "json":
+		case "ndjson":
+			content[formatContentTypes[f]] = map[string]any{"schema": g.schemaOf(model.Message{})}
+		default:
+			content[formatContentTypes[f]] = map[string]any{"schema": map[string]any{"type": "string"}}
+		}
+	}
+	for _, ct := range d.Content {
+		schema := map[string]any{"type": "string", "format": "binary"}
+		if strings.HasPrefix(ct, "text/") {
+			schema = map[string]any{"type": "string"}
+		}
+		content[ct] = map[string]any{"schema": schema}
+	}
+	status := d.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := map[string]any{"description": http.StatusText(status)}
+	if len(content) > 0 {
+		ok["content"] = content
+	}
+	responses := map[string]any{
+		statusCode(status): ok,
+		"default": map[string]any{
+			"description": "错误",
+			"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}}},
+		},
+	}
+	for code, desc := range d.Responses {
+		resp := map[string]any{"description": desc}
+		if code == http.StatusConflict {
+			resp["content"] = map[string]any{"application/json": map[string]any{"schema": g.schemaOf(ambiguousTalkerResponse{})}}
+		}
+		responses[statusCode(code)] = resp
+	}
+	if scope := apiScope(d.Method, d.Path); scope != "" {
+		op["security"] = []any{map[string]any{"bearerAuth": []string{}}, map[string]any{"tokenQuery": []string{}}}
+		op["x-token-scope"] = scope
+		responses[statusCode(http.StatusUnauthorized)] = map[string]any{"description": "配置了 API 令牌但未携带或令牌无效"}
+		responses[statusCode(http.StatusForbidden)] = map[string]any{"description": "令牌没有 " + scope + " 权限"}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// apiScope 返回接口所需的令牌权限，与 route.go 中各路由组的 authMiddleware 对应
+func apiScope(method, path string) string {
+	switch {
+	case path == "/health" || path == "/api/v1/openapi.json":
+		return ""
+	case path == "/api/v1/setting":
+		return conf.ScopeSettings
+	case strings.HasPrefix(path, "/api/v1/actions/"):
+		return conf.ScopeActions
+	case method != http.MethodGet && (path == "/api/v1/transcribe" || strings.HasPrefix(path, "/api/v1/saved-searches")):
+		return conf.ScopeActions
+	case strings.HasPrefix(path, "/api/v1/"):
+		return conf.ScopeRead
+	default:
+		return conf.ScopeMedia
+	}
+}
+
+func openAPIParam(p apiParam, in string) map[string]any {
+	schema := map[string]any{"type": p.Type}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	param := map[string]any{"name": p.Name, "in": in, "schema": schema}
+	if p.Desc != "" {
+		param["description"] = p.Desc
+	}
+	if p.Required {
+		param["required"] = true
+	}
+	return param
+}
+
+// ambiguousTalkerResponse 为 errors.AmbiguousTalkerDetail 的具体结构
+type ambiguousTalkerResponse struct {
+	errors.AmbiguousTalkerDetail
+	Candidates []*model.NameCandidate `json:"candidates"`
+}
+
+func statusCode(code int) string {
+	return strconv.Itoa(code)
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaOf 按值的类型生成 schema，具名结构体放入 components 并返回引用
+func (g *openAPISchemas) schemaOf(v any) map[string]any {
+	if alts, ok := v.(oneOf); ok {
+		schemas := make([]any, 0, len(alts))
+		for _, alt := range alts {
+			schemas = append(schemas, g.schemaOf(alt))
+		}
+		return map[string]any{"oneOf": schemas}
+	}
+	return g.schemaOfType(reflect.TypeOf(v))
+}
+
+func (g *openAPISchemas) schemaOfType(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]any{"type": "integer", "format": "int64", "description": "纳秒"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schemaOfType(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaOfType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := componentName(t)
+		if prev, ok := g.types[name]; ok {
+			if prev != t {
+				panic("openapi: duplicate schema name " + name + " for " + prev.String() + " and " + t.String())
+			}
+		} else {
+			g.types[name] = t
+			g.schemas[name] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+func componentName(t reflect.Type) string {
+	r := []rune(t.Name())
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func (g *openAPISchemas) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	g.addFields(t, props, &required)
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields 与 encoding/json 一致：忽略 "-" 与未导出字段，展开无 tag 的嵌入结构体，外层字段覆盖嵌入字段
+func (g *openAPISchemas) addFields(t reflect.Type, props map[string]any, required *[]string) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schemaOfType(f.Type)
+		switch f.Type.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		default:
+			if !strings.Contains(opts, "omitempty") {
+				*required = append(*required, name)
+			}
+		}
+	}
+	for _, ft := range embedded {
+		inner := make(map[string]any)
+		var innerRequired []string
+		g.addFields(ft, inner, &innerRequired)
+		for _, name := range innerRequired {
+			if _, ok := props[name]; !ok {
+				*required = append(*required, name)
+			}
+		}
+		for name, schema := range inner {
+			if _, ok := props[name]; !ok {
+				props[name] = schema
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPICoversRoutes 保证 apiDocs 与注册的路由一一对应，新增路由时需要同时补充描述
+func TestOpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Service{router: gin.New()}
+	s.initRouter()
+
+	docs := make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, d := range apiDocs {
+		key := d.Method + " " + d.Path
+		if docs[key] {
+			t.Errorf("duplicate apiDocs entry %s", key)
+		}
+		docs[key] = true
+		if d.ID == "" || ids[d.ID] {
+			t.Errorf("apiDocs entry %s has empty or duplicate id %q", key, d.ID)
+		}
+		ids[d.ID] = true
+	}
+
+	routes := make(map[string]bool)
+	for _, r := range s.router.Routes() {
+		if undocumentedRoutes[r.Path] || r.Method == http.MethodHead {
+			continue
+		}
+		key := r.Method + " " + r.Path
+		routes[key] = true
+		if !docs[key] {
+			t.Errorf("route %s has no entry in apiDocs", key)
+		}
+	}
+	for key := range docs {
+		if !routes[key] {
+			t.Errorf("apiDocs entry %s has no route", key)
+		}
+	}
+
+	if _, err := json.Marshal(buildOpenAPISpec()); err != nil {
+		t.Fatalf("marshal spec: %v", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"io/fs"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -21,6 +22,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 
+	"github.com/ysy950803/chatlog/internal/chatlog/conf"
 	"github.com/ysy950803/chatlog/internal/errors"
 	"github.com/ysy950803/chatlog/internal/model"
 	"github.com/ysy950803/chatlog/pkg/util"
@@ -145,11 +147,29 @@ summary{cursor:pointer;font-weight:600;color:#2c3e50;}
 .meta .talker{color:#2c3e50;font-weight:600;}
 .meta .sender{color:#2c3e50;}
 .meta .time{color:#16a085;}
+.meta a.time{text-decoration:none;}
 .meta .score{font-family:monospace;color:#a0aec0;}
+.hit{margin:18px 0;padding-bottom:6px;border-bottom:1px dashed #dde1eb;}
+.msg.ctx{border-left-color:#cbd5e0;background:#fbfcfd;opacity:.85;margin:6px 0;}
+.facets{display:flex;flex-wrap:wrap;gap:12px;margin-bottom:18px;}
+.facet{flex:1 1 200px;background:#fff;padding:12px 14px;border-radius:10px;box-shadow:0 1px 4px rgba(18,38,63,0.08);}
+.facet h3{margin:0 0 8px;font-size:14px;}
+.facet a{display:flex;justify-content:space-between;gap:8px;padding:2px 0;color:#2c3e50;text-decoration:none;font-size:13px;}
+.facet a:hover{color:#3498db;}
+.facet .count{color:#a0aec0;font-family:monospace;}
+.pager{margin:18px 0;text-align:center;}
+.pager a{color:#3498db;text-decoration:none;font-weight:600;}
 pre{white-space:pre-wrap;word-break:break-word;margin:6px 0 0;}
 .empty{padding:28px;text-align:center;color:#768390;background:#fff;border-radius:10px;box-shadow:0 1px 4px rgba(18,38,63,0.08);}
 a.media{color:#2c3e50;text-decoration:none;border-bottom:1px dashed rgba(44,62,80,0.45);}
 a.media:hover{color:#0f4c81;}
+.quote{display:block;margin:0 0 6px;padding:4px 8px;border-left:3px solid #cbd5e0;background:#f4f6f9;color:#5f6c7b;}
+a.jump{color:#3498db;text-decoration:none;font-size:12px;}
+.replies{margin-left:24px;padding-left:8px;border-left:2px solid #dde1eb;}
+details.record{margin:4px 0;box-shadow:none;background:#fbfcfd;}
+.record-item{margin:6px 0;padding-left:8px;border-left:2px solid #dde1eb;}
+.record-item .sender{color:#2c3e50;font-weight:600;}
+.record-item .time{color:#a0aec0;font-size:12px;}
 </style></head><body>`
 
 func writeChatlogHTMLHeader(w io.Writer, title string) {
@@ -181,21 +201,25 @@ func (s *Service) initBaseRouter() {
 }
 
 func (s *Service) initMediaRouter() {
-	s.router.GET("/image/*key", func(c *gin.Context) { s.handleMedia(c, "image") })
-	s.router.GET("/video/*key", func(c *gin.Context) { s.handleMedia(c, "video") })
-	s.router.GET("/file/*key", func(c *gin.Context) { s.handleMedia(c, "file") })
-	s.router.GET("/voice/*key", func(c *gin.Context) { s.handleMedia(c, "voice") })
-	s.router.GET("/data/*path", s.handleMediaData)
-	s.router.GET("/avatar/:username", s.handleAvatar)
+	media := s.router.Group("", s.authMiddleware(conf.ScopeMedia))
+	media.GET("/image/*key", func(c *gin.Context) { s.handleMedia(c, "image") })
+	media.GET("/video/*key", func(c *gin.Context) { s.handleMedia(c, "video") })
+	media.GET("/file/*key", func(c *gin.Context) { s.handleMedia(c, "file") })
+	media.GET("/voice/*key", func(c *gin.Context) { s.handleMedia(c, "voice") })
+	media.GET("/data/*path", s.handleMediaData)
+	media.GET("/avatar/:username", s.handleAvatar)
 }
 
 func (s *Service) initAPIRouter() {
 	api := s.router.Group("/api/v1")
 	{
-		api.GET("/setting", s.handleGetSetting)
-		api.POST("/setting", s.handleUpdateSetting)
+		api.GET("/openapi.json", s.handleOpenAPI)
 
-		actions := api.Group("/actions")
+		setting := api.Group("/setting", s.authMiddleware(conf.ScopeSettings))
+		setting.GET("", s.handleGetSetting)
+		setting.POST("", s.handleUpdateSetting)
+
+		actions := api.Group("/actions", s.authMiddleware(conf.ScopeActions))
 		actions.POST("/get-data-key", s.handleActionGetDataKey)
 		actions.POST("/decrypt", s.handleActionDecrypt)
 		actions.POST("/http/start", s.handleActionStartHTTP)
@@ -203,132 +227,79 @@ func (s *Service) initAPIRouter() {
 		actions.POST("/auto-decrypt/start", s.handleActionStartAutoDecrypt)
 		actions.POST("/auto-decrypt/stop", s.handleActionStopAutoDecrypt)
 
-		dataAPI := api.Group("", s.checkDBStateMiddleware())
+		indexActions := actions.Group("/index", s.checkDBStateMiddleware())
+		indexActions.POST("/rebuild", s.handleActionIndexRebuild)
+		indexActions.POST("/verify", s.handleActionIndexCheck("verify"))
+		indexActions.POST("/optimize", s.handleActionIndexCheck("optimize"))
+		indexActions.POST("/vacuum", s.handleActionIndexCheck("vacuum"))
+
+		dataAPI := api.Group("", s.authMiddleware(conf.ScopeRead), s.checkDBStateMiddleware())
 		dataAPI.GET("/chatlog", s.handleChatlog)
+		dataAPI.GET("/message/:talker/:seq", s.handleMessage)
+		dataAPI.GET("/thread/:talker/:seq", s.handleThread)
+		dataAPI.GET("/events", s.handleEvents)
+		dataAPI.GET("/ws", s.handleWebSocket)
+		dataAPI.GET("/graphql", s.handleGraphQL)
+		dataAPI.POST("/graphql", s.handleGraphQL)
 		dataAPI.GET("/contact", s.handleContacts)
 		dataAPI.GET("/chatroom", s.handleChatRooms)
+		dataAPI.GET("/resolve", s.handleResolve)
 		dataAPI.GET("/session", s.handleSessions)
 		dataAPI.GET("/diary", s.handleDiary)
 		dataAPI.GET("/dashboard", s.handleDashboard)
 		dataAPI.GET("/search", s.handleSearch)
+		dataAPI.GET("/index", s.handleIndexStatus)
+		dataAPI.GET("/transcribe", s.handleTranscribeStatus)
+		dataAPI.GET("/saved-searches", s.handleListSavedSearches)
+		dataAPI.GET("/saved-searches/:id", s.handleGetSavedSearch)
+
+		// 启停后台任务、修改常用搜索需要 actions 权限，只读令牌只能查看
+		dataActions := api.Group("", s.authMiddleware(conf.ScopeActions), s.checkDBStateMiddleware())
+		dataActions.POST("/transcribe", s.handleTranscribeStart)
+		dataActions.DELETE("/transcribe", s.handleTranscribeStop)
+		dataActions.POST("/saved-searches", s.handleCreateSavedSearch)
+		dataActions.PUT("/saved-searches/:id", s.handleUpdateSavedSearch)
+		dataActions.DELETE("/saved-searches/:id", s.handleDeleteSavedSearch)
 	}
 }
 
 func (s *Service) initMCPRouter() {
-	s.router.Any("/mcp", func(c *gin.Context) { s.mcpStreamableServer.ServeHTTP(c.Writer, c.Request) })
-	s.router.Any("/sse", func(c *gin.Context) { s.mcpSSEServer.ServeHTTP(c.Writer, c.Request) })
-	s.router.Any("/message", func(c *gin.Context) { s.mcpSSEServer.ServeHTTP(c.Writer, c.Request) })
+	mcpAPI := s.router.Group("", s.authMiddleware(conf.ScopeMCP))
+	mcpAPI.Any("/mcp", func(c *gin.Context) { s.mcpStreamableServer.ServeHTTP(c.Writer, c.Request) })
+	mcpAPI.Any("/sse", func(c *gin.Context) { s.mcpSSEServer.ServeHTTP(c.Writer, c.Request) })
+	mcpAPI.Any("/message", func(c *gin.Context) { s.mcpSSEServer.ServeHTTP(c.Writer, c.Request) })
 }
 
-// GET /api/v1/dashboard
-func (s *Service) handleDashboard(c *gin.Context) {
-	// 基础聚合
-	gstats, err := s.db.GetDB().GlobalMessageStats()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "global stats failed", "detail": err.Error()})
-		return
-	}
-	groupCounts, _ := s.db.GetDB().GroupMessageCounts()
-
-	// 文件与目录大小
-	dataDir := s.conf.GetDataDir()
-	workDir := dataDir
-	if s.db != nil {
-		if wd := s.db.GetWorkDir(); wd != "" {
-			workDir = wd
-		}
-	}
-	dirSize := safeDirSize(dataDir)
-	dbSize := estimateDBSize(workDir)
-
-	// 当前账号昵称（overview.user）：优先从 WorkDir/DataDir 路径中提取 wxid_***，再用联系人 NickName 映射；找不到则回退 wxid
-	extractWxid := func(p string) string {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			return ""
-		}
-		// 遍历路径片段，优先返回形如 wxid_ 开头的片段
-		parts := strings.Split(filepath.Clean(p), string(filepath.Separator))
-		for _, seg := range parts {
-			if strings.HasPrefix(strings.ToLower(seg), "wxid_") {
-				return seg
-			}
-		}
-		// 兜底返回最后一段
-		return filepath.Base(filepath.Clean(p))
-	}
-
-	currentUser := ""
-	accountID := ""
-	// 先从 WorkDir 提取（更贴近实际解密目录结构），再从 DataDir 提取
-	if wd := s.db.GetWorkDir(); wd != "" && accountID == "" {
-		accountID = extractWxid(wd)
-	}
-	if accountID == "" {
-		accountID = extractWxid(dataDir)
-	}
-
-	// 若拿到候选 accountID，则尝试用联系人映射 NickName
-	if accountID != "" && accountID != "." && accountID != string(filepath.Separator) {
-		// Windows WeChat 4.x: v3 对应 wxid 可能带有第二段后缀，如 wxid_xxx_yyyy
-		// 查找昵称时需要去掉第二个下划线及其后内容
-		lookupID := accountID
-		low := strings.ToLower(lookupID)
-		if strings.HasPrefix(low, "wxid_") {
-			// 定位第二个下划线位置
-			rest := lookupID[len("wxid_"):]
-			if idx := strings.Index(rest, "_"); idx >= 0 {
-				lookupID = lookupID[:len("wxid_")+idx]
-			}
-		}
-		if clist, err := s.db.GetContacts(lookupID, 0, 0); err == nil && clist != nil {
-			for _, it := range clist.Items {
-				if it != nil && it.UserName == lookupID {
-					if strings.TrimSpace(it.NickName) != "" {
-						currentUser = it.NickName
-					}
-					break
-				}
-			}
-			if currentUser == "" && len(clist.Items) > 0 && clist.Items[0] != nil && clist.Items[0].UserName == lookupID {
-				currentUser = clist.Items[0].NickName
-			}
-		}
-		// 最终兜底：回退为 wxid/accountID
-		if strings.TrimSpace(currentUser) == "" {
-			currentUser = accountID
-		}
-	}
-
-	// 使用结构体固定 JSON 输出顺序
-	type DBStats struct {
+// GET /api/v1/dashboard 的响应，使用结构体固定 JSON 输出顺序
+type (
+	DBStats struct {
 		DbSizeMB  float64 `json:"db_size_mb"`
 		DirSizeMB float64 `json:"dir_size_mb"`
 	}
-	type MsgStats struct {
+	MsgStats struct {
 		TotalMsgs      int64 `json:"total_msgs"`
 		SentMsgs       int64 `json:"sent_msgs"`
 		ReceivedMsgs   int64 `json:"received_msgs"`
 		UniqueMsgTypes int   `json:"unique_msg_types"`
 	}
-	type OverviewGroup struct {
+	OverviewGroup struct {
 		ChatRoomName string `json:"ChatRoomName"`
 		NickName     string `json:"NickName"`
 		MemberCount  int    `json:"member_count"`
 		MessageCount int64  `json:"message_count"`
 	}
-	type Timeline struct {
+	Timeline struct {
 		Earliest int64 `json:"earliest_msg_time"`
 		Latest   int64 `json:"latest_msg_time"`
 		Duration int   `json:"duration_days"`
 	}
-	type Migration struct {
+	Migration struct {
 		ID        int    `json:"id"`
 		File      string `json:"file"`
 		Status    string `json:"status"`
 		CreatedAt string `json:"created_at"`
 	}
-	type Overview struct {
+	Overview struct {
 		User       string           `json:"user"`
 		DBStats    DBStats          `json:"dbStats"`
 		MsgStats   MsgStats         `json:"msgStats"`
@@ -338,14 +309,14 @@ func (s *Service) handleDashboard(c *gin.Context) {
 		Migrations []Migration      `json:"migrations"`
 	}
 
-	type GroupOverview struct {
+	GroupOverview struct {
 		TotalGroups    int    `json:"total_groups"`
 		ActiveGroups   int    `json:"active_groups"`
 		TodayMessages  int    `json:"today_messages"`
 		WeeklyAvg      int    `json:"weekly_avg"`
 		MostActiveHour string `json:"most_active_hour"`
 	}
-	type ContentAnalysis struct {
+	ContentAnalysis struct {
 		Text   int64 `json:"text_messages"`
 		Images int64 `json:"images"`
 		Voice  int64 `json:"voice_messages"`
@@ -353,40 +324,40 @@ func (s *Service) handleDashboard(c *gin.Context) {
 		Links  int64 `json:"links"`
 		Others int64 `json:"others"`
 	}
-	type GroupListItem struct {
+	GroupListItem struct {
 		Name     string `json:"name"`
 		Members  int    `json:"members"`
 		Messages int64  `json:"messages"`
 		Active   bool   `json:"active"`
 	}
-	type GroupAnalysis struct {
+	GroupAnalysis struct {
 		Title           string          `json:"title"`
 		Overview        GroupOverview   `json:"overview"`
 		ContentAnalysis ContentAnalysis `json:"content_analysis"`
 		GroupList       []GroupListItem `json:"group_list"`
 	}
-	type ContentTypeStats struct {
+	ContentTypeStats struct {
 		Count      int64    `json:"count"`
 		Percentage float64  `json:"percentage"`
 		SizeMB     *float64 `json:"size_mb,omitempty"`
 		Trend      *string  `json:"trend,omitempty"`
 	}
-	type SourceChannel struct {
+	SourceChannel struct {
 		Count      int64   `json:"count"`
 		Percentage float64 `json:"percentage"`
 	}
-	type ProcessingStatus struct {
+	ProcessingStatus struct {
 		Processed  int `json:"processed"`
 		Processing int `json:"processing"`
 		Pending    int `json:"pending"`
 	}
-	type QualityMetrics struct {
+	QualityMetrics struct {
 		DataIntegrity          float64 `json:"data_integrity"`
 		ClassificationAccuracy float64 `json:"classification_accuracy"`
 		DuplicateRate          float64 `json:"duplicate_rate"`
 		ErrorRate              float64 `json:"error_rate"`
 	}
-	type DataTypeAnalysis struct {
+	DataTypeAnalysis struct {
 		Title            string                      `json:"title"`
 		ContentTypes     map[string]ContentTypeStats `json:"content_types"`
 		SourceChannels   map[string]SourceChannel    `json:"source_channels"`
@@ -394,28 +365,109 @@ func (s *Service) handleDashboard(c *gin.Context) {
 		QualityMetrics   QualityMetrics              `json:"quality_metrics"`
 		PieGradient      string                      `json:"pieGradient,omitempty"`
 	}
-	type VisualizationDefaults struct {
+	VisualizationDefaults struct {
 		SelectedGroupIndex int `json:"selectedGroupIndex"`
 	}
-	type RelationshipNode struct {
+	RelationshipNode struct {
 		Name     string `json:"name"`
 		Type     string `json:"type"`
 		Messages int64  `json:"messages"`
 		Avatar   string `json:"avatar,omitempty"`
 	}
-	type RelationshipNetwork struct {
+	RelationshipNetwork struct {
 		Nodes []RelationshipNode `json:"nodes"`
 	}
-	type Visualization struct {
+	Visualization struct {
 		Defaults            VisualizationDefaults `json:"defaults"`
 		GroupAnalysis       GroupAnalysis         `json:"groupAnalysis"`
 		DataTypeAnalysis    DataTypeAnalysis      `json:"dataTypeAnalysis"`
 		RelationshipNetwork RelationshipNetwork   `json:"relationshipNetwork"`
 	}
-	type Dashboard struct {
+	Dashboard struct {
 		Overview      Overview      `json:"overview"`
 		Visualization Visualization `json:"visualization"`
 	}
+)
+
+// GET /api/v1/dashboard
+func (s *Service) handleDashboard(c *gin.Context) {
+	// 基础聚合
+	gstats, err := s.db.GetDB().GlobalMessageStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "global stats failed", "detail": err.Error()})
+		return
+	}
+	groupCounts, _ := s.db.GetDB().GroupMessageCounts()
+
+	// 文件与目录大小
+	dataDir := s.conf.GetDataDir()
+	workDir := dataDir
+	if s.db != nil {
+		if wd := s.db.GetWorkDir(); wd != "" {
+			workDir = wd
+		}
+	}
+	dirSize := safeDirSize(dataDir)
+	dbSize := estimateDBSize(workDir)
+
+	// 当前账号昵称（overview.user）：优先从 WorkDir/DataDir 路径中提取 wxid_***，再用联系人 NickName 映射；找不到则回退 wxid
+	extractWxid := func(p string) string {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			return ""
+		}
+		// 遍历路径片段，优先返回形如 wxid_ 开头的片段
+		parts := strings.Split(filepath.Clean(p), string(filepath.Separator))
+		for _, seg := range parts {
+			if strings.HasPrefix(strings.ToLower(seg), "wxid_") {
+				return seg
+			}
+		}
+		// 兜底返回最后一段
+		return filepath.Base(filepath.Clean(p))
+	}
+
+	currentUser := ""
+	accountID := ""
+	// 先从 WorkDir 提取（更贴近实际解密目录结构），再从 DataDir 提取
+	if wd := s.db.GetWorkDir(); wd != "" && accountID == "" {
+		accountID = extractWxid(wd)
+	}
+	if accountID == "" {
+		accountID = extractWxid(dataDir)
+	}
+
+	// 若拿到候选 accountID，则尝试用联系人映射 NickName
+	if accountID != "" && accountID != "." && accountID != string(filepath.Separator) {
+		// Windows WeChat 4.x: v3 对应 wxid 可能带有第二段后缀，如 wxid_xxx_yyyy
+		// 查找昵称时需要去掉第二个下划线及其后内容
+		lookupID := accountID
+		low := strings.ToLower(lookupID)
+		if strings.HasPrefix(low, "wxid_") {
+			// 定位第二个下划线位置
+			rest := lookupID[len("wxid_"):]
+			if idx := strings.Index(rest, "_"); idx >= 0 {
+				lookupID = lookupID[:len("wxid_")+idx]
+			}
+		}
+		if clist, err := s.db.GetContacts(lookupID, 0, 0); err == nil && clist != nil {
+			for _, it := range clist.Items {
+				if it != nil && it.UserName == lookupID {
+					if strings.TrimSpace(it.NickName) != "" {
+						currentUser = it.NickName
+					}
+					break
+				}
+			}
+			if currentUser == "" && len(clist.Items) > 0 && clist.Items[0] != nil && clist.Items[0].UserName == lookupID {
+				currentUser = clist.Items[0].NickName
+			}
+		}
+		// 最终兜底：回退为 wxid/accountID
+		if strings.TrimSpace(currentUser) == "" {
+			currentUser = accountID
+		}
+	}
 
 	// 群信息（合并消息计数）
 	type groupAggregate struct {
@@ -848,15 +900,21 @@ func estimateDBSize(workDir string) int64 {
 
 func (s *Service) handleSearch(c *gin.Context) {
 	params := struct {
-		Query  string `form:"q"`
-		Talker string `form:"talker"`
-		Sender string `form:"sender"`
-		Time   string `form:"time"`
-		Start  string `form:"start"`
-		End    string `form:"end"`
-		Limit  int    `form:"limit"`
-		Offset int    `form:"offset"`
-		Format string `form:"format"`
+		Query   string `form:"q"`
+		Talker  string `form:"talker"`
+		Sender  string `form:"sender"`
+		Time    string `form:"time"`
+		Start   string `form:"start"`
+		End     string `form:"end"`
+		Limit   int    `form:"limit"`
+		Offset  int    `form:"offset"`
+		Type    string `form:"type"`
+		Context int    `form:"context"`
+		Cursor  string `form:"cursor"`
+		After   string `form:"search_after"`
+		Facets  bool   `form:"facets"`
+		Mode    string `form:"mode"`
+		Format  string `form:"format"`
 	}{}
 
 	if err := c.BindQuery(&params); err != nil {
@@ -880,12 +938,29 @@ func (s *Service) handleSearch(c *gin.Context) {
 		offset = 0
 	}
 
+	contextSize := params.Context
+	if contextSize < 0 {
+		contextSize = 0
+	}
+	if contextSize > model.MaxSearchContext {
+		contextSize = model.MaxSearchContext
+	}
+
 	req := &model.SearchRequest{
-		Query:  query,
-		Talker: talker,
-		Sender: strings.TrimSpace(params.Sender),
-		Limit:  limit,
-		Offset: offset,
+		Query:   query,
+		Talker:  talker,
+		Sender:  strings.TrimSpace(params.Sender),
+		Types:   strings.TrimSpace(params.Type),
+		Limit:   limit,
+		Offset:  offset,
+		Context: contextSize,
+		Cursor:  strings.TrimSpace(params.Cursor),
+		Facets:  params.Facets,
+		Mode:    strings.TrimSpace(params.Mode),
+	}
+	// search_after 为 cursor 的别名
+	if req.Cursor == "" {
+		req.Cursor = strings.TrimSpace(params.After)
 	}
 
 	if params.Time != "" {
@@ -944,11 +1019,16 @@ func (s *Service) handleSearch(c *gin.Context) {
 	resp.End = req.End
 	resp.Limit = limit
 	resp.Offset = offset
+	resp.Context = contextSize
 
 	format := strings.ToLower(strings.TrimSpace(params.Format))
 	if format == "" {
 		format = "json"
 	}
+	mediaQuery := s.mediaQuery(c.Request)
+	if resp.NextCursor != "" {
+		c.Writer.Header().Set("X-Next-Cursor", resp.NextCursor)
+	}
 
 	switch format {
 	case "html":
@@ -976,8 +1056,17 @@ func (s *Service) handleSearch(c *gin.Context) {
 			timeLabel = "<= " + resp.End.Format("2006-01-02 15:04:05")
 		}
 		c.Writer.WriteString("<p class=\"meta\"><strong>时间范围：</strong>" + template.HTMLEscapeString(timeLabel) + "</p>")
+		if resp.Types != "" {
+			c.Writer.WriteString("<p class=\"meta\"><strong>消息类型：</strong>" + template.HTMLEscapeString(resp.Types) + "</p>")
+		}
+		if resp.Context > 0 {
+			c.Writer.WriteString(fmt.Sprintf("<p class=\"meta\"><strong>上下文：</strong>前后各 %d 条</p>", resp.Context))
+		}
 		c.Writer.WriteString(fmt.Sprintf("<p class=\"meta\"><strong>命中条数：</strong>%d（本页 %d 条）</p>", resp.Total, len(resp.Hits)))
 		c.Writer.WriteString("</div>")
+		if resp.Facets != nil {
+			writeSearchFacetsHTML(c.Writer, resp.Facets, c.Request.URL)
+		}
 
 		if len(resp.Hits) == 0 {
 			c.Writer.WriteString("<div class=\"empty\">暂无搜索结果</div>")
@@ -986,33 +1075,24 @@ func (s *Service) handleSearch(c *gin.Context) {
 				if hit == nil || hit.Message == nil {
 					continue
 				}
-				msg := hit.Message
-				msg.SetContent("host", c.Request.Host)
-				talkerDisplay := msg.Talker
-				if msg.TalkerName != "" {
-					talkerDisplay = fmt.Sprintf("%s (%s)", msg.TalkerName, msg.Talker)
+				c.Writer.WriteString("<div class=\"hit\">")
+				for _, m := range hit.Before {
+					s.writeSearchMessageHTML(c.Writer, m, c.Request.Host, mediaQuery, "", 0, true)
 				}
-				senderDisplay := msg.Sender
-				if msg.IsSelf {
-					senderDisplay = "我"
-				}
-				if msg.SenderName != "" {
-					senderDisplay = fmt.Sprintf("%s(%s)", msg.SenderName, msg.Sender)
-				}
-				avatarURL := template.HTMLEscapeString(s.composeAvatarURL(msg.Sender) + "?size=big")
-				talkerText := template.HTMLEscapeString(talkerDisplay)
-				senderText := template.HTMLEscapeString(senderDisplay)
-				timeText := template.HTMLEscapeString(msg.Time.Format("2006-01-02 15:04:05"))
-				c.Writer.WriteString("<div class=\"msg\"><div class=\"msg-row\"><img class=\"avatar\" src=\"" + avatarURL + "\" loading=\"lazy\" alt=\"avatar\" onerror=\"this.style.visibility='hidden'\"/><div class=\"msg-content\">")
-				c.Writer.WriteString("<div class=\"meta\"><span class=\"talker\">#" + fmt.Sprintf("%d", idx+1) + " · " + talkerText + "</span><span class=\"sender\">" + senderText + "</span><span class=\"time\">" + timeText + "</span>")
-				if hit.Score > 0 {
-					c.Writer.WriteString("<span class=\"score\">score: " + fmt.Sprintf("%.4f", hit.Score) + "</span>")
+				s.writeSearchMessageHTML(c.Writer, hit.Message, c.Request.Host, mediaQuery, fmt.Sprintf("#%d · ", idx+1), hit.Score, false)
+				for _, m := range hit.After {
+					s.writeSearchMessageHTML(c.Writer, m, c.Request.Host, mediaQuery, "", 0, true)
 				}
 				c.Writer.WriteString("</div>")
-				c.Writer.WriteString("<pre>" + messageHTMLPlaceholder(msg) + "</pre>")
-				c.Writer.WriteString("</div></div></div>")
 			}
 		}
+		if resp.NextCursor != "" {
+			next := c.Request.URL.Query()
+			next.Del("offset")
+			next.Del("search_after")
+			next.Set("cursor", resp.NextCursor)
+			c.Writer.WriteString("<div class=\"pager\"><a href=\"" + template.HTMLEscapeString(c.Request.URL.Path+"?"+next.Encode()) + "\">下一页 »</a></div>")
+		}
 		c.Writer.WriteString(previewHTMLSnippet)
 		c.Writer.WriteString("</body></html>")
 		return
@@ -1039,33 +1119,25 @@ func (s *Service) handleSearch(c *gin.Context) {
 		default:
 			fmt.Fprintln(c.Writer, "时间: 不限")
 		}
+		if resp.Context > 0 {
+			fmt.Fprintf(c.Writer, "上下文: 前后各 %d 条\n", resp.Context)
+		}
 		fmt.Fprintf(c.Writer, "总命中: %d, 本页: %d\n", resp.Total, len(resp.Hits))
+		if resp.Facets != nil {
+			c.Writer.WriteString(resp.Facets.PlainText())
+		}
 		fmt.Fprintln(c.Writer, strings.Repeat("-", 60))
 		for idx, hit := range resp.Hits {
 			if hit == nil || hit.Message == nil {
 				continue
 			}
-			msg := hit.Message
-			msg.SetContent("host", c.Request.Host)
-			title := msg.Talker
-			if msg.TalkerName != "" {
-				title = fmt.Sprintf("%s (%s)", msg.TalkerName, msg.Talker)
-			}
-			sender := msg.Sender
-			if msg.IsSelf {
-				sender = "我"
-			}
-			if msg.SenderName != "" {
-				sender = fmt.Sprintf("%s(%s)", msg.SenderName, msg.Sender)
-			}
-			fmt.Fprintf(c.Writer, "[%d] %s @ %s\n", idx+1, msg.Time.Format("2006-01-02 15:04:05"), title)
-			fmt.Fprintf(c.Writer, "发送者: %s\n", sender)
-			fmt.Fprintf(c.Writer, "%s\n", msg.PlainTextContent())
-			if snippet := strings.TrimSpace(hit.Snippet); snippet != "" {
-				fmt.Fprintf(c.Writer, "Snippet: %s\n", snippet)
-			}
+			hit.Message.SetMediaQuery(mediaQuery)
+			c.Writer.WriteString(hit.PlainText(idx, c.Request.Host))
 			fmt.Fprintln(c.Writer, strings.Repeat("-", 60))
 		}
+		if resp.NextCursor != "" {
+			fmt.Fprintf(c.Writer, "next_cursor: %s\n", resp.NextCursor)
+		}
 		return
 	case "csv":
 		c.Writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
@@ -1073,23 +1145,34 @@ func (s *Service) handleSearch(c *gin.Context) {
 		c.Writer.Header().Set("Connection", "keep-alive")
 		c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=search_%s.csv", time.Now().Format("20060102_150405")))
 		csvWriter := csv.NewWriter(c.Writer)
-		csvWriter.Write([]string{"Seq", "Time", "Talker", "TalkerName", "Sender", "SenderName", "Content", "Snippet"})
-		for _, hit := range resp.Hits {
+		csvWriter.Write([]string{"Seq", "Time", "Talker", "TalkerName", "Sender", "SenderName", "Content", "Snippet", "Hit", "Role"})
+		writeRow := func(idx int, m *model.Message, snippet, role string) {
+			m.SetContent("host", c.Request.Host)
+			m.SetMediaQuery(mediaQuery)
+			csvWriter.Write([]string{
+				fmt.Sprintf("%d", m.Seq),
+				m.Time.Format("2006-01-02 15:04:05"),
+				m.Talker,
+				m.TalkerName,
+				m.Sender,
+				m.SenderName,
+				m.PlainTextContent(),
+				strings.ReplaceAll(snippet, "\n", " "),
+				fmt.Sprintf("%d", idx+1),
+				role,
+			})
+		}
+		for idx, hit := range resp.Hits {
 			if hit == nil || hit.Message == nil {
 				continue
 			}
-			msg := hit.Message
-			msg.SetContent("host", c.Request.Host)
-			csvWriter.Write([]string{
-				fmt.Sprintf("%d", msg.Seq),
-				msg.Time.Format("2006-01-02 15:04:05"),
-				msg.Talker,
-				msg.TalkerName,
-				msg.Sender,
-				msg.SenderName,
-				msg.PlainTextContent(),
-				strings.ReplaceAll(hit.Snippet, "\n", " "),
-			})
+			for _, m := range hit.Before {
+				writeRow(idx, m, "", "before")
+			}
+			writeRow(idx, hit.Message, hit.Snippet, "hit")
+			for _, m := range hit.After {
+				writeRow(idx, m, "", "after")
+			}
 		}
 		csvWriter.Flush()
 		return
@@ -1102,15 +1185,112 @@ func (s *Service) handleSearch(c *gin.Context) {
 	}
 }
 
+// writeSearchFacetsHTML 输出搜索分面，每个分组链接到在当前条件上追加该筛选后的搜索结果
+func writeSearchFacetsHTML(w io.Writer, facets *model.SearchFacets, current *url.URL) {
+	refine := func(key, value string) string {
+		q := current.Query()
+		q.Del("offset")
+		q.Del("cursor")
+		q.Del("search_after")
+		switch key {
+		case "type":
+			// 类别条件可能包含排除项，追加而非替换
+			if prev := strings.TrimSpace(q.Get("type")); prev != "" {
+				value = prev + "," + value
+			}
+		case "time":
+			q.Del("start")
+			q.Del("end")
+		}
+		q.Set(key, value)
+		return current.Path + "?" + q.Encode()
+	}
+
+	groups := []struct {
+		title   string
+		key     string
+		buckets []*model.FacetBucket
+	}{
+		{"会话", "talker", facets.Talkers},
+		{"发送者", "sender", facets.Senders},
+		{"消息类型", "type", facets.Types},
+		{"月份", "time", facets.Months},
+	}
+
+	io.WriteString(w, "<div class=\"facets\">")
+	for _, g := range groups {
+		if len(g.buckets) == 0 {
+			continue
+		}
+		io.WriteString(w, "<div class=\"facet\"><h3>"+g.title+"</h3>")
+		for _, b := range g.buckets {
+			label := b.Value
+			if b.Label != "" {
+				label = b.Label
+			}
+			href := template.HTMLEscapeString(refine(g.key, b.Value))
+			io.WriteString(w, "<a href=\""+href+"\" title=\""+template.HTMLEscapeString(b.Value)+"\"><span>"+template.HTMLEscapeString(label)+"</span><span class=\"count\">"+fmt.Sprintf("%d", b.Count)+"</span></a>")
+		}
+		io.WriteString(w, "</div>")
+	}
+	io.WriteString(w, "</div>")
+}
+
+// writeSearchMessageHTML 输出搜索结果中的单条消息，ctx 为 true 时按上下文样式弱化显示
+// query 为媒体链接附加的查询参数，见 mediaQuery
+func (s *Service) writeSearchMessageHTML(w io.Writer, msg *model.Message, host, query, label string, score float64, ctx bool) {
+	msg.SetContent("host", host)
+	msg.SetMediaQuery(query)
+	senderDisplay := msg.Sender
+	if msg.IsSelf {
+		senderDisplay = "我"
+	}
+	if msg.SenderName != "" {
+		senderDisplay = fmt.Sprintf("%s(%s)", msg.SenderName, msg.Sender)
+	}
+	className := "msg"
+	if ctx {
+		className = "msg ctx"
+	}
+	avatarURL := template.HTMLEscapeString(withQuery(s.composeAvatarURL(msg.Sender)+"?size=big", query))
+	senderText := template.HTMLEscapeString(senderDisplay)
+	io.WriteString(w, "<div class=\""+className+"\"><div class=\"msg-row\"><img class=\"avatar\" src=\""+avatarURL+"\" loading=\"lazy\" alt=\"avatar\" onerror=\"this.style.visibility='hidden'\"/><div class=\"msg-content\">")
+	io.WriteString(w, "<div class=\"meta\">")
+	if !ctx {
+		talkerDisplay := msg.Talker
+		if msg.TalkerName != "" {
+			talkerDisplay = fmt.Sprintf("%s (%s)", msg.TalkerName, msg.Talker)
+		}
+		io.WriteString(w, "<span class=\"talker\">"+template.HTMLEscapeString(label+talkerDisplay)+"</span>")
+	}
+	io.WriteString(w, "<span class=\"sender\">"+senderText+"</span>"+messageTimeHTML(msg))
+	if score > 0 {
+		io.WriteString(w, "<span class=\"score\">score: "+fmt.Sprintf("%.4f", score)+"</span>")
+	}
+	io.WriteString(w, "</div>")
+	io.WriteString(w, "<pre>"+messageHTMLPlaceholder(msg)+"</pre>")
+	io.WriteString(w, "</div></div></div>")
+}
+
+// talkerMessages 为未指定 talker 时聊天记录与日记接口按会话分组的消息
+type talkerMessages struct {
+	Talker     string           `json:"talker"`
+	TalkerName string           `json:"talkerName,omitempty"`
+	Messages   []*model.Message `json:"messages"`
+}
+
 func (s *Service) handleChatlog(c *gin.Context) {
 	q := struct {
-		Time    string `form:"time"`
-		Talker  string `form:"talker"`
-		Sender  string `form:"sender"`
-		Keyword string `form:"keyword"`
-		Limit   int    `form:"limit"`
-		Offset  int    `form:"offset"`
-		Format  string `form:"format"`
+		Time     string `form:"time"`
+		Talker   string `form:"talker"`
+		Sender   string `form:"sender"`
+		Keyword  string `form:"keyword"`
+		Type     string `form:"type"`
+		Limit    int    `form:"limit"`
+		Offset   int    `form:"offset"`
+		Format   string `form:"format"`
+		AfterSeq *int64 `form:"after_seq"`
+		Cursor   string `form:"cursor"`
 	}{}
 
 	if err := c.BindQuery(&q); err != nil {
@@ -1129,10 +1309,31 @@ func (s *Service) handleChatlog(c *gin.Context) {
 		q.Offset = 0
 	}
 
+	if _, err := model.ParseMessageTypeFilter(q.Type); err != nil {
+		errors.Err(c, errors.InvalidMessageType(err))
+		return
+	}
+
 	format := strings.ToLower(strings.TrimSpace(q.Format))
 	if format == "" {
 		format = "json"
 	}
+	query := s.mediaQuery(c.Request)
+
+	// 指定 cursor/after_seq 或 NDJSON 格式时按序号顺序读取，只支持指定 talker
+	cursor, useCursor, err := chatlogCursor(q.Cursor, q.AfterSeq)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if (useCursor || format == "ndjson") && q.Talker == "" {
+		errors.Err(c, errors.ErrTalkerEmpty)
+		return
+	}
+	if format == "ndjson" {
+		s.streamChatlogNDJSON(c, start, end, q.Talker, q.Sender, q.Keyword, q.Type, cursor, q.Limit)
+		return
+	}
 
 	// 1. 未指定 talker: 分组输出
 	if q.Talker == "" {
@@ -1141,18 +1342,13 @@ func (s *Service) handleChatlog(c *gin.Context) {
 			errors.Err(c, err)
 			return
 		}
-		type grouped struct {
-			Talker     string           `json:"talker"`
-			TalkerName string           `json:"talkerName,omitempty"`
-			Messages   []*model.Message `json:"messages"`
-		}
-		groups := make([]*grouped, 0)
+		groups := make([]*talkerMessages, 0)
 		for _, sess := range sessionsResp.Items {
-			msgs, err := s.db.GetMessages(start, end, sess.UserName, q.Sender, q.Keyword, 0, 0)
+			msgs, err := s.db.GetMessages(start, end, sess.UserName, q.Sender, q.Keyword, q.Type, 0, 0)
 			if err != nil || len(msgs) == 0 {
 				continue
 			}
-			groups = append(groups, &grouped{Talker: sess.UserName, TalkerName: sess.NickName, Messages: msgs})
+			groups = append(groups, &talkerMessages{Talker: sess.UserName, TalkerName: sess.NickName, Messages: msgs})
 		}
 		switch format {
 		case "html":
@@ -1167,6 +1363,7 @@ func (s *Service) handleChatlog(c *gin.Context) {
 				c.Writer.WriteString("<details open><summary>" + template.HTMLEscapeString(title) + fmt.Sprintf(" - %d 条消息</summary>", len(g.Messages)))
 				for _, m := range g.Messages {
 					m.SetContent("host", c.Request.Host)
+					m.SetMediaQuery(query)
 					senderDisplay := m.Sender
 					if m.IsSelf {
 						senderDisplay = "我"
@@ -1176,9 +1373,8 @@ func (s *Service) handleChatlog(c *gin.Context) {
 					} else {
 						senderDisplay = template.HTMLEscapeString(senderDisplay)
 					}
-					aurl := template.HTMLEscapeString(s.composeAvatarURL(m.Sender) + "?size=big")
-					timeText := template.HTMLEscapeString(m.Time.Format("2006-01-02 15:04:05"))
-					c.Writer.WriteString("<div class=\"msg\"><div class=\"msg-row\"><img class=\"avatar\" src=\"" + aurl + "\" loading=\"lazy\" alt=\"avatar\" onerror=\"this.style.visibility='hidden'\"/><div class=\"msg-content\"><div class=\"meta\"><span class=\"sender\">" + senderDisplay + "</span><span class=\"time\">" + timeText + "</span></div><pre>" + messageHTMLPlaceholder(m) + "</pre></div></div></div>")
+					aurl := template.HTMLEscapeString(withQuery(s.composeAvatarURL(m.Sender)+"?size=big", query))
+					c.Writer.WriteString("<div class=\"msg\"><div class=\"msg-row\"><img class=\"avatar\" src=\"" + aurl + "\" loading=\"lazy\" alt=\"avatar\" onerror=\"this.style.visibility='hidden'\"/><div class=\"msg-content\"><div class=\"meta\"><span class=\"sender\">" + senderDisplay + "</span>" + messageTimeHTML(m) + "</div><pre>" + messageHTMLPlaceholder(m) + "</pre></div></div></div>")
 				}
 				c.Writer.WriteString("</details>")
 			}
@@ -1196,6 +1392,7 @@ func (s *Service) handleChatlog(c *gin.Context) {
 			csvWriter.Write([]string{"Talker", "TalkerName", "Time", "SenderName", "Sender", "Content"})
 			for _, g := range groups {
 				for _, m := range g.Messages {
+					m.SetMediaQuery(query)
 					csvWriter.Write([]string{g.Talker, g.TalkerName, m.Time.Format("2006-01-02 15:04:05"), m.SenderName, m.Sender, m.PlainTextContent()})
 				}
 			}
@@ -1219,6 +1416,7 @@ func (s *Service) handleChatlog(c *gin.Context) {
 					if m.SenderName != "" {
 						sender = m.SenderName + "(" + sender + ")"
 					}
+					m.SetMediaQuery(query)
 					c.Writer.WriteString(m.Time.Format("2006-01-02 15:04:05") + " " + sender + " " + m.PlainTextContent() + "\n")
 				}
 				c.Writer.WriteString("-----------------------------\n")
@@ -1230,10 +1428,34 @@ func (s *Service) handleChatlog(c *gin.Context) {
 	}
 
 	// 2. 指定 talker: 单会话消息
-	messages, err := s.db.GetMessages(start, end, q.Talker, q.Sender, q.Keyword, q.Limit, q.Offset)
-	if err != nil {
-		errors.Err(c, err)
-		return
+	var messages []*model.Message
+	if useCursor {
+		next, err := s.db.StreamMessages(c.Request.Context(), start, end, q.Talker, q.Sender, q.Keyword, q.Type, cursor, q.Limit, func(m *model.Message) error {
+			messages = append(messages, m)
+			return nil
+		})
+		if err != nil {
+			errors.Err(c, err)
+			return
+		}
+		page := chatlogPage{Items: messages}
+		if next != nil {
+			page.NextCursor = next.String()
+			c.Writer.Header().Set("X-Next-Cursor", page.NextCursor)
+		}
+		if page.Items == nil {
+			page.Items = []*model.Message{}
+		}
+		if format == "json" {
+			c.JSON(http.StatusOK, page)
+			return
+		}
+	} else {
+		messages, err = s.db.GetMessages(start, end, q.Talker, q.Sender, q.Keyword, q.Type, q.Limit, q.Offset)
+		if err != nil {
+			errors.Err(c, err)
+			return
+		}
 	}
 	switch format {
 	case "html":
@@ -1242,8 +1464,9 @@ func (s *Service) handleChatlog(c *gin.Context) {
 		c.Writer.WriteString(fmt.Sprintf("<h2>Messages %s ~ %s (%s)</h2>", start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05"), template.HTMLEscapeString(q.Talker)))
 		for _, m := range messages {
 			m.SetContent("host", c.Request.Host)
+			m.SetMediaQuery(query)
 			c.Writer.WriteString("<div class=\"msg\"><div class=\"msg-row\">")
-			aurl := template.HTMLEscapeString(s.composeAvatarURL(m.Sender) + "?size=big")
+			aurl := template.HTMLEscapeString(withQuery(s.composeAvatarURL(m.Sender)+"?size=big", query))
 			c.Writer.WriteString("<img class=\"avatar\" src=\"" + aurl + "\" loading=\"lazy\" alt=\"avatar\" onerror=\"this.style.visibility='hidden'\"/>")
 			c.Writer.WriteString("<div class=\"msg-content\"><div class=\"meta\"><span class=\"sender\">")
 			if m.SenderName != "" {
@@ -1253,8 +1476,7 @@ func (s *Service) handleChatlog(c *gin.Context) {
 			if m.SenderName != "" {
 				c.Writer.WriteString(")")
 			}
-			timeText := template.HTMLEscapeString(m.Time.Format("2006-01-02 15:04:05"))
-			c.Writer.WriteString("</span><span class=\"time\">" + timeText + "</span></div><pre>")
+			c.Writer.WriteString("</span>" + messageTimeHTML(m) + "</div><pre>")
 			c.Writer.WriteString(messageHTMLPlaceholder(m))
 			c.Writer.WriteString("</pre></div></div></div>")
 		}
@@ -1269,6 +1491,7 @@ func (s *Service) handleChatlog(c *gin.Context) {
 		csvWriter := csv.NewWriter(c.Writer)
 		csvWriter.Write([]string{"Time", "SenderName", "Sender", "TalkerName", "Talker", "Content"})
 		for _, m := range messages {
+			m.SetMediaQuery(query)
 			csvWriter.Write(m.CSV(c.Request.Host))
 		}
 		csvWriter.Flush()
@@ -1280,6 +1503,7 @@ func (s *Service) handleChatlog(c *gin.Context) {
 		c.Writer.Header().Set("Connection", "keep-alive")
 		c.Writer.Flush()
 		for _, m := range messages {
+			m.SetMediaQuery(query)
 			c.Writer.WriteString(m.PlainText(strings.Contains(q.Talker, ","), util.PerfectTimeFormat(start, end), c.Request.Host) + "\n")
 		}
 	}
@@ -1328,7 +1552,7 @@ func (s *Service) handleContacts(c *gin.Context) {
 			remark := template.HTMLEscapeString(contact.Remark)
 			alias := template.HTMLEscapeString(contact.Alias)
 			// compose avatar URL
-			aurl := template.HTMLEscapeString(s.composeAvatarURL(contact.UserName))
+			aurl := template.HTMLEscapeString(withQuery(s.composeAvatarURL(contact.UserName), s.mediaQuery(c.Request)))
 			c.Writer.WriteString(`<div class="c-item">`)
 			c.Writer.WriteString(`<img class="c-avatar" src="` + aurl + `" loading="lazy" onerror="this.style.visibility='hidden'"/>`)
 			c.Writer.WriteString(`<div>`)
@@ -1370,6 +1594,32 @@ func (s *Service) handleContacts(c *gin.Context) {
 	}
 }
 
+// GET /api/v1/resolve?name=张三&limit=10
+// 解析会话名称：能唯一确定时返回 userName，否则 ambiguous 为 true，候选项附带最近会话时间
+// chatlog、search 等接口遇到有歧义的 talker 时返回 409 及同样的候选项
+// all=1 时只按备注、昵称、微信号及其全拼、首字母列出候选项，不做解析，用于输入时的名称补全
+func (s *Service) handleResolve(c *gin.Context) {
+	q := struct {
+		Name  string `form:"name"`
+		Limit int    `form:"limit"`
+		All   bool   `form:"all"`
+	}{}
+	if err := c.BindQuery(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+
+	resolution, err := s.db.ResolveTalker(q.Name, q.Limit, q.All)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resolution)
+}
+
 // composeAvatarURL builds a relative URL that the server can serve for any username
 func (s *Service) composeAvatarURL(username string) string {
 	if username == "" {
@@ -1568,15 +1818,10 @@ func (s *Service) handleDiary(c *gin.Context) {
 		return
 	}
 
-	type grouped struct {
-		Talker     string           `json:"talker"`
-		TalkerName string           `json:"talkerName,omitempty"`
-		Messages   []*model.Message `json:"messages"`
-	}
-	groups := make([]*grouped, 0)
+	groups := make([]*talkerMessages, 0)
 
 	for _, sess := range sessionsResp.Items {
-		msgs, err := s.db.GetMessages(start, end, sess.UserName, "", "", 0, 0)
+		msgs, err := s.db.GetMessages(start, end, sess.UserName, "", "", "", 0, 0)
 		if err != nil || len(msgs) == 0 {
 			continue
 		}
@@ -1590,17 +1835,18 @@ func (s *Service) handleDiary(c *gin.Context) {
 		if !hasSelf {
 			continue
 		}
-		groups = append(groups, &grouped{Talker: sess.UserName, TalkerName: sess.NickName, Messages: msgs})
+		groups = append(groups, &talkerMessages{Talker: sess.UserName, TalkerName: sess.NickName, Messages: msgs})
 	}
 
 	format := strings.ToLower(strings.TrimSpace(q.Format))
 	if format == "" {
 		format = "json"
 	}
+	query := s.mediaQuery(c.Request)
 	switch format {
 	case "html":
 		c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
-		c.Writer.WriteString(`<html><head><meta charset="utf-8"><title>Diary</title><style>body{font-family:Arial,Helvetica,sans-serif;font-size:14px;}details{margin:8px 0;padding:6px 8px;border:1px solid #ddd;border-radius:6px;background:#fafafa;}summary{cursor:pointer;font-weight:600;} .msg{margin:4px 0;padding:4px 6px;border-left:3px solid #2ecc71;background:#fff;} .msg-row{display:flex;gap:8px;align-items:flex-start;} .avatar{width:28px;height:28px;border-radius:6px;object-fit:cover;background:#f2f2f2;border:1px solid #eee;flex:0 0 28px} .msg-content{flex:1;min-width:0} .meta{color:#666;font-size:12px;margin-bottom:2px;} pre{white-space:pre-wrap;word-break:break-word;margin:0;} .sender{color:#27ae60;} .time{color:#16a085;margin-left:6px;} a.media{color:#2c3e50;text-decoration:none;} a.media:hover{text-decoration:underline;}</style></head><body>`)
+		c.Writer.WriteString(`<html><head><meta charset="utf-8"><title>Diary</title><style>body{font-family:Arial,Helvetica,sans-serif;font-size:14px;}details{margin:8px 0;padding:6px 8px;border:1px solid #ddd;border-radius:6px;background:#fafafa;}summary{cursor:pointer;font-weight:600;} .msg{margin:4px 0;padding:4px 6px;border-left:3px solid #2ecc71;background:#fff;} .msg-row{display:flex;gap:8px;align-items:flex-start;} .avatar{width:28px;height:28px;border-radius:6px;object-fit:cover;background:#f2f2f2;border:1px solid #eee;flex:0 0 28px} .msg-content{flex:1;min-width:0} .meta{color:#666;font-size:12px;margin-bottom:2px;} pre{white-space:pre-wrap;word-break:break-word;margin:0;} .sender{color:#27ae60;} .time{color:#16a085;margin-left:6px;} a.time{text-decoration:none;} a.media{color:#2c3e50;text-decoration:none;} a.media:hover{text-decoration:underline;} .quote{display:block;margin:0 0 4px;padding:2px 6px;border-left:3px solid #ddd;background:#f7f7f7;color:#666;} a.jump{color:#2980b9;text-decoration:none;font-size:12px;} .record-item{margin:4px 0;padding-left:6px;border-left:2px solid #ddd;} .record-item .time{color:#999;font-size:12px;}</style></head><body>`)
 		c.Writer.WriteString(fmt.Sprintf("<h2>%s</h2>", template.HTMLEscapeString(heading)))
 		for _, g := range groups {
 			title := g.Talker
@@ -1610,6 +1856,7 @@ func (s *Service) handleDiary(c *gin.Context) {
 			c.Writer.WriteString("<details open><summary>" + template.HTMLEscapeString(title) + fmt.Sprintf(" - %d 条消息</summary>", len(g.Messages)))
 			for _, m := range g.Messages {
 				m.SetContent("host", c.Request.Host)
+				m.SetMediaQuery(query)
 				senderDisplay := m.Sender
 				if m.IsSelf {
 					senderDisplay = "我"
@@ -1619,8 +1866,8 @@ func (s *Service) handleDiary(c *gin.Context) {
 				} else {
 					senderDisplay = template.HTMLEscapeString(senderDisplay)
 				}
-				aurl := template.HTMLEscapeString(s.composeAvatarURL(m.Sender) + "?size=big")
-				c.Writer.WriteString("<div class=\"msg\"><div class=\"msg-row\"><img class=\"avatar\" src=\"" + aurl + "\" loading=\"lazy\" alt=\"avatar\" onerror=\"this.style.visibility='hidden'\"/><div class=\"msg-content\"><div class=\"meta\"><span class=\"sender\">" + senderDisplay + "</span><span class=\"time\">" + m.Time.Format("2006-01-02 15:04:05") + "</span></div><pre>" + messageHTMLPlaceholder(m) + "</pre></div></div></div>")
+				aurl := template.HTMLEscapeString(withQuery(s.composeAvatarURL(m.Sender)+"?size=big", query))
+				c.Writer.WriteString("<div class=\"msg\"><div class=\"msg-row\"><img class=\"avatar\" src=\"" + aurl + "\" loading=\"lazy\" alt=\"avatar\" onerror=\"this.style.visibility='hidden'\"/><div class=\"msg-content\"><div class=\"meta\"><span class=\"sender\">" + senderDisplay + "</span>" + messageTimeHTML(m) + "</div><pre>" + messageHTMLPlaceholder(m) + "</pre></div></div></div>")
 			}
 			c.Writer.WriteString("</details>")
 		}
@@ -1637,6 +1884,7 @@ func (s *Service) handleDiary(c *gin.Context) {
 		writer.Write([]string{"Talker", "TalkerName", "Time", "SenderName", "Sender", "Content"})
 		for _, g := range groups {
 			for _, m := range g.Messages {
+				m.SetMediaQuery(query)
 				writer.Write([]string{m.Talker, m.TalkerName, m.Time.Format("2006-01-02 15:04:05"), m.SenderName, m.Sender, m.PlainTextContent()})
 			}
 		}
@@ -1664,6 +1912,7 @@ func (s *Service) handleDiary(c *gin.Context) {
 				c.Writer.WriteString(" ")
 				c.Writer.WriteString(senderDisplay)
 				c.Writer.WriteString(" ")
+				m.SetMediaQuery(query)
 				c.Writer.WriteString(m.PlainTextContent())
 				c.Writer.WriteString("\n")
 			}
@@ -1672,6 +1921,15 @@ func (s *Service) handleDiary(c *gin.Context) {
 	}
 }
 
+// redirectMediaData 跳转到 /data/ 下的文件，启用令牌校验时附加新的媒体签名，请求中的令牌不会随跳转传递
+func (s *Service) redirectMediaData(c *gin.Context, path string) {
+	target := "/data/" + path
+	if s.conf.GetAuth().Enabled() {
+		target = withQuery(target, mediaSigParam+"="+conf.SignMedia(mediaLinkTTL))
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
 func (s *Service) handleMedia(c *gin.Context, _type string) {
 	key := strings.TrimPrefix(c.Param("key"), "/")
 	if key == "" {
@@ -1689,7 +1947,7 @@ func (s *Service) handleMedia(c *gin.Context, _type string) {
 	for _, k := range keys {
 		if strings.Contains(k, "/") {
 			if absolutePath, err := s.findPath(_type, k); err == nil {
-				c.Redirect(http.StatusFound, "/data/"+absolutePath)
+				s.redirectMediaData(c, absolutePath)
 				return
 			}
 		}
@@ -1711,7 +1969,7 @@ func (s *Service) handleMedia(c *gin.Context, _type string) {
 			s.HandleVoice(c, media.Data)
 			return
 		default:
-			c.Redirect(http.StatusFound, "/data/"+media.Path)
+			s.redirectMediaData(c, media.Path)
 			return
 		}
 	}
@@ -1723,6 +1981,16 @@ func (s *Service) handleMedia(c *gin.Context, _type string) {
 }
 
 func (s *Service) handleVoiceTranscription(c *gin.Context, key string, media *model.Media) {
+	// 未指定语言或翻译时优先返回后台任务已保存的转写结果
+	if c.Query("lang") == "" && c.Query("translate") == "" {
+		if db := s.db.GetDB(); db != nil {
+			if t := db.Transcripts().Get(key); t != nil {
+				c.JSON(http.StatusOK, gin.H{"key": key, "text": t.Text, "language": t.Language, "duration": t.Duration, "cached": true})
+				return
+			}
+		}
+	}
+
 	if s.speechTranscriber == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "speech transcription not enabled"})
 		return
@@ -1875,7 +2143,95 @@ var (
 )
 
 func messageHTMLPlaceholder(m *model.Message) string {
-	content := m.PlainTextContent()
+	if q := m.Quote(); q != nil {
+		return quoteHTML(m, q)
+	}
+	if record, ok := m.Contents["record"].(*model.ForwardRecord); ok && record != nil {
+		return forwardRecordHTML(record, true, m.MediaQuery())
+	}
+	return placeholderHTML(m.PlainTextContent())
+}
+
+// forwardRecordHTML 将合并转发渲染为可折叠的聊天记录，嵌套的合并转发默认折叠，query 为媒体链接附加的查询参数
+func forwardRecordHTML(record *model.ForwardRecord, open bool, query string) string {
+	buf := strings.Builder{}
+	buf.WriteString(`<details class="record"`)
+	if open {
+		buf.WriteString(" open")
+	}
+	buf.WriteString("><summary>[合并转发|" + template.HTMLEscapeString(record.Title) + fmt.Sprintf("] %d 条</summary>", len(record.Items)))
+	for _, item := range record.Items {
+		buf.WriteString(`<div class="record-item"><span class="sender">` + template.HTMLEscapeString(item.SenderName) + `</span> <span class="time">` + template.HTMLEscapeString(item.Time) + `</span><br/>`)
+		buf.WriteString(forwardItemHTML(item, query))
+		buf.WriteString("</div>")
+	}
+	buf.WriteString("</details>")
+	return buf.String()
+}
+
+// forwardItemHTML 渲染合并转发中的一条消息，媒体链接到本地的 /image、/video、/file
+func forwardItemHTML(item *model.ForwardItem, query string) string {
+	if item.Record != nil {
+		return forwardRecordHTML(item.Record, false, query)
+	}
+	if path := item.MediaPath(); path != "" {
+		label := map[string]string{"image": "图片", "video": "视频", "file": "文件"}[item.MediaType]
+		if item.MediaType == "file" && item.Title != "" {
+			label += "|" + item.Title
+		}
+		return `<a class="media" href="` + template.HTMLEscapeString(withQuery(path, query)) + `" target="_blank">[` + template.HTMLEscapeString(label) + `]</a>`
+	}
+	switch item.DataType {
+	case model.RecordDataLink, model.RecordDataMusic:
+		label := "链接"
+		if item.DataType == model.RecordDataMusic {
+			label = "音乐"
+		}
+		if item.Title != "" {
+			label += "|" + item.Title
+		}
+		if item.URL == "" {
+			return "[" + template.HTMLEscapeString(label) + "]"
+		}
+		return `<a class="media" href="` + template.HTMLEscapeString(item.URL) + `" target="_blank">[` + template.HTMLEscapeString(label) + `]</a>`
+	case model.RecordDataLocation:
+		return "[位置|" + template.HTMLEscapeString(item.Title) + "]"
+	case model.RecordDataEmoji:
+		return "[动画表情]"
+	}
+	return strings.ReplaceAll(template.HTMLEscapeString(item.Content), "\n", "<br/>")
+}
+
+// quoteHTML 将引用消息渲染为引用块与回复内容，原消息在本地时可跳转到原消息
+func quoteHTML(m *model.Message, q *model.QuoteRef) string {
+	buf := strings.Builder{}
+	buf.WriteString(`<span class="quote">`)
+	if refer, ok := m.Contents["refer"].(*model.Message); ok && refer != nil {
+		if host, ok := m.Contents["host"].(string); ok {
+			refer.SetContent("host", host)
+		}
+		refer.SetMediaQuery(m.MediaQuery())
+		sender := refer.SenderName
+		if sender == "" {
+			sender = refer.Sender
+		}
+		buf.WriteString(template.HTMLEscapeString(sender) + ": " + messageHTMLPlaceholder(refer))
+	} else {
+		buf.WriteString("[引用]")
+	}
+	if q.Resolved() {
+		buf.WriteString(` <a class="jump" href="` + template.HTMLEscapeString(q.Permalink+"?format=html") + `" title="跳转到原消息">↑ 原消息</a>`)
+	}
+	buf.WriteString("</span>")
+	buf.WriteString(placeholderHTML(m.Content))
+	if m.Seq > 0 && m.Talker != "" {
+		buf.WriteString(` <a class="jump" href="` + template.HTMLEscapeString(model.ThreadPath(m.Talker, m.Seq)+"?format=html") + `">回复链</a>`)
+	}
+	return buf.String()
+}
+
+// placeholderHTML 将文本中的 [标签](url) 占位符渲染为链接
+func placeholderHTML(content string) string {
 	return placeholderPattern.ReplaceAllStringFunc(content, func(s string) string {
 		matches := placeholderPattern.FindStringSubmatch(s)
 		if len(matches) != 3 {
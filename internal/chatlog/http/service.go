@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/ysy950803/chatlog/internal/chatlog/conf"
 	"github.com/ysy950803/chatlog/internal/chatlog/database"
+	"github.com/ysy950803/chatlog/internal/chatlog/graphql"
 	"github.com/ysy950803/chatlog/internal/errors"
 	"github.com/ysy950803/chatlog/internal/whisper"
 )
@@ -28,8 +30,13 @@ type Service struct {
 	mcpSSEServer        *server.SSEServer
 	mcpStreamableServer *server.StreamableHTTPServer
 
+	graphql *graphql.Executor
+
 	speechTranscriber whisper.Transcriber
 	speechOptions     whisper.Options
+
+	// 后台语音转写任务
+	transcribe transcribeJob
 }
 
 type Config interface {
@@ -46,6 +53,7 @@ type Config interface {
 	IsHTTPEnabled() bool
 	IsAutoDecrypt() bool
 	GetSpeech() *conf.SpeechConfig
+	GetAuth() *conf.AuthConfig
 }
 
 type Control interface {
@@ -72,8 +80,8 @@ func NewService(conf Config, db *database.Service, control Control) *Service {
 	router.Use(
 		errors.RecoveryMiddleware(),
 		errors.ErrorHandlerMiddleware(),
-		gin.LoggerWithWriter(log.Logger, "/health"),
-		corsMiddleware(),
+		requestLogger(),
+		corsMiddleware(conf),
 	)
 
 	s := &Service{
@@ -84,6 +92,7 @@ func NewService(conf Config, db *database.Service, control Control) *Service {
 	}
 
 	s.initMCPServer()
+	s.initGraphQL()
 	s.initRouter()
 	s.initSpeech(conf)
 	return s
@@ -161,6 +170,8 @@ func (s *Service) initSpeech(cfg Config) {
 }
 
 func (s *Service) ReloadSpeech() {
+	// 转写任务持有旧的 Transcriber，重新初始化前先停止
+	s.transcribe.stop()
 	s.initSpeech(s.conf)
 }
 
@@ -179,6 +190,7 @@ func (s *Service) Start() error {
 	}()
 
 	log.Info().Msg("Starting HTTP server on " + s.conf.GetHTTPAddr())
+	s.warnNoAuth()
 
 	return nil
 }
@@ -191,9 +203,25 @@ func (s *Service) ListenAndServe() error {
 	}
 
 	log.Info().Msg("Starting HTTP server on " + s.conf.GetHTTPAddr())
+	s.warnNoAuth()
 	return s.server.ListenAndServe()
 }
 
+// warnNoAuth 在监听非回环地址且未配置 API 令牌时提示，此时局域网内任何人都可以访问所有接口
+func (s *Service) warnNoAuth() {
+	if s.conf.GetAuth().Enabled() {
+		return
+	}
+	host, _, err := net.SplitHostPort(s.conf.GetHTTPAddr())
+	if err != nil {
+		return
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return
+	}
+	log.Warn().Msg("HTTP server is listening on a non-loopback address without api tokens, add one with `chatlog token add` (with --server for the server command)")
+}
+
 func (s *Service) Stop() error {
 
 	if s.server == nil {
@@ -209,6 +237,7 @@ func (s *Service) Stop() error {
 		return nil
 	}
 
+	s.transcribe.stop()
 	if s.speechTranscriber != nil {
 		s.speechTranscriber.Close()
 		s.speechTranscriber = nil
@@ -0,0 +1,342 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb"
+	"github.com/ysy950803/chatlog/internal/whisper"
+	"github.com/ysy950803/chatlog/pkg/util"
+)
+
+const (
+	// transcribeBatchSize 每转写多少条语音写入一次转写库和全文索引
+	transcribeBatchSize = 16
+	// transcribeTimeout 单条语音的转写超时
+	transcribeTimeout = 2 * time.Minute
+)
+
+// transcribeJob 为后台语音转写任务，同一时间只运行一个
+type transcribeJob struct {
+	mu     sync.Mutex
+	status model.TranscribeStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (j *transcribeJob) snapshot() *model.TranscribeStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := j.status
+	if status.State == "" {
+		status.State = model.TranscribeStateIdle
+	}
+	return &status
+}
+
+func (j *transcribeJob) update(fn func(status *model.TranscribeStatus)) {
+	j.mu.Lock()
+	fn(&j.status)
+	j.mu.Unlock()
+}
+
+// stop 取消正在运行的任务并等待其退出
+func (j *transcribeJob) stop() bool {
+	j.mu.Lock()
+	cancel, done := j.cancel, j.done
+	j.mu.Unlock()
+	if cancel == nil {
+		return false
+	}
+	cancel()
+	<-done
+	return true
+}
+
+// startTranscribe 启动后台语音转写任务
+func (s *Service) startTranscribe(req *model.TranscribeRequest) (*model.TranscribeStatus, error) {
+	if s.speechTranscriber == nil {
+		return nil, errors.SpeechDisabled()
+	}
+	db := s.db.GetDB()
+	if db == nil {
+		return nil, errors.DBInitFailed(nil)
+	}
+
+	job := &s.transcribe
+	job.mu.Lock()
+	if job.cancel != nil {
+		job.mu.Unlock()
+		return nil, errors.TranscribeRunning()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job.cancel = cancel
+	job.done = make(chan struct{})
+	job.status = model.TranscribeStatus{
+		State:     model.TranscribeStateRunning,
+		Talker:    req.Talker,
+		Start:     req.Start,
+		End:       req.End,
+		Stored:    db.Transcripts().Count(),
+		StartedAt: time.Now(),
+	}
+	done := job.done
+	job.mu.Unlock()
+
+	transcriber, opts := s.speechTranscriber, s.speechOptions
+	go func() {
+		defer close(done)
+		err := runTranscribe(ctx, job, db, transcriber, opts, req)
+		job.update(func(status *model.TranscribeStatus) {
+			status.CurrentTalker = ""
+			status.FinishedAt = time.Now()
+			status.Stored = db.Transcripts().Count()
+			switch {
+			case err == nil:
+				status.State = model.TranscribeStateCompleted
+				status.Progress = 1
+			case ctx.Err() != nil:
+				status.State = model.TranscribeStateCancelled
+			default:
+				status.State = model.TranscribeStateFailed
+				status.LastError = err.Error()
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("voice transcribe job failed")
+		}
+		job.mu.Lock()
+		job.cancel = nil
+		job.mu.Unlock()
+		cancel()
+	}()
+
+	return job.snapshot(), nil
+}
+
+// runTranscribe 逐个会话转写语音消息，每完成一个会话保存一次断点
+func runTranscribe(ctx context.Context, job *transcribeJob, db *wechatdb.DB, transcriber whisper.Transcriber, opts whisper.Options, req *model.TranscribeRequest) error {
+	store := db.Transcripts()
+
+	talkers := util.Str2List(req.Talker, ",")
+	if len(talkers) == 0 {
+		var err error
+		if talkers, err = db.ListTalkers(ctx); err != nil {
+			return err
+		}
+		sort.Strings(talkers)
+	}
+
+	var cp *model.TranscribeCheckpoint
+	if !req.Restart {
+		saved, err := store.LoadCheckpoint()
+		if err != nil {
+			log.Warn().Err(err).Msg("load transcribe checkpoint failed")
+		}
+		if saved.Matches(req) {
+			cp = saved
+		}
+	}
+	resumed := cp != nil
+	if cp == nil {
+		cp = &model.TranscribeCheckpoint{Talker: req.Talker, Start: req.Start, End: req.End}
+	}
+	completed := make(map[string]bool, len(cp.Completed))
+	for _, talker := range cp.Completed {
+		completed[talker] = true
+	}
+
+	start, end := req.Start, req.End
+	if start.IsZero() && end.IsZero() {
+		start, end, _ = util.TimeRangeOf("all")
+	}
+
+	job.update(func(status *model.TranscribeStatus) {
+		status.Resumed = resumed
+		status.TotalTalkers = len(talkers)
+	})
+
+	for i, talker := range talkers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !completed[talker] {
+			job.update(func(status *model.TranscribeStatus) { status.CurrentTalker = talker })
+			if err := transcribeTalker(ctx, job, db, transcriber, opts, talker, start, end, req.Force); err != nil {
+				return err
+			}
+			cp.Completed = append(cp.Completed, talker)
+			if err := store.SaveCheckpoint(cp); err != nil {
+				log.Warn().Err(err).Msg("save transcribe checkpoint failed")
+			}
+		}
+		job.update(func(status *model.TranscribeStatus) {
+			status.DoneTalkers = i + 1
+			status.Progress = float64(i+1) / float64(len(talkers))
+		})
+	}
+
+	return store.SaveCheckpoint(nil)
+}
+
+func transcribeTalker(ctx context.Context, job *transcribeJob, db *wechatdb.DB, transcriber whisper.Transcriber, opts whisper.Options, talker string, start, end time.Time, force bool) error {
+	messages, err := db.GetMessages(start, end, talker, "", "", "voice", 0, 0)
+	if err != nil {
+		// 会话在时间范围内没有消息时返回 404，视为无语音；其他错误中止任务，不记录断点
+		if errors.GetCode(err) == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("list voice messages of %s: %w", talker, err)
+	}
+
+	store := db.Transcripts()
+	batchMsgs := make([]*model.Message, 0, transcribeBatchSize)
+	batchItems := make([]*model.Transcript, 0, transcribeBatchSize)
+	flush := func() error {
+		if len(batchItems) == 0 {
+			return nil
+		}
+		if err := db.SaveTranscripts(batchMsgs, batchItems); err != nil {
+			return err
+		}
+		batchMsgs, batchItems = batchMsgs[:0], batchItems[:0]
+		job.update(func(status *model.TranscribeStatus) { status.Stored = store.Count() })
+		return nil
+	}
+
+	for _, msg := range messages {
+		if err := ctx.Err(); err != nil {
+			// 已转写的部分先保存，下次启动时按 key 跳过
+			_ = flush()
+			return err
+		}
+		key := ""
+		if v, ok := msg.Contents["voice"]; ok {
+			key = strings.TrimSpace(fmt.Sprint(v))
+		}
+		if key == "" {
+			continue
+		}
+		if !force && store.Get(key) != nil {
+			job.update(func(status *model.TranscribeStatus) { status.Skipped++ })
+			continue
+		}
+
+		item, err := transcribeVoice(ctx, db, transcriber, opts, key)
+		if err != nil {
+			if ctx.Err() != nil {
+				_ = flush()
+				return ctx.Err()
+			}
+			log.Warn().Err(err).Str("talker", msg.Talker).Str("media_key", key).Msg("transcribe voice failed")
+			job.update(func(status *model.TranscribeStatus) {
+				status.Failed++
+				status.LastError = err.Error()
+			})
+			continue
+		}
+		item.Talker = msg.Talker
+		item.Seq = msg.Seq
+		msg.SetContent("transcript", item.Text)
+
+		batchMsgs = append(batchMsgs, msg)
+		batchItems = append(batchItems, item)
+		job.update(func(status *model.TranscribeStatus) { status.Transcribed++ })
+		if len(batchItems) >= transcribeBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+func transcribeVoice(ctx context.Context, db *wechatdb.DB, transcriber whisper.Transcriber, opts whisper.Options, key string) (*model.Transcript, error) {
+	media, err := db.GetMedia("voice", key)
+	if err != nil {
+		return nil, err
+	}
+	if media == nil || len(media.Data) == 0 {
+		return nil, fmt.Errorf("voice data unavailable")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transcribeTimeout)
+	defer cancel()
+
+	res, err := transcriber.TranscribeSilk(ctx, media.Data, opts)
+	if err != nil {
+		return nil, err
+	}
+	// 无识别结果时同样保存空文本，避免重复转写
+	item := &model.Transcript{Key: key, Language: opts.Language}
+	if res != nil {
+		item.Text = strings.TrimSpace(res.Text)
+		item.Language = res.Language
+		item.Duration = res.Duration.Seconds()
+	}
+	return item, nil
+}
+
+// GET /api/v1/transcribe
+func (s *Service) handleTranscribeStatus(c *gin.Context) {
+	status := s.transcribe.snapshot()
+	if db := s.db.GetDB(); db != nil && status.State != model.TranscribeStateRunning {
+		status.Stored = db.Transcripts().Count()
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// POST /api/v1/transcribe
+func (s *Service) handleTranscribeStart(c *gin.Context) {
+	q := struct {
+		Talker  string `form:"talker"`
+		Time    string `form:"time"`
+		Force   bool   `form:"force"`
+		Restart bool   `form:"restart"`
+	}{}
+	if err := c.ShouldBind(&q); err != nil {
+		errors.Err(c, err)
+		return
+	}
+
+	req := &model.TranscribeRequest{
+		Talker:  strings.TrimSpace(q.Talker),
+		Force:   q.Force,
+		Restart: q.Restart,
+	}
+	if strings.TrimSpace(q.Time) != "" {
+		start, end, ok := util.TimeRangeOf(q.Time)
+		if !ok {
+			errors.Err(c, errors.InvalidArg("time"))
+			return
+		}
+		req.Start, req.End = start, end
+	}
+
+	status, err := s.startTranscribe(req)
+	if err != nil {
+		errors.Err(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, status)
+}
+
+// DELETE /api/v1/transcribe
+func (s *Service) handleTranscribeStop(c *gin.Context) {
+	if !s.transcribe.stop() {
+		c.JSON(http.StatusOK, gin.H{"status": "not_running"})
+		return
+	}
+	c.JSON(http.StatusOK, s.transcribe.snapshot())
+}
@@ -15,10 +15,13 @@ import (
 	"github.com/ysy950803/chatlog/internal/chatlog/conf"
 	"github.com/ysy950803/chatlog/internal/chatlog/ctx"
 	"github.com/ysy950803/chatlog/internal/chatlog/database"
+	"github.com/ysy950803/chatlog/internal/chatlog/grpc"
 	"github.com/ysy950803/chatlog/internal/chatlog/http"
 	"github.com/ysy950803/chatlog/internal/chatlog/wechat"
+	"github.com/ysy950803/chatlog/internal/model"
 	"github.com/ysy950803/chatlog/internal/tray"
 	iwechat "github.com/ysy950803/chatlog/internal/wechat"
+	"github.com/ysy950803/chatlog/internal/wechatdb"
 	"github.com/ysy950803/chatlog/pkg/config"
 	"github.com/ysy950803/chatlog/pkg/util"
 	"github.com/ysy950803/chatlog/pkg/util/dat2img"
@@ -48,6 +51,7 @@ type Manager struct {
 	// Services
 	db     *database.Service
 	http   *http.Service
+	grpc   *grpc.Service
 	wechat *wechat.Service
 
 	// Terminal UI
@@ -596,6 +600,159 @@ func (m *Manager) CommandDecrypt(configPath string, cmdConf map[string]any) erro
 	return nil
 }
 
+// CommandSearch 在工作目录上执行一次搜索，索引未就绪时等待构建完成
+func (m *Manager) CommandSearch(configPath string, cmdConf map[string]any, req *model.SearchRequest) (*model.SearchResponse, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := m.sc.GetWorkDir()
+	if len(workDir) == 0 {
+		return nil, fmt.Errorf("workDir is required")
+	}
+
+	db, err := wechatdb.New(workDir, m.sc.GetPlatform(), m.sc.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	waited := false
+	for {
+		resp, err := db.SearchMessages(req)
+		if err != nil {
+			return nil, err
+		}
+		status := resp.Index
+		if status == nil || status.Ready || status.LastError != "" {
+			if waited {
+				fmt.Fprintln(os.Stderr)
+			}
+			return resp, nil
+		}
+		fmt.Fprintf(os.Stderr, "\r正在构建全文索引 %.0f%%", status.Progress*100)
+		waited = true
+		time.Sleep(time.Second)
+	}
+}
+
+// openIndexDB 打开工作目录上的数据库，不在后台自动构建索引，供索引维护命令使用
+func (m *Manager) openIndexDB(configPath string, cmdConf map[string]any) (*wechatdb.DB, error) {
+
+	var err error
+	m.sc, m.scm, err = conf.LoadServiceConfig(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := m.sc.GetWorkDir()
+	if len(workDir) == 0 {
+		return nil, fmt.Errorf("workDir is required")
+	}
+
+	return wechatdb.NewWithOptions(workDir, m.sc.GetPlatform(), m.sc.GetVersion(), wechatdb.Options{DeferIndex: true})
+}
+
+// CommandIndexStatus 返回工作目录上全文索引的状态
+func (m *Manager) CommandIndexStatus(configPath string, cmdConf map[string]any) (*model.IndexReport, error) {
+	db, err := m.openIndexDB(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return db.IndexReport()
+}
+
+// CommandIndexRebuild 重建全文索引，storeID 为空时重建全部 store
+func (m *Manager) CommandIndexRebuild(configPath string, cmdConf map[string]any, storeID string) (*model.IndexReport, error) {
+	db, err := m.openIndexDB(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- db.RebuildIndex(storeID) }()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return nil, err
+			}
+			return db.IndexReport()
+		case <-ticker.C:
+			if status := db.IndexStatus(); status != nil {
+				fmt.Fprintf(os.Stderr, "\r正在重建全文索引 %.0f%%", status.Progress*100)
+			}
+		}
+	}
+}
+
+// CommandIndexCheck 对全文索引执行 verify/optimize/vacuum，storeID 为空时处理全部 store
+func (m *Manager) CommandIndexCheck(configPath string, cmdConf map[string]any, action string, storeID string) ([]*model.IndexCheckResult, error) {
+	db, err := m.openIndexDB(configPath, cmdConf)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	switch action {
+	case "verify":
+		return db.VerifyIndex(storeID)
+	case "optimize":
+		return db.OptimizeIndex(storeID)
+	case "vacuum":
+		return db.VacuumIndex(storeID)
+	default:
+		return nil, fmt.Errorf("unknown index action: %s", action)
+	}
+}
+
+// CommandTokenList 返回配置文件中的 API 令牌，server 为 true 时读取 server 模式的配置文件
+func (m *Manager) CommandTokenList(configPath string, server bool) ([]*conf.APIToken, error) {
+	auth, _, err := conf.LoadAuthConfig(configPath, server)
+	if err != nil {
+		return nil, err
+	}
+	return auth.Tokens, nil
+}
+
+// CommandTokenAdd 生成 API 令牌并写入配置文件，返回只显示这一次的令牌明文
+func (m *Manager) CommandTokenAdd(configPath string, server bool, name string, scopes []string) (string, error) {
+	auth, cm, err := conf.LoadAuthConfig(configPath, server)
+	if err != nil {
+		return "", err
+	}
+	token, err := auth.AddToken(name, scopes)
+	if err != nil {
+		return "", err
+	}
+	if err := conf.SaveAuthConfig(cm, auth); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CommandTokenRemove 从配置文件中删除 API 令牌
+func (m *Manager) CommandTokenRemove(configPath string, server bool, name string) error {
+	auth, cm, err := conf.LoadAuthConfig(configPath, server)
+	if err != nil {
+		return err
+	}
+	if !auth.RemoveToken(name) {
+		return fmt.Errorf("token %q not found", name)
+	}
+	return conf.SaveAuthConfig(cm, auth)
+}
+
 func (m *Manager) CommandHTTPServer(configPath string, cmdConf map[string]any) error {
 
 	var err error
@@ -630,6 +787,15 @@ func (m *Manager) CommandHTTPServer(configPath string, cmdConf map[string]any) e
 
 	m.http = http.NewService(m.sc, m.db, m)
 
+	// 配置了 grpc_addr 时同时提供 gRPC 接口
+	if m.sc.GetGRPCAddr() != "" {
+		m.grpc = grpc.NewService(m.sc, m.db)
+		if err := m.grpc.Start(); err != nil {
+			return err
+		}
+		defer m.grpc.Stop()
+	}
+
 	if m.sc.GetAutoDecrypt() {
 		if err := m.wechat.StartAutoDecrypt(); err != nil {
 			return err
@@ -156,7 +156,7 @@ func NewMessageWebhook(conf *conf.WebhookItem, db *wechatdb.DB, host string) *Me
 }
 
 func (m *MessageWebhook) Do(event fsnotify.Event) {
-	messages, err := m.db.GetMessages(m.lastTime, time.Now().Add(time.Minute*10), m.conf.Talker, m.conf.Sender, m.conf.Keyword, 0, 0)
+	messages, err := m.db.GetMessages(m.lastTime, time.Now().Add(time.Minute*10), m.conf.Talker, m.conf.Sender, m.conf.Keyword, "", 0, 0)
 	if err != nil {
 		log.Error().Err(err).Msgf("get messages failed")
 		return
@@ -174,6 +174,7 @@ func (m *MessageWebhook) Do(event fsnotify.Event) {
 
 	for _, message := range messages {
 		message.SetContent("host", m.host)
+		message.SetPermalink(m.host)
 		message.Content = message.PlainTextContent()
 	}
 
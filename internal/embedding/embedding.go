@@ -0,0 +1,72 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderOpenAI = "openai"
+	ProviderHTTP   = "http"
+)
+
+// Provider 将文本转换为向量，返回的向量与 texts 一一对应
+type Provider interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Name 标识模型及维度，变化时已有向量失效需要重新生成
+	Name() string
+	// BatchSize 为单次请求允许的最大文本数
+	BatchSize() int
+}
+
+// Config 为创建 Provider 的参数
+type Config struct {
+	Provider       string
+	Model          string
+	Dimensions     int
+	APIKey         string
+	BaseURL        string
+	ServiceURL     string
+	BatchSize      int
+	RequestTimeout time.Duration
+}
+
+// New 按 cfg.Provider 创建向量模型客户端
+func New(cfg Config) (Provider, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 64
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = time.Minute
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "", ProviderOpenAI:
+		return newOpenAIProvider(cfg)
+	case ProviderHTTP:
+		return newHTTPProvider(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider %q", cfg.Provider)
+	}
+}
+
+func providerName(provider, model string, dimensions int) string {
+	name := provider + ":" + model
+	if dimensions > 0 {
+		name += fmt.Sprintf(":%d", dimensions)
+	}
+	return name
+}
+
+func checkCount(texts []string, vectors [][]float32) error {
+	if len(vectors) != len(texts) {
+		return fmt.Errorf("embedding count mismatch: got %d, want %d", len(vectors), len(texts))
+	}
+	for i, vec := range vectors {
+		if len(vec) == 0 {
+			return fmt.Errorf("empty embedding at %d", i)
+		}
+	}
+	return nil
+}
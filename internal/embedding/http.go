@@ -0,0 +1,98 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// httpProvider 调用本地部署的向量服务，用于离线环境或自建模型
+//
+// 请求：POST ServiceURL {"model": "...", "input": ["text", ...]}
+// 响应：{"embeddings": [[0.1, ...], ...]}，也接受 OpenAI 格式 {"data": [{"index": 0, "embedding": [...]}]}
+type httpProvider struct {
+	client    *http.Client
+	url       string
+	model     string
+	batchSize int
+}
+
+func newHTTPProvider(cfg Config) (*httpProvider, error) {
+	if cfg.ServiceURL == "" {
+		return nil, fmt.Errorf("embedding service url cannot be empty")
+	}
+	return &httpProvider{
+		client:    &http.Client{Timeout: cfg.RequestTimeout},
+		url:       cfg.ServiceURL,
+		model:     cfg.Model,
+		batchSize: cfg.BatchSize,
+	}, nil
+}
+
+func (p *httpProvider) Name() string {
+	return providerName(ProviderHTTP, p.model+"@"+p.url, 0)
+}
+
+func (p *httpProvider) BatchSize() int {
+	return p.batchSize
+}
+
+type httpEmbedRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type httpEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	Data       []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (p *httpProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(httpEmbedRequest{Model: p.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("embedding service returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var out httpEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode embeddings: %w", err)
+	}
+	vectors := out.Embeddings
+	if len(vectors) == 0 && len(out.Data) > 0 {
+		vectors = make([][]float32, len(texts))
+		for _, item := range out.Data {
+			if item.Index < 0 || item.Index >= len(texts) {
+				return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+			}
+			vectors[item.Index] = item.Embedding
+		}
+	}
+	if err := checkCount(texts, vectors); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
@@ -0,0 +1,79 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	openaiparam "github.com/openai/openai-go/v3/packages/param"
+)
+
+// openAIProvider 调用 OpenAI 兼容的 /embeddings 接口
+type openAIProvider struct {
+	client     *openai.Client
+	model      string
+	dimensions int
+	batchSize  int
+}
+
+func newOpenAIProvider(cfg Config) (*openAIProvider, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("embedding model cannot be empty")
+	}
+	opts := []option.RequestOption{option.WithRequestTimeout(cfg.RequestTimeout)}
+	if cfg.APIKey != "" {
+		opts = append(opts, option.WithAPIKey(cfg.APIKey))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+	client := openai.NewClient(opts...)
+	return &openAIProvider{
+		client:     &client,
+		model:      cfg.Model,
+		dimensions: cfg.Dimensions,
+		batchSize:  cfg.BatchSize,
+	}, nil
+}
+
+func (p *openAIProvider) Name() string {
+	return providerName(ProviderOpenAI, p.model, p.dimensions)
+}
+
+func (p *openAIProvider) BatchSize() int {
+	return p.batchSize
+}
+
+func (p *openAIProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	params := openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		Model: openai.EmbeddingModel(p.model),
+	}
+	if p.dimensions > 0 {
+		params.Dimensions = openaiparam.NewOpt(int64(p.dimensions))
+	}
+	resp, err := p.client.Embeddings.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("request embeddings: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range resp.Data {
+		if item.Index < 0 || int(item.Index) >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vec := make([]float32, len(item.Embedding))
+		for i, v := range item.Embedding {
+			vec[i] = float32(v)
+		}
+		vectors[item.Index] = vec
+	}
+	if err := checkCount(texts, vectors); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
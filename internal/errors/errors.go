@@ -11,10 +11,11 @@ import (
 )
 
 type Error struct {
-	Message string   `json:"message"` // 错误消息
-	Cause   error    `json:"-"`       // 原始错误
-	Code    int      `json:"-"`       // HTTP Code
-	Stack   []string `json:"-"`       // 错误堆栈
+	Message string      `json:"message"` // 错误消息
+	Cause   error       `json:"-"`       // 原始错误
+	Code    int         `json:"-"`       // HTTP Code
+	Stack   []string    `json:"-"`       // 错误堆栈
+	Detail  interface{} `json:"-"`       // 结构化的错误详情，非空时作为响应体返回
 }
 
 func (e *Error) Error() string {
@@ -80,6 +81,7 @@ func Wrap(err error, message string, code int) *Error {
 			Cause:   appErr.Cause,
 			Code:    appErr.Code,
 			Stack:   appErr.Stack,
+			Detail:  appErr.Detail,
 		}
 	}
 
@@ -112,6 +114,10 @@ func RootCause(err error) error {
 
 func Err(c *gin.Context, err error) {
 	if appErr, ok := err.(*Error); ok {
+		if appErr.Detail != nil {
+			c.JSON(appErr.Code, appErr.Detail)
+			return
+		}
 		c.JSON(appErr.Code, appErr.Error())
 		return
 	}
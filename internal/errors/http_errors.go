@@ -6,6 +6,38 @@ func InvalidArg(arg string) error {
 	return Newf(nil, http.StatusBadRequest, "invalid argument: %s", arg)
 }
 
+func InvalidQuery(cause error) error {
+	return Newf(cause, http.StatusBadRequest, "invalid search query")
+}
+
 func HTTPShutDown(cause error) error {
 	return Newf(cause, http.StatusInternalServerError, "http server shut down")
 }
+
+func SpeechDisabled() error {
+	return New(nil, http.StatusServiceUnavailable, "speech transcription not enabled")
+}
+
+func TranscribeRunning() error {
+	return New(nil, http.StatusConflict, "transcribe job already running")
+}
+
+func SavedSearchNotFound(id string) error {
+	return Newf(nil, http.StatusNotFound, "saved search not found: %s", id)
+}
+
+func EventsUnavailable() error {
+	return New(nil, http.StatusServiceUnavailable, "message events not available before database ready")
+}
+
+func ResyncRequired() error {
+	return New(nil, http.StatusGone, "too many messages since last event id, reload history and subscribe without it")
+}
+
+func Unauthorized() error {
+	return New(nil, http.StatusUnauthorized, "missing or invalid api token")
+}
+
+func ScopeDenied(scope string) error {
+	return Newf(nil, http.StatusForbidden, "api token lacks scope: %s", scope)
+}
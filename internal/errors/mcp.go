@@ -1,13 +1,26 @@
 package errors
 
-import "github.com/mark3labs/mcp-go/mcp"
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
 
 func ErrMCPTool(err error) *mcp.CallToolResult {
+	text := err.Error()
+	// 附带结构化详情，便于模型据此重新选择参数
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.Detail != nil {
+		if b, e := json.Marshal(appErr.Detail); e == nil {
+			text += "\n" + string(b)
+		}
+	}
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			mcp.TextContent{
 				Type: "text",
-				Text: err.Error(),
+				Text: text,
 			},
 		},
 		IsError: true,
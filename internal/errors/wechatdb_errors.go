@@ -2,6 +2,7 @@ package errors
 
 import (
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -30,6 +31,30 @@ func TalkerNotFound(talker string) *Error {
 	return Newf(nil, http.StatusNotFound, "talker not found: %s", talker).WithStack()
 }
 
+// AmbiguousTalkerDetail 为会话名称匹配到多个联系人或群聊时的响应体
+type AmbiguousTalkerDetail struct {
+	Error      string      `json:"error"`
+	Code       string      `json:"code"`
+	Name       string      `json:"name"`
+	Candidates interface{} `json:"candidates"`
+}
+
+// AmbiguousTalker 会话名称有歧义，summary 为各候选项的简要说明，candidates 原样返回给调用方
+func AmbiguousTalker(name string, summary []string, candidates interface{}) *Error {
+	err := Newf(nil, http.StatusConflict, "ambiguous talker %q, matches %s; use one of the userNames instead", name, strings.Join(summary, ", ")).WithStack()
+	err.Detail = &AmbiguousTalkerDetail{
+		Error:      err.Message,
+		Code:       "ambiguous_talker",
+		Name:       name,
+		Candidates: candidates,
+	}
+	return err
+}
+
+func MessageNotFound(talker string, seq int64) *Error {
+	return Newf(nil, http.StatusNotFound, "message not found: %s/%d", talker, seq).WithStack()
+}
+
 func MessageStoreNotFound(key string) *Error {
 	return Newf(nil, http.StatusNotFound, "message store not found: %s", key).WithStack()
 }
@@ -73,3 +98,35 @@ func FileGroupNotFound(name string) *Error {
 func SearchNotSupported(platform string, version int) *Error {
 	return Newf(nil, http.StatusNotImplemented, "search not supported for %s v%d", platform, version).WithStack()
 }
+
+func InvalidMessageType(cause error) *Error {
+	return New(cause, http.StatusBadRequest, "invalid message type").WithStack()
+}
+
+func IndexNotEnabled() *Error {
+	return New(nil, http.StatusServiceUnavailable, "fts index not enabled").WithStack()
+}
+
+func IndexBusy() *Error {
+	return New(nil, http.StatusConflict, "fts index is being rebuilt").WithStack()
+}
+
+func SemanticSearchDisabled() *Error {
+	return New(nil, http.StatusServiceUnavailable, "semantic search not enabled").WithStack()
+}
+
+func EmbeddingFailed(cause error) *Error {
+	return New(cause, http.StatusBadGateway, "embedding request failed").WithStack()
+}
+
+func CursorUnsupported() *Error {
+	return New(nil, http.StatusBadRequest, "cursor pagination is not supported by this data source").WithStack()
+}
+
+func CursorExpired() *Error {
+	return New(nil, http.StatusGone, "search cursor expired, search again without cursor").WithStack()
+}
+
+func ResultWindowExceeded(cause error) *Error {
+	return New(cause, http.StatusBadRequest, "search offset too deep").WithStack()
+}
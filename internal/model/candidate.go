@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// 候选项类型
+const (
+	CandidateContact  = "contact"
+	CandidateChatRoom = "chatroom"
+)
+
+// 名称匹配方式，按优先级从高到低
+const (
+	MatchExact          = "exact"
+	MatchPrefix         = "prefix"
+	MatchPinyin         = "pinyin"
+	MatchInitials       = "initials"
+	MatchContains       = "contains"
+	MatchPinyinPrefix   = "pinyin_prefix"
+	MatchInitialsPrefix = "initials_prefix"
+)
+
+// NameCandidate 为按名称查找联系人或群聊时的一个候选项，用于消除歧义
+type NameCandidate struct {
+	UserName     string `json:"userName"`
+	Kind         string `json:"kind"`
+	DisplayName  string `json:"displayName"`
+	Remark       string `json:"remark,omitempty"`
+	NickName     string `json:"nickName,omitempty"`
+	Alias        string `json:"alias,omitempty"`
+	IsFriend     bool   `json:"isFriend"`
+	MatchedField string `json:"matchedField"`
+	MatchType    string `json:"matchType"`
+	Score        int    `json:"score"`
+
+	// LastActive 为最近会话时间，没有会话记录时为空
+	LastActive *time.Time `json:"lastActive,omitempty"`
+}
+
+// TalkerResolution 为会话名称的解析结果，能唯一确定时 UserName 非空
+type TalkerResolution struct {
+	Name       string           `json:"name"`
+	UserName   string           `json:"userName,omitempty"`
+	Ambiguous  bool             `json:"ambiguous"`
+	Candidates []*NameCandidate `json:"candidates"`
+}
@@ -8,6 +8,12 @@ type Contact struct {
 	IsFriend bool   `json:"isFriend"`
 	// AvatarURL is a service-composed URL to fetch avatar image
 	AvatarURL string `json:"avatarUrl,omitempty"`
+
+	// 昵称与备注的全拼、首字母，来自微信联系人表，可能为空
+	QuanPin         string `json:"-"`
+	PYInitial       string `json:"-"`
+	RemarkQuanPin   string `json:"-"`
+	RemarkPYInitial string `json:"-"`
 }
 
 // CREATE TABLE Contact(
@@ -44,20 +50,28 @@ type Contact struct {
 // Reserved11 TEXT
 // )
 type ContactV3 struct {
-	UserName  string `json:"UserName"`
-	Alias     string `json:"Alias"`
-	Remark    string `json:"Remark"`
-	NickName  string `json:"NickName"`
-	Reserved1 int    `json:"Reserved1"` // 1 自己好友或自己加入的群聊; 0 群聊成员(非好友)
+	UserName        string `json:"UserName"`
+	Alias           string `json:"Alias"`
+	Remark          string `json:"Remark"`
+	NickName        string `json:"NickName"`
+	Reserved1       int    `json:"Reserved1"` // 1 自己好友或自己加入的群聊; 0 群聊成员(非好友)
+	PYInitial       string `json:"PYInitial"`
+	QuanPin         string `json:"QuanPin"`
+	RemarkPYInitial string `json:"RemarkPYInitial"`
+	RemarkQuanPin   string `json:"RemarkQuanPin"`
 }
 
 func (c *ContactV3) Wrap() *Contact {
 	return &Contact{
-		UserName: c.UserName,
-		Alias:    c.Alias,
-		Remark:   c.Remark,
-		NickName: c.NickName,
-		IsFriend: c.Reserved1 == 1,
+		UserName:        c.UserName,
+		Alias:           c.Alias,
+		Remark:          c.Remark,
+		NickName:        c.NickName,
+		IsFriend:        c.Reserved1 == 1,
+		QuanPin:         c.QuanPin,
+		PYInitial:       c.PYInitial,
+		RemarkQuanPin:   c.RemarkQuanPin,
+		RemarkPYInitial: c.RemarkPYInitial,
 	}
 }
 
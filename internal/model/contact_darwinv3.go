@@ -35,19 +35,27 @@ package model
 // openIMInfo BLOB
 // )
 type ContactDarwinV3 struct {
-	M_nsUsrName   string `json:"m_nsUsrName"`
-	Nickname      string `json:"nickname"`
-	M_nsRemark    string `json:"m_nsRemark"`
-	M_uiSex       int    `json:"m_uiSex"`
-	M_nsAliasName string `json:"m_nsAliasName"`
+	M_nsUsrName       string `json:"m_nsUsrName"`
+	Nickname          string `json:"nickname"`
+	M_nsRemark        string `json:"m_nsRemark"`
+	M_uiSex           int    `json:"m_uiSex"`
+	M_nsAliasName     string `json:"m_nsAliasName"`
+	M_nsFullPY        string `json:"m_nsFullPY"`
+	M_nsShortPY       string `json:"m_nsShortPY"`
+	M_nsRemarkPYFull  string `json:"m_nsRemarkPYFull"`
+	M_nsRemarkPYShort string `json:"m_nsRemarkPYShort"`
 }
 
 func (c *ContactDarwinV3) Wrap() *Contact {
 	return &Contact{
-		UserName: c.M_nsUsrName,
-		Alias:    c.M_nsAliasName,
-		Remark:   c.M_nsRemark,
-		NickName: c.Nickname,
-		IsFriend: true,
+		UserName:        c.M_nsUsrName,
+		Alias:           c.M_nsAliasName,
+		Remark:          c.M_nsRemark,
+		NickName:        c.Nickname,
+		IsFriend:        true,
+		QuanPin:         c.M_nsFullPY,
+		PYInitial:       c.M_nsShortPY,
+		RemarkQuanPin:   c.M_nsRemarkPYFull,
+		RemarkPYInitial: c.M_nsRemarkPYShort,
 	}
 }
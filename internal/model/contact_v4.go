@@ -25,19 +25,27 @@ package model
 // chat_room_type INTEGER
 // )
 type ContactV4 struct {
-	UserName  string `json:"username"`
-	Alias     string `json:"alias"`
-	Remark    string `json:"remark"`
-	NickName  string `json:"nick_name"`
-	LocalType int    `json:"local_type"` // 2 群聊; 3 群聊成员(非好友); 5,6 企业微信;
+	UserName            string `json:"username"`
+	Alias               string `json:"alias"`
+	Remark              string `json:"remark"`
+	NickName            string `json:"nick_name"`
+	LocalType           int    `json:"local_type"` // 2 群聊; 3 群聊成员(非好友); 5,6 企业微信;
+	PinYinInitial       string `json:"pin_yin_initial"`
+	QuanPin             string `json:"quan_pin"`
+	RemarkPinYinInitial string `json:"remark_pin_yin_initial"`
+	RemarkQuanPin       string `json:"remark_quan_pin"`
 }
 
 func (c *ContactV4) Wrap() *Contact {
 	return &Contact{
-		UserName: c.UserName,
-		Alias:    c.Alias,
-		Remark:   c.Remark,
-		NickName: c.NickName,
-		IsFriend: c.LocalType != 3,
+		UserName:        c.UserName,
+		Alias:           c.Alias,
+		Remark:          c.Remark,
+		NickName:        c.NickName,
+		IsFriend:        c.LocalType != 3,
+		QuanPin:         c.QuanPin,
+		PYInitial:       c.PinYinInitial,
+		RemarkQuanPin:   c.RemarkQuanPin,
+		RemarkPYInitial: c.RemarkPinYinInitial,
 	}
 }
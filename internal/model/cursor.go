@@ -0,0 +1,55 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MessageCursor 为按序号顺序读取消息时的游标，表示已读到 (Seq, Talker)
+// 多个会话的消息按 (Seq, Talker) 排序；只读取单个会话时 Talker 为空
+type MessageCursor struct {
+	Seq    int64
+	Talker string
+}
+
+// ParseMessageCursor 解析 String 输出的游标，空串返回零值游标
+func ParseMessageCursor(s string) (MessageCursor, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return MessageCursor{}, nil
+	}
+	seqText, talker, _ := strings.Cut(s, ":")
+	seq, err := strconv.ParseInt(seqText, 10, 64)
+	if err != nil || seq < 0 {
+		return MessageCursor{}, fmt.Errorf("invalid cursor: %s", s)
+	}
+	return MessageCursor{Seq: seq, Talker: talker}, nil
+}
+
+// String 将游标编码为 "<seq>" 或 "<seq>:<talker>"
+func (c MessageCursor) String() string {
+	if c.Talker == "" {
+		return strconv.FormatInt(c.Seq, 10)
+	}
+	return strconv.FormatInt(c.Seq, 10) + ":" + c.Talker
+}
+
+// Includes 判断会话 talker 中序号为 seq 的消息是否位于游标之后，零值游标包含全部消息
+func (c MessageCursor) Includes(seq int64, talker string) bool {
+	if c == (MessageCursor{}) {
+		return true
+	}
+	if seq != c.Seq {
+		return seq > c.Seq
+	}
+	return c.Talker != "" && talker > c.Talker
+}
+
+// AfterSeq 返回读取会话 talker 时应从哪个序号之后开始
+func (c MessageCursor) AfterSeq(talker string) int64 {
+	if c.Talker != "" && talker > c.Talker {
+		return c.Seq - 1
+	}
+	return c.Seq
+}
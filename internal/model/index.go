@@ -0,0 +1,72 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IndexReport 为全文索引的整体状态，Stale 表示索引指纹与当前数据不一致
+type IndexReport struct {
+	Version            string             `json:"version"`
+	Fingerprint        string             `json:"fingerprint"`
+	DatasetFingerprint string             `json:"dataset_fingerprint"`
+	Stale              bool               `json:"stale"`
+	LastBuilt          time.Time          `json:"last_built"`
+	Status             *SearchIndexStatus `json:"status,omitempty"`
+	Stores             []*IndexStoreStats `json:"stores"`
+}
+
+// IndexStoreStats 为单个消息库对应的索引统计
+// Checkpoints 为已记录断点的会话数，LastSeq 为其中最大的消息序号
+type IndexStoreStats struct {
+	ID          string `json:"id"`
+	File        string `json:"file"`
+	Path        string `json:"path"`
+	Exists      bool   `json:"exists"`
+	Docs        int64  `json:"docs"`
+	Checkpoints int64  `json:"checkpoints"`
+	LastSeq     int64  `json:"last_seq"`
+	Size        int64  `json:"size"`
+	Error       string `json:"error,omitempty"`
+}
+
+// IndexCheckResult 为对单个 store 执行校验、优化或压缩的结果
+type IndexCheckResult struct {
+	ID    string `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// PlainText 以纯文本输出索引状态
+func (r *IndexReport) PlainText() string {
+	if r == nil {
+		return ""
+	}
+	buf := strings.Builder{}
+	fmt.Fprintf(&buf, "version: %s\n", r.Version)
+	fmt.Fprintf(&buf, "fingerprint: %s\n", r.Fingerprint)
+	fmt.Fprintf(&buf, "dataset fingerprint: %s\n", r.DatasetFingerprint)
+	fmt.Fprintf(&buf, "stale: %t\n", r.Stale)
+	if !r.LastBuilt.IsZero() {
+		fmt.Fprintf(&buf, "last built: %s\n", r.LastBuilt.Format(time.DateTime))
+	}
+	if r.Status != nil {
+		fmt.Fprintf(&buf, "ready: %t, in progress: %t, progress: %.0f%%\n", r.Status.Ready, r.Status.InProgress, r.Status.Progress*100)
+		if r.Status.LastError != "" {
+			fmt.Fprintf(&buf, "last error: %s\n", r.Status.LastError)
+		}
+	}
+	for _, st := range r.Stores {
+		fmt.Fprintf(&buf, "- %s (%s)\n", st.ID, st.File)
+		if !st.Exists {
+			buf.WriteString("    index missing\n")
+			continue
+		}
+		fmt.Fprintf(&buf, "    docs: %d, checkpoints: %d, last seq: %d, size: %d bytes\n", st.Docs, st.Checkpoints, st.LastSeq, st.Size)
+		if st.Error != "" {
+			fmt.Fprintf(&buf, "    error: %s\n", st.Error)
+		}
+	}
+	return buf.String()
+}
@@ -235,7 +235,7 @@ type RecordXML struct {
 	RecordInfo RecordInfo `xml:"recordinfo,omitempty"`
 }
 
-func (r *RecordInfo) String(_type, title, host string) string {
+func (r *RecordInfo) String(_type, title, host, query string) string {
 	buf := strings.Builder{}
 	if title == "" {
 		title = r.Title
@@ -252,7 +252,7 @@ func (r *RecordInfo) String(_type, title, host string) string {
 
 		// 套娃合并转发
 		if item.DataType == "17" && item.RecordXML != nil {
-			content := item.RecordXML.RecordInfo.String(_type, item.DataTitle, host)
+			content := item.RecordXML.RecordInfo.String(_type, item.DataTitle, host, query)
 			if content != "" {
 				for _, line := range strings.Split(content, "\n") {
 					buf.WriteString(fmt.Sprintf("  %s\n", line))
@@ -264,17 +264,17 @@ func (r *RecordInfo) String(_type, title, host string) string {
 		switch item.DataType {
 		case "2":
 			// 图片
-			buf.WriteString(fmt.Sprintf("  ![图片](http://%s/image/%s)\n", host, item.FullMD5))
+			buf.WriteString(fmt.Sprintf("  ![图片](%s)\n", MediaLink(host, "image", item.FullMD5, query)))
 		case "4":
 			//视频
-			buf.WriteString(fmt.Sprintf("  ![视频](http://%s/video/%s)\n", host, item.FullMD5))
+			buf.WriteString(fmt.Sprintf("  ![视频](%s)\n", MediaLink(host, "video", item.FullMD5, query)))
 		case "8":
 			// 文件
 			// FIXME 笔记的第一条是 htm 数据，暂时跳过处理
 			if item.DataFmt == ".htm" {
 				continue
 			}
-			buf.WriteString(fmt.Sprintf("  [文件|%s](http://%s/file/%s)\n", item.DataTitle, host, item.FullMD5))
+			buf.WriteString(fmt.Sprintf("  [文件|%s](%s)\n", item.DataTitle, MediaLink(host, "file", item.FullMD5, query)))
 		case "5":
 			// Link
 			buf.WriteString(fmt.Sprintf("  [链接|%s](%s)\n", item.DataTitle, item.Link))
@@ -109,6 +109,7 @@ const (
 type Message struct {
 	Version    string                 `json:"-"`                  // 消息版本，内部判断
 	Seq        int64                  `json:"seq"`                // 消息序号，10位时间戳 + 3位序号
+	ServerID   int64                  `json:"serverId,omitempty"` // 服务端消息 ID，引用消息的 refermsg 以此指向原消息
 	Time       time.Time              `json:"time"`               // 消息创建时间，10位时间戳
 	Talker     string                 `json:"talker"`             // 聊天对象，微信 ID or 群 ID
 	TalkerName string                 `json:"talkerName"`         // 聊天对象名称
@@ -121,6 +122,9 @@ type Message struct {
 	Content    string                 `json:"content"`            // 消息内容，文字聊天内容
 	Contents   map[string]interface{} `json:"contents,omitempty"` // 消息内容，多媒体消息，采用更灵活的记录方式
 
+	// 消息固定链接，由 talker 与 seq 组成，见 PermalinkPath
+	Permalink string `json:"permalink,omitempty"`
+
 	// Debug Info
 	MediaMsg *MediaMsg `json:"mediaMsg,omitempty"` // 原始多媒体消息，XML 格式
 	SysMsg   *SysMsg   `json:"sysMsg,omitempty"`   // 原始系统消息，XML 格式
@@ -216,6 +220,9 @@ func (m *Message) ParseMediaInfo(data string) error {
 				return err
 			}
 			m.Contents["recordInfo"] = recordInfo
+			if m.SubType == MessageSubTypeMergeForward {
+				m.Contents["record"] = recordInfo.Expand(msg.App.Title)
+			}
 		case MessageSubTypeMiniProgram, MessageSubTypeMiniProgram2:
 			// 小程序
 			m.Contents["title"] = msg.App.SourceDisplayName
@@ -235,6 +242,7 @@ func (m *Message) ParseMediaInfo(data string) error {
 			if msg.App.ReferMsg == nil {
 				break
 			}
+			m.Contents["quote"] = newQuoteRef(msg.App.ReferMsg)
 			subMsg := &Message{
 				Type:       int64(msg.App.ReferMsg.Type),
 				Time:       time.Unix(msg.App.ReferMsg.CreateTime, 0),
@@ -307,6 +315,30 @@ func (m *Message) SetContent(key string, value interface{}) {
 	m.Contents[key] = value
 }
 
+// SetMediaQuery 设置渲染媒体链接时附加的查询参数，如启用令牌校验时的媒体签名，为空时清除
+func (m *Message) SetMediaQuery(query string) {
+	if query == "" {
+		delete(m.Contents, "media_query")
+		return
+	}
+	m.SetContent("media_query", query)
+}
+
+// MediaQuery 返回 SetMediaQuery 设置的查询参数
+func (m *Message) MediaQuery() string {
+	query, _ := m.Contents["media_query"].(string)
+	return query
+}
+
+// MediaLink 返回媒体的访问地址，query 非空时附加在地址后
+func MediaLink(host, kind, key, query string) string {
+	link := "http://" + host + "/" + kind + "/" + key
+	if query != "" {
+		link += "?" + query
+	}
+	return link
+}
+
 func (m *Message) PlainText(showChatRoom bool, timeFormat string, host string) string {
 
 	if timeFormat == "" {
@@ -359,6 +391,7 @@ func (m *Message) PlainTextContent() string {
 	if host == "<nil>" || host == "" {
 		host = "127.0.0.1:5030"
 	}
+	query := m.MediaQuery()
 	switch m.Type {
 	case MessageTypeText:
 		return m.Content
@@ -379,7 +412,7 @@ func (m *Message) PlainTextContent() string {
 				keylist = append(keylist, thumbpath)
 			}
 		}
-		return fmt.Sprintf("![图片](http://%s/image/%s)", host, strings.Join(keylist, ","))
+		return fmt.Sprintf("![图片](%s)", MediaLink(host, "image", strings.Join(keylist, ","), query))
 	case MessageTypeVoice:
 		if voice, ok := m.Contents["voice"]; ok {
 			// 可选时长字段（可能来源于不同表：voicelength/voiceduration/length 秒）
@@ -406,14 +439,14 @@ func (m *Message) PlainTextContent() string {
 						min := secInt / 60
 						sec := secInt % 60
 						fmtDur := fmt.Sprintf("%dm%02ds", min, sec)
-						return fmt.Sprintf("[语音(%s)](http://%s/voice/%s)", fmtDur, host, voice)
+						return m.withTranscript(fmt.Sprintf("[语音(%s)](%s)", fmtDur, MediaLink(host, "voice", fmt.Sprint(voice), query)))
 					}
 				}
-				return fmt.Sprintf("[语音(%ss)](http://%s/voice/%s)", durStr, host, voice)
+				return m.withTranscript(fmt.Sprintf("[语音(%ss)](%s)", durStr, MediaLink(host, "voice", fmt.Sprint(voice), query)))
 			}
-			return fmt.Sprintf("[语音](http://%s/voice/%s)", host, voice)
+			return m.withTranscript(fmt.Sprintf("[语音](%s)", MediaLink(host, "voice", fmt.Sprint(voice), query)))
 		}
-		return "[语音]"
+		return m.withTranscript("[语音]")
 	case MessageTypeCard:
 		return "[名片]"
 	case MessageTypeVideo:
@@ -433,7 +466,7 @@ func (m *Message) PlainTextContent() string {
 				keylist = append(keylist, path)
 			}
 		}
-		return fmt.Sprintf("![视频](http://%s/video/%s)", host, strings.Join(keylist, ","))
+		return fmt.Sprintf("![视频](%s)", MediaLink(host, "video", strings.Join(keylist, ","), query))
 	case MessageTypeAnimation:
 		if m.Contents["cdnurl"] != nil {
 			if cdnURL, ok := m.Contents["cdnurl"].(string); ok {
@@ -483,7 +516,7 @@ func (m *Message) PlainTextContent() string {
 		case MessageSubTypeLink, MessageSubTypeLink2:
 			return fmt.Sprintf("[链接|%s](%s)", m.Contents["title"], m.Contents["url"])
 		case MessageSubTypeFile:
-			return fmt.Sprintf("[文件|%s](http://%s/file/%s)", m.Contents["title"], host, m.Contents["md5"])
+			return fmt.Sprintf("[文件|%s](%s)", m.Contents["title"], MediaLink(host, "file", fmt.Sprint(m.Contents["md5"]), query))
 		case MessageSubTypeGIF:
 			if m.Contents["cdnurl"] != nil {
 				if u, ok := m.Contents["cdnurl"].(string); ok && strings.HasPrefix(u, "http") {
@@ -504,7 +537,7 @@ func (m *Message) PlainTextContent() string {
 			if m.Contents["host"] != nil {
 				host = m.Contents["host"].(string)
 			}
-			return recordInfo.String("合并转发", "", host)
+			return recordInfo.String("合并转发", "", host, query)
 		case MessageSubTypeNote:
 			_recordInfo, ok := m.Contents["recordInfo"]
 			if !ok {
@@ -518,7 +551,7 @@ func (m *Message) PlainTextContent() string {
 			if m.Contents["host"] != nil {
 				host = m.Contents["host"].(string)
 			}
-			return recordInfo.String("笔记", "", host)
+			return recordInfo.String("笔记", "", host, query)
 		case MessageSubTypeMiniProgram, MessageSubTypeMiniProgram2:
 			if m.Contents["title"] == "" {
 				return "[小程序]"
@@ -550,6 +583,7 @@ func (m *Message) PlainTextContent() string {
 			if m.Contents["host"] != nil {
 				host = m.Contents["host"].(string)
 			}
+			refer.SetMediaQuery(query)
 			referContent := refer.PlainText(false, "", host)
 			for _, line := range strings.Split(referContent, "\n") {
 				if line == "" {
@@ -581,7 +615,7 @@ func (m *Message) PlainTextContent() string {
 			if m.Contents["host"] != nil {
 				host = m.Contents["host"].(string)
 			}
-			return recordInfo.String("群公告", "", host)
+			return recordInfo.String("群公告", "", host, query)
 		case MessageSubTypeMusic:
 			return fmt.Sprintf("[音乐|%s](%s)", m.Contents["title"], m.Contents["url"])
 		case MessageSubTypePay:
@@ -606,6 +640,14 @@ func (m *Message) PlainTextContent() string {
 	}
 }
 
+// withTranscript 在语音占位文本后附加已保存的转写结果
+func (m *Message) withTranscript(text string) string {
+	if transcript, ok := m.Contents["transcript"].(string); ok && strings.TrimSpace(transcript) != "" {
+		return text + " " + strings.TrimSpace(transcript)
+	}
+	return text
+}
+
 func (m *Message) CSV(host string) []string {
 	m.SetContent("host", host)
 	return []string{
@@ -56,6 +56,7 @@ func (m *MessageV3) Wrap() *Message {
 
 	_m := &Message{
 		Seq:        m.Sequence,
+		ServerID:   m.MsgSvrID,
 		Time:       time.Unix(m.CreateTime, 0),
 		Talker:     m.StrTalker,
 		IsChatRoom: strings.HasSuffix(m.StrTalker, "@chatroom"),
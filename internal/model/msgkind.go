@@ -0,0 +1,206 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ysy950803/chatlog/pkg/util"
+)
+
+// MessageKind 表示一类消息的 Type/SubType 组合
+// SubType 为 0 时匹配该 Type 下的全部子类型
+type MessageKind struct {
+	Type    int64 `json:"type"`
+	SubType int64 `json:"subType,omitempty"`
+}
+
+// Match 判断给定的消息类型是否属于该类别
+func (k MessageKind) Match(msgType, subType int64) bool {
+	if k.Type != msgType {
+		return false
+	}
+	return k.SubType == 0 || k.SubType == subType
+}
+
+func (k MessageKind) String() string {
+	if k.SubType == 0 {
+		return strconv.FormatInt(k.Type, 10)
+	}
+	return fmt.Sprintf("%d:%d", k.Type, k.SubType)
+}
+
+// messageKindNames 为可读名称到消息类别的映射，中文别名见 messageKindAliases
+var messageKindNames = map[string][]MessageKind{
+	"text":        {{Type: MessageTypeText}},
+	"image":       {{Type: MessageTypeImage}},
+	"voice":       {{Type: MessageTypeVoice}},
+	"card":        {{Type: MessageTypeCard}},
+	"video":       {{Type: MessageTypeVideo}},
+	"emoji":       {{Type: MessageTypeAnimation}},
+	"location":    {{Type: MessageTypeLocation}},
+	"share":       {{Type: MessageTypeShare}},
+	"link":        {{Type: MessageTypeShare, SubType: MessageSubTypeLink}, {Type: MessageTypeShare, SubType: MessageSubTypeLink2}},
+	"file":        {{Type: MessageTypeShare, SubType: MessageSubTypeFile}},
+	"gif":         {{Type: MessageTypeShare, SubType: MessageSubTypeGIF}},
+	"forward":     {{Type: MessageTypeShare, SubType: MessageSubTypeMergeForward}},
+	"note":        {{Type: MessageTypeShare, SubType: MessageSubTypeNote}},
+	"miniprogram": {{Type: MessageTypeShare, SubType: MessageSubTypeMiniProgram}, {Type: MessageTypeShare, SubType: MessageSubTypeMiniProgram2}},
+	"channel":     {{Type: MessageTypeShare, SubType: MessageSubTypeChannel}, {Type: MessageTypeShare, SubType: MessageSubTypeChannelLive}},
+	"quote":       {{Type: MessageTypeShare, SubType: MessageSubTypeQuote}},
+	"pat":         {{Type: MessageTypeShare, SubType: MessageSubTypePat}},
+	"notice":      {{Type: MessageTypeShare, SubType: MessageSubTypeChatRoomNotice}},
+	"music":       {{Type: MessageTypeShare, SubType: MessageSubTypeMusic}},
+	"transfer":    {{Type: MessageTypeShare, SubType: MessageSubTypePay}},
+	"redpacket":   {{Type: MessageTypeShare, SubType: MessageSubTypeRedEnvelope}},
+	"call":        {{Type: MessageTypeVOIP}},
+	"system":      {{Type: MessageTypeSystem}},
+}
+
+var messageKindAliases = map[string]string{
+	"文本":      "text",
+	"图片":      "image",
+	"img":     "image",
+	"语音":      "voice",
+	"名片":      "card",
+	"视频":      "video",
+	"表情":      "emoji",
+	"sticker": "emoji",
+	"位置":      "location",
+	"分享":      "share",
+	"链接":      "link",
+	"url":     "link",
+	"文件":      "file",
+	"合并转发":    "forward",
+	"聊天记录":    "forward",
+	"笔记":      "note",
+	"小程序":     "miniprogram",
+	"视频号":     "channel",
+	"引用":      "quote",
+	"reply":   "quote",
+	"拍一拍":     "pat",
+	"群公告":     "notice",
+	"音乐":      "music",
+	"转账":      "transfer",
+	"红包":      "redpacket",
+	"通话":      "call",
+	"voip":    "call",
+	"系统":      "system",
+}
+
+// ParseMessageKinds 解析英文逗号分隔的消息类别
+// 支持名称（如 file、链接）以及数字形式（如 49 或 49:6）
+func ParseMessageKinds(str string) ([]MessageKind, error) {
+	kinds := make([]MessageKind, 0)
+	for _, item := range util.Str2List(str, ",") {
+		parsed, err := parseMessageKind(item)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, parsed...)
+	}
+	return kinds, nil
+}
+
+func parseMessageKind(item string) ([]MessageKind, error) {
+	name := strings.ToLower(strings.TrimSpace(item))
+	if alias, ok := messageKindAliases[name]; ok {
+		name = alias
+	}
+	if kinds, ok := messageKindNames[name]; ok {
+		return kinds, nil
+	}
+
+	typePart, subPart, hasSub := strings.Cut(name, ":")
+	t, err := strconv.ParseInt(typePart, 10, 64)
+	if err != nil || t <= 0 {
+		return nil, fmt.Errorf("unknown message type: %s", item)
+	}
+	kind := MessageKind{Type: t}
+	if hasSub {
+		st, err := strconv.ParseInt(subPart, 10, 64)
+		if err != nil || st < 0 {
+			return nil, fmt.Errorf("unknown message type: %s", item)
+		}
+		kind.SubType = st
+	}
+	return []MessageKind{kind}, nil
+}
+
+// MessageTypeFilter 为消息类别的包含/排除条件
+// 包含列表为空时不限制类别，排除列表优先于包含列表
+type MessageTypeFilter struct {
+	Include []MessageKind `json:"include,omitempty"`
+	Exclude []MessageKind `json:"exclude,omitempty"`
+}
+
+// ParseMessageTypeFilter 解析英文逗号分隔的类别条件，以 - 或 ! 开头的项表示排除
+// 如 "file,link" 仅保留文件与链接，"-system,-pat" 排除系统消息与拍一拍
+// 输入为空时返回 nil
+func ParseMessageTypeFilter(str string) (*MessageTypeFilter, error) {
+	f := &MessageTypeFilter{}
+	for _, item := range util.Str2List(str, ",") {
+		exclude := false
+		if strings.HasPrefix(item, "-") || strings.HasPrefix(item, "!") {
+			exclude = true
+			item = item[1:]
+		}
+		kinds, err := parseMessageKind(item)
+		if err != nil {
+			return nil, err
+		}
+		if exclude {
+			f.Exclude = append(f.Exclude, kinds...)
+		} else {
+			f.Include = append(f.Include, kinds...)
+		}
+	}
+	if f.Empty() {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// Empty 判断是否没有任何类别条件
+func (f *MessageTypeFilter) Empty() bool {
+	return f == nil || (len(f.Include) == 0 && len(f.Exclude) == 0)
+}
+
+// Match 判断给定的消息类型是否满足条件
+func (f *MessageTypeFilter) Match(msgType, subType int64) bool {
+	if f == nil {
+		return true
+	}
+	for _, k := range f.Exclude {
+		if k.Match(msgType, subType) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, k := range f.Include {
+		if k.Match(msgType, subType) {
+			return true
+		}
+	}
+	return false
+}
+
+var messageKindLabels = func() map[MessageKind]string {
+	labels := make(map[MessageKind]string)
+	for name, kinds := range messageKindNames {
+		for _, k := range kinds {
+			labels[k] = name
+		}
+	}
+	return labels
+}()
+
+// Name 返回类别的可读名称，未知类别返回数字形式
+func (k MessageKind) Name() string {
+	if name, ok := messageKindLabels[k]; ok {
+		return name
+	}
+	return k.String()
+}
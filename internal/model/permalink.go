@@ -0,0 +1,69 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MaxMessageContext 为按序号读取消息时前后各附带的消息条数上限
+const MaxMessageContext = 200
+
+// PermalinkPath 返回消息的固定链接路径
+// 同一会话中 seq 唯一，链接不随分页、索引重建变化
+func PermalinkPath(talker string, seq int64) string {
+	return "/api/v1/message/" + url.PathEscape(talker) + "/" + strconv.FormatInt(seq, 10)
+}
+
+// ThreadPath 返回消息所在引用回复链的路径
+func ThreadPath(talker string, seq int64) string {
+	return "/api/v1/thread/" + url.PathEscape(talker) + "/" + strconv.FormatInt(seq, 10)
+}
+
+// PermalinkURL 返回消息的固定链接，host 为空时只返回路径，消息没有序号时返回空
+func (m *Message) PermalinkURL(host string) string {
+	if m.Talker == "" || m.Seq == 0 {
+		return ""
+	}
+	if host == "" {
+		return PermalinkPath(m.Talker, m.Seq)
+	}
+	return "http://" + host + PermalinkPath(m.Talker, m.Seq)
+}
+
+// SetPermalink 设置消息的固定链接，host 非空时为完整 URL
+func (m *Message) SetPermalink(host string) {
+	m.Permalink = m.PermalinkURL(host)
+}
+
+// MessageContext 为按会话与序号读取的消息及其前后的消息，均按时间顺序排列
+type MessageContext struct {
+	Message *Message   `json:"message"`
+	Before  []*Message `json:"before"`
+	After   []*Message `json:"after"`
+}
+
+// PlainText 以纯文本输出消息及其上下文，目标消息以 ">" 标记
+func (c *MessageContext) PlainText(host string) string {
+	msg := c.Message
+	query := msg.MediaQuery()
+	title := msg.Talker
+	if msg.TalkerName != "" {
+		title = fmt.Sprintf("%s (%s)", msg.TalkerName, msg.Talker)
+	}
+
+	buf := strings.Builder{}
+	buf.WriteString(title + "\n")
+	for _, m := range c.Before {
+		buf.WriteString("  " + searchContextLine(m, host, query) + "\n")
+	}
+	buf.WriteString("> " + searchContextLine(msg, host, query) + "\n")
+	for _, m := range c.After {
+		buf.WriteString("  " + searchContextLine(m, host, query) + "\n")
+	}
+	if link := msg.PermalinkURL(host); link != "" {
+		buf.WriteString("链接: " + link + "\n")
+	}
+	return buf.String()
+}
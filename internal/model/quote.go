@@ -0,0 +1,151 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ysy950803/chatlog/pkg/util"
+)
+
+// MaxReplyDepth 为沿引用关系向上查找原消息的最大层数
+const MaxReplyDepth = 50
+
+// QuoteRef 为引用消息（49:57）中 refermsg 记录的被引用消息
+// 原消息在本地存在时 Seq 与 Permalink 指向该消息，引用与原消息总在同一会话中
+type QuoteRef struct {
+	SvrID      string    `json:"svrid"`
+	FromUser   string    `json:"fromusr"`
+	ChatUser   string    `json:"chatusr,omitempty"`
+	Sender     string    `json:"sender"`
+	Type       int64     `json:"type"`
+	SubType    int64     `json:"subType,omitempty"`
+	CreateTime time.Time `json:"createtime"`
+
+	Talker    string `json:"talker,omitempty"`
+	Seq       int64  `json:"seq,omitempty"`
+	Permalink string `json:"permalink,omitempty"`
+}
+
+// newQuoteRef 由 refermsg 生成引用记录，发送者优先取群聊中的 chatusr
+func newQuoteRef(refer *ReferMsg) *QuoteRef {
+	q := &QuoteRef{
+		SvrID:      strings.TrimSpace(refer.SvrID),
+		FromUser:   refer.FromUsr,
+		ChatUser:   refer.ChatUsr,
+		Sender:     refer.ChatUsr,
+		CreateTime: time.Unix(refer.CreateTime, 0),
+	}
+	if q.Sender == "" {
+		q.Sender = refer.FromUsr
+	}
+	q.Type, q.SubType = util.SplitInt64ToTwoInt32(refer.Type)
+	return q
+}
+
+// Resolved 判断是否已找到本地的原消息
+func (q *QuoteRef) Resolved() bool {
+	return q != nil && q.Seq > 0
+}
+
+// Resolve 记录本地的原消息
+func (q *QuoteRef) Resolve(m *Message) {
+	q.Talker = m.Talker
+	q.Seq = m.Seq
+	q.Permalink = m.PermalinkURL("")
+}
+
+// Matches 判断 m 是否为被引用的消息，双方都有服务端消息 ID 时按 ID 精确匹配
+// 缺少 ID 时（如 v3 的旧数据）退回推断：同一秒内发送且类型一致，发送者已知时须一致
+func (q *QuoteRef) Matches(m *Message) bool {
+	if m == nil {
+		return false
+	}
+	if same, known := q.SameServerID(m); known {
+		return same
+	}
+	if m.Time.Unix() != q.CreateTime.Unix() {
+		return false
+	}
+	if q.Type != 0 && m.Type != q.Type {
+		return false
+	}
+	return q.Sender == "" || m.Sender == q.Sender
+}
+
+// SameServerID 按服务端消息 ID 判断 m 是否为被引用的消息，known 为 false 表示任一方缺少 ID
+// 数据库以有符号整数保存 ID，超出 int64 范围的 svrid 按补码比较
+func (q *QuoteRef) SameServerID(m *Message) (same, known bool) {
+	if m == nil || m.ServerID == 0 || q.SvrID == "" {
+		return false, false
+	}
+	id, err := strconv.ParseUint(q.SvrID, 10, 64)
+	if err != nil {
+		signed, err := strconv.ParseInt(q.SvrID, 10, 64)
+		if err != nil {
+			return false, false
+		}
+		id = uint64(signed)
+	}
+	if id == 0 {
+		return false, false
+	}
+	return uint64(m.ServerID) == id, true
+}
+
+// Quote 返回引用消息的引用记录，不是引用消息时返回 nil
+func (m *Message) Quote() *QuoteRef {
+	if m.Type != MessageTypeShare || m.SubType != MessageSubTypeQuote {
+		return nil
+	}
+	q, _ := m.Contents["quote"].(*QuoteRef)
+	return q
+}
+
+// ReplyNode 为回复树中的一条消息及直接引用它的回复
+type ReplyNode struct {
+	Message *Message     `json:"message"`
+	Replies []*ReplyNode `json:"replies,omitempty"`
+}
+
+// ReplyThread 为以某条消息为中心的引用回复链
+// Ancestors 为该消息逐级引用的原消息，从最早的一条开始；Replies 为直接或间接引用该消息的回复
+type ReplyThread struct {
+	Message   *Message     `json:"message"`
+	Ancestors []*Message   `json:"ancestors"`
+	Replies   []*ReplyNode `json:"replies"`
+}
+
+// PlainText 以纯文本输出回复链，回复按层级缩进，目标消息以 ">" 标记
+func (t *ReplyThread) PlainText(host string) string {
+	msg := t.Message
+	query := msg.MediaQuery()
+	title := msg.Talker
+	if msg.TalkerName != "" {
+		title = msg.TalkerName + " (" + msg.Talker + ")"
+	}
+
+	buf := strings.Builder{}
+	buf.WriteString(title + "\n")
+	for _, m := range t.Ancestors {
+		buf.WriteString("  " + replyLine(m, host, query) + "\n")
+	}
+	buf.WriteString("> " + replyLine(msg, host, query) + "\n")
+	var walk func(nodes []*ReplyNode, depth int)
+	walk = func(nodes []*ReplyNode, depth int) {
+		for _, n := range nodes {
+			buf.WriteString(strings.Repeat("  ", depth+1) + "↳ " + replyLine(n.Message, host, query) + "\n")
+			walk(n.Replies, depth+1)
+		}
+	}
+	walk(t.Replies, 0)
+	return buf.String()
+}
+
+// replyLine 输出回复链中的一行，已找到原消息的引用消息只输出回复内容，原消息已在链中
+func replyLine(m *Message, host, query string) string {
+	if !m.Quote().Resolved() || m.Content == "" {
+		return searchContextLine(m, host, query)
+	}
+	return m.Time.Format("2006-01-02 15:04:05") + " " + searchSenderLabel(m) + ": " + m.Content
+}
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 合并转发中 dataitem 的 datatype
+const (
+	RecordDataText        = "1"
+	RecordDataImage       = "2"
+	RecordDataVoice       = "3"
+	RecordDataVideo       = "4"
+	RecordDataLink        = "5"
+	RecordDataLocation    = "6"
+	RecordDataFile        = "8"
+	RecordDataRecord      = "17"
+	RecordDataChannel     = "22"
+	RecordDataChannelLive = "23"
+	RecordDataMusic       = "32"
+	RecordDataEmoji       = "37"
+)
+
+// recordDataKinds 为 datatype 对应的消息类别名称，与 type 过滤参数中的名称一致
+var recordDataKinds = map[string]string{
+	RecordDataText:        "text",
+	RecordDataImage:       "image",
+	RecordDataVoice:       "voice",
+	RecordDataVideo:       "video",
+	RecordDataLink:        "link",
+	RecordDataLocation:    "location",
+	RecordDataFile:        "file",
+	RecordDataRecord:      "forward",
+	RecordDataChannel:     "channel",
+	RecordDataChannelLive: "channel",
+	RecordDataMusic:       "music",
+	RecordDataEmoji:       "emoji",
+}
+
+// ForwardRecord 为展开后的合并转发聊天记录，记录项中可以再嵌套合并转发
+type ForwardRecord struct {
+	Title      string         `json:"title"`
+	Desc       string         `json:"desc,omitempty"`
+	IsChatRoom bool           `json:"isChatRoom"`
+	Items      []*ForwardItem `json:"items"`
+}
+
+// ForwardItem 为合并转发中的一条消息
+// 图片、视频、文件的 MediaKey 可直接用于 /image、/video、/file 接口，有多个候选 key（如原图与缩略图）时以英文逗号分隔，由接口依次查找
+type ForwardItem struct {
+	Kind         string `json:"kind"`
+	DataType     string `json:"dataType"`
+	SenderName   string `json:"senderName"`
+	SenderAvatar string `json:"senderAvatar,omitempty"`
+	Time         string `json:"time"`
+	Unix         int64  `json:"unix,omitempty"`
+	Content      string `json:"content,omitempty"`
+	Title        string `json:"title,omitempty"`
+	URL          string `json:"url,omitempty"`
+
+	MediaType string `json:"mediaType,omitempty"`
+	MediaKey  string `json:"mediaKey,omitempty"`
+	FileExt   string `json:"fileExt,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+
+	Record *ForwardRecord `json:"record,omitempty"`
+}
+
+// MediaPath 返回记录项媒体的访问路径，没有媒体时返回空
+func (i *ForwardItem) MediaPath() string {
+	if i.MediaType == "" || i.MediaKey == "" {
+		return ""
+	}
+	return "/" + i.MediaType + "/" + i.MediaKey
+}
+
+// Expand 将 recordinfo 递归展开为合并转发聊天记录，title 为空时使用 recordinfo 中的标题
+func (r *RecordInfo) Expand(title string) *ForwardRecord {
+	if title == "" {
+		title = r.Title
+	}
+	ret := &ForwardRecord{
+		Title:      title,
+		Desc:       r.Desc,
+		IsChatRoom: r.IsChatRoom == "1",
+		Items:      make([]*ForwardItem, 0, len(r.DataList.DataItems)),
+	}
+	for _, item := range r.DataList.DataItems {
+		// FIXME 笔记的第一条是 htm 数据，暂时跳过处理
+		if item.DataType == RecordDataFile && item.DataFmt == ".htm" {
+			continue
+		}
+		ret.Items = append(ret.Items, item.expand())
+	}
+	return ret
+}
+
+func (d *DataItem) expand() *ForwardItem {
+	item := &ForwardItem{
+		Kind:         recordDataKinds[d.DataType],
+		DataType:     d.DataType,
+		SenderName:   d.SourceName,
+		SenderAvatar: d.SourceHeadURL,
+		Time:         d.SourceTime,
+		Content:      d.DataDesc,
+		Title:        d.DataTitle,
+	}
+	if item.Kind == "" {
+		item.Kind = "other"
+	}
+	if unix, err := strconv.ParseInt(d.SrcMsgCreateTime, 10, 64); err == nil {
+		item.Unix = unix
+	}
+
+	switch d.DataType {
+	case RecordDataImage:
+		item.setMedia("image", d.FullMD5, d.ThumbFullMD5)
+	case RecordDataVideo:
+		item.setMedia("video", d.FullMD5)
+	case RecordDataFile:
+		item.setMedia("file", d.FullMD5)
+		item.FileExt = strings.TrimPrefix(d.DataFmt, ".")
+		item.Size, _ = strconv.ParseInt(d.DataSize, 10, 64)
+	case RecordDataLink:
+		item.URL = d.Link
+	case RecordDataMusic:
+		item.URL = d.StreamWebURL
+	case RecordDataLocation:
+		item.Title = d.Location.PoiName
+		item.Content = d.Location.Label
+	case RecordDataRecord:
+		if d.RecordXML != nil {
+			item.Record = d.RecordXML.RecordInfo.Expand(d.DataTitle)
+		}
+	}
+	return item
+}
+
+// setMedia 记录媒体类型与候选 key，不在展开时查找本地文件，请求媒体时再依次尝试
+func (i *ForwardItem) setMedia(mediaType string, keys ...string) {
+	var candidates []string
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	i.MediaType = mediaType
+	i.MediaKey = strings.Join(candidates, ",")
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+const nestedRecordXML = `<recordinfo>
+<title>群聊的聊天记录</title>
+<isChatRoom>1</isChatRoom>
+<datalist count="6">
+<dataitem datatype="8"><datafmt>.htm</datafmt><fullmd5>note-md5</fullmd5></dataitem>
+<dataitem datatype="1"><sourcename>张三</sourcename><sourcetime>2024-03-01 10:00</sourcetime><datadesc>看下这几个文件</datadesc><srcMsgCreateTime>1709258400</srcMsgCreateTime></dataitem>
+<dataitem datatype="2"><sourcename>张三</sourcename><fullmd5>img-full</fullmd5><thumbfullmd5>img-thumb</thumbfullmd5></dataitem>
+<dataitem datatype="8"><sourcename>李四</sourcename><datatitle>报价单.xlsx</datatitle><datafmt>.xlsx</datafmt><datasize>2048</datasize><fullmd5>file-md5</fullmd5></dataitem>
+<dataitem datatype="4"><sourcename>李四</sourcename><fullmd5>video-md5</fullmd5></dataitem>
+<dataitem datatype="17"><sourcename>王五</sourcename><datatitle>内层记录</datatitle><recordxml><recordinfo>
+<title>原标题</title>
+<datalist count="2">
+<dataitem datatype="2"><sourcename>赵六</sourcename><thumbfullmd5>inner-thumb</thumbfullmd5></dataitem>
+<dataitem datatype="17"><datatitle>最内层</datatitle><recordxml><recordinfo><datalist count="1">
+<dataitem datatype="1"><sourcename>孙七</sourcename><datadesc>到底了</datadesc></dataitem>
+</datalist></recordinfo></recordxml></dataitem>
+</datalist>
+</recordinfo></recordxml></dataitem>
+</datalist>
+</recordinfo>`
+
+func TestRecordInfoExpand(t *testing.T) {
+	var info RecordInfo
+	if err := xml.Unmarshal([]byte(nestedRecordXML), &info); err != nil {
+		t.Fatalf("unmarshal recordinfo: %v", err)
+	}
+	// 笔记的 htm 数据不作为记录项
+	record := info.Expand("")
+	if record.Title != "群聊的聊天记录" || !record.IsChatRoom || len(record.Items) != 5 {
+		t.Fatalf("record = %+v", record)
+	}
+
+	text, img, file, video, nested := record.Items[0], record.Items[1], record.Items[2], record.Items[3], record.Items[4]
+	if text.Kind != "text" || text.Content != "看下这几个文件" || text.Unix != 1709258400 || text.MediaPath() != "" {
+		t.Errorf("text item = %+v", text)
+	}
+	if img.Kind != "image" || img.MediaPath() != "/image/img-full,img-thumb" {
+		t.Errorf("image item = %+v", img)
+	}
+	if file.Kind != "file" || file.MediaPath() != "/file/file-md5" || file.Title != "报价单.xlsx" || file.FileExt != "xlsx" || file.Size != 2048 {
+		t.Errorf("file item = %+v", file)
+	}
+	if video.Kind != "video" || video.MediaPath() != "/video/video-md5" {
+		t.Errorf("video item = %+v", video)
+	}
+
+	// 内层记录使用外层记录项的标题
+	if nested.Kind != "forward" || nested.Record == nil {
+		t.Fatalf("nested item = %+v", nested)
+	}
+	inner := nested.Record
+	if inner.Title != "内层记录" || len(inner.Items) != 2 {
+		t.Fatalf("inner record = %+v", inner)
+	}
+	if inner.Items[0].MediaPath() != "/image/inner-thumb" {
+		t.Errorf("inner image item = %+v", inner.Items[0])
+	}
+	innermost := inner.Items[1].Record
+	if innermost == nil || innermost.Title != "最内层" || len(innermost.Items) != 1 || innermost.Items[0].Content != "到底了" {
+		t.Errorf("innermost record = %+v", innermost)
+	}
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SavedSearch 为持久化的常用搜索，增量索引新消息时自动执行并对新命中发出通知
+// Seen 为各消息库索引中已检查到的位置，按写入索引的顺序读取新命中，晚写入但时间较早的消息同样会提醒，重启后不会重复提醒
+// LastSeen 为已检查过的最新消息时间位置，消息库没有对应的 Seen（新建常用搜索或索引文件重建）时只提醒晚于该位置的命中
+type SavedSearch struct {
+	ID            string               `json:"id"`
+	Name          string               `json:"name"`
+	Request       *SearchRequest       `json:"request"`
+	Notify        *SearchNotify        `json:"notify,omitempty"`
+	Disabled      bool                 `json:"disabled"`
+	LastSeen      MessageMarker        `json:"last_seen"`
+	Seen          map[string]IndexMark `json:"seen,omitempty"`
+	Matches       int                  `json:"matches"`
+	LastMatchedAt time.Time            `json:"last_matched_at"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// SearchNotify 为常用搜索的通知目标，新命中以 JSON POST 到 URL
+type SearchNotify struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// IndexMark 标记消息库索引中的写入位置，Gen 标识索引文件，文件重建后 RowID 从头计数
+type IndexMark struct {
+	Gen   string `json:"gen"`
+	RowID int64  `json:"rowid"`
+}
+
+// MessageMarker 以 (Unix, Seq) 标记消息在时间线上的位置
+type MessageMarker struct {
+	Unix int64 `json:"unix"`
+	Seq  int64 `json:"seq"`
+}
+
+// MarkerOf 返回消息所在的位置
+func MarkerOf(m *Message) MessageMarker {
+	return MessageMarker{Unix: m.Time.Unix(), Seq: m.Seq}
+}
+
+// Before 判断 m 是否早于 other
+func (m MessageMarker) Before(other MessageMarker) bool {
+	if m.Unix != other.Unix {
+		return m.Unix < other.Unix
+	}
+	return m.Seq < other.Seq
+}
+
+// String 将位置编码为 "<unix>-<seq>"，用作推送事件的 ID
+func (m MessageMarker) String() string {
+	return strconv.FormatInt(m.Unix, 10) + "-" + strconv.FormatInt(m.Seq, 10)
+}
+
+// ParseMessageMarker 解析 String 输出的位置
+func ParseMessageMarker(s string) (MessageMarker, error) {
+	unixText, seqText, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return MessageMarker{}, fmt.Errorf("invalid message marker: %s", s)
+	}
+	unix, err := strconv.ParseInt(unixText, 10, 64)
+	if err != nil {
+		return MessageMarker{}, fmt.Errorf("invalid message marker: %s", s)
+	}
+	seq, err := strconv.ParseInt(seqText, 10, 64)
+	if err != nil {
+		return MessageMarker{}, fmt.Errorf("invalid message marker: %s", s)
+	}
+	return MessageMarker{Unix: unix, Seq: seq}, nil
+}
+
+// SavedSearchAlert 为常用搜索发出的通知内容
+type SavedSearchAlert struct {
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Query  string       `json:"query"`
+	Length int          `json:"length"`
+	Hits   []*SearchHit `json:"hits"`
+}
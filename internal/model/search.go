@@ -1,22 +1,66 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // SearchRequest 表示一次搜索查询的参数
 // Start 和 End 为闭区间；如未提供则由调用方决定默认范围
 // Limit/Offset 由调用链路在进入数据源前进行裁剪
 // Sender 使用英文逗号分隔多个筛选条件
 // Talker 可选：留空时后端会遍历所有会话；如需限定多个会话，使用英文逗号分隔
+// Types 为英文逗号分隔的消息类别（如 file,link 或 49:6），以 - 开头表示排除，留空表示不限
+// Context 大于 0 时，为每条命中附带同一会话中前后各 Context 条消息
+// Cursor 为上一页返回的 NextCursor，非空时按游标续读并忽略 Offset，翻页期间新增索引的消息不会打乱已读位置
+// 有检索词时游标指向首页的相关度排序快照，只包含首页时已索引的消息；只有过滤条件时按时间顺序续读，均不限深度
+// Facets 为 true 时额外统计全部命中在会话、发送者、类别、月份上的分布
+// Query 中可使用 from:/in:/type:/has:/after:/before: 操作符，见 SearchQuery
+// Mode 为 semantic 时按向量相似度检索，hybrid 时与关键词检索融合排序；这两种模式只支持 Offset 翻页，不统计分面
 type SearchRequest struct {
-	Query  string    `json:"query"`
-	Talker string    `json:"talker"`
-	Sender string    `json:"sender"`
-	Start  time.Time `json:"start"`
-	End    time.Time `json:"end"`
-	Limit  int       `json:"limit"`
-	Offset int       `json:"offset"`
+	Query   string    `json:"query"`
+	Talker  string    `json:"talker"`
+	Sender  string    `json:"sender"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Limit   int       `json:"limit"`
+	Offset  int       `json:"offset"`
+	Types   string    `json:"types"`
+	Context int       `json:"context"`
+	Cursor  string    `json:"cursor"`
+	Facets  bool      `json:"facets"`
+	Mode    string    `json:"mode"`
 }
 
+const (
+	SearchModeKeyword  = "keyword"
+	SearchModeSemantic = "semantic"
+	SearchModeHybrid   = "hybrid"
+)
+
+// ParseSearchMode 规范化检索模式，留空时为关键词检索
+func ParseSearchMode(mode string) (string, error) {
+	switch m := strings.ToLower(strings.TrimSpace(mode)); m {
+	case "", SearchModeKeyword, "bm25":
+		return SearchModeKeyword, nil
+	case SearchModeSemantic, "vector":
+		return SearchModeSemantic, nil
+	case SearchModeHybrid:
+		return SearchModeHybrid, nil
+	default:
+		return "", fmt.Errorf("unknown search mode %q", mode)
+	}
+}
+
+// Semantic 判断是否需要向量检索
+func (r *SearchRequest) Semantic() bool {
+	return r.Mode == SearchModeSemantic || r.Mode == SearchModeHybrid
+}
+
+// MaxSearchContext 为单条命中允许附带的上下文条数上限（单侧）
+const MaxSearchContext = 50
+
 // Clone 生成请求的浅拷贝，便于在不同层级添加额外参数
 func (r *SearchRequest) Clone() *SearchRequest {
 	if r == nil {
@@ -27,17 +71,73 @@ func (r *SearchRequest) Clone() *SearchRequest {
 }
 
 // SearchHit 表示一次搜索命中的消息及其高亮片段
-// Score 使用 SQLite FTS5 的 bm25 分值，越小代表相关度越高
+// Score 使用 SQLite FTS5 的 bm25 分值，越小代表相关度越高；semantic / hybrid 模式下为相似度 / 融合分，越大越相关
+// Before / After 为同一会话中紧邻命中消息的上下文，均按时间升序排列
 type SearchHit struct {
-	Message *Message `json:"message"`
-	Snippet string   `json:"snippet"`
-	Score   float64  `json:"score"`
+	Message *Message   `json:"message"`
+	Snippet string     `json:"snippet"`
+	Score   float64    `json:"score"`
+	Before  []*Message `json:"before,omitempty"`
+	After   []*Message `json:"after,omitempty"`
+}
+
+// PlainText 以纯文本输出单条命中，idx 为从 0 开始的序号
+// 附带上下文时按对话顺序输出，命中消息以 ">" 标记
+func (h *SearchHit) PlainText(idx int, host string) string {
+	msg := h.Message
+	msg.SetContent("host", host)
+	query := msg.MediaQuery()
+	title := msg.Talker
+	if msg.TalkerName != "" {
+		title = fmt.Sprintf("%s (%s)", msg.TalkerName, msg.Talker)
+	}
+
+	buf := strings.Builder{}
+	buf.WriteString(fmt.Sprintf("[%d] %s @ %s\n", idx+1, msg.Time.Format("2006-01-02 15:04:05"), title))
+	if link := msg.PermalinkURL(host); link != "" {
+		buf.WriteString("链接: " + link + "\n")
+	}
+	if len(h.Before) == 0 && len(h.After) == 0 {
+		buf.WriteString(fmt.Sprintf("发送者: %s\n", searchSenderLabel(msg)))
+		buf.WriteString(msg.PlainTextContent() + "\n")
+	} else {
+		for _, m := range h.Before {
+			buf.WriteString("  " + searchContextLine(m, host, query) + "\n")
+		}
+		buf.WriteString("> " + searchContextLine(msg, host, query) + "\n")
+		for _, m := range h.After {
+			buf.WriteString("  " + searchContextLine(m, host, query) + "\n")
+		}
+	}
+	if snippet := strings.TrimSpace(h.Snippet); snippet != "" {
+		buf.WriteString("Snippet: " + snippet + "\n")
+	}
+	return buf.String()
+}
+
+// searchContextLine 输出上下文中的一行，query 为媒体链接附加的查询参数，与目标消息一致
+func searchContextLine(m *Message, host, query string) string {
+	m.SetContent("host", host)
+	m.SetMediaQuery(query)
+	return m.Time.Format("2006-01-02 15:04:05") + " " + searchSenderLabel(m) + ": " + m.PlainTextContent()
+}
+
+func searchSenderLabel(m *Message) string {
+	sender := m.Sender
+	if m.IsSelf {
+		sender = "我"
+	}
+	if m.SenderName != "" {
+		sender = fmt.Sprintf("%s(%s)", m.SenderName, m.Sender)
+	}
+	return sender
 }
 
 // SearchResponse 汇总搜索结果
 // DurationMs 统计搜索耗时（毫秒），仅供参考
 // Limit / Offset 为实际生效的分页参数
 // Hits 序列按相关度排序，命中数可能小于 limit（例如过滤后不足）
+// NextCursor 非空时表示还有下一页，使用游标翻页时 Total 为首页统计的总数
 type SearchResponse struct {
 	Total      int                `json:"total"`
 	Hits       []*SearchHit       `json:"hits"`
@@ -49,9 +149,33 @@ type SearchResponse struct {
 	Sender     string             `json:"sender"`
 	Start      time.Time          `json:"start"`
 	End        time.Time          `json:"end"`
+	Types      string             `json:"types,omitempty"`
+	Context    int                `json:"context,omitempty"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	Facets     *SearchFacets      `json:"facets,omitempty"`
 	Index      *SearchIndexStatus `json:"index_status,omitempty"`
 }
 
+// MaxFacetBuckets 为每个分面返回的最大分组数
+const MaxFacetBuckets = 20
+
+// SearchFacets 为全部命中（不受分页影响）的分布统计
+// Talkers / Senders / Types 按数量降序，Months 按月份降序
+type SearchFacets struct {
+	Talkers []*FacetBucket `json:"talkers"`
+	Senders []*FacetBucket `json:"senders"`
+	Types   []*FacetBucket `json:"types"`
+	Months  []*FacetBucket `json:"months"`
+}
+
+// FacetBucket 为分面中的一个分组
+// Value 可直接作为对应的筛选参数（talker、sender、type、time）使用
+type FacetBucket struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
 // SearchIndexStatus 表示全文索引的构建状态
 type SearchIndexStatus struct {
 	Ready           bool      `json:"ready"`
@@ -60,4 +184,47 @@ type SearchIndexStatus struct {
 	LastStartedAt   time.Time `json:"last_started_at"`
 	LastCompletedAt time.Time `json:"last_completed_at"`
 	LastError       string    `json:"last_error,omitempty"`
+
+	// Semantic 为语义检索向量的生成状态，未配置向量模型时为空
+	Semantic *SemanticIndexStatus `json:"semantic,omitempty"`
+}
+
+// SemanticIndexStatus 表示语义检索向量的生成状态
+type SemanticIndexStatus struct {
+	Model           string    `json:"model"`
+	InProgress      bool      `json:"in_progress"`
+	LastCompletedAt time.Time `json:"last_completed_at"`
+	LastError       string    `json:"last_error,omitempty"`
+}
+
+// PlainText 以纯文本输出分面统计，每个分面一行
+func (f *SearchFacets) PlainText() string {
+	if f == nil {
+		return ""
+	}
+	groups := []struct {
+		title   string
+		buckets []*FacetBucket
+	}{
+		{"会话", f.Talkers},
+		{"发送者", f.Senders},
+		{"类型", f.Types},
+		{"月份", f.Months},
+	}
+	b := strings.Builder{}
+	for _, g := range groups {
+		if len(g.buckets) == 0 {
+			continue
+		}
+		items := make([]string, 0, len(g.buckets))
+		for _, bucket := range g.buckets {
+			label := bucket.Value
+			if bucket.Label != "" && bucket.Label != bucket.Value {
+				label = fmt.Sprintf("%s(%s)", bucket.Label, bucket.Value)
+			}
+			items = append(items, fmt.Sprintf("%s %d", label, bucket.Count))
+		}
+		fmt.Fprintf(&b, "%s: %s\n", g.title, strings.Join(items, ", "))
+	}
+	return b.String()
 }
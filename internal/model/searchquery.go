@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ysy950803/chatlog/pkg/util"
+)
+
+// SearchQuery 为从单个搜索框输入中解析出的结构化条件
+// 支持的操作符：
+//
+//	from:张三        发送者（可重复，多个值为“或”关系）
+//	in:工作群        会话（可重复）
+//	type:file       消息类别，取值见 ParseMessageKinds
+//	has:link        包含某类内容，与 type 取值相同
+//	-type:system    排除某类消息（-has: 同理）
+//	after:2024-03-01  该时间（含）之后
+//	before:2024-04    该时间之前（不含）
+//
+// 操作符的值可以使用双引号包裹以包含空格，未识别的 key:value 原样保留为检索词
+type SearchQuery struct {
+	Text   string
+	From   []string
+	In     []string
+	Types  []string
+	After  time.Time
+	Before time.Time
+}
+
+// ParseSearchQuery 解析带操作符的搜索输入
+func ParseSearchQuery(input string) (*SearchQuery, error) {
+	q := &SearchQuery{}
+	rest := make([]string, 0)
+
+	for _, word := range splitQueryWords(input) {
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			rest = append(rest, word)
+			continue
+		}
+		value = strings.Trim(value, "\"“”")
+
+		switch strings.ToLower(key) {
+		case "from":
+			q.From = append(q.From, value)
+		case "in":
+			q.In = append(q.In, value)
+		case "type", "has", "-type", "-has":
+			if _, err := ParseMessageTypeFilter(value); err != nil {
+				return nil, err
+			}
+			for _, item := range util.Str2List(value, ",") {
+				if strings.HasPrefix(key, "-") {
+					item = "-" + item
+				}
+				q.Types = append(q.Types, item)
+			}
+		case "after":
+			start, _, ok := util.TimeRangeOf(value)
+			if !ok {
+				return nil, fmt.Errorf("invalid time in after: %s", value)
+			}
+			q.After = start
+		case "before":
+			start, _, ok := util.TimeRangeOf(value)
+			if !ok {
+				return nil, fmt.Errorf("invalid time in before: %s", value)
+			}
+			q.Before = start.Add(-time.Nanosecond)
+		default:
+			rest = append(rest, word)
+		}
+	}
+
+	q.Text = strings.Join(rest, " ")
+	return q, nil
+}
+
+// splitQueryWords 按空白切分输入，双引号内的空白不作为分隔符
+func splitQueryWords(input string) []string {
+	words := make([]string, 0)
+	var b strings.Builder
+	quoted := false
+	for _, r := range input {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			quoted = !quoted
+			b.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if b.Len() > 0 {
+				words = append(words, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		words = append(words, b.String())
+	}
+	return words
+}
+
+// ExpandOperators 解析 Query 中的操作符并合并到请求字段中
+// 操作符给出的会话、发送者、类别与已有参数合并；时间范围取两者的交集
+func (r *SearchRequest) ExpandOperators() error {
+	if r == nil || !strings.Contains(r.Query, ":") {
+		return nil
+	}
+
+	q, err := ParseSearchQuery(r.Query)
+	if err != nil {
+		return err
+	}
+
+	r.Query = q.Text
+	r.Talker = joinList(r.Talker, q.In)
+	r.Sender = joinList(r.Sender, q.From)
+	r.Types = joinList(r.Types, q.Types)
+	if !q.After.IsZero() && (r.Start.IsZero() || q.After.After(r.Start)) {
+		r.Start = q.After
+	}
+	if !q.Before.IsZero() && (r.End.IsZero() || q.Before.Before(r.End)) {
+		r.End = q.Before
+	}
+	return nil
+}
+
+func joinList(base string, values []string) string {
+	if len(values) == 0 {
+		return base
+	}
+	list := util.Str2List(base, ",")
+	list = append(list, values...)
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	q, err := ParseSearchQuery(`from:张三 in:"工作 群" type:file after:2024-03-01 before:2024-04-01 has:link 合同 http://example.com`)
+	if err != nil {
+		t.Fatalf("ParseSearchQuery error: %v", err)
+	}
+
+	if q.Text != "合同 http://example.com" {
+		t.Errorf("Text = %q", q.Text)
+	}
+	if len(q.From) != 1 || q.From[0] != "张三" {
+		t.Errorf("From = %v", q.From)
+	}
+	if len(q.In) != 1 || q.In[0] != "工作 群" {
+		t.Errorf("In = %v", q.In)
+	}
+	if len(q.Types) != 2 || q.Types[0] != "file" || q.Types[1] != "link" {
+		t.Errorf("Types = %v", q.Types)
+	}
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local); !q.After.Equal(want) {
+		t.Errorf("After = %v, want %v", q.After, want)
+	}
+	if want := time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local); !q.Before.Before(want) || q.Before.Before(want.Add(-time.Second)) {
+		t.Errorf("Before = %v, want just before %v", q.Before, want)
+	}
+
+	if _, err := ParseSearchQuery("type:unknown"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestParseMessageKinds(t *testing.T) {
+	kinds, err := ParseMessageKinds("链接,49:6,3")
+	if err != nil {
+		t.Fatalf("ParseMessageKinds error: %v", err)
+	}
+	want := []MessageKind{
+		{Type: MessageTypeShare, SubType: MessageSubTypeLink},
+		{Type: MessageTypeShare, SubType: MessageSubTypeLink2},
+		{Type: MessageTypeShare, SubType: MessageSubTypeFile},
+		{Type: MessageTypeImage},
+	}
+	if len(kinds) != len(want) {
+		t.Fatalf("kinds = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("kinds[%d] = %v, want %v", i, kinds[i], want[i])
+		}
+	}
+}
+
+func TestParseMessageTypeFilter(t *testing.T) {
+	f, err := ParseMessageTypeFilter("share,-file,!system")
+	if err != nil {
+		t.Fatalf("ParseMessageTypeFilter error: %v", err)
+	}
+	cases := []struct {
+		typ, sub int64
+		want     bool
+	}{
+		{MessageTypeShare, MessageSubTypeLink, true},
+		{MessageTypeShare, MessageSubTypeFile, false},
+		{MessageTypeSystem, 0, false},
+		{MessageTypeText, 0, false},
+	}
+	for _, c := range cases {
+		if got := f.Match(c.typ, c.sub); got != c.want {
+			t.Errorf("Match(%d, %d) = %v, want %v", c.typ, c.sub, got, c.want)
+		}
+	}
+
+	f, err = ParseMessageTypeFilter("-system")
+	if err != nil {
+		t.Fatalf("ParseMessageTypeFilter error: %v", err)
+	}
+	if !f.Match(MessageTypeText, 0) || f.Match(MessageTypeSystem, 0) {
+		t.Errorf("exclude-only filter = %+v", f)
+	}
+
+	if f, err := ParseMessageTypeFilter(""); err != nil || f != nil {
+		t.Errorf("empty filter = %+v, %v", f, err)
+	}
+
+	q, err := ParseSearchQuery("-type:system has:file 报告")
+	if err != nil {
+		t.Fatalf("ParseSearchQuery error: %v", err)
+	}
+	if len(q.Types) != 2 || q.Types[0] != "-system" || q.Types[1] != "file" || q.Text != "报告" {
+		t.Errorf("query = %+v", q)
+	}
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+)
+
+// Transcript 为语音消息的转写结果，以语音 key（Contents["voice"]）为主键
+type Transcript struct {
+	Key       string    `json:"key"`
+	Talker    string    `json:"talker"`
+	Seq       int64     `json:"seq"`
+	Text      string    `json:"text"`
+	Language  string    `json:"language,omitempty"`
+	Duration  float64   `json:"duration"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TranscribeRequest 为后台语音转写任务的参数
+type TranscribeRequest struct {
+	Talker  string    `json:"talker"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Force   bool      `json:"force"`   // 重新转写已有结果的语音
+	Restart bool      `json:"restart"` // 忽略断点，从头开始
+}
+
+// TranscribeCheckpoint 记录转写任务已完成的会话，中断后以相同参数启动时跳过这些会话
+type TranscribeCheckpoint struct {
+	Talker    string    `json:"talker"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Completed []string  `json:"completed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Matches 判断断点是否由相同范围的任务产生
+func (c *TranscribeCheckpoint) Matches(req *TranscribeRequest) bool {
+	if c == nil || req == nil {
+		return false
+	}
+	return c.Talker == req.Talker && c.Start.Equal(req.Start) && c.End.Equal(req.End)
+}
+
+const (
+	TranscribeStateIdle      = "idle"
+	TranscribeStateRunning   = "running"
+	TranscribeStateCompleted = "completed"
+	TranscribeStateCancelled = "cancelled"
+	TranscribeStateFailed    = "failed"
+)
+
+// TranscribeStatus 为后台语音转写任务的进度
+type TranscribeStatus struct {
+	State         string    `json:"state"`
+	Talker        string    `json:"talker,omitempty"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Resumed       bool      `json:"resumed"`
+	TotalTalkers  int       `json:"total_talkers"`
+	DoneTalkers   int       `json:"done_talkers"`
+	CurrentTalker string    `json:"current_talker,omitempty"`
+	Transcribed   int       `json:"transcribed"`
+	Skipped       int       `json:"skipped"`
+	Failed        int       `json:"failed"`
+	Stored        int       `json:"stored"`
+	Progress      float64   `json:"progress"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	LastError     string    `json:"last_error,omitempty"`
+}
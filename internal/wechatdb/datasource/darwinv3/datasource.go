@@ -467,7 +467,7 @@ func (ds *DataSource) initChatRoomDb() error {
 	return nil
 }
 
-func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
+func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, msgType string, limit, offset int) ([]*model.Message, error) {
 	if talker == "" {
 		return nil, errors.ErrTalkerEmpty
 	}
@@ -491,6 +491,12 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 		}
 	}
 
+	// 解析消息类别条件（如 file,link 或 -system）
+	types, err := model.ParseMessageTypeFilter(msgType)
+	if err != nil {
+		return nil, errors.InvalidMessageType(err)
+	}
+
 	// 从每个相关数据库中查询消息，并在读取时进行过滤
 	filteredMessages := []*model.Message{}
 
@@ -555,6 +561,11 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 			// 将消息包装为通用模型
 			message := msg.Wrap(talkerItem)
 
+			// 应用类别过滤
+			if !types.Match(message.Type, message.SubType) {
+				continue // 不匹配类别，跳过此消息
+			}
+
 			// 应用sender过滤
 			if len(senders) > 0 {
 				senderMatch := false
@@ -646,13 +657,15 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 
 	if key != "" {
 		// 按照关键字查询
-		query = `SELECT IFNULL(m_nsUsrName,""), IFNULL(nickname,""), IFNULL(m_nsRemark,""), m_uiSex, IFNULL(m_nsAliasName,"") 
+		query = `SELECT IFNULL(m_nsUsrName,""), IFNULL(nickname,""), IFNULL(m_nsRemark,""), m_uiSex, IFNULL(m_nsAliasName,""),
+				IFNULL(m_nsFullPY,""), IFNULL(m_nsShortPY,""), IFNULL(m_nsRemarkPYFull,""), IFNULL(m_nsRemarkPYShort,"")
 				FROM WCContact 
 				WHERE m_nsUsrName = ? OR nickname = ? OR m_nsRemark = ? OR m_nsAliasName = ?`
 		args = []interface{}{key, key, key, key}
 	} else {
 		// 查询所有联系人
-		query = `SELECT IFNULL(m_nsUsrName,""), IFNULL(nickname,""), IFNULL(m_nsRemark,""), m_uiSex, IFNULL(m_nsAliasName,"") 
+		query = `SELECT IFNULL(m_nsUsrName,""), IFNULL(nickname,""), IFNULL(m_nsRemark,""), m_uiSex, IFNULL(m_nsAliasName,""),
+				IFNULL(m_nsFullPY,""), IFNULL(m_nsShortPY,""), IFNULL(m_nsRemarkPYFull,""), IFNULL(m_nsRemarkPYShort,"")
 				FROM WCContact`
 	}
 
@@ -685,6 +698,10 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 			&contactDarwinV3.M_nsRemark,
 			&contactDarwinV3.M_uiSex,
 			&contactDarwinV3.M_nsAliasName,
+			&contactDarwinV3.M_nsFullPY,
+			&contactDarwinV3.M_nsShortPY,
+			&contactDarwinV3.M_nsRemarkPYFull,
+			&contactDarwinV3.M_nsRemarkPYShort,
 		)
 
 		if err != nil {
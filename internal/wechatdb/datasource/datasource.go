@@ -18,7 +18,7 @@ type DataSource interface {
 	msgstore.Provider
 
 	// 消息
-	GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error)
+	GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, msgType string, limit, offset int) ([]*model.Message, error)
 	GetDatasetFingerprint(ctx context.Context) (string, error)
 
 	// 联系人
@@ -301,7 +301,7 @@ func (ds *DataSource) getDBInfosForTimeRange(startTime, endTime time.Time) []Mes
 	return dbs
 }
 
-func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
+func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, msgType string, limit, offset int) ([]*model.Message, error) {
 	if talker == "" {
 		return nil, errors.ErrTalkerEmpty
 	}
@@ -331,6 +331,12 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 		}
 	}
 
+	// 解析消息类别条件（如 file,link 或 -system）
+	types, err := model.ParseMessageTypeFilter(msgType)
+	if err != nil {
+		return nil, errors.InvalidMessageType(err)
+	}
+
 	// 从每个相关数据库中查询消息，并在读取时进行过滤
 	filteredMessages := []*model.Message{}
 
@@ -413,6 +419,11 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 				// 将消息转换为标准格式
 				message := msg.Wrap(talkerItem)
 
+				// 应用类别过滤
+				if !types.Match(message.Type, message.SubType) {
+					continue // 不匹配类别，跳过此消息
+				}
+
 				// 应用sender过滤
 				if len(senders) > 0 {
 					senderMatch := false
@@ -596,12 +607,6 @@ func (ds *DataSource) IterateMessages(ctx context.Context, talkers []string, han
 		return nil
 	}
 
-	tableNames := make(map[string]string, len(talkers))
-	for _, talker := range talkers {
-		hash := md5.Sum([]byte(talker))
-		tableNames[talker] = "Msg_" + hex.EncodeToString(hash[:])
-	}
-
 	for _, info := range ds.messageInfos {
 		if err := ctx.Err(); err != nil {
 			return err
@@ -613,62 +618,112 @@ func (ds *DataSource) IterateMessages(ctx context.Context, talkers []string, han
 		}
 
 		for _, talker := range talkers {
-			if err := ctx.Err(); err != nil {
+			if err := ds.iterateTalker(ctx, db, talker, 0, handler); err != nil {
 				return err
 			}
-			tableName := tableNames[talker]
+		}
+	}
 
-			query := fmt.Sprintf(`
-				SELECT m.sort_seq, m.server_id, m.local_type, n.user_name,
-				       m.create_time, m.message_content, m.packed_info_data, m.status
-				FROM %s AS m
-				LEFT JOIN Name2Id n ON m.real_sender_id = n.rowid
-				ORDER BY m.sort_seq ASC
-			`, tableName)
+	return nil
+}
 
-			rows, err := db.QueryContext(ctx, query)
-			if err != nil {
-				if strings.Contains(err.Error(), "no such table") {
-					continue
-				}
-				return errors.QueryFailed("iterate messages", err)
-			}
+// IterateStoreMessages 按 sort_seq 升序遍历单个消息库中 talker 序号大于 afterSeq 的消息
+func (ds *DataSource) IterateStoreMessages(ctx context.Context, store *msgstore.Store, talker string, afterSeq int64, handler func(*model.Message) error) error {
+	if handler == nil {
+		return errors.InvalidArg("handler")
+	}
+	db, err := ds.openStoreDB(store)
+	if err != nil {
+		return err
+	}
+	return ds.iterateTalker(ctx, db, talker, afterSeq, handler)
+}
 
-			for rows.Next() {
-				if err := ctx.Err(); err != nil {
-					rows.Close()
-					return err
-				}
-				var msg model.MessageV4
-				var messageContent []byte
-				if scanErr := rows.Scan(
-					&msg.SortSeq,
-					&msg.ServerID,
-					&msg.LocalType,
-					&msg.UserName,
-					&msg.CreateTime,
-					&messageContent,
-					&msg.PackedInfoData,
-					&msg.Status,
-				); scanErr != nil {
-					rows.Close()
-					return errors.ScanRowFailed(scanErr)
-				}
-				msg.MessageContent = messageContent
-				message := msg.Wrap(talker)
-				if err := handler(message); err != nil {
-					rows.Close()
-					return err
-				}
-			}
-			if err := rows.Err(); err != nil {
-				rows.Close()
-				return errors.QueryFailed("iterate message rows", err)
-			}
-			rows.Close()
+// StoreMaxSeq 返回单个消息库中 talker 的最大 sort_seq，会话表不存在时返回 0
+func (ds *DataSource) StoreMaxSeq(ctx context.Context, store *msgstore.Store, talker string) (int64, error) {
+	db, err := ds.openStoreDB(store)
+	if err != nil {
+		return 0, err
+	}
+	var seq int64
+	query := fmt.Sprintf(`SELECT IFNULL(MAX(sort_seq), 0) FROM %s`, talkerTable(talker))
+	if err := db.QueryRowContext(ctx, query).Scan(&seq); err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return 0, nil
+		}
+		return 0, errors.QueryFailed(query, err)
+	}
+	return seq, nil
+}
+
+func (ds *DataSource) openStoreDB(store *msgstore.Store) (*sql.DB, error) {
+	if store == nil {
+		return nil, errors.InvalidArg("store")
+	}
+	path := filepath.Clean(store.FilePath)
+	for _, info := range ds.messageInfos {
+		if filepath.Clean(info.FilePath) == path {
+			return ds.dbm.OpenDB(info.FilePath)
+		}
+	}
+	return nil, errors.MessageStoreNotFound(store.ID)
+}
+
+func talkerTable(talker string) string {
+	hash := md5.Sum([]byte(talker))
+	return "Msg_" + hex.EncodeToString(hash[:])
+}
+
+// iterateTalker 遍历 db 中 talker 序号大于 afterSeq 的消息，会话表不存在时直接返回
+func (ds *DataSource) iterateTalker(ctx context.Context, db *sql.DB, talker string, afterSeq int64, handler func(*model.Message) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT m.sort_seq, m.server_id, m.local_type, n.user_name,
+		       m.create_time, m.message_content, m.packed_info_data, m.status
+		FROM %s AS m
+		LEFT JOIN Name2Id n ON m.real_sender_id = n.rowid
+		WHERE m.sort_seq > ?
+		ORDER BY m.sort_seq ASC
+	`, talkerTable(talker))
+
+	rows, err := db.QueryContext(ctx, query, afterSeq)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil
 		}
+		return errors.QueryFailed("iterate messages", err)
 	}
+	defer rows.Close()
 
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var msg model.MessageV4
+		var messageContent []byte
+		if scanErr := rows.Scan(
+			&msg.SortSeq,
+			&msg.ServerID,
+			&msg.LocalType,
+			&msg.UserName,
+			&msg.CreateTime,
+			&messageContent,
+			&msg.PackedInfoData,
+			&msg.Status,
+		); scanErr != nil {
+			return errors.ScanRowFailed(scanErr)
+		}
+		msg.MessageContent = messageContent
+		if err := handler(msg.Wrap(talker)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.QueryFailed("iterate message rows", err)
+	}
 	return nil
 }
 
@@ -683,13 +738,16 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 
 	if key != "" {
 		// 按照关键字查询
-		query = `SELECT username, local_type, alias, remark, nick_name 
+		query = `SELECT username, local_type, alias, remark, nick_name,
+				IFNULL(pin_yin_initial, ''), IFNULL(quan_pin, ''), IFNULL(remark_pin_yin_initial, ''), IFNULL(remark_quan_pin, '')
 				FROM contact 
 				WHERE username = ? OR alias = ? OR remark = ? OR nick_name = ?`
 		args = []interface{}{key, key, key, key}
 	} else {
 		// 查询所有联系人
-		query = `SELECT username, local_type, alias, remark, nick_name FROM contact`
+		query = `SELECT username, local_type, alias, remark, nick_name,
+				IFNULL(pin_yin_initial, ''), IFNULL(quan_pin, ''), IFNULL(remark_pin_yin_initial, ''), IFNULL(remark_quan_pin, '')
+				FROM contact`
 	}
 
 	// 添加排序、分页
@@ -721,6 +779,10 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 			&contactV4.Alias,
 			&contactV4.Remark,
 			&contactV4.NickName,
+			&contactV4.PinYinInitial,
+			&contactV4.QuanPin,
+			&contactV4.RemarkPinYinInitial,
+			&contactV4.RemarkQuanPin,
 		)
 
 		if err != nil {
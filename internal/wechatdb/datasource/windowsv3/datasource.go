@@ -275,7 +275,7 @@ func (ds *DataSource) getDBInfosForTimeRange(startTime, endTime time.Time) []Mes
 	return dbs
 }
 
-func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
+func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, msgType string, limit, offset int) ([]*model.Message, error) {
 	if talker == "" {
 		return nil, errors.ErrTalkerEmpty
 	}
@@ -305,6 +305,12 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 		}
 	}
 
+	// 解析消息类别条件（如 file,link 或 -system）
+	types, err := model.ParseMessageTypeFilter(msgType)
+	if err != nil {
+		return nil, errors.InvalidMessageType(err)
+	}
+
 	// 从每个相关数据库中查询消息
 	filteredMessages := []*model.Message{}
 
@@ -383,6 +389,11 @@ func (ds *DataSource) GetMessages(ctx context.Context, startTime, endTime time.T
 				// 将消息转换为标准格式
 				message := msg.Wrap()
 
+				// 应用类别过滤
+				if !types.Match(message.Type, message.SubType) {
+					continue // 不匹配类别，跳过此消息
+				}
+
 				// 应用sender过滤
 				if len(senders) > 0 {
 					senderMatch := false
@@ -672,76 +683,127 @@ func (ds *DataSource) IterateMessages(ctx context.Context, talkers []string, han
 		}
 
 		for _, talker := range uniqueTalkers {
-			if err := ctx.Err(); err != nil {
+			if err := ds.iterateTalker(ctx, db, info, talker, 0, handler); err != nil {
 				return err
 			}
+		}
+	}
 
-			conditions := []string{"StrContent IS NOT NULL"}
-			args := make([]interface{}, 0, 1)
-			if talkerID, ok := info.TalkerMap[talker]; ok {
-				conditions = append(conditions, "TalkerId = ?")
-				args = append(args, talkerID)
-			} else {
-				conditions = append(conditions, "StrTalker = ?")
-				args = append(args, talker)
-			}
+	return nil
+}
 
-			query := fmt.Sprintf(`
-				SELECT MsgSvrID, Sequence, CreateTime, StrTalker, IsSender,
-				       Type, SubType, StrContent, CompressContent, BytesExtra
-				FROM MSG
-				WHERE %s
-				ORDER BY Sequence ASC
-			`, strings.Join(conditions, " AND "))
+// IterateStoreMessages 按 Sequence 升序遍历单个消息库中 talker 序号大于 afterSeq 的消息
+func (ds *DataSource) IterateStoreMessages(ctx context.Context, store *msgstore.Store, talker string, afterSeq int64, handler func(*model.Message) error) error {
+	if handler == nil {
+		return errors.InvalidArg("handler")
+	}
+	info, db, err := ds.openStoreDB(store)
+	if err != nil {
+		return err
+	}
+	return ds.iterateTalker(ctx, db, info, talker, afterSeq, handler)
+}
 
-			rows, err := db.QueryContext(ctx, query, args...)
-			if err != nil {
-				if strings.Contains(err.Error(), "no such table") {
-					continue
-				}
-				return errors.QueryFailed("iterate messages", err)
-			}
+// StoreMaxSeq 返回单个消息库中 talker 的最大 Sequence，没有消息时返回 0
+func (ds *DataSource) StoreMaxSeq(ctx context.Context, store *msgstore.Store, talker string) (int64, error) {
+	info, db, err := ds.openStoreDB(store)
+	if err != nil {
+		return 0, err
+	}
+	conditions, args := talkerConditions(info, talker)
+	query := `SELECT IFNULL(MAX(Sequence), 0) FROM MSG WHERE ` + strings.Join(conditions, " AND ")
+	var seq int64
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&seq); err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return 0, nil
+		}
+		return 0, errors.QueryFailed(query, err)
+	}
+	return seq, nil
+}
 
-			for rows.Next() {
-				if err := ctx.Err(); err != nil {
-					rows.Close()
-					return err
-				}
-				var msg model.MessageV3
-				var compressContent []byte
-				var bytesExtra []byte
-				if scanErr := rows.Scan(
-					&msg.MsgSvrID,
-					&msg.Sequence,
-					&msg.CreateTime,
-					&msg.StrTalker,
-					&msg.IsSender,
-					&msg.Type,
-					&msg.SubType,
-					&msg.StrContent,
-					&compressContent,
-					&bytesExtra,
-				); scanErr != nil {
-					rows.Close()
-					return errors.ScanRowFailed(scanErr)
-				}
-				msg.CompressContent = compressContent
-				msg.BytesExtra = bytesExtra
+func (ds *DataSource) openStoreDB(store *msgstore.Store) (MessageDBInfo, *sql.DB, error) {
+	if store == nil {
+		return MessageDBInfo{}, nil, errors.InvalidArg("store")
+	}
+	path := filepath.Clean(store.FilePath)
+	for _, info := range ds.messageInfos {
+		if filepath.Clean(info.FilePath) == path {
+			db, err := ds.dbm.OpenDB(info.FilePath)
+			return info, db, err
+		}
+	}
+	return MessageDBInfo{}, nil, errors.MessageStoreNotFound(store.ID)
+}
 
-				wrapped := msg.Wrap()
-				if err := handler(wrapped); err != nil {
-					rows.Close()
-					return err
-				}
-			}
-			if err := rows.Err(); err != nil {
-				rows.Close()
-				return errors.QueryFailed("iterate message rows", err)
-			}
-			rows.Close()
+func talkerConditions(info MessageDBInfo, talker string) ([]string, []interface{}) {
+	conditions := []string{"StrContent IS NOT NULL"}
+	if talkerID, ok := info.TalkerMap[talker]; ok {
+		return append(conditions, "TalkerId = ?"), []interface{}{talkerID}
+	}
+	return append(conditions, "StrTalker = ?"), []interface{}{talker}
+}
+
+// iterateTalker 遍历 db 中 talker 序号大于 afterSeq 的消息
+func (ds *DataSource) iterateTalker(ctx context.Context, db *sql.DB, info MessageDBInfo, talker string, afterSeq int64, handler func(*model.Message) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	conditions, args := talkerConditions(info, talker)
+	if afterSeq > 0 {
+		conditions = append(conditions, "Sequence > ?")
+		args = append(args, afterSeq)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT MsgSvrID, Sequence, CreateTime, StrTalker, IsSender,
+		       Type, SubType, StrContent, CompressContent, BytesExtra
+		FROM MSG
+		WHERE %s
+		ORDER BY Sequence ASC
+	`, strings.Join(conditions, " AND "))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil
 		}
+		return errors.QueryFailed("iterate messages", err)
 	}
+	defer rows.Close()
 
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var msg model.MessageV3
+		var compressContent []byte
+		var bytesExtra []byte
+		if scanErr := rows.Scan(
+			&msg.MsgSvrID,
+			&msg.Sequence,
+			&msg.CreateTime,
+			&msg.StrTalker,
+			&msg.IsSender,
+			&msg.Type,
+			&msg.SubType,
+			&msg.StrContent,
+			&compressContent,
+			&bytesExtra,
+		); scanErr != nil {
+			return errors.ScanRowFailed(scanErr)
+		}
+		msg.CompressContent = compressContent
+		msg.BytesExtra = bytesExtra
+
+		if err := handler(msg.Wrap()); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.QueryFailed("iterate message rows", err)
+	}
 	return nil
 }
 
@@ -752,12 +814,16 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 
 	if key != "" {
 		// 按照关键字查询
-		query = `SELECT UserName, Alias, Remark, NickName, Reserved1 FROM Contact 
+		query = `SELECT UserName, Alias, Remark, NickName, Reserved1,
+				IFNULL(PYInitial, ''), IFNULL(QuanPin, ''), IFNULL(RemarkPYInitial, ''), IFNULL(RemarkQuanPin, '')
+				FROM Contact 
                 WHERE UserName = ? OR Alias = ? OR Remark = ? OR NickName = ?`
 		args = []interface{}{key, key, key, key}
 	} else {
 		// 查询所有联系人
-		query = `SELECT UserName, Alias, Remark, NickName, Reserved1 FROM Contact`
+		query = `SELECT UserName, Alias, Remark, NickName, Reserved1,
+				IFNULL(PYInitial, ''), IFNULL(QuanPin, ''), IFNULL(RemarkPYInitial, ''), IFNULL(RemarkQuanPin, '')
+				FROM Contact`
 	}
 
 	// 添加排序、分页
@@ -789,6 +855,10 @@ func (ds *DataSource) GetContacts(ctx context.Context, key string, limit, offset
 			&contactV3.Remark,
 			&contactV3.NickName,
 			&contactV3.Reserved1,
+			&contactV3.PYInitial,
+			&contactV3.QuanPin,
+			&contactV3.RemarkPYInitial,
+			&contactV3.RemarkQuanPin,
 		)
 
 		if err != nil {
@@ -0,0 +1,261 @@
+package indexer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+// go test -tags fts5 -run '^$' -bench . -benchtime 5x ./internal/wechatdb/indexer/
+//
+// BenchmarkIndexBuild 的 ns/op 为写入 benchMessages 条消息的耗时，bytes/msg 为 VACUUM 后的索引文件大小
+// Baseline 后缀的用例按紧凑记录之前的表结构（message_json 列 + 外部内容 FTS 表）写入同样的消息，作为对照
+
+const benchMessages = 20000
+
+var benchPhrases = []string{
+	"明天下午三点开会讨论季度报价单",
+	"晚上一起吃饭吗 我在公司楼下等你",
+	"release v1.2.3 is out, please update the deployment",
+	"这个文件帮忙看一下，周五之前给客户回复",
+	"收到，谢谢！",
+}
+
+func benchMessage(i int) *model.Message {
+	msg := &model.Message{
+		Version:    model.WeChatV4,
+		Seq:        int64(1700000000000 + i),
+		Time:       time.Unix(int64(1700000000+i*60), 0),
+		Talker:     fmt.Sprintf("wxid_talker%02d", i%40),
+		Sender:     fmt.Sprintf("wxid_sender%02d", i%97),
+		SenderName: "发送者",
+		IsChatRoom: i%3 == 0,
+		IsSelf:     i%5 == 0,
+		Type:       model.MessageTypeText,
+		SubType:    0,
+		Content:    fmt.Sprintf("%s #%d", benchPhrases[i%len(benchPhrases)], i),
+	}
+	if i%10 == 0 {
+		msg.Type = model.MessageTypeShare
+		msg.SubType = model.MessageSubTypeLink
+		msg.Content = ""
+		msg.Contents = map[string]interface{}{
+			"title": "季度报价单模板 " + fmt.Sprint(i),
+			"desc":  "点击查看详细报价与付款条件",
+			"url":   fmt.Sprintf("https://example.com/docs/%d", i),
+		}
+	}
+	return msg
+}
+
+func buildBenchIndex(b *testing.B) (*Index, *msgstore.Store, string) {
+	b.Helper()
+	dir := b.TempDir()
+	idx, err := Open(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	store := &msgstore.Store{ID: "message_0"}
+	batch := make([]*model.Message, 0, 512)
+	for i := 0; i < benchMessages; i++ {
+		batch = append(batch, benchMessage(i))
+		if len(batch) == cap(batch) {
+			if err := idx.IndexStoreMessages(store, batch); err != nil {
+				b.Fatal(err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := idx.IndexStoreMessages(store, batch); err != nil {
+		b.Fatal(err)
+	}
+	if err := idx.VacuumStore(store); err != nil {
+		b.Fatal(err)
+	}
+	return idx, store, filepath.Join(dir, store.ID+".fts.db")
+}
+
+func BenchmarkIndexBuild(b *testing.B) {
+	var size int64
+	for i := 0; i < b.N; i++ {
+		idx, _, path := buildBenchIndex(b)
+		info, err := os.Stat(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = info.Size()
+		_ = idx.Close()
+	}
+	b.ReportMetric(float64(size)/benchMessages, "bytes/msg")
+	b.ReportMetric(float64(size)/(1<<20), "MiB")
+}
+
+func BenchmarkSearch(b *testing.B) {
+	idx, _, _ := buildBenchIndex(b)
+	defer idx.Close()
+	req := &model.SearchRequest{Query: "报价单", Limit: 20}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := idx.Search(req, &Filter{}, 0, 20)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(result.Hits) == 0 {
+			b.Fatal("no hits")
+		}
+	}
+}
+
+// baselineSchema 为紧凑记录之前的索引表结构：messages 保存分词文本与 JSON 编码的完整消息，FTS 表通过触发器同步
+func baselineSchema() []string {
+	fieldDefs := make([]string, 0, len(fieldColumns))
+	for _, col := range fieldColumns {
+		fieldDefs = append(fieldDefs, col+" TEXT NOT NULL DEFAULT ''")
+	}
+	ftsColumns := "tokens, " + columnList("")
+	return []string{
+		`CREATE TABLE messages (
+doc_id       TEXT NOT NULL UNIQUE,
+talker       TEXT NOT NULL,
+sender       TEXT NOT NULL,
+unix         INTEGER NOT NULL,
+seq          INTEGER NOT NULL,
+type         INTEGER NOT NULL DEFAULT 0,
+sub_type     INTEGER NOT NULL DEFAULT 0,
+content      TEXT NOT NULL,
+tokens       TEXT NOT NULL,
+message_json TEXT NOT NULL,
+` + strings.Join(fieldDefs, ",\n") + `
+);`,
+		`CREATE INDEX idx_messages_talker ON messages(talker);`,
+		`CREATE INDEX idx_messages_sender ON messages(sender);`,
+		`CREATE INDEX idx_messages_unix ON messages(unix);`,
+		`CREATE INDEX idx_messages_talker_seq ON messages(talker, seq);`,
+		`CREATE INDEX idx_messages_type ON messages(type, sub_type);`,
+		`CREATE VIRTUAL TABLE messages_fts USING fts5(
+` + ftsColumns + `,
+content='messages',
+content_rowid='rowid',
+tokenize='unicode61 remove_diacritics 2'
+);`,
+		`CREATE TRIGGER messages_ai AFTER INSERT ON messages BEGIN
+INSERT INTO messages_fts(rowid, ` + ftsColumns + `) VALUES (new.rowid, new.tokens, ` + columnList("new.") + `);
+END;`,
+	}
+}
+
+func buildBaselineIndex(b *testing.B) (*sql.DB, string) {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "baseline.fts.db")
+	db, err := sql.Open("sqlite3", "file:"+filepath.ToSlash(path)+"?_journal=WAL&_synchronous=NORMAL")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, stmt := range baselineSchema() {
+		if _, err := db.Exec(stmt); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	insertSQL := `INSERT INTO messages (doc_id, talker, sender, unix, seq, type, sub_type, content, tokens, message_json, ` + columnList("") +
+		`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?` + strings.Repeat(", ?", len(fieldColumns)) + `)`
+	for start := 0; start < benchMessages; start += 512 {
+		tx, err := db.Begin()
+		if err != nil {
+			b.Fatal(err)
+		}
+		stmt, err := tx.Prepare(insertSQL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := start; i < start+512 && i < benchMessages; i++ {
+			msg := benchMessage(i)
+			doc, err := newDocument(msg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			data, err := json.Marshal(msg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			args := []interface{}{doc.ID, doc.Talker, doc.Sender, doc.Unix, doc.Seq, doc.Type, doc.SubType, doc.Content, doc.Tokens, string(data)}
+			for _, field := range doc.Fields {
+				args = append(args, field)
+			}
+			if _, err := stmt.Exec(args...); err != nil {
+				b.Fatal(err)
+			}
+		}
+		_ = stmt.Close()
+		if err := tx.Commit(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE); VACUUM;"); err != nil {
+		b.Fatal(err)
+	}
+	return db, path
+}
+
+func BenchmarkIndexBuildBaseline(b *testing.B) {
+	var size int64
+	for i := 0; i < b.N; i++ {
+		db, path := buildBaselineIndex(b)
+		info, err := os.Stat(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = info.Size()
+		_ = db.Close()
+	}
+	b.ReportMetric(float64(size)/benchMessages, "bytes/msg")
+	b.ReportMetric(float64(size)/(1<<20), "MiB")
+}
+
+func BenchmarkSearchBaseline(b *testing.B) {
+	db, _ := buildBaselineIndex(b)
+	defer db.Close()
+	query, err := buildFTSQuery("报价单")
+	if err != nil {
+		b.Fatal(err)
+	}
+	countSQL := `SELECT COUNT(*) FROM messages_fts JOIN messages m ON m.rowid = messages_fts.rowid WHERE messages_fts MATCH ?`
+	dataSQL := `SELECT m.message_json, m.content FROM messages_fts JOIN messages m ON m.rowid = messages_fts.rowid
+WHERE messages_fts MATCH ? ORDER BY bm25(messages_fts) ASC, m.unix DESC, m.seq DESC LIMIT 20`
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var total int
+		if err := db.QueryRow(countSQL, query.match).Scan(&total); err != nil {
+			b.Fatal(err)
+		}
+		rows, err := db.Query(dataSQL, query.match)
+		if err != nil {
+			b.Fatal(err)
+		}
+		hits := 0
+		for rows.Next() {
+			var data, content string
+			if err := rows.Scan(&data, &content); err != nil {
+				b.Fatal(err)
+			}
+			var msg model.Message
+			if err := json.Unmarshal([]byte(data), &msg); err != nil {
+				b.Fatal(err)
+			}
+			_ = buildSnippet(normalizeContent(content), query.terms)
+			hits++
+		}
+		_ = rows.Close()
+		if hits == 0 {
+			b.Fatal("no hits")
+		}
+	}
+}
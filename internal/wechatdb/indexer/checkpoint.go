@@ -0,0 +1,211 @@
+package indexer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+const (
+	// sourceStatKey 记录上次同步时消息库文件的大小与修改时间，用于跳过没有变化的库、发现被截断或替换的库
+	sourceStatKey = "source_stat"
+	// sourceSizeKey 为旧版本记录的文件大小
+	sourceSizeKey = "source_size"
+)
+
+// Checkpoints 返回 store 中各会话已入索引的最大消息序号
+func (i *Index) Checkpoints(store *msgstore.Store) (map[string]int64, error) {
+	checkpoints := make(map[string]int64)
+	err := i.readStore(store, func(db *sql.DB) error {
+		rows, err := db.Query(`SELECT talker, last_seq FROM checkpoints`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var talker string
+			var seq int64
+			if err := rows.Scan(&talker, &seq); err != nil {
+				return err
+			}
+			checkpoints[talker] = seq
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// SourceStat 为消息库文件及其 WAL 文件的大小与修改时间（纳秒），与上次同步时一致说明 store 没有新消息
+type SourceStat struct {
+	Size       int64 `json:"size"`
+	ModTime    int64 `json:"mtime"`
+	WALSize    int64 `json:"wal_size,omitempty"`
+	WALModTime int64 `json:"wal_mtime,omitempty"`
+}
+
+// SourceStat 返回上次同步时记录的消息库文件状态，未记录时返回零值
+func (i *Index) SourceStat(store *msgstore.Store) (SourceStat, error) {
+	var stat SourceStat
+	err := i.readStore(store, func(db *sql.DB) error {
+		var value string
+		err := db.QueryRow(`SELECT value FROM metadata WHERE key = ?`, sourceStatKey).Scan(&value)
+		if errors.Is(err, sql.ErrNoRows) {
+			// 旧版本索引只记录了文件大小
+			err = db.QueryRow(`SELECT value FROM metadata WHERE key = ?`, sourceSizeKey).Scan(&value)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			stat.Size, err = strconv.ParseInt(value, 10, 64)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(value), &stat)
+	})
+	return stat, err
+}
+
+// SetSourceStat 记录本次同步时消息库文件的状态
+func (i *Index) SetSourceStat(store *msgstore.Store, stat SourceStat) error {
+	si, err := i.ensureStoreIndex(store)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(stat)
+	if err != nil {
+		return err
+	}
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	if si.db == nil {
+		return errIndexNotInitialized
+	}
+	_, err = si.db.Exec(`INSERT INTO metadata (key, value) VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value`, sourceStatKey, string(value))
+	return err
+}
+
+// RefreshMessages 更新已入索引的消息，不新增文档也不推进断点，返回更新的条数
+// 用于为旧消息补充转写等内容；新消息只由按断点顺序读取数据源的同步写入
+func (i *Index) RefreshMessages(messages []*model.Message) (int, error) {
+	if i == nil {
+		return 0, errIndexNotInitialized
+	}
+	docs := make([]*document, 0, len(messages))
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		doc, err := newDocument(msg)
+		if err != nil {
+			return 0, err
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	i.mu.RLock()
+	stores := make([]*storeIndex, 0, len(i.stores))
+	for _, si := range i.stores {
+		stores = append(stores, si)
+	}
+	i.mu.RUnlock()
+
+	updated := 0
+	for _, si := range stores {
+		n, err := si.refresh(docs)
+		if err != nil {
+			return updated, err
+		}
+		updated += n
+	}
+	return updated, nil
+}
+
+func (s *storeIndex) refresh(docs []*document) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return 0, errIndexNotInitialized
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`UPDATE messages SET sender = ?, unix = ?, type = ?, sub_type = ?, content = ?, record = ?
+WHERE talker = ? AND seq = ? RETURNING rowid`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	ftsStmt, err := tx.Prepare(ftsUpsertSQL)
+	if err != nil {
+		return 0, err
+	}
+	defer ftsStmt.Close()
+	// 已生成过向量的消息内容变化后需要重新生成
+	embedded, err := embeddedUpTo(tx)
+	if err != nil {
+		return 0, err
+	}
+	staleStmt, err := tx.Prepare(markStaleSQL)
+	if err != nil {
+		return 0, err
+	}
+	defer staleStmt.Close()
+
+	updated := 0
+	for _, doc := range docs {
+		var rowID int64
+		err := stmt.QueryRow(doc.Sender, doc.Unix, doc.Type, doc.SubType, doc.Content, doc.Record, doc.Talker, doc.Seq).Scan(&rowID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("refresh message %s: %w", doc.ID, err)
+		}
+		if _, err := ftsStmt.Exec(doc.ftsArgs(rowID)...); err != nil {
+			return 0, fmt.Errorf("refresh message %s: %w", doc.ID, err)
+		}
+		if rowID <= embedded {
+			if _, err := staleStmt.Exec(rowID); err != nil {
+				return 0, fmt.Errorf("refresh message %s: %w", doc.ID, err)
+			}
+		}
+		updated++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// readStore 在持有 store 读锁的情况下执行 fn，索引文件不存在时会创建空索引
+func (i *Index) readStore(store *msgstore.Store, fn func(db *sql.DB) error) error {
+	si, err := i.ensureStoreIndex(store)
+	if err != nil {
+		return err
+	}
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+	if si.db == nil {
+		return errIndexNotInitialized
+	}
+	return fn(si.db)
+}
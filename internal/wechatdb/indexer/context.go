@@ -0,0 +1,202 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// Neighbors 返回同一会话中 seq 之前 before 条与之后 after 条已索引的消息，结果均按 seq 升序排列。
+// 同一会话的消息可能分布在多个消息库中，因此需要遍历全部 store 后再合并截断。
+func (i *Index) Neighbors(talker string, seq int64, before, after int) ([]*model.Message, []*model.Message, error) {
+	if i == nil {
+		return nil, nil, errIndexNotInitialized
+	}
+	talker = strings.TrimSpace(talker)
+	if talker == "" || (before <= 0 && after <= 0) {
+		return nil, nil, nil
+	}
+
+	i.mu.RLock()
+	stores := make([]*storeIndex, 0, len(i.stores))
+	for _, si := range i.stores {
+		stores = append(stores, si)
+	}
+	i.mu.RUnlock()
+
+	prev := make([]*model.Message, 0)
+	next := make([]*model.Message, 0)
+	for _, si := range stores {
+		if before > 0 {
+			msgs, err := si.neighbors(talker, seq, before, false)
+			if err != nil {
+				return nil, nil, err
+			}
+			prev = append(prev, msgs...)
+		}
+		if after > 0 {
+			msgs, err := si.neighbors(talker, seq, after, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			next = append(next, msgs...)
+		}
+	}
+
+	sort.Slice(prev, func(a, b int) bool { return prev[a].Seq < prev[b].Seq })
+	sort.Slice(next, func(a, b int) bool { return next[a].Seq < next[b].Seq })
+	if len(prev) > before {
+		prev = prev[len(prev)-before:]
+	}
+	if len(next) > after {
+		next = next[:after]
+	}
+
+	return prev, next, nil
+}
+
+func (s *storeIndex) neighbors(talker string, seq int64, limit int, forward bool) ([]*model.Message, error) {
+	if s == nil {
+		return nil, errIndexNotInitialized
+	}
+
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db == nil {
+		return nil, errIndexNotInitialized
+	}
+
+	query := "SELECT " + messageColumns + " FROM messages m WHERE m.talker = ? AND m.seq < ? ORDER BY m.seq DESC LIMIT ?"
+	if forward {
+		query = "SELECT " + messageColumns + " FROM messages m WHERE m.talker = ? AND m.seq > ? ORDER BY m.seq ASC LIMIT ?"
+	}
+
+	rows, err := db.QueryContext(context.Background(), query, talker, seq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query neighbors: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]*model.Message, 0, limit)
+	for rows.Next() {
+		var row messageRow
+		if err := rows.Scan(row.dest()...); err != nil {
+			return nil, fmt.Errorf("scan neighbor: %w", err)
+		}
+		msg, err := row.message()
+		if err != nil {
+			return nil, fmt.Errorf("decode message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate neighbors: %w", err)
+	}
+
+	return messages, nil
+}
+
+// Message 返回会话中序号为 seq 的已索引消息，不存在时返回 nil
+func (i *Index) Message(talker string, seq int64) (*model.Message, error) {
+	if i == nil {
+		return nil, errIndexNotInitialized
+	}
+
+	i.mu.RLock()
+	stores := make([]*storeIndex, 0, len(i.stores))
+	for _, si := range i.stores {
+		stores = append(stores, si)
+	}
+	i.mu.RUnlock()
+
+	for _, si := range stores {
+		msg, err := si.message(talker, seq)
+		if err != nil {
+			return nil, err
+		}
+		if msg != nil {
+			return msg, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *storeIndex) message(talker string, seq int64) (*model.Message, error) {
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db == nil {
+		return nil, errIndexNotInitialized
+	}
+
+	var row messageRow
+	err := db.QueryRowContext(context.Background(), "SELECT "+messageColumns+" FROM messages m WHERE m.talker = ? AND m.seq = ?", talker, seq).Scan(row.dest()...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query message: %w", err)
+	}
+	msg, err := row.message()
+	if err != nil {
+		return nil, fmt.Errorf("decode message: %w", err)
+	}
+	return msg, nil
+}
+
+// MessagesAt 返回会话中发送时间（秒）在 unixes 之中的全部消息，用于批量查找被引用的原消息
+func (i *Index) MessagesAt(ctx context.Context, talker string, unixes []int64) ([]*model.Message, error) {
+	if i == nil {
+		return nil, errIndexNotInitialized
+	}
+	if len(unixes) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, 0, len(unixes)+1)
+	args = append(args, talker)
+	for _, unix := range unixes {
+		args = append(args, unix)
+	}
+	query := "SELECT " + messageColumns + " FROM messages m WHERE m.talker = ? AND m.unix IN (" +
+		strings.TrimSuffix(strings.Repeat("?,", len(unixes)), ",") + ")"
+
+	var messages []*model.Message
+	for _, entry := range i.storeIndexes() {
+		entry.index.mu.RLock()
+		db := entry.index.db
+		entry.index.mu.RUnlock()
+		if db == nil {
+			continue
+		}
+
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("query messages: %w", err)
+		}
+		for rows.Next() {
+			var row messageRow
+			if err := rows.Scan(row.dest()...); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan message: %w", err)
+			}
+			msg, err := row.message()
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("decode message: %w", err)
+			}
+			messages = append(messages, msg)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
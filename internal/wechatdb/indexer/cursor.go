@@ -0,0 +1,111 @@
+package indexer
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor 表示分页游标无法解析
+var ErrInvalidCursor = errors.New("invalid search cursor")
+
+// Cursor 为跨 store 检索的分页位置
+// 有检索词时按相关度排序，bm25 会随增量索引变化，因此 Snapshot/Pos 指向首页保存的排序快照，不按分数定位
+// Key 为检索条件的摘要，Bounds 为首页时各 store 的最大 rowid，快照被淘汰后据此在同一批消息内重新排序
+// 只有过滤条件时按 unix、seq、doc_id 降序排列，Unix/Seq/DocID 为上一页最后一条命中的排序键
+// Total 为首页统计的命中总数，后续翻页不再对每个 store 执行 COUNT(*)
+type Cursor struct {
+	Snapshot string           `json:"id,omitempty"`
+	Pos      int              `json:"p,omitempty"`
+	Key      string           `json:"k,omitempty"`
+	Bounds   map[string]int64 `json:"b,omitempty"`
+	Unix     int64            `json:"u,omitempty"`
+	Seq      int64            `json:"q,omitempty"`
+	DocID    string           `json:"d,omitempty"`
+	Total    int              `json:"n"`
+}
+
+// ParseCursor 解析不透明的游标字符串，空字符串返回 nil
+func ParseCursor(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || (c.Snapshot == "" && c.DocID == "") || c.Pos < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// String 将游标编码为可放入 URL 的字符串
+func (c *Cursor) String() string {
+	if c == nil {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func cursorOf(hit *SearchHit, total int) *Cursor {
+	return &Cursor{Unix: hit.unix, Seq: hit.seq, DocID: hit.docID, Total: total}
+}
+
+// hitLess 与 SQL 中的 ORDER BY score ASC, unix DESC, seq DESC, doc_id DESC 保持一致
+func hitLess(a, b *SearchHit) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	if a.unix != b.unix {
+		return a.unix > b.unix
+	}
+	if a.seq != b.seq {
+		return a.seq > b.seq
+	}
+	return a.docID > b.docID
+}
+
+// mergeHits 对各 store 已排好序的命中做 k 路归并，最多返回 n 条
+func mergeHits(lists [][]*SearchHit, n int) []*SearchHit {
+	h := &hitHeap{}
+	for _, list := range lists {
+		if len(list) > 0 {
+			*h = append(*h, list)
+		}
+	}
+	heap.Init(h)
+
+	merged := make([]*SearchHit, 0, n)
+	for h.Len() > 0 && len(merged) < n {
+		list := (*h)[0]
+		merged = append(merged, list[0])
+		if len(list) == 1 {
+			heap.Pop(h)
+			continue
+		}
+		(*h)[0] = list[1:]
+		heap.Fix(h, 0)
+	}
+	return merged
+}
+
+// hitHeap 以各列表的首个命中作为堆元素的排序依据
+type hitHeap [][]*SearchHit
+
+func (h hitHeap) Len() int           { return len(h) }
+func (h hitHeap) Less(i, j int) bool { return hitLess(h[i][0], h[j][0]) }
+func (h hitHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *hitHeap) Push(x interface{}) { *h = append(*h, x.([]*SearchHit)) }
+
+func (h *hitHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,181 @@
+package indexer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+func TestMergeHits(t *testing.T) {
+	hit := func(score float64, unix, seq int64) *SearchHit {
+		return &SearchHit{Score: score, unix: unix, seq: seq, docID: "t:" + string(rune('a'+seq))}
+	}
+	lists := [][]*SearchHit{
+		{hit(-3, 10, 1), hit(-1, 30, 4)},
+		{hit(-2, 20, 2), hit(-1, 30, 5), hit(0, 5, 6)},
+		{},
+		{hit(-2, 10, 3)},
+	}
+	merged := mergeHits(lists, 5)
+	want := []int64{1, 2, 3, 5, 4}
+	if len(merged) != len(want) {
+		t.Fatalf("merged %d hits, want %d", len(merged), len(want))
+	}
+	for i, seq := range want {
+		if merged[i].seq != seq {
+			t.Errorf("merged[%d].seq = %d, want %d", i, merged[i].seq, seq)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, c := range []*Cursor{
+		{Unix: 1700000000, Seq: 42, DocID: "wxid_a:42", Total: 3000},
+		{Snapshot: "0123456789abcdef", Pos: 40, Key: "3w5e11264sgsg", Bounds: map[string]int64{"message_0": 1200}, Total: 3000},
+	} {
+		parsed, err := ParseCursor(c.String())
+		if err != nil {
+			t.Fatalf("ParseCursor error: %v", err)
+		}
+		if !reflect.DeepEqual(parsed, c) {
+			t.Errorf("parsed = %+v, want %+v", parsed, c)
+		}
+	}
+
+	if c, err := ParseCursor(""); c != nil || err != nil {
+		t.Errorf("empty cursor = %+v, %v", c, err)
+	}
+	if _, err := ParseCursor("not-a-cursor"); err != ErrInvalidCursor {
+		t.Errorf("invalid cursor error = %v", err)
+	}
+}
+
+// 翻页期间不断写入新的命中，bm25 随之变化，游标翻页仍应不重不漏地读完首页时的结果
+func TestSearchCursorStable(t *testing.T) {
+	idx, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	stores := []*msgstore.Store{{ID: "message_0"}, {ID: "message_1"}}
+	seq := int64(0)
+	index := func(n int, content string) {
+		for k := 0; k < n; k++ {
+			seq++
+			msg := &model.Message{
+				Version: model.WeChatV4,
+				Seq:     seq,
+				Time:    time.Unix(1700000000+seq, 0),
+				Talker:  fmt.Sprintf("wxid_%d", seq%3),
+				Sender:  "wxid_sender",
+				Type:    model.MessageTypeText,
+				Content: fmt.Sprintf(content, seq),
+			}
+			if err := idx.IndexStoreMessages(stores[seq%2], []*model.Message{msg}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	index(50, "报价单 %d")
+	index(50, "报价单 报价单 付款 %d")
+
+	req := &model.SearchRequest{Query: "报价单"}
+	seen := make(map[int64]int)
+	for page := 0; ; page++ {
+		result, err := idx.Search(req, &Filter{}, 0, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Total != 100 {
+			t.Fatalf("page %d total = %d, want 100", page, result.Total)
+		}
+		for _, hit := range result.Hits {
+			seen[hit.Message.Seq]++
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		req.Cursor = result.NextCursor
+		index(5, "报价单 报价单 报价单 %d")
+	}
+
+	if len(seen) != 100 {
+		t.Errorf("read %d distinct hits, want 100", len(seen))
+	}
+	for s, n := range seen {
+		if s > 100 || n != 1 {
+			t.Errorf("hit %d read %d times", s, n)
+		}
+	}
+}
+
+// 深度翻页不设上限，快照被淘汰后按游标中的 rowid 上界重建
+func TestSearchDeepPages(t *testing.T) {
+	idx, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	stores := []*msgstore.Store{{ID: "message_0"}, {ID: "message_1"}}
+	batches := make([][]*model.Message, len(stores))
+	for seq := int64(1); seq <= 1200; seq++ {
+		batches[seq%2] = append(batches[seq%2], &model.Message{
+			Version: model.WeChatV4,
+			Seq:     seq,
+			Time:    time.Unix(1700000000+seq, 0),
+			Talker:  fmt.Sprintf("wxid_%d", seq%5),
+			Sender:  "wxid_sender",
+			Type:    model.MessageTypeText,
+			Content: fmt.Sprintf("报价单 %d", seq),
+		})
+	}
+	for k, store := range stores {
+		if err := idx.IndexStoreMessages(store, batches[k]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := &model.SearchRequest{Query: "报价单"}
+	result, err := idx.Search(req, &Filter{}, 1100, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 20 || result.Total != 1200 {
+		t.Fatalf("deep offset = %d hits of %d, want 20 of 1200", len(result.Hits), result.Total)
+	}
+
+	seen := make(map[int64]int)
+	for page := 0; ; page++ {
+		result, err := idx.Search(req, &Filter{}, 0, 150)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, hit := range result.Hits {
+			seen[hit.Message.Seq]++
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		req.Cursor = result.NextCursor
+		if page == 3 {
+			idx.snapshots.mu.Lock()
+			idx.snapshots.items = nil
+			idx.snapshots.mu.Unlock()
+		}
+	}
+
+	if len(seen) != 1200 {
+		t.Errorf("read %d distinct hits, want 1200", len(seen))
+	}
+	for s, n := range seen {
+		if n != 1 {
+			t.Errorf("hit %d read %d times", s, n)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// facetColumns 为各分面的分组表达式，类别仅对分享消息（49）区分子类型
+var facetColumns = []struct {
+	name string
+	expr string
+}{
+	{"talker", "m.talker"},
+	{"sender", "m.sender"},
+	{"type", "m.type || ':' || CASE WHEN m.type = 49 THEN m.sub_type ELSE 0 END"},
+	{"month", "strftime('%Y-%m', m.unix, 'unixepoch', 'localtime')"},
+}
+
+// Facets 统计全部命中在会话、发送者、类别、月份上的分布
+// 各 store 分别分组计数后再合并，会话、发送者、类别最多保留 size 个分组
+func (i *Index) Facets(req *model.SearchRequest, filter *Filter, size int) (*model.SearchFacets, error) {
+	if req == nil {
+		return nil, errors.New("search request is nil")
+	}
+
+	query, err := buildFTSQuery(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		filter = &Filter{}
+	}
+	filter.Talkers = dedupeStrings(filter.Talkers)
+	filter.Senders = dedupeStrings(filter.Senders)
+	if size <= 0 || size > model.MaxFacetBuckets {
+		size = model.MaxFacetBuckets
+	}
+
+	facets := &model.SearchFacets{
+		Talkers: []*model.FacetBucket{},
+		Senders: []*model.FacetBucket{},
+		Types:   []*model.FacetBucket{},
+		Months:  []*model.FacetBucket{},
+	}
+	if query.match == "" && filter.empty() {
+		return facets, nil
+	}
+
+	i.mu.RLock()
+	stores := make([]*storeIndex, 0, len(i.stores))
+	for _, si := range i.stores {
+		stores = append(stores, si)
+	}
+	i.mu.RUnlock()
+
+	merged := make(map[string]map[string]int, len(facetColumns))
+	for _, col := range facetColumns {
+		merged[col.name] = make(map[string]int)
+	}
+	for _, si := range stores {
+		for _, col := range facetColumns {
+			if err := si.facet(query, filter, col.expr, merged[col.name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	facets.Talkers = topBuckets(merged["talker"], size, false)
+	facets.Senders = topBuckets(merged["sender"], size, false)
+	facets.Types = topBuckets(merged["type"], size, false)
+	// 月份按时间顺序完整输出，不做截断
+	facets.Months = topBuckets(merged["month"], len(merged["month"]), true)
+
+	// 类别分组以 Type:SubType 计数，SubType 为 0 时输出为单个 Type 以便直接作为 type 参数
+	for _, b := range facets.Types {
+		kind := parseFacetKind(b.Value)
+		b.Value = kind.String()
+		b.Label = kind.Name()
+	}
+
+	return facets, nil
+}
+
+func (s *storeIndex) facet(query *ftsQuery, filter *Filter, expr string, counts map[string]int) error {
+	if s == nil {
+		return errIndexNotInitialized
+	}
+
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db == nil {
+		return errIndexNotInitialized
+	}
+
+	baseQuery, args := matchClause(query, filter)
+	facetQuery := "SELECT " + expr + " AS value, COUNT(*) " + baseQuery + " GROUP BY value"
+
+	rows, err := db.QueryContext(context.Background(), facetQuery, args...)
+	if err != nil {
+		return fmt.Errorf("query facet: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			return fmt.Errorf("scan facet: %w", err)
+		}
+		if value == "" {
+			continue
+		}
+		counts[value] += count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate facet: %w", err)
+	}
+	return nil
+}
+
+// topBuckets 按数量降序取前 size 个分组；byValue 为 true 时改为按取值降序（用于月份）
+func topBuckets(counts map[string]int, size int, byValue bool) []*model.FacetBucket {
+	buckets := make([]*model.FacetBucket, 0, len(counts))
+	for value, count := range counts {
+		buckets = append(buckets, &model.FacetBucket{Value: value, Count: count})
+	}
+	sort.Slice(buckets, func(a, b int) bool {
+		if !byValue && buckets[a].Count != buckets[b].Count {
+			return buckets[a].Count > buckets[b].Count
+		}
+		if byValue {
+			return buckets[a].Value > buckets[b].Value
+		}
+		return buckets[a].Value < buckets[b].Value
+	})
+	if len(buckets) > size {
+		buckets = buckets[:size]
+	}
+	return buckets
+}
+
+func parseFacetKind(value string) model.MessageKind {
+	var kind model.MessageKind
+	typePart, subPart, _ := strings.Cut(value, ":")
+	kind.Type, _ = strconv.ParseInt(typePart, 10, 64)
+	kind.SubType, _ = strconv.ParseInt(subPart, 10, 64)
+	return kind
+}
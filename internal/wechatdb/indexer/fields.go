@@ -0,0 +1,133 @@
+package indexer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// fieldColumns 为除正文（tokens）外单独建列的结构化字段，顺序与 FTS 表中的列一致
+var fieldColumns = []string{"title", "description", "url", "file", "location", "quote", "forward"}
+
+// fieldAliases 为查询中可用的字段前缀，如 title:报价单、url:github.com
+var fieldAliases = map[string]string{
+	"title":    "title",
+	"desc":     "description",
+	"url":      "url",
+	"host":     "url",
+	"file":     "file",
+	"filename": "file",
+	"location": "location",
+	"loc":      "location",
+	"quote":    "quote",
+	"reply":    "quote",
+	"forward":  "forward",
+	"record":   "forward",
+}
+
+// extractFields 从 Message.Contents 中提取各结构化字段的原始文本，顺序与 fieldColumns 一致
+func extractFields(msg *model.Message) []string {
+	fields := make([]string, len(fieldColumns))
+	set := func(column string, values ...string) {
+		for idx, name := range fieldColumns {
+			if name != column {
+				continue
+			}
+			for _, v := range values {
+				v = strings.TrimSpace(v)
+				if v == "" {
+					continue
+				}
+				if fields[idx] != "" {
+					fields[idx] += " "
+				}
+				fields[idx] += v
+			}
+			return
+		}
+	}
+
+	switch msg.Type {
+	case model.MessageTypeLocation:
+		set("location", contentString(msg, "label"), contentString(msg, "cityname"))
+	case model.MessageTypeShare:
+		title := contentString(msg, "title")
+		set("title", title)
+		set("description", contentString(msg, "desc"))
+		set("url", urlHost(contentString(msg, "url")))
+		switch msg.SubType {
+		case model.MessageSubTypeFile:
+			set("file", title)
+		case model.MessageSubTypeQuote:
+			if refer, ok := msg.Contents["refer"].(*model.Message); ok && refer != nil {
+				set("quote", refer.PlainTextContent())
+			}
+		case model.MessageSubTypeMergeForward, model.MessageSubTypeNote:
+			set("forward", title)
+			if info, ok := msg.Contents["recordInfo"].(*model.RecordInfo); ok && info != nil {
+				set("forward", recordTitles(info, 0)...)
+			}
+		}
+	}
+
+	for idx, v := range fields {
+		fields[idx] = tokenizeContent(normalizeContent(v))
+	}
+	return fields
+}
+
+// recordTitles 收集合并转发中的标题，包括嵌套的合并转发
+func recordTitles(info *model.RecordInfo, depth int) []string {
+	if info == nil || depth > 8 {
+		return nil
+	}
+	titles := []string{info.Title}
+	for _, item := range info.DataList.DataItems {
+		titles = append(titles, item.DataTitle)
+		if item.RecordXML != nil {
+			titles = append(titles, recordTitles(&item.RecordXML.RecordInfo, depth+1)...)
+		}
+	}
+	return titles
+}
+
+func contentString(msg *model.Message, key string) string {
+	if msg.Contents == nil {
+		return ""
+	}
+	v, ok := msg.Contents[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// urlHost 返回链接的主机名，无法解析时返回空
+func urlHost(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// columnList 以逗号连接全部字段列名，prefix 用于触发器中的 new./old.
+func columnList(prefix string) string {
+	cols := make([]string, 0, len(fieldColumns))
+	for _, col := range fieldColumns {
+		cols = append(cols, prefix+col)
+	}
+	return strings.Join(cols, ", ")
+}
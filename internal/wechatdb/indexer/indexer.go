@@ -6,9 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"os"
 	"path/filepath"
-	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -21,7 +22,7 @@ import (
 )
 
 const (
-	runtimeIndexVersion = "3"
+	runtimeIndexVersion = "9"
 )
 
 var (
@@ -38,6 +39,8 @@ type storeIndex struct {
 	mu   sync.RWMutex
 	db   *sql.DB
 	path string
+	// gen 在索引文件创建时生成，文件被删除重建后 rowid 从头计数，gen 随之改变
+	gen string
 }
 
 // Index coordinates a set of per-store SQLite FTS indices.
@@ -47,6 +50,8 @@ type Index struct {
 	metaPath string
 	meta     metadata
 	stores   map[string]*storeIndex
+
+	snapshots snapshotCache
 }
 
 // Open prepares an Index rooted at basePath.
@@ -102,6 +107,12 @@ func (i *Index) Reset() error {
 		_ = os.Remove(si.path)
 		delete(i.stores, id)
 	}
+
+	// 清理尚未打开的旧版本索引文件，避免沿用过期的表结构
+	matches, _ := filepath.Glob(filepath.Join(i.basePath, "*.fts.db*"))
+	for _, path := range matches {
+		_ = os.Remove(path)
+	}
 	return nil
 }
 
@@ -261,22 +272,48 @@ func (i *Index) IndexStoreMessages(store *msgstore.Store, messages []*model.Mess
 	return si.indexMessages(messages)
 }
 
+// SearchResult 为跨 store 检索的一页结果
+// NextCursor 非空时表示还有后续结果，可作为下一次请求的 Cursor
+type SearchResult struct {
+	Hits       []*SearchHit
+	Total      int
+	NextCursor string
+}
+
+// maxSearchWorkers 限制同时检索的 store 数量
+const maxSearchWorkers = 8
+
 // Search performs a federated search across all store indices.
-func (i *Index) Search(req *model.SearchRequest, talkers []string, senders []string, startUnix, endUnix int64, offset, limit int) ([]*SearchHit, int, error) {
+// 有检索词时按相关度排序：各 store 并发取出排序键，按 (score, unix, seq) 做 k 路归并后保存为快照，只加载当前页的消息；
+// 游标翻页直接读取快照，不会因增量索引引起的 bm25 变化而跳过或重复命中，读到快照末尾时按需扩大，不限深度。
+// 只有过滤条件时按时间排序，req.Cursor 非空时按 (unix, seq, doc_id) 定位（keyset），忽略 offset，且不再统计总数。
+func (i *Index) Search(req *model.SearchRequest, filter *Filter, offset, limit int) (*SearchResult, error) {
+	return i.search(req, filter, offset, limit, true)
+}
+
+// search 为 Search 的实现，keep 为 false 时不保存排序快照，供混合检索召回候选使用
+func (i *Index) search(req *model.SearchRequest, filter *Filter, offset, limit int, keep bool) (*SearchResult, error) {
 	if req == nil {
-		return nil, 0, errors.New("search request is nil")
+		return nil, errors.New("search request is nil")
 	}
 
-	match, err := buildFTSQuery(req.Query)
+	query, err := buildFTSQuery(req.Query)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+	after, err := ParseCursor(req.Cursor)
+	if err != nil {
+		return nil, err
 	}
-	if match == "" {
-		return []*SearchHit{}, 0, nil
+	if filter == nil {
+		filter = &Filter{}
+	}
+	if query.match == "" && filter.empty() {
+		return &SearchResult{Hits: []*SearchHit{}}, nil
 	}
 
-	talkers = dedupeStrings(talkers)
-	senders = dedupeStrings(senders)
+	filter.Talkers = dedupeStrings(filter.Talkers)
+	filter.Senders = dedupeStrings(filter.Senders)
 
 	if limit <= 0 {
 		limit = 20
@@ -284,68 +321,307 @@ func (i *Index) Search(req *model.SearchRequest, talkers []string, senders []str
 	if limit > 200 {
 		limit = 200
 	}
-	if offset < 0 {
+	if offset < 0 || after != nil {
 		offset = 0
 	}
 
-	i.mu.RLock()
-	stores := make([]*storeIndex, 0, len(i.stores))
-	for _, si := range i.stores {
-		stores = append(stores, si)
+	if query.match != "" {
+		if after != nil {
+			return i.searchSnapshot(after, query, filter, limit)
+		}
+		return i.searchRanked(query, filter, offset, limit, keep)
+	}
+	if after != nil && after.DocID == "" {
+		return nil, ErrInvalidCursor
 	}
-	i.mu.RUnlock()
 
+	stores := i.storeIndexes()
 	if len(stores) == 0 {
-		return []*SearchHit{}, 0, nil
+		return &SearchResult{Hits: []*SearchHit{}}, nil
 	}
 
-	perStoreLimit := offset + limit
-	if perStoreLimit <= 0 {
-		perStoreLimit = limit
+	// 多取一条用于判断是否还有下一页
+	need := offset + limit + 1
+
+	lists := make([][]*SearchHit, len(stores))
+	counts := make([]int, len(stores))
+	errs := make([]error, len(stores))
+	sem := make(chan struct{}, maxSearchWorkers)
+	var wg sync.WaitGroup
+	for idx, si := range stores {
+		wg.Add(1)
+		go func(idx int, si *storeIndex) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			lists[idx], counts[idx], errs[idx] = si.search(query, filter, after, need)
+		}(idx, si.index)
 	}
+	wg.Wait()
 
-	combined := make([]*SearchHit, 0, len(stores)*limit)
 	total := 0
-	for _, si := range stores {
-		hits, count, err := si.search(match, talkers, senders, startUnix, endUnix, 0, perStoreLimit)
+	for idx := range stores {
+		if errs[idx] != nil {
+			return nil, errs[idx]
+		}
+		total += counts[idx]
+	}
+	if after != nil {
+		total = after.Total
+	}
+
+	merged := mergeHits(lists, need)
+	result := &SearchResult{Hits: []*SearchHit{}, Total: total}
+	if offset >= len(merged) {
+		return result, nil
+	}
+
+	end := offset + limit
+	if end > len(merged) {
+		end = len(merged)
+	}
+	result.Hits = merged[offset:end]
+	if len(merged) > end {
+		result.NextCursor = cursorOf(merged[end-1], total).String()
+	}
+
+	return result, nil
+}
+
+// searchRanked 按相关度检索，每个 store 只排序 offset+limit+1 条，keep 为 true 且还有后续结果时保存排序快照
+func (i *Index) searchRanked(query *ftsQuery, filter *Filter, offset, limit int, keep bool) (*SearchResult, error) {
+	window := offset + limit + 1
+	lists, total, bounds, err := i.rankStores(query, filter, nil, window)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := mergeHits(lists, window)
+	result := &SearchResult{Hits: []*SearchHit{}, Total: total}
+	if offset >= len(ranked) {
+		return result, nil
+	}
+
+	end := offset + limit
+	if end > len(ranked) {
+		end = len(ranked)
+	}
+	hits, err := i.loadHits(ranked[offset:end], query.terms)
+	if err != nil {
+		return nil, err
+	}
+	result.Hits = hits
+	if keep && len(ranked) > end {
+		snap := &snapshot{terms: query.terms, bounds: bounds, window: window, complete: rankedAll(lists, window), total: total}
+		snap.append(ranked)
+		id := i.snapshots.put(snap)
+		result.NextCursor = (&Cursor{Snapshot: id, Pos: end, Key: snapshotKey(query, filter), Bounds: bounds, Total: total}).String()
+	}
+
+	return result, nil
+}
+
+// searchSnapshot 从首页保存的排序快照中读取游标之后的一页
+// 快照已过期或被淘汰时按游标中的 rowid 上界重建，重建后的排序按当前 bm25 计算
+func (i *Index) searchSnapshot(after *Cursor, query *ftsQuery, filter *Filter, limit int) (*SearchResult, error) {
+	if after.Snapshot == "" {
+		return nil, ErrInvalidCursor
+	}
+	if after.Key != snapshotKey(query, filter) {
+		return nil, ErrInvalidCursor
+	}
+	snap := i.snapshots.get(after.Snapshot)
+	if snap == nil {
+		if after.Bounds == nil {
+			return nil, ErrCursorExpired
+		}
+		snap = i.snapshots.restore(after.Snapshot, &snapshot{terms: query.terms, bounds: after.Bounds, total: after.Total})
+	}
+
+	snap.mu.Lock()
+	err := i.extendSnapshot(snap, query, filter, after.Pos+limit+1)
+	page, more := snap.page(after.Pos, limit)
+	snap.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{Hits: []*SearchHit{}, Total: snap.total}
+	if len(page) == 0 {
+		return result, nil
+	}
+	hits, err := i.loadHits(page, snap.terms)
+	if err != nil {
+		return nil, err
+	}
+	result.Hits = hits
+	if more {
+		next := *after
+		next.Pos = after.Pos + len(page)
+		result.NextCursor = next.String()
+	}
+
+	return result, nil
+}
+
+// extendSnapshot 在快照的 rowid 上界内加倍排序窗口，直到快照至少有 need 条或已包含全部命中，调用方需持有 snap.mu
+func (i *Index) extendSnapshot(snap *snapshot, query *ftsQuery, filter *Filter, need int) error {
+	for len(snap.hits) < need && !snap.complete {
+		window := snap.window * 2
+		if window < need {
+			window = need
+		}
+		lists, _, _, err := i.rankStores(query, filter, snap.bounds, window)
 		if err != nil {
-			return nil, 0, err
+			return err
+		}
+		n := 0
+		for _, list := range lists {
+			n += len(list)
 		}
-		total += count
-		combined = append(combined, hits...)
+		snap.append(mergeHits(lists, n))
+		snap.window = window
+		snap.complete = rankedAll(lists, window)
 	}
+	return nil
+}
 
-	if len(combined) == 0 {
-		return []*SearchHit{}, total, nil
+// page 返回从 pos 开始的至多 limit 条排序键，more 表示之后还有命中
+func (s *snapshot) page(pos, limit int) ([]*SearchHit, bool) {
+	if pos >= len(s.hits) {
+		return nil, false
 	}
+	end := pos + limit
+	if end > len(s.hits) {
+		end = len(s.hits)
+	}
+	page := make([]*SearchHit, end-pos)
+	copy(page, s.hits[pos:end])
+	return page, end < len(s.hits)
+}
 
-	sort.Slice(combined, func(a, b int) bool {
-		ha := combined[a]
-		hb := combined[b]
-		if ha == nil || hb == nil {
-			return ha != nil
+// rankStores 并发对各 store 排序，每个 store 最多取 window 条
+// bounds 为 nil 时以各 store 当前的最大 rowid 为上界并返回；否则只检索 bounds 中的 store，且不超过对应上界
+func (i *Index) rankStores(query *ftsQuery, filter *Filter, bounds map[string]int64, window int) ([][]*SearchHit, int, map[string]int64, error) {
+	stores := i.storeIndexes()
+	if bounds != nil {
+		kept := stores[:0]
+		for _, si := range stores {
+			if _, ok := bounds[si.id]; ok {
+				kept = append(kept, si)
+			}
 		}
-		if ha.Score != hb.Score {
-			return ha.Score < hb.Score
+		stores = kept
+	}
+
+	lists := make([][]*SearchHit, len(stores))
+	counts := make([]int, len(stores))
+	maxIDs := make([]int64, len(stores))
+	errs := make([]error, len(stores))
+	sem := make(chan struct{}, maxSearchWorkers)
+	var wg sync.WaitGroup
+	for idx, si := range stores {
+		bound := int64(-1)
+		if bounds != nil {
+			bound = bounds[si.id]
 		}
-		ta := ha.Message.Time.Unix()
-		tb := hb.Message.Time.Unix()
-		if ta != tb {
-			return ta > tb
+		wg.Add(1)
+		go func(idx int, si storeEntry, bound int64) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			lists[idx], counts[idx], maxIDs[idx], errs[idx] = si.index.rank(si.id, query, filter, bound, window)
+		}(idx, si, bound)
+	}
+	wg.Wait()
+
+	total := 0
+	used := make(map[string]int64, len(stores))
+	for idx, si := range stores {
+		if errs[idx] != nil {
+			return nil, 0, nil, errs[idx]
 		}
-		return ha.Message.Seq > hb.Message.Seq
-	})
+		total += counts[idx]
+		used[si.id] = maxIDs[idx]
+	}
+	return lists, total, used, nil
+}
 
-	if offset >= len(combined) {
-		return []*SearchHit{}, total, nil
+// rankedAll 判断各 store 的命中是否都已取完
+func rankedAll(lists [][]*SearchHit, window int) bool {
+	for _, list := range lists {
+		if len(list) >= window {
+			return false
+		}
 	}
+	return true
+}
 
-	end := offset + limit
-	if end > len(combined) {
-		end = len(combined)
+// loadHits 按 (talker, seq) 加载排序键对应的消息，已被删除的消息（如 store 重建后不再存在）直接跳过
+func (i *Index) loadHits(ranked []*SearchHit, terms []string) ([]*SearchHit, error) {
+	byStore := make(map[string][]*SearchHit)
+	for _, hit := range ranked {
+		byStore[hit.store] = append(byStore[hit.store], hit)
+	}
+
+	i.mu.RLock()
+	indexes := make(map[string]*storeIndex, len(byStore))
+	for id := range byStore {
+		indexes[id] = i.stores[id]
 	}
+	i.mu.RUnlock()
 
-	return combined[offset:end], total, nil
+	rows := make(map[string]*messageRow, len(ranked))
+	for id, group := range byStore {
+		si := indexes[id]
+		if si == nil {
+			continue
+		}
+		if err := si.load(group, rows); err != nil {
+			return nil, err
+		}
+	}
+
+	hits := make([]*SearchHit, 0, len(ranked))
+	for _, ref := range ranked {
+		row, ok := rows[ref.docID]
+		if !ok {
+			continue
+		}
+		msg, err := row.message()
+		if err != nil {
+			return nil, fmt.Errorf("decode message %s: %w", ref.docID, err)
+		}
+		hit := *ref
+		hit.Message = msg
+		hit.Snippet = buildSnippet(normalizeContent(row.content), terms)
+		hits = append(hits, &hit)
+	}
+	return hits, nil
+}
+
+// snapshotKey 标识快照对应的检索条件，游标只能用于生成它的检索
+func snapshotKey(query *ftsQuery, filter *Filter) string {
+	clauses, args := filter.where()
+	h := fnv.New64a()
+	fmt.Fprint(h, query.match, clauses, args)
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+type storeEntry struct {
+	id    string
+	index *storeIndex
+}
+
+func (i *Index) storeIndexes() []storeEntry {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	stores := make([]storeEntry, 0, len(i.stores))
+	for id, si := range i.stores {
+		stores = append(stores, storeEntry{id: id, index: si})
+	}
+	return stores
 }
 
 func (i *Index) ensureStoreIndex(store *msgstore.Store) (*storeIndex, error) {
@@ -417,8 +693,13 @@ func newStoreIndex(path string) (*storeIndex, error) {
 		_ = db.Close()
 		return nil, err
 	}
+	gen, err := ensureGeneration(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 
-	return &storeIndex{db: db, path: path}, nil
+	return &storeIndex{db: db, path: path, gen: gen}, nil
 }
 
 func (s *storeIndex) close() error {
@@ -450,43 +731,46 @@ func initSchema(db *sql.DB) error {
 		}
 	}
 
+	// messages 只保存过滤与还原消息所需的列，FTS 表不保存内容（contentless），分词文本由写入方直接插入
+	// FTS 与向量表以 messages.id 关联，显式的 INTEGER PRIMARY KEY 保证 VACUUM 不会重排 rowid
+	ftsColumns := "tokens, " + columnList("")
+
 	statements := []string{
 		`CREATE TABLE IF NOT EXISTS metadata (
 key   TEXT PRIMARY KEY,
 value TEXT NOT NULL
 );`,
 		`CREATE TABLE IF NOT EXISTS messages (
-doc_id       TEXT NOT NULL UNIQUE,
-talker       TEXT NOT NULL,
-sender       TEXT NOT NULL,
-unix         INTEGER NOT NULL,
-seq          INTEGER NOT NULL,
-content      TEXT NOT NULL,
-message_json TEXT NOT NULL
+id       INTEGER PRIMARY KEY,
+talker   TEXT NOT NULL,
+sender   TEXT NOT NULL,
+unix     INTEGER NOT NULL,
+seq      INTEGER NOT NULL,
+type     INTEGER NOT NULL DEFAULT 0,
+sub_type INTEGER NOT NULL DEFAULT 0,
+content  TEXT NOT NULL,
+record   BLOB,
+UNIQUE (talker, seq)
 );`,
-		`CREATE INDEX IF NOT EXISTS idx_messages_talker ON messages(talker);`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages(sender);`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_unix ON messages(unix);`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_type ON messages(type, sub_type);`,
 		`CREATE TABLE IF NOT EXISTS checkpoints (
 talker   TEXT PRIMARY KEY,
 last_seq INTEGER NOT NULL
 );`,
 		`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
-content,
-content='messages',
-content_rowid='rowid',
+` + ftsColumns + `,
+content='',
+contentless_delete=1,
 tokenize='unicode61 remove_diacritics 2'
 );`,
-		`CREATE TRIGGER IF NOT EXISTS messages_ai AFTER INSERT ON messages BEGIN
-INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
-END;`,
-		`CREATE TRIGGER IF NOT EXISTS messages_ad AFTER DELETE ON messages BEGIN
-INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
-END;`,
-		`CREATE TRIGGER IF NOT EXISTS messages_au AFTER UPDATE ON messages BEGIN
-INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
-INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
-END;`,
+		`CREATE TABLE IF NOT EXISTS vectors (
+doc   INTEGER PRIMARY KEY,
+vec   BLOB NOT NULL,
+stale INTEGER NOT NULL DEFAULT 0
+);`,
+		`CREATE INDEX IF NOT EXISTS idx_vectors_stale ON vectors(doc) WHERE stale = 1;`,
 	}
 
 	for _, stmt := range statements {
@@ -541,25 +825,37 @@ func (s *storeIndex) indexMessages(messages []*model.Message) error {
 	}()
 
 	insertStmt, err := tx.Prepare(`
-INSERT INTO messages (doc_id, talker, sender, unix, seq, content, message_json)
-VALUES (?, ?, ?, ?, ?, ?, ?)
-ON CONFLICT(doc_id) DO UPDATE SET
-talker = excluded.talker,
+INSERT INTO messages (talker, sender, unix, seq, type, sub_type, content, record)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(talker, seq) DO UPDATE SET
 sender = excluded.sender,
 unix = excluded.unix,
-seq = excluded.seq,
+type = excluded.type,
+sub_type = excluded.sub_type,
 content = excluded.content,
-message_json = excluded.message_json
+record = excluded.record
+RETURNING rowid
 `)
 	if err != nil {
 		return err
 	}
 	defer insertStmt.Close()
 
+	ftsStmt, err := tx.Prepare(ftsUpsertSQL)
+	if err != nil {
+		return err
+	}
+	defer ftsStmt.Close()
+
 	for _, doc := range docs {
-		if _, err = insertStmt.Exec(doc.ID, doc.Talker, doc.Sender, doc.Unix, doc.Seq, doc.Content, doc.MessageJSON); err != nil {
+		var rowID int64
+		err = insertStmt.QueryRow(doc.Talker, doc.Sender, doc.Unix, doc.Seq, doc.Type, doc.SubType, doc.Content, doc.Record).Scan(&rowID)
+		if err != nil {
 			return fmt.Errorf("insert message %s: %w", doc.ID, err)
 		}
+		if _, err = ftsStmt.Exec(doc.ftsArgs(rowID)...); err != nil {
+			return fmt.Errorf("index message %s: %w", doc.ID, err)
+		}
 	}
 
 	checkpointStmt, err := tx.Prepare(`
@@ -585,7 +881,9 @@ ON CONFLICT(talker) DO UPDATE SET last_seq = CASE WHEN excluded.last_seq > last_
 	return nil
 }
 
-func (s *storeIndex) search(match string, talkers []string, senders []string, startUnix, endUnix int64, offset, limit int) ([]*SearchHit, int, error) {
+// search 按时间倒序返回该 store 中排在 after 之后的前 limit 条命中；after 为空时同时统计命中总数
+// 只用于没有检索词的过滤查询，排序键 (unix, seq, doc_id) 不随索引写入变化
+func (s *storeIndex) search(query *ftsQuery, filter *Filter, after *Cursor, limit int) ([]*SearchHit, int, error) {
 	if s == nil {
 		return nil, 0, errIndexNotInitialized
 	}
@@ -597,61 +895,25 @@ func (s *storeIndex) search(match string, talkers []string, senders []string, st
 		return nil, 0, errIndexNotInitialized
 	}
 
-	whereClauses := []string{}
-	args := []interface{}{match}
+	baseQuery, args := matchClause(query, filter)
+	ctx := context.Background()
 
-	if len(talkers) > 0 {
-		placeholders := strings.Repeat("?,", len(talkers))
-		whereClauses = append(whereClauses, fmt.Sprintf("m.talker IN (%s)", strings.TrimSuffix(placeholders, ",")))
-		for _, t := range talkers {
-			args = append(args, t)
-		}
-	}
-	if len(senders) > 0 {
-		placeholders := strings.Repeat("?,", len(senders))
-		whereClauses = append(whereClauses, fmt.Sprintf("m.sender IN (%s)", strings.TrimSuffix(placeholders, ",")))
-		for _, s := range senders {
-			args = append(args, s)
+	total := 0
+	if after == nil {
+		countQuery := "SELECT COUNT(*) " + baseQuery
+		if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("count search results: %w", err)
 		}
 	}
-	if startUnix > 0 {
-		whereClauses = append(whereClauses, "m.unix >= ?")
-		args = append(args, startUnix)
-	}
-	if endUnix > 0 {
-		whereClauses = append(whereClauses, "m.unix <= ?")
-		args = append(args, endUnix)
-	}
-
-	baseQuery := strings.Builder{}
-	baseQuery.WriteString(`
-FROM messages_fts
-JOIN messages m ON m.rowid = messages_fts.rowid
-WHERE messages_fts MATCH ?
-`)
-	if len(whereClauses) > 0 {
-		baseQuery.WriteString(" AND ")
-		baseQuery.WriteString(strings.Join(whereClauses, " AND "))
-	}
 
-	countQuery := "SELECT COUNT(*) " + baseQuery.String()
-
-	dataQuery := "SELECT m.message_json, " +
-		"COALESCE(snippet(messages_fts, 0, '<mark>', '</mark>', '...', 16), '') AS snippet, " +
-		"COALESCE(bm25(messages_fts), 0.0) AS score " +
-		baseQuery.String() +
-		" ORDER BY score ASC, m.unix DESC, m.seq DESC LIMIT ? OFFSET ?"
-
-	countArgs := append([]interface{}{}, args...)
+	dataQuery := "SELECT doc_id, " + strings.ReplaceAll(messageColumns, "m.", "") + " FROM (SELECT m.talker || ':' || m.seq AS doc_id, " + messageColumns + baseQuery + ")"
 	dataArgs := append([]interface{}{}, args...)
-	dataArgs = append(dataArgs, limit, offset)
-
-	ctx := context.Background()
-
-	var total int
-	if err := db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
-		return nil, 0, fmt.Errorf("count search results: %w", err)
+	if after != nil {
+		dataQuery += " WHERE unix < ? OR (unix = ? AND (seq < ? OR (seq = ? AND doc_id < ?)))"
+		dataArgs = append(dataArgs, after.Unix, after.Unix, after.Seq, after.Seq, after.DocID)
 	}
+	dataQuery += " ORDER BY unix DESC, seq DESC, doc_id DESC LIMIT ?"
+	dataArgs = append(dataArgs, limit)
 
 	rows, err := db.QueryContext(ctx, dataQuery, dataArgs...)
 	if err != nil {
@@ -661,22 +923,25 @@ WHERE messages_fts MATCH ?
 
 	hits := make([]*SearchHit, 0)
 	for rows.Next() {
-		var messageJSON string
-		var snippet sql.NullString
-		var score sql.NullFloat64
-		if err := rows.Scan(&messageJSON, &snippet, &score); err != nil {
+		var (
+			docID string
+			row   messageRow
+		)
+		if err := rows.Scan(append([]interface{}{&docID}, row.dest()...)...); err != nil {
 			return nil, 0, fmt.Errorf("scan search hit: %w", err)
 		}
 
-		var msg model.Message
-		if err := json.Unmarshal([]byte(messageJSON), &msg); err != nil {
-			return nil, 0, fmt.Errorf("decode message: %w", err)
+		msg, err := row.message()
+		if err != nil {
+			return nil, 0, fmt.Errorf("decode message %s: %w", docID, err)
 		}
 
 		hits = append(hits, &SearchHit{
-			Message: &msg,
-			Snippet: snippet.String,
-			Score:   score.Float64,
+			Message: msg,
+			Snippet: buildSnippet(normalizeContent(row.content), query.terms),
+			docID:   docID,
+			unix:    row.unix,
+			seq:     row.seq,
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -686,11 +951,215 @@ WHERE messages_fts MATCH ?
 	return hits, total, nil
 }
 
+// rank 返回该 store 中 rowid 不超过 bound 的命中按相关度排序的前 limit 条排序键（不含消息内容）、命中总数及实际使用的上界
+// bound 小于 0 时以当前最大 rowid 为上界
+func (s *storeIndex) rank(store string, query *ftsQuery, filter *Filter, bound int64, limit int) ([]*SearchHit, int, int64, error) {
+	if s == nil {
+		return nil, 0, 0, errIndexNotInitialized
+	}
+
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db == nil {
+		return nil, 0, 0, errIndexNotInitialized
+	}
+
+	ctx := context.Background()
+	if bound < 0 {
+		if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(rowid), 0) FROM messages").Scan(&bound); err != nil {
+			return nil, 0, 0, fmt.Errorf("query max rowid: %w", err)
+		}
+	}
+	baseQuery, args := matchClause(query, filter)
+	baseQuery += " AND m.rowid <= ?"
+	args = append(args, bound)
+
+	total := 0
+	countQuery := "SELECT COUNT(*) " + baseQuery
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, 0, fmt.Errorf("count search results: %w", err)
+	}
+
+	rankQuery := "SELECT doc_id, talker, unix, seq, score FROM (SELECT m.talker || ':' || m.seq AS doc_id, m.talker, m.unix, m.seq, COALESCE(bm25(messages_fts), 0.0) AS score " +
+		baseQuery + ") ORDER BY score ASC, unix DESC, seq DESC, doc_id DESC LIMIT ?"
+	rows, err := db.QueryContext(ctx, rankQuery, append(args, limit)...)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("execute search query: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]*SearchHit, 0)
+	for rows.Next() {
+		hit := &SearchHit{store: store}
+		if err := rows.Scan(&hit.docID, &hit.talker, &hit.unix, &hit.seq, &hit.Score); err != nil {
+			return nil, 0, 0, fmt.Errorf("scan search hit: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("iterate search hits: %w", err)
+	}
+
+	return hits, total, bound, nil
+}
+
+// load 按 (talker, seq) 读取命中对应的消息行，结果以 doc_id 为键写入 rows
+func (s *storeIndex) load(hits []*SearchHit, rows map[string]*messageRow) error {
+	if s == nil || len(hits) == 0 {
+		return nil
+	}
+
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db == nil {
+		return nil
+	}
+
+	values := strings.TrimSuffix(strings.Repeat("(?, ?),", len(hits)), ",")
+	args := make([]interface{}, 0, len(hits)*2)
+	for _, hit := range hits {
+		args = append(args, hit.talker, hit.seq)
+	}
+
+	query := "SELECT m.talker || ':' || m.seq, " + messageColumns + " FROM messages m WHERE (m.talker, m.seq) IN (VALUES " + values + ")"
+	result, err := db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return fmt.Errorf("load search hits: %w", err)
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var (
+			docID string
+			row   messageRow
+		)
+		if err := result.Scan(append([]interface{}{&docID}, row.dest()...)...); err != nil {
+			return fmt.Errorf("scan search hit: %w", err)
+		}
+		rows[docID] = &row
+	}
+	return result.Err()
+}
+
+// matchClause 生成检索的 FROM/WHERE 部分及参数，messages 表别名为 m
+// 仅有过滤条件、没有检索词时直接扫描 messages 表
+func matchClause(query *ftsQuery, filter *Filter) (string, []interface{}) {
+	b := strings.Builder{}
+	args := []interface{}{}
+	if query.match != "" {
+		b.WriteString(`
+FROM messages_fts
+JOIN messages m ON m.rowid = messages_fts.rowid
+WHERE messages_fts MATCH ?
+`)
+		args = append(args, query.match)
+	} else {
+		b.WriteString(`
+FROM messages m
+WHERE 1 = 1
+`)
+	}
+
+	whereClauses, filterArgs := filter.where()
+	args = append(args, filterArgs...)
+	if len(whereClauses) > 0 {
+		b.WriteString(" AND ")
+		b.WriteString(strings.Join(whereClauses, " AND "))
+	}
+	return b.String(), args
+}
+
+// Filter 描述全文检索的结构化过滤条件，各字段为空时表示不限
+type Filter struct {
+	Talkers   []string
+	Senders   []string
+	StartUnix int64
+	EndUnix   int64
+	Types     *model.MessageTypeFilter
+}
+
+func (f *Filter) empty() bool {
+	return f == nil || (len(f.Talkers) == 0 && len(f.Senders) == 0 && f.StartUnix <= 0 && f.EndUnix <= 0 && f.Types.Empty())
+}
+
+// where 生成针对 messages 表（别名 m）的过滤子句及参数
+func (f *Filter) where() ([]string, []interface{}) {
+	clauses := []string{}
+	args := []interface{}{}
+	if f == nil {
+		return clauses, args
+	}
+
+	if len(f.Talkers) > 0 {
+		placeholders := strings.Repeat("?,", len(f.Talkers))
+		clauses = append(clauses, fmt.Sprintf("m.talker IN (%s)", strings.TrimSuffix(placeholders, ",")))
+		for _, t := range f.Talkers {
+			args = append(args, t)
+		}
+	}
+	if len(f.Senders) > 0 {
+		placeholders := strings.Repeat("?,", len(f.Senders))
+		clauses = append(clauses, fmt.Sprintf("m.sender IN (%s)", strings.TrimSuffix(placeholders, ",")))
+		for _, s := range f.Senders {
+			args = append(args, s)
+		}
+	}
+	if f.StartUnix > 0 {
+		clauses = append(clauses, "m.unix >= ?")
+		args = append(args, f.StartUnix)
+	}
+	if f.EndUnix > 0 {
+		clauses = append(clauses, "m.unix <= ?")
+		args = append(args, f.EndUnix)
+	}
+	if f.Types != nil {
+		if len(f.Types.Include) > 0 {
+			clause, kindArgs := kindClause(f.Types.Include)
+			clauses = append(clauses, clause)
+			args = append(args, kindArgs...)
+		}
+		if len(f.Types.Exclude) > 0 {
+			clause, kindArgs := kindClause(f.Types.Exclude)
+			clauses = append(clauses, "NOT "+clause)
+			args = append(args, kindArgs...)
+		}
+	}
+
+	return clauses, args
+}
+
+// kindClause 生成匹配任一消息类别的 OR 子句
+func kindClause(kinds []model.MessageKind) (string, []interface{}) {
+	clauses := make([]string, 0, len(kinds))
+	args := make([]interface{}, 0, len(kinds)*2)
+	for _, kind := range kinds {
+		if kind.SubType == 0 {
+			clauses = append(clauses, "m.type = ?")
+			args = append(args, kind.Type)
+			continue
+		}
+		clauses = append(clauses, "(m.type = ? AND m.sub_type = ?)")
+		args = append(args, kind.Type, kind.SubType)
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
 // SearchHit represents a single FTS search hit mapped to the domain model.
 type SearchHit struct {
 	Message *model.Message
 	Snippet string
 	Score   float64
+
+	// 排序键，用于跨 store 归并与生成游标
+	docID string
+	unix  int64
+	seq   int64
+
+	// 所属 store 与会话，用于从排序快照加载消息
+	store  string
+	talker string
 }
 
 func loadMetadata(path string) (metadata, error) {
@@ -730,47 +1199,6 @@ func (i *Index) saveMetadataLocked() error {
 	return os.Rename(tmp, i.metaPath)
 }
 
-func buildFTSQuery(input string) (string, error) {
-	s := strings.TrimSpace(input)
-	if s == "" {
-		return "", nil
-	}
-
-	upper := strings.ToUpper(s)
-	advanced := strings.ContainsAny(s, "\"'*()") ||
-		strings.Contains(upper, " AND ") ||
-		strings.Contains(upper, " OR ") ||
-		strings.HasPrefix(upper, "NOT ")
-	if advanced {
-		return s, nil
-	}
-
-	tokens := strings.Fields(s)
-	if len(tokens) == 0 {
-		return "", nil
-	}
-
-	escaped := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		t := strings.TrimSpace(token)
-		if t == "" {
-			continue
-		}
-		t = strings.ReplaceAll(t, "\"", "\"\"")
-		escaped = append(escaped, fmt.Sprintf("\"%s\"", t))
-	}
-
-	if len(escaped) == 0 {
-		return "", nil
-	}
-
-	if len(escaped) == 1 {
-		return escaped[0], nil
-	}
-
-	return strings.Join(escaped, " AND "), nil
-}
-
 func dedupeStrings(values []string) []string {
 	if len(values) == 0 {
 		return nil
@@ -793,13 +1221,17 @@ func dedupeStrings(values []string) []string {
 }
 
 type document struct {
-	ID          string
-	Talker      string
-	Sender      string
-	Unix        int64
-	Seq         int64
-	Content     string
-	MessageJSON string
+	ID      string
+	Talker  string
+	Sender  string
+	Unix    int64
+	Seq     int64
+	Type    int64
+	SubType int64
+	Content string // 未归一化的纯文本，用于生成摘要
+	Tokens  string
+	Record  []byte
+	Fields  []string // 分词后的结构化字段，顺序与 fieldColumns 一致
 }
 
 func newDocument(msg *model.Message) (*document, error) {
@@ -807,23 +1239,34 @@ func newDocument(msg *model.Message) (*document, error) {
 		return nil, errors.New("nil message")
 	}
 
-	content := normalizeContent(msg.PlainTextContent())
-	messageJSON, err := json.Marshal(msg)
-	if err != nil {
-		return nil, fmt.Errorf("marshal message: %w", err)
-	}
+	plain := msg.PlainTextContent()
 
 	return &document{
-		ID:          fmt.Sprintf("%s:%d", msg.Talker, msg.Seq),
-		Talker:      msg.Talker,
-		Sender:      msg.Sender,
-		Unix:        msg.Time.Unix(),
-		Seq:         msg.Seq,
-		Content:     content,
-		MessageJSON: string(messageJSON),
+		ID:      fmt.Sprintf("%s:%d", msg.Talker, msg.Seq),
+		Talker:  msg.Talker,
+		Sender:  msg.Sender,
+		Unix:    msg.Time.Unix(),
+		Seq:     msg.Seq,
+		Type:    msg.Type,
+		SubType: msg.SubType,
+		Content: plain,
+		Tokens:  tokenizeContent(normalizeContent(plain)),
+		Record:  encodeRecord(msg, plain),
+		Fields:  extractFields(msg),
 	}, nil
 }
 
+// ftsUpsertSQL 写入或替换 FTS 行，rowid 与 messages 表一致
+var ftsUpsertSQL = `INSERT OR REPLACE INTO messages_fts (rowid, tokens, ` + columnList("") + `) VALUES (?, ?` + strings.Repeat(", ?", len(fieldColumns)) + `)`
+
+func (d *document) ftsArgs(rowID int64) []interface{} {
+	args := []interface{}{rowID, d.Tokens}
+	for _, field := range d.Fields {
+		args = append(args, field)
+	}
+	return args
+}
+
 type runeClass int
 
 const (
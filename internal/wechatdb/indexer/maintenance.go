@@ -0,0 +1,152 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+// Version 返回磁盘上记录的索引版本
+func (i *Index) Version() string {
+	if i == nil {
+		return ""
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.meta.Version
+}
+
+// StoreStats 统计 store 对应索引的文档数与断点；索引文件不存在时不会创建
+func (i *Index) StoreStats(store *msgstore.Store) (*model.IndexStoreStats, error) {
+	if i == nil || store == nil {
+		return nil, errIndexNotInitialized
+	}
+
+	stats := &model.IndexStoreStats{
+		ID:   store.ID,
+		File: store.FileName,
+		Path: i.resolveStorePath(store),
+	}
+	info, err := os.Stat(stats.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	stats.Exists = true
+	stats.Size = info.Size()
+	// WAL 模式下尚未 checkpoint 的数据位于 -wal 文件中
+	if wal, err := os.Stat(stats.Path + "-wal"); err == nil {
+		stats.Size += wal.Size()
+	}
+
+	si, err := i.ensureStoreIndex(store)
+	if err != nil {
+		return nil, err
+	}
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+	if si.db == nil {
+		return nil, errIndexNotInitialized
+	}
+
+	ctx := context.Background()
+	if err := si.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages`).Scan(&stats.Docs); err != nil {
+		return nil, fmt.Errorf("count documents: %w", err)
+	}
+	if err := si.db.QueryRowContext(ctx, `SELECT COUNT(*), IFNULL(MAX(last_seq), 0) FROM checkpoints`).Scan(&stats.Checkpoints, &stats.LastSeq); err != nil {
+		return nil, fmt.Errorf("read checkpoints: %w", err)
+	}
+	return stats, nil
+}
+
+// VerifyStore 校验 SQLite 文件与 FTS 表的完整性
+func (i *Index) VerifyStore(store *msgstore.Store) error {
+	return i.withStoreDB(store, func(db *sql.DB) error {
+		var result string
+		if err := db.QueryRow(`PRAGMA quick_check`).Scan(&result); err != nil {
+			return err
+		}
+		if result != "ok" {
+			return fmt.Errorf("quick_check: %s", result)
+		}
+		if _, err := db.Exec(`INSERT INTO messages_fts(messages_fts) VALUES('integrity-check')`); err != nil {
+			return err
+		}
+		// FTS 表不保存内容，只能比对行数确认与 messages 表一致
+		var docs, rows int64
+		if err := db.QueryRow(`SELECT (SELECT COUNT(*) FROM messages), (SELECT COUNT(*) FROM messages_fts)`).Scan(&docs, &rows); err != nil {
+			return err
+		}
+		if docs != rows {
+			return fmt.Errorf("fts rows %d do not match messages %d", rows, docs)
+		}
+		return nil
+	})
+}
+
+// OptimizeStore 合并 FTS 表的 b-tree 段，减少检索时需要扫描的段数
+func (i *Index) OptimizeStore(store *msgstore.Store) error {
+	return i.withStoreDB(store, func(db *sql.DB) error {
+		_, err := db.Exec(`INSERT INTO messages_fts(messages_fts) VALUES('optimize')`)
+		return err
+	})
+}
+
+// VacuumStore 回收索引文件中的空闲页
+func (i *Index) VacuumStore(store *msgstore.Store) error {
+	return i.withStoreDB(store, func(db *sql.DB) error {
+		_, err := db.Exec(`VACUUM`)
+		return err
+	})
+}
+
+// ResetStore 删除单个 store 的索引文件并重新创建空表
+func (i *Index) ResetStore(store *msgstore.Store) error {
+	if i == nil || store == nil {
+		return errIndexNotInitialized
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	path := i.resolveStorePath(store)
+	if si, ok := i.stores[store.ID]; ok {
+		_ = si.close()
+		delete(i.stores, store.ID)
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	_, err := i.ensureStoreIndexLocked(store)
+	return err
+}
+
+// withStoreDB 在持有 store 写锁的情况下执行 fn，索引文件不存在时返回错误
+func (i *Index) withStoreDB(store *msgstore.Store, fn func(db *sql.DB) error) error {
+	if i == nil || store == nil {
+		return errIndexNotInitialized
+	}
+	if _, err := os.Stat(i.resolveStorePath(store)); err != nil {
+		return fmt.Errorf("index of store %s not found: %w", store.ID, err)
+	}
+
+	si, err := i.ensureStoreIndex(store)
+	if err != nil {
+		return err
+	}
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	if si.db == nil {
+		return errIndexNotInitialized
+	}
+	return fn(si.db)
+}
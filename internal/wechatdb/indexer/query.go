@@ -0,0 +1,329 @@
+package indexer
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var errUnbalancedQuery = errors.New("unbalanced quotes or parentheses in search query")
+
+type queryTokenKind int
+
+const (
+	queryTokenTerm queryTokenKind = iota
+	queryTokenOperator
+	queryTokenLParen
+	queryTokenRParen
+)
+
+type queryToken struct {
+	kind   queryTokenKind
+	text   string
+	quoted bool
+	prefix bool
+	column string // 非空时仅在该字段列中匹配，见 fieldAliases
+}
+
+// ftsQuery 表示解析后的检索表达式
+// match 为传给 FTS5 MATCH 的表达式，terms 为用于生成摘要高亮的原始关键词
+type ftsQuery struct {
+	match string
+	terms []string
+}
+
+// lexQuery 将用户输入切分为关键词、短语、括号以及 AND/OR/NOT 操作符
+// 以字段前缀开头的关键词或括号（如 title:报价单、file:"季度 报告"、title:(合同 OR 报价)）仅在对应列中匹配
+func lexQuery(input string) ([]queryToken, error) {
+	tokens := make([]queryToken, 0)
+	runes := []rune(input)
+	// column 为紧跟在 "title:" 之后、尚未消费的字段前缀
+	column := ""
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: queryTokenLParen, column: column})
+			column = ""
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: queryTokenRParen})
+			i++
+		case r == '"' || r == '“' || r == '”':
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' && runes[j] != '”' && runes[j] != '“' {
+				j++
+			}
+			if j >= len(runes) {
+				return nil, errUnbalancedQuery
+			}
+			tok := queryToken{kind: queryTokenTerm, text: string(runes[i+1 : j]), quoted: true, column: column}
+			column = ""
+			j++
+			if j < len(runes) && runes[j] == '*' {
+				tok.prefix = true
+				j++
+			}
+			tokens = append(tokens, tok)
+			i = j
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && runes[j] != '(' && runes[j] != ')' && runes[j] != '"' {
+				j++
+			}
+			word := string(runes[i:j])
+			i = j
+			switch word {
+			case "AND", "OR", "NOT":
+				tokens = append(tokens, queryToken{kind: queryTokenOperator, text: word})
+				continue
+			}
+			if key, value, ok := strings.Cut(word, ":"); ok {
+				if col, known := fieldAliases[strings.ToLower(key)]; known {
+					if value == "" {
+						column = col
+						continue
+					}
+					column, word = col, value
+				}
+			}
+			tok := queryToken{kind: queryTokenTerm, text: word, column: column}
+			column = ""
+			if strings.HasSuffix(word, "*") {
+				tok.text = strings.TrimRight(word, "*")
+				tok.prefix = true
+			}
+			tokens = append(tokens, tok)
+		}
+	}
+
+	return tokens, nil
+}
+
+// renderTerm 将单个关键词或短语转换为 FTS5 表达式
+// 关键词内若同时包含 CJK 与非 CJK 片段，未加引号时按 AND 组合，加引号时要求片段相邻
+func renderTerm(tok queryToken) string {
+	segments := splitSegments(tok.text)
+	if tok.column == "url" {
+		// url 列只索引主机名，查询时同样只取主机名，并要求各段按顺序相邻
+		host := urlHost(tok.text)
+		if host == "" {
+			return ""
+		}
+		parts := make([]string, 0)
+		for _, seg := range splitSegments(host) {
+			parts = append(parts, seg.text)
+		}
+		if len(parts) == 0 {
+			return ""
+		}
+		phrase := quoteFTS(strings.Join(parts, " "))
+		if tok.prefix {
+			phrase += "*"
+		}
+		return "url : " + phrase
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+
+	phrases := make([]string, 0, len(segments))
+	for idx, seg := range segments {
+		phrases = append(phrases, phraseForSegment(seg, tok.prefix && idx == len(segments)-1))
+	}
+
+	var expr string
+	switch {
+	case len(phrases) == 1:
+		expr = phrases[0]
+	case tok.quoted:
+		// 相邻片段之间最多隔着一个 CJK 尾字
+		expr = "NEAR(" + strings.Join(phrases, " ") + ", 1)"
+	default:
+		expr = "(" + strings.Join(phrases, " AND ") + ")"
+	}
+	if tok.column != "" {
+		return tok.column + " : " + expr
+	}
+	return expr
+}
+
+// buildFTSQuery 解析用户输入，生成 CJK 友好的 FTS5 MATCH 表达式
+func buildFTSQuery(input string) (*ftsQuery, error) {
+	s := strings.TrimSpace(input)
+	if s == "" {
+		return &ftsQuery{}, nil
+	}
+
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	terms := make([]string, 0)
+	depth := 0
+	// needOperand 为 true 时表示上一个输出是操作符或左括号
+	needOperand := true
+
+	for _, tok := range tokens {
+		switch tok.kind {
+		case queryTokenLParen:
+			if !needOperand {
+				b.WriteString(" AND ")
+			}
+			if tok.column != "" {
+				b.WriteString(tok.column + " : ")
+			}
+			b.WriteString("(")
+			depth++
+			needOperand = true
+		case queryTokenRParen:
+			if depth == 0 || needOperand {
+				return nil, errUnbalancedQuery
+			}
+			b.WriteString(")")
+			depth--
+		case queryTokenOperator:
+			if needOperand {
+				// 孤立的操作符按普通关键词处理
+				tok = queryToken{kind: queryTokenTerm, text: tok.text}
+				expr := renderTerm(tok)
+				b.WriteString(expr)
+				terms = append(terms, tok.text)
+				needOperand = false
+				continue
+			}
+			b.WriteString(" " + tok.text + " ")
+			needOperand = true
+		case queryTokenTerm:
+			expr := renderTerm(tok)
+			if expr == "" {
+				continue
+			}
+			if !needOperand {
+				b.WriteString(" AND ")
+			}
+			b.WriteString(expr)
+			terms = append(terms, strings.TrimSpace(tok.text))
+			needOperand = false
+		}
+	}
+
+	if depth != 0 {
+		return nil, errUnbalancedQuery
+	}
+
+	match := strings.TrimSpace(b.String())
+	// 去掉结尾悬空的操作符
+	for _, op := range []string{"AND", "OR", "NOT"} {
+		match = strings.TrimSpace(strings.TrimSuffix(match, " "+op))
+	}
+	if match == "AND" || match == "OR" || match == "NOT" {
+		match = ""
+	}
+
+	return &ftsQuery{match: match, terms: terms}, nil
+}
+
+const snippetRadius = 24
+
+// buildSnippet 在原文中定位关键词并生成带 <mark> 高亮的摘要
+func buildSnippet(content string, terms []string) string {
+	if content == "" {
+		return ""
+	}
+
+	lower := foldCase(content)
+	needles := make([]string, 0, len(terms))
+	for _, term := range terms {
+		for _, seg := range splitSegments(term) {
+			needles = append(needles, seg.text)
+		}
+	}
+
+	first := -1
+	for _, needle := range needles {
+		if idx := strings.Index(lower, needle); idx >= 0 && (first < 0 || idx < first) {
+			first = idx
+		}
+	}
+
+	runes := []rune(content)
+	start, end := 0, len(runes)
+	if first >= 0 {
+		center := utf8.RuneCountInString(content[:first])
+		if center > snippetRadius {
+			start = center - snippetRadius
+		}
+		if center+snippetRadius*2 < end {
+			end = center + snippetRadius*2
+		}
+	} else if end > snippetRadius*3 {
+		end = snippetRadius * 3
+	}
+
+	window := string(runes[start:end])
+	windowLower := foldCase(window)
+
+	marks := make([]bool, len(window))
+	for _, needle := range needles {
+		if needle == "" {
+			continue
+		}
+		for offset := 0; offset < len(windowLower); {
+			idx := strings.Index(windowLower[offset:], needle)
+			if idx < 0 {
+				break
+			}
+			for k := offset + idx; k < offset+idx+len(needle) && k < len(marks); k++ {
+				marks[k] = true
+			}
+			offset += idx + len(needle)
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	inMark := false
+	for i := 0; i < len(window); {
+		_, size := utf8.DecodeRuneInString(window[i:])
+		if marks[i] && !inMark {
+			b.WriteString("<mark>")
+			inMark = true
+		} else if !marks[i] && inMark {
+			b.WriteString("</mark>")
+			inMark = false
+		}
+		b.WriteString(window[i : i+size])
+		i += size
+	}
+	if inMark {
+		b.WriteString("</mark>")
+	}
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+
+	return b.String()
+}
+
+// foldCase 转为小写，同时保证字节长度不变，便于在原文与小写文本之间共用下标
+func foldCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		lr := unicode.ToLower(r)
+		if utf8.RuneLen(lr) != utf8.RuneLen(r) {
+			lr = r
+		}
+		b.WriteRune(lr)
+	}
+	return b.String()
+}
@@ -0,0 +1,275 @@
+package indexer
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// 索引中的消息只在列中保存检索与过滤需要的字段，其余字段以 protobuf 线格式紧凑编码在 record 列：
+//
+//	message Record {
+//	  string version = 1;
+//	  bool is_chat_room = 2;
+//	  bool is_self = 3;
+//	  optional string content = 4; // 原始 Content，与 content 列相同时省略
+//	  repeated Entry contents = 5;
+//	  bool partial = 6;            // Contents 中有无法编码的结构化内容（合并转发、引用等）
+//	  int64 server_id = 7;
+//	}
+//	message Entry {
+//	  string key = 1;
+//	  oneof value { string str = 2; double num = 3; sint64 int = 4; bool flag = 5; }
+//	}
+//
+// 显示名等可由联系人补全的字段不入索引；partial 的消息检索后按 talker/seq 从数据源补全
+const (
+	recordVersion    protowire.Number = 1
+	recordIsChatRoom protowire.Number = 2
+	recordIsSelf     protowire.Number = 3
+	recordContent    protowire.Number = 4
+	recordContents   protowire.Number = 5
+	recordPartial    protowire.Number = 6
+	recordServerID   protowire.Number = 7
+
+	entryKey  protowire.Number = 1
+	entryStr  protowire.Number = 2
+	entryNum  protowire.Number = 3
+	entryInt  protowire.Number = 4
+	entryFlag protowire.Number = 5
+)
+
+// PartialKey 为需要从数据源补全的消息在 Contents 中的标记，补全失败时保留，表示消息只有索引中的部分内容
+const PartialKey = "partial"
+
+var errBadRecord = errors.New("malformed message record")
+
+// messageColumns 为还原消息需要读取的列，m 为 messages 表别名，顺序与 messageRow.dest 一致
+const messageColumns = "m.talker, m.sender, m.unix, m.seq, m.type, m.sub_type, m.content, m.record"
+
+type messageRow struct {
+	talker, sender          string
+	unix, seq, typ, subType int64
+	content                 string
+	record                  []byte
+}
+
+func (r *messageRow) dest() []interface{} {
+	return []interface{}{&r.talker, &r.sender, &r.unix, &r.seq, &r.typ, &r.subType, &r.content, &r.record}
+}
+
+func (r *messageRow) message() (*model.Message, error) {
+	return decodeRecord(r.talker, r.sender, r.unix, r.seq, r.typ, r.subType, r.content, r.record)
+}
+
+// NeedsHydration 判断从索引解码的消息是否缺少结构化内容，需要按 talker/seq 从数据源补全
+func NeedsHydration(msg *model.Message) bool {
+	if msg == nil {
+		return false
+	}
+	partial, _ := msg.Contents[PartialKey].(bool)
+	return partial
+}
+
+// encodeRecord 编码 content 列之外的消息字段，plain 为 content 列的值
+func encodeRecord(msg *model.Message, plain string) []byte {
+	var b []byte
+	if msg.Version != "" {
+		b = protowire.AppendTag(b, recordVersion, protowire.BytesType)
+		b = protowire.AppendString(b, msg.Version)
+	}
+	if msg.IsChatRoom {
+		b = protowire.AppendTag(b, recordIsChatRoom, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	if msg.IsSelf {
+		b = protowire.AppendTag(b, recordIsSelf, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	if msg.ServerID != 0 {
+		b = protowire.AppendTag(b, recordServerID, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(msg.ServerID))
+	}
+	if msg.Content != plain {
+		b = protowire.AppendTag(b, recordContent, protowire.BytesType)
+		b = protowire.AppendString(b, msg.Content)
+	}
+
+	keys := make([]string, 0, len(msg.Contents))
+	for key := range msg.Contents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	partial := false
+	for _, key := range keys {
+		if key == PartialKey {
+			partial = true
+			continue
+		}
+		entry, ok := encodeEntry(key, msg.Contents[key])
+		if !ok {
+			partial = true
+			continue
+		}
+		b = protowire.AppendTag(b, recordContents, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	if partial {
+		b = protowire.AppendTag(b, recordPartial, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	return b
+}
+
+func encodeEntry(key string, value interface{}) ([]byte, bool) {
+	b := protowire.AppendTag(nil, entryKey, protowire.BytesType)
+	b = protowire.AppendString(b, key)
+	switch v := value.(type) {
+	case string:
+		b = protowire.AppendTag(b, entryStr, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	case float64:
+		b = protowire.AppendTag(b, entryNum, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	case int:
+		b = appendInt(b, int64(v))
+	case int32:
+		b = appendInt(b, int64(v))
+	case int64:
+		b = appendInt(b, v)
+	case uint32:
+		b = appendInt(b, int64(v))
+	case bool:
+		b = protowire.AppendTag(b, entryFlag, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	default:
+		return nil, false
+	}
+	return b, true
+}
+
+func appendInt(b []byte, v int64) []byte {
+	b = protowire.AppendTag(b, entryInt, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeZigZag(v))
+}
+
+// decodeRecord 由索引列与 record 还原消息
+func decodeRecord(talker, sender string, unix, seq, msgType, subType int64, plain string, record []byte) (*model.Message, error) {
+	msg := &model.Message{
+		Seq:     seq,
+		Time:    time.Unix(unix, 0),
+		Talker:  talker,
+		Sender:  sender,
+		Type:    msgType,
+		SubType: subType,
+		Content: plain,
+	}
+
+	partial := false
+	err := eachField(record, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == recordVersion && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			msg.Version = v
+			return n, nil
+		case num == recordContent && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			msg.Content = v
+			return n, nil
+		case num == recordContents && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			key, value, err := decodeEntry(v)
+			if err != nil {
+				return 0, err
+			}
+			if msg.Contents == nil {
+				msg.Contents = make(map[string]interface{})
+			}
+			msg.Contents[key] = value
+			return n, nil
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			switch num {
+			case recordIsChatRoom:
+				msg.IsChatRoom = v != 0
+			case recordIsSelf:
+				msg.IsSelf = v != 0
+			case recordPartial:
+				partial = v != 0
+			case recordServerID:
+				msg.ServerID = int64(v)
+			}
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if partial {
+		if msg.Contents == nil {
+			msg.Contents = make(map[string]interface{})
+		}
+		msg.Contents[PartialKey] = true
+	}
+	return msg, nil
+}
+
+func decodeEntry(b []byte) (string, interface{}, error) {
+	var key string
+	var value interface{}
+	err := eachField(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == entryKey && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			key = v
+			return n, nil
+		case num == entryStr && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			value = v
+			return n, nil
+		case num == entryNum && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			value = math.Float64frombits(v)
+			return n, nil
+		case num == entryInt && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			value = protowire.DecodeZigZag(v)
+			return n, nil
+		case num == entryFlag && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			value = protowire.DecodeBool(v)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return key, value, err
+}
+
+// eachField 依次读取线格式中的字段，fn 返回字段值占用的字节数
+func eachField(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errBadRecord
+		}
+		b = b[n:]
+		m, err := fn(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if m < 0 {
+			return errBadRecord
+		}
+		b = b[m:]
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package indexer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	snapshotTTL  = 10 * time.Minute
+	maxSnapshots = 64
+)
+
+// ErrCursorExpired 表示游标指向的排序快照已过期，需要不带游标重新检索
+var ErrCursorExpired = errors.New("search cursor expired")
+
+// snapshot 保存一次相关度检索已排好的前若干条，翻页时按位置读取，不再重新计算 bm25
+// bounds 为首页时各 store 的最大 rowid，之后写入的消息不进入本次检索；读到末尾时在 bounds 内扩大窗口重新排序，
+// 只追加尚未出现的命中，已返回的位置保持不变
+// hits 只包含排序键与定位信息，Message 在读取对应页时按 (talker, seq) 加载
+type snapshot struct {
+	mu       sync.Mutex
+	terms    []string
+	bounds   map[string]int64
+	hits     []*SearchHit
+	seen     map[string]struct{}
+	window   int
+	complete bool
+	total    int
+	expires  time.Time
+}
+
+// append 追加尚未出现过的命中
+func (s *snapshot) append(hits []*SearchHit) {
+	if s.seen == nil {
+		s.seen = make(map[string]struct{}, len(hits))
+	}
+	for _, hit := range hits {
+		if _, ok := s.seen[hit.docID]; ok {
+			continue
+		}
+		s.seen[hit.docID] = struct{}{}
+		s.hits = append(s.hits, hit)
+	}
+}
+
+type snapshotCache struct {
+	mu    sync.Mutex
+	items map[string]*snapshot
+}
+
+// put 保存快照并返回其 ID
+func (c *snapshotCache) put(s *snapshot) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	id := hex.EncodeToString(b)
+	c.restore(id, s)
+	return id
+}
+
+// restore 以指定 ID 保存快照，已存在未过期的同 ID 快照时返回已有的快照，超出数量上限时淘汰最早过期的快照
+func (c *snapshotCache) restore(id string, s *snapshot) *snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.items == nil {
+		c.items = make(map[string]*snapshot)
+	}
+	now := time.Now()
+	if old, ok := c.items[id]; ok && !now.After(old.expires) {
+		return old
+	}
+	for k, v := range c.items {
+		if now.After(v.expires) {
+			delete(c.items, k)
+		}
+	}
+	for len(c.items) >= maxSnapshots {
+		oldest := ""
+		for k, v := range c.items {
+			if oldest == "" || v.expires.Before(c.items[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(c.items, oldest)
+	}
+
+	s.expires = now.Add(snapshotTTL)
+	c.items[id] = s
+	return s
+}
+
+func (c *snapshotCache) get(id string) *snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.items[id]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(s.expires) {
+		delete(c.items, id)
+		return nil
+	}
+	return s
+}
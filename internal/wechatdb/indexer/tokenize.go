@@ -0,0 +1,129 @@
+package indexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// isCJK 判断字符是否属于不使用空格分词的文字（中日韩）
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+type segment struct {
+	text string
+	cjk  bool
+}
+
+// splitSegments 将文本切分为连续的 CJK 片段与非 CJK 片段，空白与标点作为分隔符丢弃
+func splitSegments(input string) []segment {
+	segments := make([]segment, 0)
+
+	var b strings.Builder
+	cjk := false
+	flush := func() {
+		if b.Len() > 0 {
+			segments = append(segments, segment{text: b.String(), cjk: cjk})
+			b.Reset()
+		}
+	}
+
+	for _, r := range input {
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return segments
+}
+
+// cjkBigrams 将一段连续的 CJK 文本展开为重叠的二元组。
+// 单字片段原样返回；多字片段在末尾额外保留最后一个字，便于单字前缀查询命中。
+func cjkBigrams(run string, withTail bool) []string {
+	runes := []rune(run)
+	if len(runes) <= 1 {
+		return []string{run}
+	}
+
+	grams := make([]string, 0, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	if withTail {
+		grams = append(grams, string(runes[len(runes)-1]))
+	}
+	return grams
+}
+
+// tokenizeContent 生成写入 FTS 的分词文本：
+// 非 CJK 片段交给 unicode61 处理，CJK 片段展开为二元组，使子串查询可以命中。
+func tokenizeContent(input string) string {
+	if input == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.Grow(len(input) * 2)
+
+	write := func(token string) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(token)
+	}
+
+	for _, seg := range splitSegments(input) {
+		if !seg.cjk {
+			write(seg.text)
+			continue
+		}
+		for _, gram := range cjkBigrams(seg.text, true) {
+			write(gram)
+		}
+	}
+
+	return b.String()
+}
+
+// phraseForSegment 将单个片段转换为 FTS5 短语表达式
+func phraseForSegment(seg segment, prefix bool) string {
+	if seg.cjk {
+		if utf8.RuneCountInString(seg.text) == 1 {
+			// 单字既可能是二元组的首字，也可能是片段末尾保留的单字
+			return quoteFTS(seg.text) + "*"
+		}
+		phrase := quoteFTS(strings.Join(cjkBigrams(seg.text, false), " "))
+		if prefix {
+			phrase += "*"
+		}
+		return phrase
+	}
+
+	phrase := quoteFTS(seg.text)
+	if prefix {
+		phrase += "*"
+	}
+	return phrase
+}
+
+func quoteFTS(s string) string {
+	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+}
@@ -0,0 +1,60 @@
+package indexer
+
+import "testing"
+
+func TestTokenizeContent(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"hello world", "hello world"},
+		{"火锅", "火锅 锅"},
+		{"吃火锅吧", "吃火 火锅 锅吧 吧"},
+		{"开会 at 3pm，别迟到", "开会 会 at 3pm 别迟 迟到 到"},
+	}
+
+	for _, tt := range tests {
+		if got := tokenizeContent(tt.input); got != tt.want {
+			t.Errorf("tokenizeContent(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestBuildFTSQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"火锅", `"火锅"`},
+		{"吃", `"吃"*`},
+		{"吃火锅 hello", `"吃火 火锅" AND "hello"`},
+		{"火锅 OR 会议", `"火锅" OR "会议"`},
+		{`"火锅吧hello"`, `NEAR("火锅 锅吧" "hello", 1)`},
+		{"hel*", `"hel"*`},
+		{"火锅 AND", `"火锅"`},
+		{"title:报价单", `title : "报价 价单"`},
+		{`file:"季度报告"`, `file : "季度 度报 报告"`},
+		{"url:https://www.github.com/x", `url : "www github com"`},
+		{"title:(合同 OR 报价) 张三", `title : ("合同" OR "报价") AND "张三"`},
+		{"foo:bar", `("foo" AND "bar")`},
+	}
+
+	for _, tt := range tests {
+		got, err := buildFTSQuery(tt.input)
+		if err != nil {
+			t.Errorf("buildFTSQuery(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got.match != tt.want {
+			t.Errorf("buildFTSQuery(%q) = %q, want %q", tt.input, got.match, tt.want)
+		}
+	}
+
+	for _, input := range []string{"(火锅", "火锅)", `"火锅`} {
+		if _, err := buildFTSQuery(input); err == nil {
+			t.Errorf("buildFTSQuery(%q) expected error", input)
+		}
+	}
+}
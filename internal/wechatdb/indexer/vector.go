@@ -0,0 +1,552 @@
+package indexer
+
+import (
+	"container/heap"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+// 语义检索的向量保存在各 store 索引的 vectors 表，doc 为 messages.id
+// 向量先做 L2 归一化再量化为 int8（每维 1 字节），两向量的点积除以 127² 即余弦相似度
+// 检索时逐条计算相似度，不建近似索引；stale 标记内容已变化、需要重新生成的向量
+const (
+	embeddingModelKey = "embedding_model"
+	// embeddingDocKey 记录已处理到的最大 messages.id，之后写入的消息尚未生成向量
+	embeddingDocKey = "embedding_doc"
+
+	// maxEmbedRunes 为单条消息参与生成向量的最大字符数
+	maxEmbedRunes = 512
+	// rrfK 为倒数排名融合的平滑常数
+	rrfK = 60
+	// maxSemanticCandidates 为语义检索每路最多召回的候选数
+	maxSemanticCandidates = 1000
+)
+
+// ErrResultWindow 表示语义检索的 offset+limit 超出 maxSemanticCandidates
+var ErrResultWindow = fmt.Errorf("offset + limit must not exceed %d in semantic search", maxSemanticCandidates)
+
+const markStaleSQL = `INSERT INTO vectors (doc, vec, stale) VALUES (?, X'', 1) ON CONFLICT(doc) DO UPDATE SET stale = 1`
+
+// EmbedFunc 为一批文本生成向量，返回值与 texts 一一对应
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+type pendingEmbed struct {
+	doc     int64
+	text    string
+	forward bool // 位于断点之后的新消息，否则为 stale 的旧向量
+}
+
+// EmbedStore 为 store 中尚未生成向量或内容已变化的消息生成向量，返回写入的向量数
+// model 变化时清空已有向量重新生成；embed 在不持有索引锁的情况下调用
+func (i *Index) EmbedStore(ctx context.Context, store *msgstore.Store, model string, batch int, embed EmbedFunc) (int, error) {
+	si, err := i.ensureStoreIndex(store)
+	if err != nil {
+		return 0, err
+	}
+	if batch <= 0 {
+		batch = 64
+	}
+	if err := si.prepareVectors(model); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		pending, err := si.pendingEmbeds(batch)
+		if err != nil {
+			return written, err
+		}
+		if len(pending) == 0 {
+			return written, nil
+		}
+
+		texts := make([]string, 0, len(pending))
+		for _, p := range pending {
+			if p.text != "" {
+				texts = append(texts, p.text)
+			}
+		}
+		var vectors [][]float32
+		if len(texts) > 0 {
+			vectors, err = embed(ctx, texts)
+			if err != nil {
+				return written, err
+			}
+			if len(vectors) != len(texts) {
+				return written, fmt.Errorf("embedding count mismatch: got %d, want %d", len(vectors), len(texts))
+			}
+		}
+		if err := si.saveVectors(pending, vectors); err != nil {
+			return written, err
+		}
+		written += len(vectors)
+	}
+}
+
+// VectorCount 返回 store 中已生成的向量数
+func (i *Index) VectorCount(store *msgstore.Store) (int64, error) {
+	var count int64
+	err := i.readStore(store, func(db *sql.DB) error {
+		return db.QueryRow(`SELECT COUNT(*) FROM vectors WHERE length(vec) > 0`).Scan(&count)
+	})
+	return count, err
+}
+
+func (s *storeIndex) prepareVectors(model string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return errIndexNotInitialized
+	}
+
+	var current string
+	err := s.db.QueryRow(`SELECT value FROM metadata WHERE key = ?`, embeddingModelKey).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if current == model {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM vectors`); err != nil {
+		return err
+	}
+	if err := setMetadata(tx, embeddingModelKey, model); err != nil {
+		return err
+	}
+	if err := setMetadata(tx, embeddingDocKey, "0"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pendingEmbeds 先取 stale 的旧向量，再取断点之后的新消息，合计不超过 limit 条
+func (s *storeIndex) pendingEmbeds(limit int) ([]pendingEmbed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return nil, errIndexNotInitialized
+	}
+
+	pending := make([]pendingEmbed, 0, limit)
+	scan := func(rows *sql.Rows, forward bool) error {
+		defer rows.Close()
+		for rows.Next() {
+			var p pendingEmbed
+			var content string
+			if err := rows.Scan(&p.doc, &content); err != nil {
+				return err
+			}
+			p.text = embedText(content)
+			p.forward = forward
+			pending = append(pending, p)
+		}
+		return rows.Err()
+	}
+
+	rows, err := s.db.Query(`SELECT v.doc, IFNULL(m.content, '') FROM vectors v
+LEFT JOIN messages m ON m.id = v.doc
+WHERE v.stale = 1 LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := scan(rows, false); err != nil {
+		return nil, err
+	}
+	if len(pending) >= limit {
+		return pending, nil
+	}
+
+	embedded, err := embeddedUpTo(s.db)
+	if err != nil {
+		return nil, err
+	}
+	rows, err = s.db.Query(`SELECT id, content FROM messages WHERE id > ? ORDER BY id LIMIT ?`, embedded, limit-len(pending))
+	if err != nil {
+		return nil, err
+	}
+	if err := scan(rows, true); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// saveVectors 写入生成的向量并推进断点，vectors 与 pending 中 text 非空的项依次对应
+func (s *storeIndex) saveVectors(pending []pendingEmbed, vectors [][]float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return errIndexNotInitialized
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	upsert, err := tx.Prepare(`INSERT OR REPLACE INTO vectors (doc, vec, stale) VALUES (?, ?, 0)`)
+	if err != nil {
+		return err
+	}
+	defer upsert.Close()
+
+	next := 0
+	var lastDoc int64
+	for _, p := range pending {
+		if p.forward && p.doc > lastDoc {
+			lastDoc = p.doc
+		}
+		if p.text == "" {
+			if !p.forward {
+				if _, err := tx.Exec(`DELETE FROM vectors WHERE doc = ?`, p.doc); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if _, err := upsert.Exec(p.doc, quantize(vectors[next])); err != nil {
+			return fmt.Errorf("save vector of doc %d: %w", p.doc, err)
+		}
+		next++
+	}
+	if lastDoc > 0 {
+		if err := setMetadata(tx, embeddingDocKey, strconv.FormatInt(lastDoc, 10)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// embeddedUpTo 返回已处理到的最大 messages.id，尚未生成过向量时返回 0
+func embeddedUpTo(db queryRower) (int64, error) {
+	var value string
+	err := db.QueryRow(`SELECT value FROM metadata WHERE key = ?`, embeddingDocKey).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func setMetadata(tx *sql.Tx, key, value string) error {
+	_, err := tx.Exec(`INSERT INTO metadata (key, value) VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+var (
+	// markdownLink 匹配 PlainTextContent 生成的 [文本](链接) 与 ![文本](链接)
+	markdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	// placeholderOnly 匹配只有类别占位的消息，如 [图片]、[语音(3s)](链接)、[名片]
+	placeholderOnly = regexp.MustCompile(`^!?\[[^\]|]*\](\([^)]*\))?$`)
+)
+
+// embedText 返回用于生成向量的文本，图片、表情等没有文字内容的消息返回空串
+func embedText(content string) string {
+	text := strings.TrimSpace(content)
+	if text == "" || placeholderOnly.MatchString(text) {
+		return ""
+	}
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = strings.TrimSpace(strings.NewReplacer("[", " ", "]", " ", "|", " ").Replace(text))
+	if utf8.RuneCountInString(text) < 2 {
+		return ""
+	}
+	if runes := []rune(text); len(runes) > maxEmbedRunes {
+		text = string(runes[:maxEmbedRunes])
+	}
+	return text
+}
+
+// quantize 将向量归一化后量化为 int8
+func quantize(vec []float32) []byte {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	norm = math.Sqrt(norm)
+	out := make([]byte, len(vec))
+	if norm == 0 {
+		return out
+	}
+	for i, v := range vec {
+		q := math.Round(float64(v) / norm * 127)
+		out[i] = byte(int8(math.Max(-127, math.Min(127, q))))
+	}
+	return out
+}
+
+// similarity 返回量化向量间的余弦相似度，维度不一致时返回 -1
+func similarity(a, b []byte) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return -1
+	}
+	var dot int64
+	for i := range a {
+		dot += int64(int8(a[i])) * int64(int8(b[i]))
+	}
+	return float64(dot) / (127 * 127)
+}
+
+// SemanticSearch 按向量相似度检索，hybrid 为 true 时与 bm25 排序做倒数排名融合（RRF）
+// 结果按融合分（或相似度）降序，Score 越大越相关；只支持 offset 翻页，offset+limit 不能超过 maxSemanticCandidates，Total 为召回的候选数
+func (i *Index) SemanticSearch(req *model.SearchRequest, filter *Filter, query []float32, hybrid bool, offset, limit int) (*SearchResult, error) {
+	if req == nil {
+		return nil, errors.New("search request is nil")
+	}
+	if filter == nil {
+		filter = &Filter{}
+	}
+	filter.Talkers = dedupeStrings(filter.Talkers)
+	filter.Senders = dedupeStrings(filter.Senders)
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset+limit > maxSemanticCandidates {
+		return nil, ErrResultWindow
+	}
+
+	window := offset + limit
+	if window < 100 {
+		window = 100
+	}
+
+	vectorHits, err := i.vectorSearch(quantize(query), filter, window)
+	if err != nil {
+		return nil, err
+	}
+	lists := [][]*SearchHit{vectorHits}
+	if hybrid {
+		kwReq := req.Clone()
+		kwReq.Cursor = ""
+		kw, err := i.search(kwReq, filter, 0, window, false)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, kw.Hits)
+	}
+
+	fused := fuseRanks(lists, !hybrid)
+	result := &SearchResult{Hits: []*SearchHit{}, Total: len(fused)}
+	if offset >= len(fused) {
+		return result, nil
+	}
+	end := offset + limit
+	if end > len(fused) {
+		end = len(fused)
+	}
+	result.Hits = fused[offset:end]
+	return result, nil
+}
+
+// fuseRanks 按倒数排名融合多路已排序的命中，同一消息的摘要优先取关键词命中
+// keepScore 为 true 时只有一路结果，保留原始相似度作为分值
+func fuseRanks(lists [][]*SearchHit, keepScore bool) []*SearchHit {
+	byDoc := make(map[string]*SearchHit)
+	scores := make(map[string]float64)
+	order := make([]*SearchHit, 0)
+	for _, list := range lists {
+		for rank, hit := range list {
+			scores[hit.docID] += 1 / float64(rrfK+rank+1)
+			existing, ok := byDoc[hit.docID]
+			if !ok {
+				copied := *hit
+				byDoc[hit.docID] = &copied
+				order = append(order, &copied)
+				continue
+			}
+			if hit.Snippet != "" {
+				existing.Snippet = hit.Snippet
+			}
+		}
+	}
+	if !keepScore {
+		for _, hit := range order {
+			hit.Score = scores[hit.docID]
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if order[a].Score != order[b].Score {
+			return order[a].Score > order[b].Score
+		}
+		if order[a].unix != order[b].unix {
+			return order[a].unix > order[b].unix
+		}
+		return order[a].docID > order[b].docID
+	})
+	return order
+}
+
+// vectorSearch 在全部 store 中找出与 query 最相似的前 k 条消息
+func (i *Index) vectorSearch(query []byte, filter *Filter, k int) ([]*SearchHit, error) {
+	i.mu.RLock()
+	stores := make([]*storeIndex, 0, len(i.stores))
+	for _, si := range i.stores {
+		stores = append(stores, si)
+	}
+	i.mu.RUnlock()
+
+	lists := make([][]*SearchHit, len(stores))
+	errs := make([]error, len(stores))
+	sem := make(chan struct{}, maxSearchWorkers)
+	var wg sync.WaitGroup
+	for idx, si := range stores {
+		wg.Add(1)
+		go func(idx int, si *storeIndex) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			lists[idx], errs[idx] = si.vectorSearch(query, filter, k)
+		}(idx, si)
+	}
+	wg.Wait()
+
+	hits := make([]*SearchHit, 0)
+	for idx := range stores {
+		if errs[idx] != nil {
+			return nil, errs[idx]
+		}
+		hits = append(hits, lists[idx]...)
+	}
+	sort.SliceStable(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].unix > hits[b].unix
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+func (s *storeIndex) vectorSearch(query []byte, filter *Filter, k int) ([]*SearchHit, error) {
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db == nil {
+		return nil, errIndexNotInitialized
+	}
+
+	where, args := filter.where()
+	q := `SELECT v.doc, v.vec FROM vectors v JOIN messages m ON m.id = v.doc WHERE length(v.vec) > 0`
+	if len(where) > 0 {
+		q += " AND " + strings.Join(where, " AND ")
+	}
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("scan vectors: %w", err)
+	}
+	top := &scoredDocs{}
+	for rows.Next() {
+		var doc int64
+		var vec []byte
+		if err := rows.Scan(&doc, &vec); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		score := similarity(query, vec)
+		if top.Len() < k {
+			heap.Push(top, scoredDoc{doc: doc, score: score})
+		} else if score > (*top)[0].score {
+			(*top)[0] = scoredDoc{doc: doc, score: score}
+			heap.Fix(top, 0)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if top.Len() == 0 {
+		return nil, nil
+	}
+
+	scores := make(map[int64]float64, top.Len())
+	placeholders := make([]string, 0, top.Len())
+	docArgs := make([]interface{}, 0, top.Len())
+	for _, d := range *top {
+		scores[d.doc] = d.score
+		placeholders = append(placeholders, "?")
+		docArgs = append(docArgs, d.doc)
+	}
+	rows, err = db.Query(`SELECT m.id, `+messageColumns+` FROM messages m WHERE m.id IN (`+strings.Join(placeholders, ",")+`)`, docArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("load vector hits: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]*SearchHit, 0, top.Len())
+	for rows.Next() {
+		var doc int64
+		var row messageRow
+		if err := rows.Scan(append([]interface{}{&doc}, row.dest()...)...); err != nil {
+			return nil, fmt.Errorf("scan vector hit: %w", err)
+		}
+		msg, err := row.message()
+		if err != nil {
+			return nil, fmt.Errorf("decode message %s:%d: %w", row.talker, row.seq, err)
+		}
+		hits = append(hits, &SearchHit{
+			Message: msg,
+			Snippet: buildSnippet(normalizeContent(row.content), nil),
+			Score:   scores[doc],
+			docID:   row.talker + ":" + strconv.FormatInt(row.seq, 10),
+			unix:    row.unix,
+			seq:     row.seq,
+		})
+	}
+	return hits, rows.Err()
+}
+
+type scoredDoc struct {
+	doc   int64
+	score float64
+}
+
+// scoredDocs 为按分值排序的小顶堆，堆顶为当前前 k 条中分值最低的一条
+type scoredDocs []scoredDoc
+
+func (h scoredDocs) Len() int            { return len(h) }
+func (h scoredDocs) Less(i, j int) bool  { return h[i].score < h[j].score }
+func (h scoredDocs) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scoredDocs) Push(x interface{}) { *h = append(*h, x.(scoredDoc)) }
+
+func (h *scoredDocs) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
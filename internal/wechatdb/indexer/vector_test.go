@@ -0,0 +1,168 @@
+package indexer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+func TestEmbedText(t *testing.T) {
+	cases := map[string]string{
+		"收到": "收到",
+		"好":  "",
+		"![图片](http://127.0.0.1:5030/image/abc)":  "",
+		"[语音(3s)](http://127.0.0.1:5030/voice/1)": "",
+		"[语音](http://127.0.0.1:5030/voice/1) 明天见": "语音 明天见",
+		"[链接|季度报价单](https://example.com)":         "链接 季度报价单",
+	}
+	for in, want := range cases {
+		if got := embedText(in); got != want {
+			t.Errorf("embedText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFuseRanks(t *testing.T) {
+	hit := func(doc string, snippet string) *SearchHit {
+		return &SearchHit{docID: doc, Snippet: snippet}
+	}
+	vector := []*SearchHit{hit("t:1", "a"), hit("t:2", "b"), hit("t:3", "c")}
+	keyword := []*SearchHit{hit("t:3", "<mark>c</mark>"), hit("t:4", "d")}
+	fused := fuseRanks([][]*SearchHit{vector, keyword}, false)
+	if len(fused) != 4 {
+		t.Fatalf("fused %d hits, want 4", len(fused))
+	}
+	if fused[0].docID != "t:3" || fused[0].Snippet != "<mark>c</mark>" {
+		t.Errorf("fused[0] = %s %q, want t:3 with keyword snippet", fused[0].docID, fused[0].Snippet)
+	}
+	if similarity(quantize([]float32{3, 4}), quantize([]float32{6, 8})) < 0.99 {
+		t.Error("parallel vectors should be similar")
+	}
+}
+
+// fakeEmbed 以关键词出现次数作为向量，记录每次生成向量的文本
+type fakeEmbed struct {
+	texts []string
+}
+
+func (f *fakeEmbed) embed(_ context.Context, texts []string) ([][]float32, error) {
+	f.texts = append(f.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for k, text := range texts {
+		vectors[k] = []float32{float32(strings.Count(text, "报价")), float32(strings.Count(text, "吃饭")), 0.1}
+	}
+	return vectors, nil
+}
+
+func indexTexts(t *testing.T, idx *Index, store *msgstore.Store, contents ...string) []*model.Message {
+	t.Helper()
+	messages := make([]*model.Message, len(contents))
+	for k, content := range contents {
+		messages[k] = &model.Message{
+			Version: model.WeChatV4,
+			Seq:     int64(k + 1),
+			Time:    time.Unix(1700000000+int64(k), 0),
+			Talker:  "wxid_a",
+			Sender:  "wxid_b",
+			Type:    model.MessageTypeText,
+			Content: content,
+		}
+	}
+	if err := idx.IndexStoreMessages(store, messages); err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+// 只为有文字的新消息生成向量，内容变化后只重新生成对应的向量
+func TestEmbedStore(t *testing.T) {
+	idx, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	store := &msgstore.Store{ID: "message_0"}
+	messages := indexTexts(t, idx, store, "季度报价单 已发送", "晚上一起吃饭", "好")
+	fake := &fakeEmbed{}
+	ctx := context.Background()
+
+	if n, err := idx.EmbedStore(ctx, store, "m1", 2, fake.embed); err != nil || n != 2 {
+		t.Fatalf("first embed = %d, %v, want 2", n, err)
+	}
+	if n, err := idx.EmbedStore(ctx, store, "m1", 2, fake.embed); err != nil || n != 0 {
+		t.Fatalf("second embed = %d, %v, want 0", n, err)
+	}
+
+	changed := *messages[1]
+	changed.Content = "明天讨论报价"
+	if _, err := idx.RefreshMessages([]*model.Message{&changed}); err != nil {
+		t.Fatal(err)
+	}
+	fake.texts = nil
+	if n, err := idx.EmbedStore(ctx, store, "m1", 2, fake.embed); err != nil || n != 1 {
+		t.Fatalf("stale embed = %d, %v, want 1", n, err)
+	}
+	if len(fake.texts) != 1 || fake.texts[0] != "明天讨论报价" {
+		t.Errorf("re-embedded %q, want the changed message only", fake.texts)
+	}
+	if count, err := idx.VectorCount(store); err != nil || count != 2 {
+		t.Errorf("vector count = %d, %v, want 2", count, err)
+	}
+
+	result, err := idx.SemanticSearch(&model.SearchRequest{}, nil, []float32{1, 0, 0}, false, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两条消息的向量相同，较新的一条在前
+	if len(result.Hits) != 2 || result.Hits[0].Message.Content != "明天讨论报价" {
+		t.Errorf("semantic hits = %d, want both quotes with the refreshed content first", len(result.Hits))
+	}
+
+	// 更换模型后全部重新生成
+	if n, err := idx.EmbedStore(ctx, store, "m2", 2, fake.embed); err != nil || n != 2 {
+		t.Errorf("embed with new model = %d, %v, want 2", n, err)
+	}
+}
+
+// 混合检索融合向量与关键词两路排序，两路都靠前的消息排在最前
+func TestHybridSearch(t *testing.T) {
+	idx, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	store := &msgstore.Store{ID: "message_0"}
+	indexTexts(t, idx, store, "季度报价单 已发送", "晚上一起吃饭", "报价单 吃饭 再聊")
+	fake := &fakeEmbed{}
+	if _, err := idx.EmbedStore(context.Background(), store, "m1", 0, fake.embed); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &model.SearchRequest{Query: "报价单"}
+	result, err := idx.SemanticSearch(req, nil, []float32{0, 1, 0}, true, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || len(result.Hits) != 3 {
+		t.Fatalf("hybrid = %d hits of %d, want 3", len(result.Hits), result.Total)
+	}
+	top := result.Hits[0]
+	if top.Message == nil || top.Message.Seq != 3 || !strings.Contains(top.Snippet, "<mark>") {
+		t.Errorf("top hit = %+v, want seq 3 with keyword snippet", top)
+	}
+	for k := 1; k < len(result.Hits); k++ {
+		if result.Hits[k].Score > result.Hits[k-1].Score {
+			t.Errorf("hits not sorted by fused score at %d", k)
+		}
+	}
+
+	if _, err := idx.SemanticSearch(req, nil, []float32{0, 1, 0}, true, maxSemanticCandidates-5, 10); err != ErrResultWindow {
+		t.Errorf("deep offset error = %v, want ErrResultWindow", err)
+	}
+}
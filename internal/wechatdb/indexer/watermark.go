@@ -0,0 +1,145 @@
+package indexer
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// generationKey 记录索引文件创建时生成的标识
+const generationKey = "generation"
+
+// ensureGeneration 返回索引文件的标识，新文件（包括旧版本创建的文件）首次打开时生成
+func ensureGeneration(db *sql.DB) (string, error) {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	if _, err := db.Exec(`INSERT OR IGNORE INTO metadata (key, value) VALUES (?, ?)`, generationKey, hex.EncodeToString(b)); err != nil {
+		return "", fmt.Errorf("init index generation: %w", err)
+	}
+	var gen string
+	if err := db.QueryRow(`SELECT value FROM metadata WHERE key = ?`, generationKey).Scan(&gen); err != nil {
+		return "", fmt.Errorf("read index generation: %w", err)
+	}
+	return gen, nil
+}
+
+// StoreMarks 返回各 store 索引的标识与当前最大 rowid，rowid 按写入索引的顺序递增
+func (i *Index) StoreMarks() (map[string]model.IndexMark, error) {
+	marks := make(map[string]model.IndexMark)
+	for _, si := range i.storeIndexes() {
+		si.index.mu.RLock()
+		db, gen := si.index.db, si.index.gen
+		si.index.mu.RUnlock()
+		if db == nil {
+			continue
+		}
+		var rowID int64
+		if err := db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM messages`).Scan(&rowID); err != nil {
+			return nil, fmt.Errorf("query max rowid of %s: %w", si.id, err)
+		}
+		marks[si.id] = model.IndexMark{Gen: gen, RowID: rowID}
+	}
+	return marks, nil
+}
+
+// SearchStoreAfter 按写入索引的顺序返回 store 中 rowid 在 (after, bound] 内的前 limit 条命中，不计算相关度也不统计总数
+// floor 非零时只返回晚于 floor 的消息；last 为本页最后一条命中的 rowid，more 为 true 时表示还有后续命中
+// 供常用搜索逐页读取新写入的消息，晚写入但时间较早的消息同样能读到
+func (i *Index) SearchStoreAfter(req *model.SearchRequest, filter *Filter, store string, after, bound int64, floor model.MessageMarker, limit int) (hits []*SearchHit, last int64, more bool, err error) {
+	if req == nil {
+		return nil, 0, false, errors.New("search request is nil")
+	}
+
+	query, err := buildFTSQuery(req.Query)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if filter == nil {
+		filter = &Filter{}
+	}
+	if query.match == "" && filter.empty() {
+		return nil, bound, false, nil
+	}
+	if limit <= 0 {
+		limit = 200
+	}
+
+	i.mu.RLock()
+	si := i.stores[store]
+	i.mu.RUnlock()
+	if si == nil {
+		return nil, bound, false, nil
+	}
+
+	hits, rowIDs, err := si.searchAfter(query, filter, after, bound, floor, limit+1)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if len(hits) <= limit {
+		return hits, bound, false, nil
+	}
+	return hits[:limit], rowIDs[limit-1], true, nil
+}
+
+// searchAfter 按 rowid 升序返回该 store 中 rowid 在 (after, bound] 内的前 limit 条命中及其 rowid
+func (s *storeIndex) searchAfter(query *ftsQuery, filter *Filter, after, bound int64, floor model.MessageMarker, limit int) ([]*SearchHit, []int64, error) {
+	s.mu.RLock()
+	db := s.db
+	s.mu.RUnlock()
+	if db == nil {
+		return nil, nil, errIndexNotInitialized
+	}
+
+	baseQuery, args := matchClause(query, filter)
+	baseQuery += " AND m.rowid > ? AND m.rowid <= ?"
+	args = append(args, after, bound)
+	if floor != (model.MessageMarker{}) {
+		baseQuery += " AND (m.unix > ? OR (m.unix = ? AND m.seq > ?))"
+		args = append(args, floor.Unix, floor.Unix, floor.Seq)
+	}
+	dataQuery := "SELECT m.rowid, m.talker || ':' || m.seq, " + messageColumns + baseQuery + " ORDER BY m.rowid ASC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(context.Background(), dataQuery, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("execute search query: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]*SearchHit, 0)
+	rowIDs := make([]int64, 0)
+	for rows.Next() {
+		var (
+			rowID int64
+			docID string
+			row   messageRow
+		)
+		if err := rows.Scan(append([]interface{}{&rowID, &docID}, row.dest()...)...); err != nil {
+			return nil, nil, fmt.Errorf("scan search hit: %w", err)
+		}
+
+		msg, err := row.message()
+		if err != nil {
+			return nil, nil, fmt.Errorf("decode message %s: %w", docID, err)
+		}
+
+		hits = append(hits, &SearchHit{
+			Message: msg,
+			Snippet: buildSnippet(normalizeContent(row.content), query.terms),
+			docID:   docID,
+			unix:    row.unix,
+			seq:     row.seq,
+		})
+		rowIDs = append(rowIDs, rowID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterate search hits: %w", err)
+	}
+
+	return hits, rowIDs, nil
+}
@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"sort"
-	"strings"
 
 	"github.com/rs/zerolog/log"
 
@@ -18,8 +17,7 @@ func (r *Repository) initChatRoomCache(ctx context.Context) error {
 	remarkToChatRoom := make(map[string][]*model.ChatRoom)
 	nickNameToChatRoom := make(map[string][]*model.ChatRoom)
 	chatRoomList := make([]string, 0)
-	chatRoomRemark := make([]string, 0)
-	chatRoomNickName := make([]string, 0)
+	chatRoomNames := make([]*nameEntry, 0)
 
 	// 加载所有群聊到缓存
 	// 暂时忽略获取不到群聊的错误
@@ -33,6 +31,7 @@ func (r *Repository) initChatRoomCache(ctx context.Context) error {
 		r.enrichChatRoom(chatRoom)
 		chatRoomMap[chatRoom.Name] = chatRoom
 		chatRoomList = append(chatRoomList, chatRoom.Name)
+		chatRoomNames = append(chatRoomNames, newChatRoomEntry(chatRoom, r.contactCache[chatRoom.Name]))
 		if chatRoom.Remark != "" {
 			remark, ok := remarkToChatRoom[chatRoom.Remark]
 			if !ok {
@@ -40,7 +39,6 @@ func (r *Repository) initChatRoomCache(ctx context.Context) error {
 			}
 			remark = append(remark, chatRoom)
 			remarkToChatRoom[chatRoom.Remark] = remark
-			chatRoomRemark = append(chatRoomRemark, chatRoom.Remark)
 		}
 		if chatRoom.NickName != "" {
 			nickName, ok := nickNameToChatRoom[chatRoom.NickName]
@@ -49,7 +47,6 @@ func (r *Repository) initChatRoomCache(ctx context.Context) error {
 			}
 			nickName = append(nickName, chatRoom)
 			nickNameToChatRoom[chatRoom.NickName] = nickName
-			chatRoomNickName = append(chatRoomNickName, chatRoom.NickName)
 		}
 	}
 
@@ -62,6 +59,7 @@ func (r *Repository) initChatRoomCache(ctx context.Context) error {
 			}
 			chatRoomMap[contact.UserName] = chatRoom
 			chatRoomList = append(chatRoomList, contact.UserName)
+			chatRoomNames = append(chatRoomNames, newChatRoomEntry(chatRoom, contact))
 			if contact.Remark != "" {
 				remark, ok := remarkToChatRoom[chatRoom.Remark]
 				if !ok {
@@ -69,7 +67,6 @@ func (r *Repository) initChatRoomCache(ctx context.Context) error {
 				}
 				remark = append(remark, chatRoom)
 				remarkToChatRoom[chatRoom.Remark] = remark
-				chatRoomRemark = append(chatRoomRemark, contact.Remark)
 			}
 			if contact.NickName != "" {
 				nickName, ok := nickNameToChatRoom[chatRoom.NickName]
@@ -78,20 +75,16 @@ func (r *Repository) initChatRoomCache(ctx context.Context) error {
 				}
 				nickName = append(nickName, chatRoom)
 				nickNameToChatRoom[chatRoom.NickName] = nickName
-				chatRoomNickName = append(chatRoomNickName, contact.NickName)
 			}
 		}
 	}
 	sort.Strings(chatRoomList)
-	sort.Strings(chatRoomRemark)
-	sort.Strings(chatRoomNickName)
 
 	r.chatRoomCache = chatRoomMap
 	r.remarkToChatRoom = remarkToChatRoom
 	r.nickNameToChatRoom = nickNameToChatRoom
 	r.chatRoomList = chatRoomList
-	r.chatRoomRemark = chatRoomRemark
-	r.chatRoomNickName = chatRoomNickName
+	r.chatRoomNames = chatRoomNames
 
 	return nil
 }
@@ -162,66 +155,18 @@ func (r *Repository) findChatRoom(key string) *model.ChatRoom {
 		return chatRoom[0]
 	}
 
-	// Contain
-	for _, remark := range r.chatRoomRemark {
-		if strings.Contains(remark, key) {
-			return r.remarkToChatRoom[remark][0]
-		}
-	}
-	for _, nickName := range r.chatRoomNickName {
-		if strings.Contains(nickName, key) {
-			return r.nickNameToChatRoom[nickName][0]
-		}
+	if matches := matchNames(r.chatRoomNames, key); len(matches) > 0 {
+		return r.chatRoomCache[matches[0].entry.userName]
 	}
-
 	return nil
 }
 
+// findChatRooms 返回与 key 匹配的群聊，按匹配程度排序，支持全拼与首字母
 func (r *Repository) findChatRooms(key string) []*model.ChatRoom {
-	ret := make([]*model.ChatRoom, 0)
-	distinct := make(map[string]bool)
-	if chatRoom, ok := r.chatRoomCache[key]; ok {
-		ret = append(ret, chatRoom)
-		distinct[chatRoom.Name] = true
-	}
-	if chatRooms, ok := r.remarkToChatRoom[key]; ok {
-		for _, chatRoom := range chatRooms {
-			if !distinct[chatRoom.Name] {
-				ret = append(ret, chatRoom)
-				distinct[chatRoom.Name] = true
-			}
-		}
-	}
-	if chatRooms, ok := r.nickNameToChatRoom[key]; ok {
-		for _, chatRoom := range chatRooms {
-			if !distinct[chatRoom.Name] {
-				ret = append(ret, chatRoom)
-				distinct[chatRoom.Name] = true
-			}
-		}
-	}
-
-	// Contain
-	for _, remark := range r.chatRoomRemark {
-		if strings.Contains(remark, key) {
-			for _, chatRoom := range r.remarkToChatRoom[remark] {
-				if !distinct[chatRoom.Name] {
-					ret = append(ret, chatRoom)
-					distinct[chatRoom.Name] = true
-				}
-			}
-		}
+	matches := matchNames(r.chatRoomNames, key)
+	ret := make([]*model.ChatRoom, 0, len(matches))
+	for _, m := range matches {
+		ret = append(ret, r.chatRoomCache[m.entry.userName])
 	}
-	for _, nickName := range r.chatRoomNickName {
-		if strings.Contains(nickName, key) {
-			for _, chatRoom := range r.nickNameToChatRoom[nickName] {
-				if !distinct[chatRoom.Name] {
-					ret = append(ret, chatRoom)
-					distinct[chatRoom.Name] = true
-				}
-			}
-		}
-	}
-
 	return ret
 }
@@ -21,9 +21,7 @@ func (r *Repository) initContactCache(ctx context.Context) error {
 	chatRoomUserMap := make(map[string]*model.Contact)
 	chatRoomInContactMap := make(map[string]*model.Contact)
 	contactList := make([]string, 0)
-	aliasList := make([]string, 0)
-	remarkList := make([]string, 0)
-	nickNameList := make([]string, 0)
+	contactNames := make([]*nameEntry, 0)
 
 	// 加载所有联系人到缓存
 	// 暂时忽略获取不到联系人的错误
@@ -35,6 +33,7 @@ func (r *Repository) initContactCache(ctx context.Context) error {
 	for _, contact := range contacts {
 		contactMap[contact.UserName] = contact
 		contactList = append(contactList, contact.UserName)
+		contactNames = append(contactNames, newContactEntry(contact))
 
 		// 建立快速查找索引
 		if contact.Alias != "" {
@@ -44,7 +43,6 @@ func (r *Repository) initContactCache(ctx context.Context) error {
 			}
 			alias = append(alias, contact)
 			aliasMap[contact.Alias] = alias
-			aliasList = append(aliasList, contact.Alias)
 		}
 		if contact.Remark != "" {
 			remark, ok := remarkMap[contact.Remark]
@@ -53,7 +51,6 @@ func (r *Repository) initContactCache(ctx context.Context) error {
 			}
 			remark = append(remark, contact)
 			remarkMap[contact.Remark] = remark
-			remarkList = append(remarkList, contact.Remark)
 		}
 		if contact.NickName != "" {
 			nickName, ok := nickNameMap[contact.NickName]
@@ -62,7 +59,6 @@ func (r *Repository) initContactCache(ctx context.Context) error {
 			}
 			nickName = append(nickName, contact)
 			nickNameMap[contact.NickName] = nickName
-			nickNameList = append(nickNameList, contact.NickName)
 		}
 
 		// 如果是群聊成员（非好友），添加到群聊成员索引
@@ -76,9 +72,6 @@ func (r *Repository) initContactCache(ctx context.Context) error {
 	}
 
 	sort.Strings(contactList)
-	sort.Strings(aliasList)
-	sort.Strings(remarkList)
-	sort.Strings(nickNameList)
 
 	r.contactCache = contactMap
 	r.aliasToContact = aliasMap
@@ -87,9 +80,7 @@ func (r *Repository) initContactCache(ctx context.Context) error {
 	r.chatRoomUserToInfo = chatRoomUserMap
 	r.chatRoomInContact = chatRoomInContactMap
 	r.contactList = contactList
-	r.aliasList = aliasList
-	r.remarkList = remarkList
-	r.nickNameList = nickNameList
+	r.contactNames = contactNames
 	return nil
 }
 
@@ -152,88 +143,19 @@ func (r *Repository) findContact(key string) *model.Contact {
 		return contact[0]
 	}
 
-	// Contain
-	for _, alias := range r.aliasList {
-		if strings.Contains(alias, key) {
-			return r.aliasToContact[alias][0]
-		}
-	}
-	for _, remark := range r.remarkList {
-		if strings.Contains(remark, key) {
-			return r.remarkToContact[remark][0]
-		}
-	}
-	for _, nickName := range r.nickNameList {
-		if strings.Contains(nickName, key) {
-			return r.nickNameToContact[nickName][0]
-		}
+	if matches := matchNames(r.contactNames, key); len(matches) > 0 {
+		return r.contactCache[matches[0].entry.userName]
 	}
 	return nil
 }
 
+// findContacts 返回与 key 匹配的联系人，按匹配程度排序，支持全拼与首字母
 func (r *Repository) findContacts(key string) []*model.Contact {
-	ret := make([]*model.Contact, 0)
-	distinct := make(map[string]bool)
-	if contact, ok := r.contactCache[key]; ok {
-		ret = append(ret, contact)
-		distinct[contact.UserName] = true
-	}
-	if contacts, ok := r.aliasToContact[key]; ok {
-		for _, contact := range contacts {
-			if !distinct[contact.UserName] {
-				ret = append(ret, contact)
-				distinct[contact.UserName] = true
-			}
-		}
-	}
-	if contacts, ok := r.remarkToContact[key]; ok {
-		for _, contact := range contacts {
-			if !distinct[contact.UserName] {
-				ret = append(ret, contact)
-				distinct[contact.UserName] = true
-			}
-		}
+	matches := matchNames(r.contactNames, key)
+	ret := make([]*model.Contact, 0, len(matches))
+	for _, m := range matches {
+		ret = append(ret, r.contactCache[m.entry.userName])
 	}
-	if contacts, ok := r.nickNameToContact[key]; ok {
-		for _, contact := range contacts {
-			if !distinct[contact.UserName] {
-				ret = append(ret, contact)
-				distinct[contact.UserName] = true
-			}
-		}
-	}
-	// Contain
-	for _, alias := range r.aliasList {
-		if strings.Contains(alias, key) {
-			for _, contact := range r.aliasToContact[alias] {
-				if !distinct[contact.UserName] {
-					ret = append(ret, contact)
-					distinct[contact.UserName] = true
-				}
-			}
-		}
-	}
-	for _, remark := range r.remarkList {
-		if strings.Contains(remark, key) {
-			for _, contact := range r.remarkToContact[remark] {
-				if !distinct[contact.UserName] {
-					ret = append(ret, contact)
-					distinct[contact.UserName] = true
-				}
-			}
-		}
-	}
-	for _, nickName := range r.nickNameList {
-		if strings.Contains(nickName, key) {
-			for _, contact := range r.nickNameToContact[nickName] {
-				if !distinct[contact.UserName] {
-					ret = append(ret, contact)
-					distinct[contact.UserName] = true
-				}
-			}
-		}
-	}
-
 	return ret
 }
 
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	cerrors "github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
+)
+
+// queryEmbedTimeout 为检索时生成查询向量的超时时间
+const queryEmbedTimeout = 30 * time.Second
+
+// startEmbedding 在后台为各 store 新写入索引的消息生成向量
+// 已有任务在运行时只标记需要再执行一轮，以覆盖运行期间新同步的消息
+func (r *Repository) startEmbedding() {
+	if r.embedder == nil || r.index == nil {
+		return
+	}
+
+	r.indexMu.Lock()
+	if r.embedStatus.InProgress {
+		r.embedAgain = true
+		r.indexMu.Unlock()
+		return
+	}
+	r.embedStatus.Model = r.embedder.Name()
+	r.embedStatus.InProgress = true
+	r.embedStatus.LastError = ""
+	r.indexMu.Unlock()
+
+	go func() {
+		for {
+			err := r.embedStores(r.indexCtx)
+
+			r.indexMu.Lock()
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					r.embedStatus.LastError = err.Error()
+					log.Warn().Err(err).Msg("generate message embeddings failed")
+				}
+			} else {
+				r.embedStatus.LastCompletedAt = time.Now()
+			}
+			again := r.embedAgain && err == nil
+			r.embedAgain = false
+			if !again {
+				r.embedStatus.InProgress = false
+			}
+			r.indexMu.Unlock()
+			if !again {
+				return
+			}
+		}
+	}()
+}
+
+func (r *Repository) embedStores(ctx context.Context) error {
+	stores, err := r.ds.ListMessageStores(ctx)
+	if err != nil {
+		return err
+	}
+	for _, store := range stores {
+		if store == nil {
+			continue
+		}
+		n, err := r.index.EmbedStore(ctx, store, r.embedder.Name(), r.embedder.BatchSize(), r.embedder.Embed)
+		if err != nil {
+			return fmt.Errorf("embed store %s: %w", store.ID, err)
+		}
+		if n > 0 {
+			log.Debug().Str("store", store.ID).Int("vectors", n).Msg("message embeddings updated")
+		}
+	}
+	return nil
+}
+
+// semanticSearch 生成查询向量后按相似度检索，hybrid 模式同时融合关键词检索的排序
+func (r *Repository) semanticSearch(ctx context.Context, req *model.SearchRequest, filter *indexer.Filter) (*indexer.SearchResult, error) {
+	embedCtx, cancel := context.WithTimeout(ctx, queryEmbedTimeout)
+	defer cancel()
+	vectors, err := r.embedder.Embed(embedCtx, []string{req.Query})
+	if err != nil {
+		return nil, cerrors.EmbeddingFailed(err)
+	}
+	if len(vectors) == 0 {
+		return nil, cerrors.EmbeddingFailed(fmt.Errorf("empty query embedding"))
+	}
+	return r.index.SemanticSearch(req, filter, vectors[0], req.Mode == model.SearchModeHybrid, req.Offset, req.Limit)
+}
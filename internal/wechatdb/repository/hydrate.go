@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
+)
+
+// hydrateMessages 将索引中缺少结构化内容（合并转发、引用等）的消息按 talker/seq 从数据源补全
+// 回源失败时保留索引中的内容与版本，Contents 中的 partial 标记保留，表示合并转发等结构化内容缺失
+func (r *Repository) hydrateMessages(ctx context.Context, messages []*model.Message) {
+	for _, msg := range messages {
+		if !indexer.NeedsHydration(msg) {
+			continue
+		}
+		full, err := r.loadMessage(ctx, msg.Talker, msg.Seq, msg.Time)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", msg.Talker).Int64("seq", msg.Seq).Msg("hydrate message failed")
+		}
+		if full != nil {
+			*msg = *full
+		}
+	}
+}
+
+// loadMessage 从数据源读取 talker 在 t 所在秒内序号为 seq 的消息，不存在时返回 nil
+func (r *Repository) loadMessage(ctx context.Context, talker string, seq int64, t time.Time) (*model.Message, error) {
+	start := time.Unix(t.Unix(), 0)
+	messages, err := r.ds.GetMessages(ctx, start, start.Add(time.Second), talker, "", "", "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		if m != nil && m.Seq == seq {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	cerrors "github.com/ysy950803/chatlog/internal/errors"
 	"github.com/ysy950803/chatlog/internal/model"
 	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
 	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
@@ -36,6 +37,9 @@ func (r *Repository) initIndex() error {
 
 	r.index = idx
 	r.indexCtx, r.indexCancel = context.WithCancel(context.Background())
+	if r.deferIndex {
+		return nil
+	}
 
 	go func() {
 		ready, err := r.ensureIndex(r.indexCtx)
@@ -45,6 +49,7 @@ func (r *Repository) initIndex() error {
 		}
 		if ready {
 			log.Info().Msg("fts index ready")
+			r.startEmbedding()
 		}
 	}()
 
@@ -91,41 +96,77 @@ func (r *Repository) ensureIndex(ctx context.Context) (bool, error) {
 		return true, nil
 	}
 	if r.indexStatus.InProgress {
+		ready := r.indexStatus.Ready
 		r.indexMu.Unlock()
-		return false, nil
+		return ready, nil
+	}
+
+	// 版本一致且磁盘上已有完整构建过的索引时只做增量同步，期间继续使用旧索引检索
+	full := !versionMatched || r.index.Fingerprint() == ""
+	if !full && r.index.Fingerprint() == fp {
+		r.indexFingerprint = fp
+		r.indexStatus.Ready = true
+		r.indexStatus.Progress = 1
+		r.indexMu.Unlock()
+		r.flushPendingRefresh()
+		return true, nil
+	}
+	r.beginIndexTaskLocked(full)
+	r.indexMu.Unlock()
+
+	if !full {
+		go func() {
+			if err := r.runIndexTask(r.indexCtx, fp, false); err != nil && !errors.Is(err, context.Canceled) {
+				log.Warn().Err(err).Msg("incremental fts index update failed")
+			}
+		}()
+		return true, nil
+	}
+
+	if err := r.runIndexTask(ctx, fp, true); err != nil {
+		return false, err
 	}
+	return true, nil
+}
+
+// beginIndexTaskLocked 标记索引任务开始，全量重建期间索引不可用，调用方需持有 indexMu
+func (r *Repository) beginIndexTaskLocked(full bool) {
 	r.indexStatus.InProgress = true
-	r.indexStatus.Ready = false
+	if full {
+		r.indexStatus.Ready = false
+	}
 	r.indexStatus.Progress = 0
 	r.indexStatus.LastStartedAt = time.Now()
 	r.indexStatus.LastError = ""
-	r.indexMu.Unlock()
+}
 
-	err = r.rebuildIndex(ctx, fp)
+// runIndexTask 执行全量重建或增量同步并更新状态，增量同步失败时旧索引仍然可用
+func (r *Repository) runIndexTask(ctx context.Context, fp string, full bool) error {
+	var err error
+	if full {
+		err = r.rebuildIndex(ctx, fp)
+	} else {
+		err = r.updateIndex(ctx, fp)
+	}
+
+	r.indexMu.Lock()
+	r.indexStatus.InProgress = false
 	if err != nil {
-		if err == context.Canceled || errors.Is(err, context.Canceled) {
-			r.indexMu.Lock()
-			r.indexStatus.InProgress = false
-			r.indexMu.Unlock()
-			return false, err
+		if !errors.Is(err, context.Canceled) {
+			r.indexStatus.LastError = err.Error()
 		}
-
-		r.indexMu.Lock()
-		r.indexStatus.InProgress = false
-		r.indexStatus.LastError = err.Error()
 		r.indexMu.Unlock()
-		return false, err
+		return err
 	}
-
-	r.indexMu.Lock()
 	r.indexFingerprint = fp
-	r.indexStatus.InProgress = false
 	r.indexStatus.Ready = true
 	r.indexStatus.Progress = 1
 	r.indexStatus.LastCompletedAt = time.Now()
 	r.indexMu.Unlock()
 
-	return true, nil
+	r.flushPendingRefresh()
+	r.startEmbedding()
+	return nil
 }
 
 func (r *Repository) rebuildIndex(ctx context.Context, fp string) error {
@@ -156,6 +197,27 @@ func (r *Repository) rebuildIndex(ctx context.Context, fp string) error {
 		return r.index.UpdateLastBuilt(time.Now())
 	}
 
+	if incremental, ok := r.ds.(ftsIncremental); ok {
+		if _, err := r.syncStores(ctx, incremental, stores, ""); err != nil {
+			return err
+		}
+	} else if err := r.indexStores(ctx, indexable, stores, ""); err != nil {
+		return err
+	}
+
+	if err := r.index.UpdateFingerprint(fp); err != nil {
+		return err
+	}
+	if err := r.index.UpdateLastBuilt(time.Now()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// indexStores 遍历全部会话的消息写入对应 store 的索引；onlyID 非空时只写入该 store
+// 用于不支持按 store 读取的数据源，消息所属 store 由 LocateMessageStore 推断
+func (r *Repository) indexStores(ctx context.Context, indexable ftsIndexable, stores []*msgstore.Store, onlyID string) error {
 	storeByID := make(map[string]*msgstore.Store, len(stores))
 	storeByPath := make(map[string]*msgstore.Store, len(stores))
 	talkerHashStore := make(map[string]*msgstore.Store)
@@ -237,13 +299,7 @@ func (r *Repository) rebuildIndex(ctx context.Context, fp string) error {
 	}
 
 	if len(talkers) == 0 {
-		if err := flushDirty(); err != nil {
-			return err
-		}
-		if err := r.index.UpdateFingerprint(fp); err != nil {
-			return err
-		}
-		return r.index.UpdateLastBuilt(time.Now())
+		return nil
 	}
 
 	sort.Strings(talkers)
@@ -258,11 +314,15 @@ func (r *Repository) rebuildIndex(ctx context.Context, fp string) error {
 			if msg == nil {
 				return nil
 			}
+			r.attachTranscript(msg)
 			store, err := locateStore(msg)
 			if err != nil {
 				log.Warn().Err(err).Str("talker", msg.Talker).Msg("skip message without store")
 				return nil
 			}
+			if onlyID != "" && store.ID != onlyID {
+				return nil
+			}
 			batch := storeBuffers[store.ID]
 			batch = append(batch, msg)
 			if len(batch) >= perStoreBatchSize {
@@ -287,18 +347,7 @@ func (r *Repository) rebuildIndex(ctx context.Context, fp string) error {
 		r.updateIndexProgress(float64(i+1) / total)
 	}
 
-	if err := flushDirty(); err != nil {
-		return err
-	}
-
-	if err := r.index.UpdateFingerprint(fp); err != nil {
-		return err
-	}
-	if err := r.index.UpdateLastBuilt(time.Now()); err != nil {
-		return err
-	}
-
-	return nil
+	return flushDirty()
 }
 
 func (r *Repository) updateIndexProgress(progress float64) {
@@ -325,10 +374,13 @@ func (r *Repository) indexStatusSnapshot() *model.SearchIndexStatus {
 
 	r.indexMu.Lock()
 	status := r.indexStatus
+	if r.embedder != nil {
+		semantic := r.embedStatus
+		status.Semantic = &semantic
+	}
 	r.indexMu.Unlock()
 
-	copied := status
-	return &copied
+	return &status
 }
 
 func (r *Repository) searchMessagesWithIndex(ctx context.Context, req *model.SearchRequest) (*model.SearchResponse, error) {
@@ -348,7 +400,13 @@ func (r *Repository) searchMessagesWithIndex(ctx context.Context, req *model.Sea
 		}
 	}
 
-	if strings.TrimSpace(req.Query) == "" {
+	filter, err := searchFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 没有检索词时仅在给出过滤条件的情况下列出消息
+	if !searchable(req, filter) {
 		return makeEmpty(), nil
 	}
 
@@ -364,29 +422,26 @@ func (r *Repository) searchMessagesWithIndex(ctx context.Context, req *model.Sea
 		return makeEmpty(), nil
 	}
 
-	talkers := util.Str2List(req.Talker, ",")
-	senders := util.Str2List(req.Sender, ",")
-
-	startUnix := int64(0)
-	if !req.Start.IsZero() {
-		startUnix = req.Start.Unix()
-	}
-	endUnix := int64(0)
-	if !req.End.IsZero() {
-		endUnix = req.End.Unix()
-	}
-	if startUnix > 0 && endUnix > 0 && endUnix < startUnix {
-		startUnix, endUnix = endUnix, startUnix
-	}
-
 	begin := time.Now()
-	hits, total, err := r.index.Search(req, talkers, senders, startUnix, endUnix, req.Offset, req.Limit)
-	if err != nil {
+	var result *indexer.SearchResult
+	if req.Semantic() {
+		result, err = r.semanticSearch(ctx, req, filter)
+	} else {
+		result, err = r.index.Search(req, filter, req.Offset, req.Limit)
+	}
+	switch {
+	case errors.Is(err, indexer.ErrCursorExpired):
+		return nil, cerrors.CursorExpired()
+	case errors.Is(err, indexer.ErrInvalidCursor):
+		return nil, cerrors.InvalidArg("cursor")
+	case errors.Is(err, indexer.ErrResultWindow):
+		return nil, cerrors.ResultWindowExceeded(err)
+	case err != nil:
 		return nil, err
 	}
 
-	mapped := make([]*model.SearchHit, 0, len(hits))
-	for _, hit := range hits {
+	mapped := make([]*model.SearchHit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
 		if hit == nil || hit.Message == nil {
 			continue
 		}
@@ -397,8 +452,28 @@ func (r *Repository) searchMessagesWithIndex(ctx context.Context, req *model.Sea
 		})
 	}
 
+	if req.Context > 0 {
+		for _, hit := range mapped {
+			before, after, err := r.index.Neighbors(hit.Message.Talker, hit.Message.Seq, req.Context, req.Context)
+			if err != nil {
+				log.Debug().Err(err).Str("talker", hit.Message.Talker).Msg("load search hit context failed")
+				continue
+			}
+			hit.Before = before
+			hit.After = after
+		}
+	}
+
+	var facets *model.SearchFacets
+	if req.Facets && !req.Semantic() {
+		facets, err = r.index.Facets(req, filter, model.MaxFacetBuckets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	resp := &model.SearchResponse{
-		Total:      total,
+		Total:      result.Total,
 		Hits:       mapped,
 		DurationMs: time.Since(begin).Milliseconds(),
 		Limit:      req.Limit,
@@ -408,8 +483,46 @@ func (r *Repository) searchMessagesWithIndex(ctx context.Context, req *model.Sea
 		Sender:     req.Sender,
 		Start:      req.Start,
 		End:        req.End,
+		Types:      req.Types,
+		Context:    req.Context,
+		NextCursor: result.NextCursor,
+		Facets:     facets,
 		Index:      r.indexStatusSnapshot(),
 	}
 
 	return resp, nil
 }
+
+// searchFilter 将检索请求中的会话、发送者、时间范围与类别转换为索引的过滤条件
+func searchFilter(req *model.SearchRequest) (*indexer.Filter, error) {
+	types, err := model.ParseMessageTypeFilter(req.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	startUnix := int64(0)
+	if !req.Start.IsZero() {
+		startUnix = req.Start.Unix()
+	}
+	endUnix := int64(0)
+	if !req.End.IsZero() {
+		endUnix = req.End.Unix()
+	}
+	if startUnix > 0 && endUnix > 0 && endUnix < startUnix {
+		startUnix, endUnix = endUnix, startUnix
+	}
+
+	return &indexer.Filter{
+		Talkers:   util.Str2List(req.Talker, ","),
+		Senders:   util.Str2List(req.Sender, ","),
+		StartUnix: startUnix,
+		EndUnix:   endUnix,
+		Types:     types,
+	}, nil
+}
+
+// searchable 返回请求是否给出了检索词，或会话、发送者、类别、时间范围中的至少一个过滤条件
+func searchable(req *model.SearchRequest, filter *indexer.Filter) bool {
+	return strings.TrimSpace(req.Query) != "" || len(filter.Talkers) > 0 || len(filter.Senders) > 0 ||
+		filter.StartUnix > 0 || filter.EndUnix > 0 || !filter.Types.Empty()
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+// IndexStatus 返回当前索引构建状态，未启用索引时返回 nil
+func (r *Repository) IndexStatus() *model.SearchIndexStatus {
+	return r.indexStatusSnapshot()
+}
+
+// IndexReport 汇总索引版本、指纹以及各 store 的文档数和断点
+func (r *Repository) IndexReport(ctx context.Context) (*model.IndexReport, error) {
+	if r.index == nil {
+		return nil, errors.IndexNotEnabled()
+	}
+
+	stores, err := r.ds.ListMessageStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.IndexReport{
+		Version:     r.index.Version(),
+		Fingerprint: r.index.Fingerprint(),
+		LastBuilt:   r.index.LastBuilt(),
+		Status:      r.indexStatusSnapshot(),
+		Stores:      make([]*model.IndexStoreStats, 0, len(stores)),
+	}
+	if fp, err := r.ds.GetDatasetFingerprint(ctx); err == nil {
+		report.DatasetFingerprint = fp
+		report.Stale = fp != report.Fingerprint
+	}
+
+	for _, store := range stores {
+		if store == nil {
+			continue
+		}
+		stats, err := r.index.StoreStats(store)
+		if err != nil {
+			stats = &model.IndexStoreStats{ID: store.ID, File: store.FileName, Error: err.Error()}
+		}
+		report.Stores = append(report.Stores, stats)
+	}
+	return report, nil
+}
+
+// RebuildIndex 重建全文索引；storeID 非空时只清空并重建该 store，其余 store 不受影响
+func (r *Repository) RebuildIndex(ctx context.Context, storeID string) error {
+	if r.index == nil {
+		return errors.IndexNotEnabled()
+	}
+
+	if storeID == "" {
+		fp, err := r.ds.GetDatasetFingerprint(ctx)
+		if err != nil {
+			return err
+		}
+		r.indexMu.Lock()
+		if r.indexStatus.InProgress {
+			r.indexMu.Unlock()
+			return errors.IndexBusy()
+		}
+		r.beginIndexTaskLocked(true)
+		r.indexMu.Unlock()
+		return r.runIndexTask(ctx, fp, true)
+	}
+
+	indexable, ok := r.ds.(ftsIndexable)
+	if !ok {
+		return fmt.Errorf("datasource does not support fts indexing")
+	}
+	stores, err := r.ds.ListMessageStores(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := findStore(stores, storeID)
+	if err != nil {
+		return err
+	}
+
+	r.indexMu.Lock()
+	if r.indexStatus.InProgress {
+		r.indexMu.Unlock()
+		return errors.IndexBusy()
+	}
+	r.indexStatus.InProgress = true
+	r.indexStatus.Progress = 0
+	r.indexStatus.LastStartedAt = time.Now()
+	r.indexStatus.LastError = ""
+	r.indexMu.Unlock()
+
+	err = r.index.ResetStore(target)
+	if err == nil {
+		if incremental, ok := r.ds.(ftsIncremental); ok {
+			_, err = r.syncStores(ctx, incremental, stores, target.ID)
+		} else {
+			err = r.indexStores(ctx, indexable, stores, target.ID)
+		}
+	}
+
+	r.indexMu.Lock()
+	r.indexStatus.InProgress = false
+	if err != nil {
+		r.indexStatus.LastError = err.Error()
+	} else {
+		r.indexStatus.Progress = 1
+		r.indexStatus.LastCompletedAt = time.Now()
+	}
+	r.indexMu.Unlock()
+	return err
+}
+
+// VerifyIndex 校验各 store 索引的完整性；storeID 非空时只校验该 store
+func (r *Repository) VerifyIndex(ctx context.Context, storeID string) ([]*model.IndexCheckResult, error) {
+	return r.eachIndexStore(ctx, storeID, r.index.VerifyStore)
+}
+
+// OptimizeIndex 对各 store 执行 FTS5 optimize
+func (r *Repository) OptimizeIndex(ctx context.Context, storeID string) ([]*model.IndexCheckResult, error) {
+	return r.eachIndexStore(ctx, storeID, r.index.OptimizeStore)
+}
+
+// VacuumIndex 对各 store 的索引文件执行 VACUUM
+func (r *Repository) VacuumIndex(ctx context.Context, storeID string) ([]*model.IndexCheckResult, error) {
+	return r.eachIndexStore(ctx, storeID, r.index.VacuumStore)
+}
+
+func (r *Repository) eachIndexStore(ctx context.Context, storeID string, fn func(store *msgstore.Store) error) ([]*model.IndexCheckResult, error) {
+	if r.index == nil {
+		return nil, errors.IndexNotEnabled()
+	}
+
+	stores, err := r.ds.ListMessageStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if storeID != "" {
+		target, err := findStore(stores, storeID)
+		if err != nil {
+			return nil, err
+		}
+		stores = []*msgstore.Store{target}
+	}
+
+	results := make([]*model.IndexCheckResult, 0, len(stores))
+	for _, store := range stores {
+		if store == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result := &model.IndexCheckResult{ID: store.ID, OK: true}
+		if err := fn(store); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// findStore 按 ID 或文件名查找 store
+func findStore(stores []*msgstore.Store, key string) (*msgstore.Store, error) {
+	for _, store := range stores {
+		if store != nil && (store.ID == key || store.FileName == key) {
+			return store, nil
+		}
+	}
+	return nil, errors.MessageStoreNotFound(key)
+}
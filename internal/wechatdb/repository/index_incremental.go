@@ -2,16 +2,16 @@ package repository
 
 import (
 	"context"
-	"strings"
-	"time"
+	"fmt"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/ysy950803/chatlog/internal/model"
-	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
 )
 
-// IndexMessages incrementally indexes the provided messages into the FTS cache.
+// IndexMessages 刷新已入索引的消息内容，并触发按断点的增量同步
+// 传入的消息可能只是筛选后的片段，因此不直接追加到索引，新消息由同步按序号顺序写入，完成后执行常用搜索
+// 索引构建期间先记下待刷新的消息，构建完成后再写入，避免转写结果等后补的内容丢失
 func (r *Repository) IndexMessages(ctx context.Context, messages []*model.Message) error {
 	if len(messages) == 0 || r == nil {
 		return nil
@@ -22,69 +22,65 @@ func (r *Repository) IndexMessages(ctx context.Context, messages []*model.Messag
 	}
 
 	r.indexMu.Lock()
-	status := r.indexStatus
-	r.indexMu.Unlock()
-
-	if status.InProgress || !status.Ready {
-		return nil
-	}
-
-	batches := make(map[string][]*model.Message)
-	stores := make(map[string]*msgstore.Store)
-
-	for _, msg := range messages {
-		if msg == nil {
-			continue
-		}
-
-		store, err := r.ds.LocateMessageStore(msg)
-		if err != nil {
-			log.Warn().Err(err).Str("talker", msg.Talker).Msg("locate message store for incremental index failed")
-			continue
-		}
-		if store == nil {
-			log.Warn().Str("talker", msg.Talker).Msg("skip incremental index: message store not found")
-			continue
+	ready := r.indexStatus.Ready
+	if !ready {
+		if r.pendingRefresh == nil {
+			r.pendingRefresh = make(map[string]*model.Message)
 		}
-
-		batches[store.ID] = append(batches[store.ID], msg)
-		if _, ok := stores[store.ID]; !ok {
-			stores[store.ID] = store
+		for _, msg := range messages {
+			if msg != nil {
+				r.pendingRefresh[fmt.Sprintf("%s:%d", msg.Talker, msg.Seq)] = msg
+			}
 		}
 	}
+	r.indexMu.Unlock()
 
-	if len(batches) == 0 {
-		return nil
-	}
-
-	for id, batch := range batches {
-		store := stores[id]
-		if len(batch) == 0 || store == nil {
-			continue
-		}
-		if err := r.index.IndexStoreMessages(store, batch); err != nil {
+	if ready {
+		if err := r.refreshMessages(messages); err != nil {
 			return err
 		}
 	}
 
-	fp, err := r.ds.GetDatasetFingerprint(ctx)
-	if err != nil {
-		log.Debug().Err(err).Msg("get dataset fingerprint for incremental index failed")
-		return nil
-	}
+	_, err := r.ensureIndex(ctx)
+	return err
+}
 
-	if strings.TrimSpace(fp) == "" {
-		return nil
+// flushPendingRefresh 在索引可用后写入构建期间积压的消息，失败时放回队列等待下次构建完成
+func (r *Repository) flushPendingRefresh() {
+	r.indexMu.Lock()
+	pending := r.pendingRefresh
+	r.pendingRefresh = nil
+	r.indexMu.Unlock()
+	if len(pending) == 0 {
+		return
 	}
 
-	if err := r.index.UpdateFingerprint(fp); err != nil {
-		return err
+	messages := make([]*model.Message, 0, len(pending))
+	for _, msg := range pending {
+		messages = append(messages, msg)
 	}
+	if err := r.refreshMessages(messages); err != nil {
+		log.Warn().Err(err).Int("messages", len(messages)).Msg("refresh pending index messages failed")
+		r.indexMu.Lock()
+		if r.pendingRefresh == nil {
+			r.pendingRefresh = pending
+		} else {
+			for key, msg := range pending {
+				if _, ok := r.pendingRefresh[key]; !ok {
+					r.pendingRefresh[key] = msg
+				}
+			}
+		}
+		r.indexMu.Unlock()
+	}
+}
 
-	r.indexMu.Lock()
-	r.indexFingerprint = fp
-	r.indexStatus.LastCompletedAt = time.Now()
-	r.indexMu.Unlock()
-
-	return nil
+func (r *Repository) refreshMessages(messages []*model.Message) error {
+	for _, msg := range messages {
+		if msg != nil {
+			r.attachTranscript(msg)
+		}
+	}
+	_, err := r.index.RefreshMessages(messages)
+	return err
 }
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+// ftsIncremental 为支持按断点续读的数据源，序号即 model.Message.Seq
+type ftsIncremental interface {
+	IterateStoreMessages(ctx context.Context, store *msgstore.Store, talker string, afterSeq int64, fn func(*model.Message) error) error
+	StoreMaxSeq(ctx context.Context, store *msgstore.Store, talker string) (int64, error)
+}
+
+// updateIndex 从各 store 的断点之后读取新消息追加到索引
+func (r *Repository) updateIndex(ctx context.Context, fp string) error {
+	incremental, ok := r.ds.(ftsIncremental)
+	if !ok {
+		return r.rebuildIndex(ctx, fp)
+	}
+
+	stores, err := r.ds.ListMessageStores(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.index.SyncStores(stores); err != nil {
+		return err
+	}
+	latest, err := r.syncStores(ctx, incremental, stores, "")
+	if err != nil {
+		return err
+	}
+
+	if err := r.index.UpdateFingerprint(fp); err != nil {
+		return err
+	}
+	if latest.Unix > 0 {
+		r.evaluateSavedSearches(ctx, latest)
+	}
+	return nil
+}
+
+// syncStores 逐个 store 按会话从断点之后读取消息写入该 store 的索引，onlyID 非空时只处理该 store
+// 消息库文件的大小与修改时间都与上次同步时一致的 store 直接跳过（onlyID 指定的 store 除外）
+// 消息库变小或某个会话的最大序号低于断点时，认为数据已与索引分叉，清空该 store 后从头读取
+// 返回本次写入的最新消息位置
+func (r *Repository) syncStores(ctx context.Context, incremental ftsIncremental, stores []*msgstore.Store, onlyID string) (model.MessageMarker, error) {
+	var latest model.MessageMarker
+
+	indexable, ok := r.ds.(ftsIndexable)
+	if !ok {
+		return latest, fmt.Errorf("datasource does not support fts indexing")
+	}
+	talkers, err := indexable.ListTalkers(ctx)
+	if err != nil {
+		return latest, err
+	}
+	sort.Strings(talkers)
+
+	for i, store := range stores {
+		if store == nil || (onlyID != "" && store.ID != onlyID) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return latest, err
+		}
+
+		// 在读取消息之前取文件状态，读取期间写入的消息留到下次同步
+		stat, statOK := sourceStat(store)
+		recorded, err := r.index.SourceStat(store)
+		if err != nil {
+			return latest, err
+		}
+		if onlyID == "" && statOK && stat == recorded {
+			r.updateIndexProgress(float64(i+1) / float64(len(stores)))
+			continue
+		}
+
+		checkpoints, err := r.index.Checkpoints(store)
+		if err != nil {
+			return latest, err
+		}
+		if reason := r.storeDivergence(ctx, incremental, store, checkpoints, recorded, stat); reason != "" {
+			log.Info().Str("store", store.ID).Str("reason", reason).Msg("message store diverged from index, rebuild store")
+			if err := r.index.ResetStore(store); err != nil {
+				return latest, err
+			}
+			checkpoints = map[string]int64{}
+		}
+
+		added := 0
+		const batchSize = 512
+		batch := make([]*model.Message, 0, batchSize)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := r.index.IndexStoreMessages(store, batch); err != nil {
+				return err
+			}
+			added += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+		handler := func(msg *model.Message) error {
+			if msg == nil {
+				return nil
+			}
+			r.attachTranscript(msg)
+			if marker := model.MarkerOf(msg); latest.Before(marker) {
+				latest = marker
+			}
+			batch = append(batch, msg)
+			if len(batch) >= batchSize {
+				return flush()
+			}
+			return nil
+		}
+
+		for _, talker := range talkers {
+			if err := incremental.IterateStoreMessages(ctx, store, talker, checkpoints[talker], handler); err != nil {
+				return latest, err
+			}
+			if err := flush(); err != nil {
+				return latest, err
+			}
+		}
+		if statOK {
+			if err := r.index.SetSourceStat(store, stat); err != nil {
+				return latest, err
+			}
+		}
+		if added > 0 {
+			log.Debug().Str("store", store.ID).Int("messages", added).Msg("fts index appended")
+		}
+		r.updateIndexProgress(float64(i+1) / float64(len(stores)))
+	}
+	return latest, nil
+}
+
+// storeDivergence 检查 store 是否与索引分叉，返回原因，未分叉时返回空串
+func (r *Repository) storeDivergence(ctx context.Context, incremental ftsIncremental, store *msgstore.Store, checkpoints map[string]int64, recorded, current indexer.SourceStat) string {
+	if recorded.Size > 0 && current.Size > 0 && current.Size < recorded.Size {
+		return "message db shrunk"
+	}
+	for talker, seq := range checkpoints {
+		maxSeq, err := incremental.StoreMaxSeq(ctx, store, talker)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Msg("read max seq failed")
+			continue
+		}
+		if maxSeq < seq {
+			return "seq regression of " + talker
+		}
+	}
+	return ""
+}
+
+// sourceStat 返回消息库文件及其 WAL 文件的当前状态，文件不存在时返回 false
+func sourceStat(store *msgstore.Store) (indexer.SourceStat, bool) {
+	var stat indexer.SourceStat
+	if store.FilePath == "" {
+		return stat, false
+	}
+	info, err := os.Stat(store.FilePath)
+	if err != nil {
+		return stat, false
+	}
+	stat.Size = info.Size()
+	stat.ModTime = info.ModTime().UnixNano()
+	if wal, err := os.Stat(store.FilePath + "-wal"); err == nil {
+		stat.WALSize = wal.Size()
+		stat.WALModTime = wal.ModTime().UnixNano()
+	}
+	return stat, true
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/datasource"
+	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+)
+
+// syncSource 为内存中的消息库，每个 store 的消息按会话、序号排列
+type syncSource struct {
+	datasource.DataSource
+	messages map[string][]*model.Message
+	reads    int
+}
+
+func (s *syncSource) ListTalkers(context.Context) ([]string, error) {
+	return []string{"wxid_a", "wxid_b"}, nil
+}
+
+func (s *syncSource) IterateMessages(context.Context, []string, func(*model.Message) error) error {
+	return nil
+}
+
+func (s *syncSource) IterateStoreMessages(_ context.Context, store *msgstore.Store, talker string, afterSeq int64, fn func(*model.Message) error) error {
+	s.reads++
+	for _, msg := range s.messages[store.ID] {
+		if msg.Talker == talker && msg.Seq > afterSeq {
+			if err := fn(msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *syncSource) StoreMaxSeq(_ context.Context, store *msgstore.Store, talker string) (int64, error) {
+	var seq int64
+	for _, msg := range s.messages[store.ID] {
+		if msg.Talker == talker && msg.Seq > seq {
+			seq = msg.Seq
+		}
+	}
+	return seq, nil
+}
+
+// 追加、截断后重新同步，索引中的消息应与消息库完全一致，没有变化的 store 不再读取
+func TestSyncStores(t *testing.T) {
+	dir := t.TempDir()
+	idx, err := indexer.Open(filepath.Join(dir, "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	store := &msgstore.Store{ID: "message_0", FilePath: filepath.Join(dir, "message_0.db")}
+	src := &syncSource{messages: map[string][]*model.Message{}}
+	r := &Repository{ds: src, index: idx}
+
+	// setSource 将消息库替换为序号 1..n 的消息，并按 size 改写文件以模拟追加或截断
+	mtime := time.Unix(1700000000, 0)
+	setSource := func(n int, content string, size int) {
+		messages := make([]*model.Message, 0, n)
+		for seq := 1; seq <= n; seq++ {
+			messages = append(messages, &model.Message{
+				Version: model.WeChatV4,
+				Seq:     int64(seq),
+				Time:    time.Unix(1700000000+int64(seq), 0),
+				Talker:  fmt.Sprintf("wxid_%c", 'a'+seq%2),
+				Sender:  "wxid_sender",
+				Type:    model.MessageTypeText,
+				Content: fmt.Sprintf("%s %d", content, seq),
+			})
+		}
+		src.messages[store.ID] = messages
+		if err := os.WriteFile(store.FilePath, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mtime = mtime.Add(time.Second)
+		if err := os.Chtimes(store.FilePath, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sync := func() {
+		t.Helper()
+		if _, err := r.syncStores(context.Background(), src, []*msgstore.Store{store}, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(n int, content string) {
+		t.Helper()
+		req := &model.SearchRequest{}
+		seen := make([]int, 0, n)
+		for {
+			result, err := idx.Search(req, &indexer.Filter{Talkers: []string{"wxid_a", "wxid_b"}}, 0, 200)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, hit := range result.Hits {
+				if want := fmt.Sprintf("%s %d", content, hit.Message.Seq); hit.Message.Content != want {
+					t.Errorf("seq %d content = %q, want %q", hit.Message.Seq, hit.Message.Content, want)
+				}
+				seen = append(seen, int(hit.Message.Seq))
+			}
+			if result.NextCursor == "" {
+				break
+			}
+			req.Cursor = result.NextCursor
+		}
+		sort.Ints(seen)
+		if len(seen) != n {
+			t.Fatalf("indexed %d messages, want %d", len(seen), n)
+		}
+		for i, seq := range seen {
+			if seq != i+1 {
+				t.Fatalf("indexed seq %v, want 1..%d without duplicates or gaps", seen, n)
+			}
+		}
+	}
+
+	setSource(300, "first", 4096)
+	sync()
+	check(300, "first")
+
+	setSource(450, "first", 8192)
+	sync()
+	check(450, "first")
+
+	reads := src.reads
+	sync()
+	if src.reads != reads {
+		t.Errorf("unchanged store read %d more times", src.reads-reads)
+	}
+
+	// 消息库被替换为更小的库，应清空该 store 的索引后重新读取
+	setSource(120, "second", 2048)
+	sync()
+	check(120, "second")
+
+	setSource(160, "second", 2048)
+	sync()
+	check(160, "second")
+}
+
+// 只给出 after:/before: 的查询按时间范围列出消息，不视为空查询
+func TestSearchableTimeRange(t *testing.T) {
+	for query, want := range map[string]bool{
+		"after:2024-03-01":                   true,
+		"before:2024-03-01":                  true,
+		"after:2024-03-01 before:2024-04-01": true,
+		"":                                   false,
+	} {
+		req := &model.SearchRequest{Query: query}
+		if err := req.ExpandOperators(); err != nil {
+			t.Fatal(err)
+		}
+		filter, err := searchFilter(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := searchable(req, filter); got != want {
+			t.Errorf("searchable(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
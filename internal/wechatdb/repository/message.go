@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ysy950803/chatlog/internal/errors"
 	"github.com/ysy950803/chatlog/internal/model"
 	"github.com/ysy950803/chatlog/pkg/util"
 
@@ -12,10 +13,13 @@ import (
 )
 
 // GetMessages 实现 Repository 接口的 GetMessages 方法
-func (r *Repository) GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
+func (r *Repository) GetMessages(ctx context.Context, startTime, endTime time.Time, talker string, sender string, keyword string, msgType string, limit, offset int) ([]*model.Message, error) {
 
-	talker, sender = r.parseTalkerAndSender(ctx, talker, sender)
-	messages, err := r.ds.GetMessages(ctx, startTime, endTime, talker, sender, keyword, limit, offset)
+	talker, sender, err := r.parseTalkerAndSender(ctx, talker, sender)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := r.ds.GetMessages(ctx, startTime, endTime, talker, sender, keyword, msgType, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -28,16 +32,100 @@ func (r *Repository) GetMessages(ctx context.Context, startTime, endTime time.Ti
 	return messages, nil
 }
 
+// GetMessageContext 返回会话中序号为 seq 的消息及其前后各若干条消息
+// 优先从全文索引读取，索引中还没有该消息时（如刚同步的新消息）从数据源读取
+func (r *Repository) GetMessageContext(ctx context.Context, talker string, seq int64, before, after int) (*model.MessageContext, error) {
+	talker, err := r.resolveTalker(ctx, strings.TrimSpace(talker))
+	if err != nil {
+		return nil, err
+	}
+	before = max(0, min(before, model.MaxMessageContext))
+	after = max(0, min(after, model.MaxMessageContext))
+
+	var ret *model.MessageContext
+	if status := r.indexStatusSnapshot(); status != nil && status.Ready {
+		ret, err = r.messageContextFromIndex(talker, seq, before, after)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Int64("seq", seq).Msg("load message context from index failed")
+		}
+	}
+	if ret == nil {
+		ret, err = r.messageContextFromSource(ctx, talker, seq, before, after)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ret == nil {
+		return nil, errors.MessageNotFound(talker, seq)
+	}
+
+	messages := append([]*model.Message{ret.Message}, ret.Before...)
+	messages = append(messages, ret.After...)
+	r.hydrateMessages(ctx, messages)
+	r.EnrichMessages(ctx, messages)
+	return ret, nil
+}
+
+func (r *Repository) messageContextFromIndex(talker string, seq int64, before, after int) (*model.MessageContext, error) {
+	msg, err := r.index.Message(talker, seq)
+	if err != nil || msg == nil {
+		return nil, err
+	}
+	prev, next, err := r.index.Neighbors(talker, seq, before, after)
+	if err != nil {
+		return nil, err
+	}
+	return &model.MessageContext{Message: msg, Before: prev, After: next}, nil
+}
+
+// messageSourceSpans 为从数据源读取上下文时依次尝试的时间窗口，以消息时间为中心
+var messageSourceSpans = []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour, 365 * 24 * time.Hour}
+
+// messageContextFromSource 按 seq 中的时间戳从数据源读取消息，上下文不足时逐步扩大时间窗口
+func (r *Repository) messageContextFromSource(ctx context.Context, talker string, seq int64, before, after int) (*model.MessageContext, error) {
+	t := time.Unix(seq/1000, 0)
+	for i, span := range messageSourceSpans {
+		messages, err := r.ds.GetMessages(ctx, t.Add(-span), t.Add(span), talker, "", "", "", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		idx := -1
+		for j, m := range messages {
+			if m != nil && m.Seq == seq {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, nil
+		}
+		if (idx < before || len(messages)-idx-1 < after) && i < len(messageSourceSpans)-1 {
+			continue
+		}
+		return &model.MessageContext{
+			Message: messages[idx],
+			Before:  messages[max(0, idx-before):idx],
+			After:   messages[idx+1 : min(len(messages), idx+1+after)],
+		}, nil
+	}
+	return nil, nil
+}
+
 // EnrichMessages 补充消息的额外信息
+// 引用的原消息按会话批量查找，不在每条消息上单独查询数据源
 func (r *Repository) EnrichMessages(ctx context.Context, messages []*model.Message) error {
+	r.resolveQuotes(ctx, messages)
 	for _, msg := range messages {
 		r.enrichMessage(msg)
 	}
-	return nil
+	return ctx.Err()
 }
 
-// enrichMessage 补充单条消息的额外信息
+// enrichMessage 补充单条消息的额外信息，不包括需要查询数据源的引用原消息
 func (r *Repository) enrichMessage(msg *model.Message) {
+	r.attachTranscript(msg)
+	msg.SetPermalink("")
+
 	// 处理群聊消息
 	if msg.IsChatRoom {
 		// 补充群聊名称
@@ -60,18 +148,24 @@ func (r *Repository) enrichMessage(msg *model.Message) {
 	}
 }
 
-func (r *Repository) parseTalkerAndSender(ctx context.Context, talker, sender string) (string, string) {
+// ResolveTalkerAndSender 将 talker/sender 中的名称解析为 userName，用于订阅新消息等在读取之外过滤消息的场景
+func (r *Repository) ResolveTalkerAndSender(ctx context.Context, talker, sender string) (string, string, error) {
+	return r.parseTalkerAndSender(ctx, talker, sender)
+}
+
+// parseTalkerAndSender 将 talker/sender 中的名称解析为 userName，talker 有歧义时返回错误
+func (r *Repository) parseTalkerAndSender(ctx context.Context, talker, sender string) (string, string, error) {
 	displayName2User := make(map[string]string)
 	users := make(map[string]bool)
 
 	talkers := util.Str2List(talker, ",")
 	if len(talkers) > 0 {
 		for i := 0; i < len(talkers); i++ {
-			if contact, _ := r.GetContact(ctx, talkers[i]); contact != nil {
-				talkers[i] = contact.UserName
-			} else if chatRoom, _ := r.GetChatRoom(ctx, talker); chatRoom != nil {
-				talkers[i] = chatRoom.Name
+			userName, err := r.resolveTalker(ctx, talkers[i])
+			if err != nil {
+				return "", "", err
 			}
+			talkers[i] = userName
 		}
 		// 获取群聊的用户列表
 		for i := 0; i < len(talkers); i++ {
@@ -94,18 +188,26 @@ func (r *Repository) parseTalkerAndSender(ctx context.Context, talker, sender st
 				senders[i] = user
 			} else {
 				// FIXME 大量群聊用户名称重复，无法直接通过 GetContact 获取 ID，后续再优化
+				found := false
 				for user := range users {
 					if contact := r.getFullContact(user); contact != nil {
 						if contact.DisplayName() == senders[i] {
 							senders[i] = user
+							found = true
 							break
 						}
 					}
 				}
+				// 未限定会话时（如 from: 操作符），回退到联系人缓存；已是 userName 的保持不变
+				if !found && !users[senders[i]] && r.getFullContact(senders[i]) == nil {
+					if contact, _ := r.GetContact(ctx, senders[i]); contact != nil {
+						senders[i] = contact.UserName
+					}
+				}
 			}
 		}
 		sender = strings.Join(senders, ",")
 	}
 
-	return talker, sender
+	return talker, sender, nil
 }
@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/pkg/util/pinyin"
+)
+
+// 参与匹配的名称字段
+const (
+	fieldRemark   = "remark"
+	fieldNickName = "nickName"
+	fieldAlias    = "alias"
+	fieldUserName = "userName"
+)
+
+// fieldWeight 为同一匹配方式下各字段的先后次序，备注优先
+var fieldWeight = map[string]int{
+	fieldRemark:   4,
+	fieldNickName: 3,
+	fieldAlias:    2,
+	fieldUserName: 1,
+}
+
+// matchScore 为各匹配方式的基础分，拼音只在关键词为至少两个字母时参与匹配
+var matchScore = map[string]int{
+	model.MatchExact:          100,
+	model.MatchPrefix:         80,
+	model.MatchPinyin:         70,
+	model.MatchInitials:       65,
+	model.MatchContains:       60,
+	model.MatchPinyinPrefix:   50,
+	model.MatchInitialsPrefix: 40,
+}
+
+// nameField 为一个名称字段及其小写、全拼与首字母形式
+type nameField struct {
+	kind     string
+	text     string
+	lower    string
+	full     string
+	initials string
+}
+
+// nameEntry 为联系人或群聊在名称索引中的条目
+type nameEntry struct {
+	userName string
+	friend   bool
+	fields   []nameField
+}
+
+// add 添加名称字段，优先使用微信记录的拼音，缺失时由汉字生成
+func (e *nameEntry) add(kind, text, full, initials string) {
+	if text == "" {
+		return
+	}
+	f := nameField{kind: kind, text: text, lower: strings.ToLower(text)}
+	if pinyin.HasHan(text) {
+		f.full, f.initials = normalizePinyin(full), normalizePinyin(initials)
+		if f.full == "" || f.initials == "" {
+			f.full, f.initials = pinyin.Convert(text)
+		}
+	}
+	e.fields = append(e.fields, f)
+}
+
+func newContactEntry(contact *model.Contact) *nameEntry {
+	e := &nameEntry{userName: contact.UserName, friend: contact.IsFriend}
+	e.add(fieldRemark, contact.Remark, contact.RemarkQuanPin, contact.RemarkPYInitial)
+	e.add(fieldNickName, contact.NickName, contact.QuanPin, contact.PYInitial)
+	e.add(fieldAlias, contact.Alias, "", "")
+	e.add(fieldUserName, contact.UserName, "", "")
+	return e
+}
+
+// newChatRoomEntry 创建群聊条目，群聊在联系人表中有记录时沿用其拼音
+func newChatRoomEntry(chatRoom *model.ChatRoom, contact *model.Contact) *nameEntry {
+	e := &nameEntry{userName: chatRoom.Name, friend: true}
+	if contact == nil {
+		contact = &model.Contact{}
+	}
+	e.add(fieldRemark, chatRoom.Remark, contact.RemarkQuanPin, contact.RemarkPYInitial)
+	e.add(fieldNickName, chatRoom.NickName, contact.QuanPin, contact.PYInitial)
+	e.add(fieldUserName, chatRoom.Name, "", "")
+	return e
+}
+
+// nameMatch 为一个条目的最佳匹配
+type nameMatch struct {
+	entry     *nameEntry
+	field     *nameField
+	matchType string
+	score     int
+}
+
+// matchNames 按匹配程度返回与 key 匹配的条目，同分时好友在前、名称较短的在前
+func matchNames(entries []*nameEntry, key string) []nameMatch {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil
+	}
+	lowerKey := strings.ToLower(key)
+	pinyinKey := pinyinQuery(key)
+
+	var ret []nameMatch
+	for _, e := range entries {
+		best := nameMatch{}
+		for i := range e.fields {
+			f := &e.fields[i]
+			matchType := matchField(f, lowerKey, pinyinKey)
+			if matchType == "" {
+				continue
+			}
+			score := matchScore[matchType]*10 + fieldWeight[f.kind]
+			if score > best.score {
+				best = nameMatch{entry: e, field: f, matchType: matchType, score: score}
+			}
+		}
+		if best.entry != nil {
+			ret = append(ret, best)
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.entry.friend != b.entry.friend {
+			return a.entry.friend
+		}
+		if len(a.field.text) != len(b.field.text) {
+			return len(a.field.text) < len(b.field.text)
+		}
+		return a.entry.userName < b.entry.userName
+	})
+	return ret
+}
+
+func matchField(f *nameField, lowerKey, pinyinKey string) string {
+	switch {
+	case f.lower == lowerKey:
+		return model.MatchExact
+	case f.kind == fieldUserName:
+		// userName 只做精确匹配
+		return ""
+	case strings.HasPrefix(f.lower, lowerKey):
+		return model.MatchPrefix
+	}
+	if pinyinKey != "" && f.full != "" {
+		switch {
+		case f.full == pinyinKey:
+			return model.MatchPinyin
+		case f.initials == pinyinKey:
+			return model.MatchInitials
+		}
+	}
+	if strings.Contains(f.lower, lowerKey) {
+		return model.MatchContains
+	}
+	if pinyinKey != "" && f.full != "" {
+		switch {
+		case strings.HasPrefix(f.full, pinyinKey):
+			return model.MatchPinyinPrefix
+		case strings.HasPrefix(f.initials, pinyinKey):
+			return model.MatchInitialsPrefix
+		}
+	}
+	return ""
+}
+
+// pinyinQuery 返回用于拼音匹配的关键词，只接受至少两个字母（可含空格或分隔符 '）
+func pinyinQuery(key string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(key) {
+		switch {
+		case c >= 'a' && c <= 'z':
+			b.WriteRune(c)
+		case c == ' ' || c == '\'':
+		default:
+			return ""
+		}
+	}
+	if b.Len() < 2 {
+		return ""
+	}
+	return b.String()
+}
+
+// normalizePinyin 将微信记录的拼音转为小写并去掉字母数字以外的字符
+func normalizePinyin(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// findCandidates 按名称在联系人与群聊中查找候选项，按匹配程度排序
+func (r *Repository) findCandidates(key string, limit int) []*model.NameCandidate {
+	matches := matchNames(r.chatRoomNames, key)
+	for _, m := range matchNames(r.contactNames, key) {
+		// 群聊同时出现在联系人表中，以群聊条目为准
+		if _, ok := r.chatRoomCache[m.entry.userName]; ok {
+			continue
+		}
+		matches = append(matches, m)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.entry.friend != b.entry.friend {
+			return a.entry.friend
+		}
+		return len(a.field.text) < len(b.field.text)
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	ret := make([]*model.NameCandidate, 0, len(matches))
+	for _, m := range matches {
+		c := &model.NameCandidate{
+			UserName:     m.entry.userName,
+			IsFriend:     m.entry.friend,
+			MatchedField: m.field.kind,
+			MatchType:    m.matchType,
+			Score:        m.score,
+		}
+		if chatRoom, ok := r.chatRoomCache[m.entry.userName]; ok {
+			c.Kind = model.CandidateChatRoom
+			c.Remark = chatRoom.Remark
+			c.NickName = chatRoom.NickName
+			c.DisplayName = chatRoom.DisplayName()
+		} else if contact, ok := r.contactCache[m.entry.userName]; ok {
+			c.Kind = model.CandidateContact
+			c.Remark = contact.Remark
+			c.NickName = contact.NickName
+			c.Alias = contact.Alias
+			c.DisplayName = contact.DisplayName()
+		}
+		if c.DisplayName == "" {
+			c.DisplayName = c.UserName
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// maxAmbiguousCandidates 为歧义错误中最多列出的候选项数
+const maxAmbiguousCandidates = 10
+
+// ResolveTalker 解析会话名称，返回按匹配程度与最近活跃时间排序的候选项
+// all 为 true 时只列出候选项，不判断能否唯一确定会话
+func (r *Repository) ResolveTalker(ctx context.Context, name string, limit int, all bool) (*model.TalkerResolution, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.InvalidArg("name")
+	}
+	ret := &model.TalkerResolution{Name: name, Candidates: r.findCandidates(name, 0)}
+	r.fillLastActive(ctx, ret.Candidates)
+	if !all {
+		if picked, ambiguous := pickTalker(name, ret.Candidates); picked != nil {
+			ret.UserName = picked.UserName
+		} else {
+			ret.Ambiguous = len(ambiguous) > 0
+		}
+	}
+	if limit > 0 && len(ret.Candidates) > limit {
+		ret.Candidates = ret.Candidates[:limit]
+	}
+	return ret, nil
+}
+
+// resolveTalker 将会话名称解析为 userName，匹配到多个同等程度的候选项时返回歧义错误
+// 未匹配到任何联系人或群聊时原样返回
+func (r *Repository) resolveTalker(ctx context.Context, key string) (string, error) {
+	if _, ok := r.contactCache[key]; ok {
+		return key, nil
+	}
+	if _, ok := r.chatRoomCache[key]; ok {
+		return key, nil
+	}
+	candidates := r.findCandidates(key, 0)
+	if len(candidates) == 0 {
+		return key, nil
+	}
+	picked, ambiguous := pickTalker(key, candidates)
+	if picked != nil {
+		return picked.UserName, nil
+	}
+
+	r.fillLastActive(ctx, ambiguous)
+	if len(ambiguous) > maxAmbiguousCandidates {
+		ambiguous = ambiguous[:maxAmbiguousCandidates]
+	}
+	summary := make([]string, 0, len(ambiguous))
+	for _, c := range ambiguous {
+		summary = append(summary, fmt.Sprintf("%s (%s, %s)", c.UserName, c.DisplayName, c.Kind))
+	}
+	return "", errors.AmbiguousTalker(key, summary, ambiguous)
+}
+
+// pickTalker 在已排序的候选项中选出唯一的会话
+// 匹配方式最好的一档只有一个候选项时选中它；该档中既有好友又有非好友群成员时只看好友
+// 否则返回该档的全部候选项作为歧义列表
+func pickTalker(key string, candidates []*model.NameCandidate) (*model.NameCandidate, []*model.NameCandidate) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	if candidates[0].UserName == key {
+		return candidates[0], nil
+	}
+	best := candidates[0].MatchType
+	top := make([]*model.NameCandidate, 0)
+	friends := 0
+	for _, c := range candidates {
+		if c.MatchType != best {
+			continue
+		}
+		top = append(top, c)
+		if c.IsFriend {
+			friends++
+		}
+	}
+	if friends > 0 && friends < len(top) {
+		filtered := top[:0]
+		for _, c := range top {
+			if c.IsFriend {
+				filtered = append(filtered, c)
+			}
+		}
+		top = filtered
+	}
+	if len(top) == 1 {
+		return top[0], nil
+	}
+	return nil, top
+}
+
+// fillLastActive 从会话列表补充候选项的最近活跃时间，同分的候选项中最近活跃的在前
+func (r *Repository) fillLastActive(ctx context.Context, candidates []*model.NameCandidate) {
+	if len(candidates) == 0 {
+		return
+	}
+	sessions, err := r.ds.GetSessions(ctx, "", 0, 0)
+	if err != nil {
+		log.Debug().Err(err).Msg("load sessions for talker candidates failed")
+		return
+	}
+	lastActive := make(map[string]time.Time, len(sessions))
+	for _, session := range sessions {
+		lastActive[session.UserName] = session.NTime
+	}
+	for _, c := range candidates {
+		if t, ok := lastActive[c.UserName]; ok && !t.IsZero() {
+			c.LastActive = &t
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.IsFriend != b.IsFriend {
+			return a.IsFriend
+		}
+		if a.LastActive == nil || b.LastActive == nil {
+			return a.LastActive != nil && b.LastActive == nil
+		}
+		return a.LastActive.After(*b.LastActive)
+	})
+}
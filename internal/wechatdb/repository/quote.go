@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// quoteSpan 为从数据源批量读取被引用消息时合并到同一次查询的最大时间跨度（秒）
+const quoteSpan = 60
+
+// resolveQuotes 在引用消息所在会话中查找被引用的原消息，找到时记录其序号与固定链接
+// refermsg 中的 svrid 不在消息表的读取范围内，按发送时间（秒）、类型与发送者匹配
+// 同一会话的引用一起查找：索引可用时一次查询全部被引用的秒，其余的按相近时间合并后从数据源读取
+func (r *Repository) resolveQuotes(ctx context.Context, messages []*model.Message) {
+	pending := make(map[string][]*model.Message)
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		q := msg.Quote()
+		if q == nil || q.Resolved() || msg.Talker == "" || q.CreateTime.Unix() <= 0 {
+			continue
+		}
+		pending[msg.Talker] = append(pending[msg.Talker], msg)
+	}
+
+	for talker, quoting := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		seconds := make([]int64, 0, len(quoting))
+		for _, msg := range quoting {
+			seconds = append(seconds, msg.Quote().CreateTime.Unix())
+		}
+		candidates := r.quotedCandidates(ctx, talker, seconds)
+		for _, msg := range quoting {
+			q := msg.Quote()
+			if orig := pickQuoted(q, candidates[q.CreateTime.Unix()], msg.Seq); orig != nil {
+				q.Resolve(orig)
+			}
+		}
+	}
+}
+
+// quotedCandidates 返回会话中在给定各秒内发送的消息，按秒分组
+func (r *Repository) quotedCandidates(ctx context.Context, talker string, seconds []int64) map[int64][]*model.Message {
+	seconds = slices.Compact(slices.Sorted(slices.Values(seconds)))
+	found := make(map[int64][]*model.Message, len(seconds))
+
+	if status := r.indexStatusSnapshot(); status != nil && status.Ready {
+		messages, err := r.index.MessagesAt(ctx, talker, seconds)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Msg("load quoted messages from index failed")
+		}
+		for _, m := range messages {
+			found[m.Time.Unix()] = append(found[m.Time.Unix()], m)
+		}
+	}
+
+	// 索引中没有的（如刚收到、还未同步的消息）从数据源读取，相近的秒合并为一次查询
+	missing := slices.DeleteFunc(seconds, func(sec int64) bool { return len(found[sec]) > 0 })
+	for len(missing) > 0 {
+		end := 1
+		for end < len(missing) && missing[end]-missing[0] <= quoteSpan {
+			end++
+		}
+		start, last := time.Unix(missing[0], 0), time.Unix(missing[end-1], 0)
+		messages, err := r.ds.GetMessages(ctx, start, last, talker, "", "", "", 0, 0)
+		if err != nil {
+			log.Debug().Err(err).Str("talker", talker).Msg("load quoted messages failed")
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		for _, m := range messages {
+			if m != nil && slices.Contains(missing[:end], m.Time.Unix()) {
+				found[m.Time.Unix()] = append(found[m.Time.Unix()], m)
+			}
+		}
+		missing = missing[end:]
+	}
+	return found
+}
+
+// pickQuoted 从同一秒内的消息中选出被引用的消息，有服务端消息 ID 的候选只按 ID 精确匹配
+// 缺少 ID 的候选（如 v3 的旧数据）按推断：发送者一致的优先；发送者都不一致时（如自己发送的消息记录的发送者不同），同类型的消息唯一才采用
+func pickQuoted(q *model.QuoteRef, candidates []*model.Message, self int64) *model.Message {
+	var typed []*model.Message
+	for _, m := range candidates {
+		if m == nil || m.Seq == self {
+			continue
+		}
+		if same, known := q.SameServerID(m); known {
+			if same {
+				return m
+			}
+			continue
+		}
+		if m.Time.Unix() != q.CreateTime.Unix() {
+			continue
+		}
+		if q.Matches(m) {
+			return m
+		}
+		if q.Type == 0 || m.Type == q.Type {
+			typed = append(typed, m)
+		}
+	}
+	if len(typed) == 1 {
+		return typed[0]
+	}
+	return nil
+}
+
+// GetReplyThread 返回会话中序号为 seq 的消息所在的引用回复链
+// 向上逐级查找被引用的原消息，向下在该消息之后 window 内查找直接或间接引用它的回复
+func (r *Repository) GetReplyThread(ctx context.Context, talker string, seq int64, window time.Duration) (*model.ReplyThread, error) {
+	mc, err := r.GetMessageContext(ctx, talker, seq, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	msg := mc.Message
+	thread := &model.ReplyThread{Message: msg, Ancestors: []*model.Message{}, Replies: []*model.ReplyNode{}}
+
+	seen := map[int64]bool{msg.Seq: true}
+	for cur := msg; len(thread.Ancestors) < model.MaxReplyDepth; {
+		q := cur.Quote()
+		if !q.Resolved() || seen[q.Seq] {
+			break
+		}
+		parent, err := r.loadMessage(ctx, cur.Talker, q.Seq, q.CreateTime)
+		if err != nil || parent == nil {
+			break
+		}
+		r.EnrichMessages(ctx, []*model.Message{parent})
+		seen[parent.Seq] = true
+		thread.Ancestors = append([]*model.Message{parent}, thread.Ancestors...)
+		cur = parent
+	}
+
+	root := &model.ReplyNode{Message: msg}
+	nodes := map[int64]*model.ReplyNode{msg.Seq: root}
+	_, err = r.StreamMessages(ctx, msg.Time, msg.Time.Add(window), msg.Talker, "", "", "quote", model.MessageCursor{Seq: msg.Seq}, 0, func(m *model.Message) error {
+		q := m.Quote()
+		if !q.Resolved() {
+			return nil
+		}
+		parent, ok := nodes[q.Seq]
+		if !ok {
+			return nil
+		}
+		node := &model.ReplyNode{Message: m}
+		parent.Replies = append(parent.Replies, node)
+		nodes[m.Seq] = node
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if root.Replies != nil {
+		thread.Replies = root.Replies
+	}
+	return thread, nil
+}
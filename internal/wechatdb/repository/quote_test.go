@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/datasource"
+)
+
+// messageSource 为内存中的消息表，GetMessages 每次返回新的副本，与真实数据源一致
+type messageSource struct {
+	datasource.DataSource
+	messages []*model.Message
+	calls    int
+}
+
+func (s *messageSource) GetMessages(_ context.Context, start, end time.Time, talker, _, _, _ string, _, _ int) ([]*model.Message, error) {
+	s.calls++
+	var ret []*model.Message
+	for _, m := range s.messages {
+		if m.Talker != talker || m.Time.Before(start) || m.Time.After(end) {
+			continue
+		}
+		copied := *m
+		if q := m.Quote(); q != nil {
+			qc := *q
+			copied.Contents = map[string]interface{}{"quote": &qc}
+		}
+		ret = append(ret, &copied)
+	}
+	return ret, nil
+}
+
+var quoteBase = time.Unix(1700000000, 0)
+
+func textMessage(sec int64, sender, content string) *model.Message {
+	return &model.Message{
+		Seq:     (quoteBase.Unix() + sec) * 1000,
+		Time:    quoteBase.Add(time.Duration(sec) * time.Second),
+		Talker:  "wxid_a",
+		Sender:  sender,
+		Type:    model.MessageTypeText,
+		Content: content,
+	}
+}
+
+func quoteMessage(sec int64, sender string, orig *model.Message) *model.Message {
+	m := textMessage(sec, sender, "回复")
+	m.Type = model.MessageTypeShare
+	m.SubType = model.MessageSubTypeQuote
+	m.Contents = map[string]interface{}{"quote": &model.QuoteRef{Sender: orig.Sender, Type: orig.Type, CreateTime: orig.Time}}
+	return m
+}
+
+func TestPickQuoted(t *testing.T) {
+	a := textMessage(0, "alice", "a")
+	b := textMessage(0, "bob", "b")
+	b.Seq++
+	img := textMessage(0, "carol", "")
+	img.Seq += 2
+	img.Type = model.MessageTypeImage
+	later := textMessage(1, "alice", "later")
+	// 带服务端消息 ID 的候选只按 ID 匹配
+	first := textMessage(0, "alice", "first")
+	first.ServerID = 101
+	second := textMessage(0, "alice", "second")
+	second.Seq++
+	second.ServerID = -2
+
+	tests := []struct {
+		name       string
+		q          *model.QuoteRef
+		candidates []*model.Message
+		self       int64
+		want       *model.Message
+	}{
+		{"sender match", &model.QuoteRef{Sender: "bob", Type: model.MessageTypeText, CreateTime: a.Time}, []*model.Message{a, b, img}, 0, b},
+		{"unique type", &model.QuoteRef{Sender: "dave", Type: model.MessageTypeImage, CreateTime: a.Time}, []*model.Message{a, b, img}, 0, img},
+		{"ambiguous type", &model.QuoteRef{Sender: "dave", Type: model.MessageTypeText, CreateTime: a.Time}, []*model.Message{a, b, img}, 0, nil},
+		{"skip self", &model.QuoteRef{Sender: "alice", Type: model.MessageTypeText, CreateTime: a.Time}, []*model.Message{a}, a.Seq, nil},
+		{"other second", &model.QuoteRef{Sender: "alice", Type: model.MessageTypeText, CreateTime: a.Time}, []*model.Message{later}, 0, nil},
+		{"server id", &model.QuoteRef{SvrID: "18446744073709551614", Sender: "alice", Type: model.MessageTypeText, CreateTime: a.Time}, []*model.Message{first, second}, 0, second},
+		{"server id mismatch", &model.QuoteRef{SvrID: "999", Sender: "alice", Type: model.MessageTypeText, CreateTime: a.Time}, []*model.Message{first, second}, 0, nil},
+	}
+	for _, tt := range tests {
+		if got := pickQuoted(tt.q, tt.candidates, tt.self); got != tt.want {
+			t.Errorf("%s: picked %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 同一批消息中相近时间的引用应合并为一次数据源查询
+func TestResolveQuotes(t *testing.T) {
+	m1 := textMessage(0, "alice", "早")
+	m2 := textMessage(5, "bob", "早上好")
+	src := &messageSource{messages: []*model.Message{m1, m2}}
+	r := &Repository{ds: src}
+
+	batch := []*model.Message{quoteMessage(10, "carol", m1), quoteMessage(11, "dave", m2), quoteMessage(12, "erin", m1)}
+	if err := r.EnrichMessages(context.Background(), batch); err != nil {
+		t.Fatal(err)
+	}
+	if src.calls != 1 {
+		t.Errorf("datasource queried %d times, want 1", src.calls)
+	}
+	for i, want := range []int64{m1.Seq, m2.Seq, m1.Seq} {
+		if got := batch[i].Quote().Seq; got != want {
+			t.Errorf("batch[%d] resolved to %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestGetReplyThread(t *testing.T) {
+	m1 := textMessage(0, "alice", "周五开会吗")
+	m2 := quoteMessage(10, "bob", m1)
+	m3 := quoteMessage(20, "alice", m2)
+	m4 := quoteMessage(30, "carol", m2)
+	m5 := textMessage(40, "dave", "无关")
+	m6 := quoteMessage(50, "bob", m3)
+	src := &messageSource{messages: []*model.Message{m1, m2, m3, m4, m5, m6}}
+	r := &Repository{ds: src}
+
+	thread, err := r.GetReplyThread(context.Background(), "wxid_a", m2.Seq, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thread.Message.Seq != m2.Seq {
+		t.Fatalf("thread message = %d, want %d", thread.Message.Seq, m2.Seq)
+	}
+	if len(thread.Ancestors) != 1 || thread.Ancestors[0].Seq != m1.Seq {
+		t.Errorf("ancestors = %v, want [%d]", thread.Ancestors, m1.Seq)
+	}
+	if len(thread.Replies) != 2 || thread.Replies[0].Message.Seq != m3.Seq || thread.Replies[1].Message.Seq != m4.Seq {
+		t.Fatalf("replies = %+v, want %d and %d", thread.Replies, m3.Seq, m4.Seq)
+	}
+	if nested := thread.Replies[0].Replies; len(nested) != 1 || nested[0].Message.Seq != m6.Seq {
+		t.Errorf("replies of %d = %+v, want [%d]", m3.Seq, nested, m6.Seq)
+	}
+	if len(thread.Replies[1].Replies) != 0 {
+		t.Errorf("replies of %d = %+v, want none", m4.Seq, thread.Replies[1].Replies)
+	}
+}
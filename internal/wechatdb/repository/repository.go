@@ -7,10 +7,13 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog/log"
 
+	"github.com/ysy950803/chatlog/internal/embedding"
 	"github.com/ysy950803/chatlog/internal/errors"
 	"github.com/ysy950803/chatlog/internal/model"
 	"github.com/ysy950803/chatlog/internal/wechatdb/datasource"
 	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
+	"github.com/ysy950803/chatlog/internal/wechatdb/savedsearch"
+	"github.com/ysy950803/chatlog/internal/wechatdb/transcript"
 )
 
 // Repository 实现了 repository.Repository 接口
@@ -18,12 +21,26 @@ type Repository struct {
 	ds datasource.DataSource
 
 	indexPath        string
+	deferIndex       bool
 	index            *indexer.Index
 	indexMu          sync.Mutex
 	indexStatus      model.SearchIndexStatus
 	indexFingerprint string
 	indexCtx         context.Context
 	indexCancel      context.CancelFunc
+	// 索引构建期间收到的待刷新消息，以 talker:seq 为键，构建完成后写入
+	pendingRefresh map[string]*model.Message
+
+	// 语音转写结果，补充到语音消息的 Contents["transcript"]
+	transcripts *transcript.Store
+
+	// 常用搜索，增量索引后对新消息执行
+	savedSearches *savedsearch.Store
+
+	// 语义检索的向量模型，为空时只支持关键词检索
+	embedder    embedding.Provider
+	embedStatus model.SemanticIndexStatus
+	embedAgain  bool
 
 	// Cache for contact
 	contactCache      map[string]*model.Contact
@@ -32,42 +49,52 @@ type Repository struct {
 	nickNameToContact map[string][]*model.Contact
 	chatRoomInContact map[string]*model.Contact
 	contactList       []string
-	aliasList         []string
-	remarkList        []string
-	nickNameList      []string
+	contactNames      []*nameEntry
 
 	// Cache for chat room
 	chatRoomCache      map[string]*model.ChatRoom
 	remarkToChatRoom   map[string][]*model.ChatRoom
 	nickNameToChatRoom map[string][]*model.ChatRoom
 	chatRoomList       []string
-	chatRoomRemark     []string
-	chatRoomNickName   []string
+	chatRoomNames      []*nameEntry
 
 	// 快速查找索引
 	chatRoomUserToInfo map[string]*model.Contact
 }
 
+// Options 为创建 Repository 的可选项
+type Options struct {
+	// IndexPath 为全文索引目录，留空时不启用全文索引
+	IndexPath string
+	// DeferIndex 为 true 时不在后台自动构建全文索引，供索引维护命令使用
+	DeferIndex bool
+	// Transcripts 为语音转写结果存储
+	Transcripts *transcript.Store
+	// SavedSearches 为常用搜索存储
+	SavedSearches *savedsearch.Store
+	// Embedder 为语义检索的向量模型，索引同步后为新消息生成向量
+	Embedder embedding.Provider
+}
+
 // New 创建一个新的 Repository
-func New(ds datasource.DataSource, indexPath string) (*Repository, error) {
+func New(ds datasource.DataSource, opts Options) (*Repository, error) {
 	r := &Repository{
 		ds:                 ds,
-		indexPath:          indexPath,
+		indexPath:          opts.IndexPath,
+		deferIndex:         opts.DeferIndex,
+		transcripts:        opts.Transcripts,
+		savedSearches:      opts.SavedSearches,
+		embedder:           opts.Embedder,
 		contactCache:       make(map[string]*model.Contact),
 		aliasToContact:     make(map[string][]*model.Contact),
 		remarkToContact:    make(map[string][]*model.Contact),
 		nickNameToContact:  make(map[string][]*model.Contact),
 		chatRoomUserToInfo: make(map[string]*model.Contact),
 		contactList:        make([]string, 0),
-		aliasList:          make([]string, 0),
-		remarkList:         make([]string, 0),
-		nickNameList:       make([]string, 0),
 		chatRoomCache:      make(map[string]*model.ChatRoom),
 		remarkToChatRoom:   make(map[string][]*model.ChatRoom),
 		nickNameToChatRoom: make(map[string][]*model.ChatRoom),
 		chatRoomList:       make([]string, 0),
-		chatRoomRemark:     make([]string, 0),
-		chatRoomNickName:   make([]string, 0),
 	}
 
 	// 初始化缓存
@@ -135,6 +162,13 @@ func (r *Repository) Close() error {
 		r.index = nil
 	}
 
+	if r.transcripts != nil {
+		if err := r.transcripts.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		r.transcripts = nil
+	}
+
 	if err := r.ds.Close(); err != nil && firstErr == nil {
 		firstErr = err
 	}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
+	"github.com/ysy950803/chatlog/internal/wechatdb/savedsearch"
+)
+
+// savedSearchPageSize 为执行常用搜索时每页读取的命中数，每页发送一次通知
+const savedSearchPageSize = 200
+
+// evaluateSavedSearches 在索引同步后执行全部常用搜索，latest 为本次写入的最新消息位置
+func (r *Repository) evaluateSavedSearches(ctx context.Context, latest model.MessageMarker) {
+	if r.savedSearches == nil || r.index == nil {
+		return
+	}
+
+	marks, err := r.index.StoreMarks()
+	if err != nil {
+		log.Warn().Err(err).Msg("read index marks for saved searches failed")
+		return
+	}
+	for _, ss := range r.savedSearches.List() {
+		if ss.Disabled || ss.Request == nil || !savedSearchPending(ss, marks) {
+			continue
+		}
+		if err := r.evaluateSavedSearch(ctx, ss, marks, latest); err != nil {
+			log.Warn().Err(err).Str("saved_search", ss.Name).Msg("evaluate saved search failed")
+		}
+	}
+}
+
+// savedSearchPending 判断是否有消息库写入了常用搜索尚未检查的消息
+func savedSearchPending(ss *model.SavedSearch, marks map[string]model.IndexMark) bool {
+	for id, mark := range marks {
+		if ss.Seen[id] != mark {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateSavedSearch 逐个消息库按写入索引的顺序逐页读取 Seen 之后的命中，直到读到 marks 记录的位置
+// 每页先将 Seen 推进到该页最后一条命中并持久化，再异步发送通知，保证每条命中只提醒一次；
+// 消息库没有对应的 Seen 或索引文件已重建时从头读取，只保留晚于 LastSeen 的命中
+func (r *Repository) evaluateSavedSearch(ctx context.Context, ss *model.SavedSearch, marks map[string]model.IndexMark, latest model.MessageMarker) error {
+	// 常用搜索只按关键词匹配新消息
+	req := ss.Request.Clone()
+	req.Mode = ""
+	req.Cursor = ""
+	req, err := r.normalizeSearchRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	filter, err := searchFilter(req)
+	if err != nil {
+		return err
+	}
+
+	stores := make([]string, 0, len(marks))
+	for id := range marks {
+		stores = append(stores, id)
+	}
+	sort.Strings(stores)
+
+	for _, id := range stores {
+		mark := marks[id]
+		seen, ok := ss.Seen[id]
+		if ok && seen == mark {
+			continue
+		}
+		after, floor := seen.RowID, model.MessageMarker{}
+		if !ok || seen.Gen != mark.Gen {
+			after, floor = 0, ss.LastSeen
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var (
+				found []*indexer.SearchHit
+				last  = mark.RowID
+				more  bool
+			)
+			if searchable(req, filter) {
+				found, last, more, err = r.index.SearchStoreAfter(req, filter, id, after, mark.RowID, floor, savedSearchPageSize)
+				if err != nil {
+					return err
+				}
+			}
+
+			hits := make([]*model.SearchHit, 0, len(found))
+			messages := make([]*model.Message, 0, len(found))
+			for _, hit := range found {
+				if hit == nil || hit.Message == nil {
+					continue
+				}
+				hits = append(hits, &model.SearchHit{Message: hit.Message, Snippet: hit.Snippet})
+				messages = append(messages, hit.Message)
+			}
+			if len(messages) > 0 {
+				r.hydrateMessages(ctx, messages)
+				if err := r.EnrichMessages(ctx, messages); err != nil {
+					log.Debug().Msgf("EnrichMessages in saved search failed: %v", err)
+				}
+			}
+
+			// 先持久化 Seen 再通知，保证重启后不会重复提醒
+			next := model.IndexMark{Gen: mark.Gen, RowID: last}
+			updated, err := r.savedSearches.Update(ss.ID, func(item *model.SavedSearch) {
+				if item.Seen == nil {
+					item.Seen = make(map[string]model.IndexMark)
+				}
+				item.Seen[id] = next
+				if len(hits) > 0 {
+					item.Matches += len(hits)
+					item.LastMatchedAt = time.Now()
+				}
+			})
+			if err != nil {
+				return err
+			}
+			if len(hits) > 0 {
+				r.notifySavedSearch(updated, hits)
+			}
+			if !more {
+				break
+			}
+			after = last
+		}
+	}
+
+	// 移除已不存在的消息库，LastSeen 推进到本次写入的最新位置，供之后新增或重建的消息库使用
+	_, err = r.savedSearches.Update(ss.ID, func(item *model.SavedSearch) {
+		for id := range item.Seen {
+			if _, ok := marks[id]; !ok {
+				delete(item.Seen, id)
+			}
+		}
+		if item.LastSeen.Before(latest) {
+			item.LastSeen = latest
+		}
+	})
+	return err
+}
+
+// notifySavedSearch 异步发送一页新命中的通知
+func (r *Repository) notifySavedSearch(ss *model.SavedSearch, hits []*model.SearchHit) {
+	log.Info().Str("saved_search", ss.Name).Int("hits", len(hits)).Msg("saved search matched new messages")
+	if ss.Notify == nil || ss.Notify.URL == "" {
+		return
+	}
+	alert := &model.SavedSearchAlert{
+		ID:     ss.ID,
+		Name:   ss.Name,
+		Query:  ss.Request.Query,
+		Length: len(hits),
+		Hits:   hits,
+	}
+	go func(target *model.SearchNotify) {
+		if err := savedsearch.Send(target, alert); err != nil {
+			log.Error().Err(err).Str("saved_search", alert.Name).Msg("send saved search notification failed")
+		}
+	}(ss.Notify)
+}
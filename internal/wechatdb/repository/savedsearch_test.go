@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+	"github.com/ysy950803/chatlog/internal/wechatdb/savedsearch"
+)
+
+// 新命中超过一页时应逐页通知，每条命中只提醒一次；之后才写入索引的较早消息同样提醒
+func TestEvaluateSavedSearches(t *testing.T) {
+	dir := t.TempDir()
+	idx, err := indexer.Open(filepath.Join(dir, "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	saved, err := savedsearch.Open(filepath.Join(dir, "saved_searches.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu       sync.Mutex
+		notified = make(map[string]int)
+		received = 0
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var alert model.SavedSearchAlert
+		if err := json.NewDecoder(req.Body).Decode(&alert); err != nil {
+			t.Errorf("decode alert: %v", err)
+		}
+		mu.Lock()
+		for _, hit := range alert.Hits {
+			notified[fmt.Sprintf("%s:%d", hit.Message.Talker, hit.Message.Seq)]++
+		}
+		received += len(alert.Hits)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	base := int64(1700000000)
+	ss, err := saved.Create(&model.SavedSearch{
+		Name:     "报价",
+		Request:  &model.SearchRequest{Query: "报价单"},
+		Notify:   &model.SearchNotify{URL: srv.URL},
+		LastSeen: model.MessageMarker{Unix: base - 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Repository{index: idx, savedSearches: saved}
+	stores := []*msgstore.Store{{ID: "message_0"}, {ID: "message_1"}}
+	next := 0
+	// 两个会话的消息两两共用同一 (unix, seq)，检查分页不会在并列处丢失命中
+	index := func(n int) model.MessageMarker {
+		var latest model.MessageMarker
+		for k := 0; k < n; k++ {
+			content := "报价单 %d"
+			if next%10 == 9 {
+				content = "晚上一起吃饭 %d"
+			}
+			msg := &model.Message{
+				Version: model.WeChatV4,
+				Seq:     int64(next/2 + 1),
+				Time:    time.Unix(base+int64(next/4), 0),
+				Talker:  fmt.Sprintf("wxid_%d", next%2),
+				Sender:  "wxid_sender",
+				Type:    model.MessageTypeText,
+				Content: fmt.Sprintf(content, next),
+			}
+			if err := idx.IndexStoreMessages(stores[next%2], []*model.Message{msg}); err != nil {
+				t.Fatal(err)
+			}
+			latest = model.MarkerOf(msg)
+			next++
+		}
+		return latest
+	}
+
+	ctx := context.Background()
+	r.evaluateSavedSearches(ctx, index(500))
+	latest := index(30)
+	r.evaluateSavedSearches(ctx, latest)
+
+	// 补写入一条时间早于 LastSeen 的消息
+	late := &model.Message{
+		Version: model.WeChatV4,
+		Seq:     10000,
+		Time:    time.Unix(base+1, 0),
+		Talker:  "wxid_0",
+		Sender:  "wxid_sender",
+		Type:    model.MessageTypeText,
+		Content: "报价单 补发",
+	}
+	if err := idx.IndexStoreMessages(stores[0], []*model.Message{late}); err != nil {
+		t.Fatal(err)
+	}
+	r.evaluateSavedSearches(ctx, model.MarkerOf(late))
+
+	want := 530 - 53 + 1
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := received
+		mu.Unlock()
+		if n >= want || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if received != want || len(notified) != want {
+		t.Errorf("notified %d hits (%d distinct), want %d", received, len(notified), want)
+	}
+	for doc, n := range notified {
+		if n != 1 {
+			t.Errorf("%s notified %d times", doc, n)
+		}
+	}
+
+	updated, err := saved.Get(ss.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Matches != want {
+		t.Errorf("matches = %d, want %d", updated.Matches, want)
+	}
+	if updated.LastSeen != latest {
+		t.Errorf("last seen = %+v, want %+v", updated.LastSeen, latest)
+	}
+}
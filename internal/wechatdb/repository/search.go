@@ -2,15 +2,58 @@ package repository
 
 import (
 	"context"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/ysy950803/chatlog/internal/errors"
 	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/indexer"
+	"github.com/ysy950803/chatlog/pkg/util"
 )
 
 // SearchMessages 执行全文检索，并在返回前补充联系人/群聊信息。
 func (r *Repository) SearchMessages(ctx context.Context, req *model.SearchRequest) (*model.SearchResponse, error) {
+	nReq, err := r.normalizeSearchRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.searchMessagesWithIndex(ctx, nReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		resp = &model.SearchResponse{Hits: []*model.SearchHit{}, Limit: nReq.Limit, Offset: nReq.Offset}
+	}
+
+	// Enrich message metadata（头像、群昵称、显示名等）
+	messages := make([]*model.Message, 0, len(resp.Hits)*(1+2*nReq.Context))
+	for _, hit := range resp.Hits {
+		if hit == nil || hit.Message == nil {
+			continue
+		}
+		messages = append(messages, hit.Message)
+		messages = append(messages, hit.Before...)
+		messages = append(messages, hit.After...)
+	}
+
+	if len(messages) > 0 {
+		r.hydrateMessages(ctx, messages)
+		if err := r.EnrichMessages(ctx, messages); err != nil {
+			log.Debug().Msgf("EnrichMessages in search failed: %v", err)
+		}
+	}
+
+	if resp.Facets != nil {
+		r.labelFacets(resp.Facets, util.Str2List(nReq.Talker, ","))
+	}
+
+	return resp, nil
+}
+
+// normalizeSearchRequest 展开检索操作符、解析会话/发送者别名并裁剪分页参数，返回新的请求
+func (r *Repository) normalizeSearchRequest(ctx context.Context, req *model.SearchRequest) (*model.SearchRequest, error) {
 	if req == nil {
 		return nil, errors.InvalidArg("request")
 	}
@@ -20,8 +63,36 @@ func (r *Repository) SearchMessages(ctx context.Context, req *model.SearchReques
 		nReq = &model.SearchRequest{}
 	}
 
+	// 展开 from:/in:/type: 等操作符，之后统一走别名解析
+	if err := nReq.ExpandOperators(); err != nil {
+		return nil, errors.InvalidQuery(err)
+	}
+	if _, err := model.ParseMessageTypeFilter(nReq.Types); err != nil {
+		return nil, errors.InvalidQuery(err)
+	}
+	if _, err := indexer.ParseCursor(nReq.Cursor); err != nil {
+		return nil, errors.InvalidArg("cursor")
+	}
+	mode, err := model.ParseSearchMode(nReq.Mode)
+	if err != nil {
+		return nil, errors.InvalidArg("mode")
+	}
+	nReq.Mode = mode
+	if nReq.Semantic() {
+		if r.embedder == nil {
+			return nil, errors.SemanticSearchDisabled()
+		}
+		if strings.TrimSpace(nReq.Query) == "" {
+			return nil, errors.InvalidArg("query")
+		}
+		nReq.Cursor = ""
+	}
+
 	// 兼容现有的联系人/群聊别名：在进入数据源前将 talker/sender 解析成真实 userName
-	normalizedTalker, normalizedSender := r.parseTalkerAndSender(ctx, nReq.Talker, nReq.Sender)
+	normalizedTalker, normalizedSender, err := r.parseTalkerAndSender(ctx, nReq.Talker, nReq.Sender)
+	if err != nil {
+		return nil, err
+	}
 	nReq.Talker = normalizedTalker
 	nReq.Sender = normalizedSender
 
@@ -34,29 +105,41 @@ func (r *Repository) SearchMessages(ctx context.Context, req *model.SearchReques
 	if nReq.Offset < 0 {
 		nReq.Offset = 0
 	}
-
-	resp, err := r.searchMessagesWithIndex(ctx, nReq)
-	if err != nil {
-		return nil, err
+	if nReq.Context < 0 {
+		nReq.Context = 0
 	}
-	if resp == nil {
-		resp = &model.SearchResponse{Hits: []*model.SearchHit{}, Limit: nReq.Limit, Offset: nReq.Offset}
+	if nReq.Context > model.MaxSearchContext {
+		nReq.Context = model.MaxSearchContext
 	}
 
-	// Enrich message metadata（头像、群昵称、显示名等）
-	messages := make([]*model.Message, 0, len(resp.Hits))
-	for _, hit := range resp.Hits {
-		if hit == nil || hit.Message == nil {
-			continue
+	return nReq, nil
+}
+
+// labelFacets 为会话与发送者分面补充显示名
+// 限定了群聊时优先使用群昵称，与 enrichMessage 的规则一致
+func (r *Repository) labelFacets(facets *model.SearchFacets, talkers []string) {
+	for _, b := range facets.Talkers {
+		if chatRoom, ok := r.chatRoomCache[b.Value]; ok {
+			b.Label = chatRoom.DisplayName()
+		} else if contact := r.getFullContact(b.Value); contact != nil {
+			b.Label = contact.DisplayName()
 		}
-		messages = append(messages, hit.Message)
 	}
 
-	if len(messages) > 0 {
-		if err := r.EnrichMessages(ctx, messages); err != nil {
-			log.Debug().Msgf("EnrichMessages in search failed: %v", err)
+	for _, b := range facets.Senders {
+		for _, talker := range talkers {
+			if chatRoom, ok := r.chatRoomCache[talker]; ok {
+				if displayName, ok := chatRoom.User2DisplayName[b.Value]; ok && displayName != "" {
+					b.Label = displayName
+					break
+				}
+			}
+		}
+		if b.Label != "" {
+			continue
+		}
+		if contact := r.getFullContact(b.Value); contact != nil {
+			b.Label = contact.DisplayName()
 		}
 	}
-
-	return resp, nil
 }
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/errors"
+	"github.com/ysy950803/chatlog/internal/model"
+	"github.com/ysy950803/chatlog/internal/wechatdb/msgstore"
+	"github.com/ysy950803/chatlog/pkg/util"
+)
+
+// streamBuffer 为每个消息库读取协程预读的消息条数
+const streamBuffer = 64
+
+// enrichBatchSize 为流式读取时一起补充信息（查找引用原消息等）的消息条数
+const enrichBatchSize = 64
+
+// errStreamEnd 表示会话已读到时间范围末尾，用于提前结束该会话的遍历
+var errStreamEnd = stderrors.New("stream reached end time")
+
+// messageFilter 为 GetMessages 的过滤条件，在读取时逐条应用
+type messageFilter struct {
+	start, end time.Time
+	senders    map[string]struct{}
+	regex      *regexp.Regexp
+	types      *model.MessageTypeFilter
+}
+
+func newMessageFilter(start, end time.Time, sender, keyword, msgType string) (*messageFilter, error) {
+	f := &messageFilter{start: start, end: end}
+	if senders := util.Str2List(sender, ","); len(senders) > 0 {
+		f.senders = make(map[string]struct{}, len(senders))
+		for _, s := range senders {
+			f.senders[s] = struct{}{}
+		}
+	}
+	if keyword != "" {
+		regex, err := regexp.Compile(keyword)
+		if err != nil {
+			return nil, errors.QueryFailed("invalid regex pattern", err)
+		}
+		f.regex = regex
+	}
+	types, err := model.ParseMessageTypeFilter(msgType)
+	if err != nil {
+		return nil, errors.InvalidMessageType(err)
+	}
+	f.types = types
+	return f, nil
+}
+
+func (f *messageFilter) match(msg *model.Message) bool {
+	if msg.Time.Before(f.start) || msg.Time.After(f.end) {
+		return false
+	}
+	if !f.types.Match(msg.Type, msg.SubType) {
+		return false
+	}
+	if f.senders != nil {
+		if _, ok := f.senders[msg.Sender]; !ok {
+			return false
+		}
+	}
+	return f.regex == nil || f.regex.MatchString(msg.PlainTextContent())
+}
+
+// messageStream 为单个消息库中单个会话的有序消息流
+type messageStream struct {
+	ch   chan *model.Message
+	err  error
+	head *model.Message
+}
+
+// next 读取下一条消息到 head，流结束时 head 为 nil
+func (s *messageStream) next() {
+	s.head = <-s.ch
+}
+
+// enrichBatch 攒够一批消息后统一补充信息再依次交给 handler，引用原消息等查询按批进行
+type enrichBatch struct {
+	r        *Repository
+	ctx      context.Context
+	handler  func(*model.Message) error
+	messages []*model.Message
+}
+
+func (b *enrichBatch) add(msg *model.Message) error {
+	b.messages = append(b.messages, msg)
+	if len(b.messages) < enrichBatchSize {
+		return nil
+	}
+	return b.flush()
+}
+
+func (b *enrichBatch) flush() error {
+	if len(b.messages) == 0 {
+		return nil
+	}
+	if err := b.r.EnrichMessages(b.ctx, b.messages); err != nil {
+		return err
+	}
+	for _, msg := range b.messages {
+		if err := b.handler(msg); err != nil {
+			return err
+		}
+	}
+	b.messages = b.messages[:0]
+	return nil
+}
+
+// StreamMessages 按 (Seq, Talker) 升序逐条读取游标之后的消息并交给 handler
+// 各消息库按会话并行顺序读取后归并，内存占用与消息总数无关；limit 大于 0 时最多读取 limit 条
+// 序号以毫秒时间戳开头，每个会话从 max(游标, startTime) 对应的序号之后读起，读到晚于 endTime 的消息即停止
+// 返回继续读取用的游标，已读完时返回 nil
+func (r *Repository) StreamMessages(ctx context.Context, startTime, endTime time.Time, talker, sender, keyword, msgType string, cursor model.MessageCursor, limit int, handler func(*model.Message) error) (*model.MessageCursor, error) {
+	talker, sender, err := r.parseTalkerAndSender(ctx, talker, sender)
+	if err != nil {
+		return nil, err
+	}
+	talkers := util.Str2List(talker, ",")
+	if len(talkers) == 0 {
+		return nil, errors.ErrTalkerEmpty
+	}
+	filter, err := newMessageFilter(startTime, endTime, sender, keyword, msgType)
+	if err != nil {
+		return nil, err
+	}
+	multi := len(talkers) > 1
+	cursorOf := func(msg *model.Message) *model.MessageCursor {
+		c := &model.MessageCursor{Seq: msg.Seq}
+		if multi {
+			c.Talker = msg.Talker
+		}
+		return c
+	}
+
+	incremental, ok := r.ds.(ftsIncremental)
+	if !ok {
+		return r.streamMessagesFallback(ctx, startTime, endTime, talker, sender, keyword, msgType, cursor, limit, cursorOf, handler)
+	}
+	stores, err := r.ds.ListMessageStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	startSeq := startTime.Unix()*1000 - 1
+	var streams []*messageStream
+	for _, store := range stores {
+		if store == nil || !storeOverlaps(store, startTime, endTime) {
+			continue
+		}
+		for _, t := range talkers {
+			s := &messageStream{ch: make(chan *model.Message, streamBuffer)}
+			streams = append(streams, s)
+			afterSeq := max(cursor.AfterSeq(t), startSeq)
+			go func(store *msgstore.Store, talker string) {
+				defer close(s.ch)
+				err := incremental.IterateStoreMessages(ctx, store, talker, afterSeq, func(msg *model.Message) error {
+					if msg == nil {
+						return nil
+					}
+					if msg.Time.After(endTime) {
+						return errStreamEnd
+					}
+					if !cursor.Includes(msg.Seq, msg.Talker) || !filter.match(msg) {
+						return nil
+					}
+					select {
+					case s.ch <- msg:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				})
+				if !stderrors.Is(err, errStreamEnd) {
+					s.err = err
+				}
+			}(store, t)
+		}
+	}
+
+	// 流关闭后读取 err，close 保证读取到协程写入的值
+	var streamErr error
+	advance := func(s *messageStream) {
+		s.next()
+		if s.head == nil && s.err != nil && streamErr == nil && ctx.Err() == nil {
+			streamErr = s.err
+		}
+	}
+	for _, s := range streams {
+		advance(s)
+	}
+
+	batch := &enrichBatch{r: r, ctx: ctx, handler: handler}
+	count := 0
+	var last *model.Message
+	for {
+		var best *messageStream
+		for _, s := range streams {
+			if s.head == nil {
+				continue
+			}
+			if best == nil || s.head.Seq < best.head.Seq || (s.head.Seq == best.head.Seq && s.head.Talker < best.head.Talker) {
+				best = s
+			}
+		}
+		if streamErr != nil {
+			return nil, streamErr
+		}
+		if best == nil {
+			return nil, batch.flush()
+		}
+		if limit > 0 && count >= limit {
+			if err := batch.flush(); err != nil {
+				return nil, err
+			}
+			return cursorOf(last), nil
+		}
+
+		msg := best.head
+		if err := batch.add(msg); err != nil {
+			return nil, err
+		}
+		last = msg
+		count++
+		advance(best)
+	}
+}
+
+// streamMessagesFallback 用于不支持按序号读取的数据源，一次读出时间范围内的消息后按游标输出
+func (r *Repository) streamMessagesFallback(ctx context.Context, startTime, endTime time.Time, talker, sender, keyword, msgType string, cursor model.MessageCursor, limit int, cursorOf func(*model.Message) *model.MessageCursor, handler func(*model.Message) error) (*model.MessageCursor, error) {
+	messages, err := r.ds.GetMessages(ctx, startTime, endTime, talker, sender, keyword, msgType, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		if messages[i].Seq != messages[j].Seq {
+			return messages[i].Seq < messages[j].Seq
+		}
+		return messages[i].Talker < messages[j].Talker
+	})
+
+	batch := &enrichBatch{r: r, ctx: ctx, handler: handler}
+	count := 0
+	var last *model.Message
+	for _, msg := range messages {
+		if !cursor.Includes(msg.Seq, msg.Talker) {
+			continue
+		}
+		if limit > 0 && count >= limit {
+			// 没有序号的消息（如 macOS 3.x）无法生成游标
+			if last.Seq == 0 {
+				return nil, errors.CursorUnsupported()
+			}
+			if err := batch.flush(); err != nil {
+				return nil, err
+			}
+			return cursorOf(last), nil
+		}
+		if err := batch.add(msg); err != nil {
+			return nil, err
+		}
+		last = msg
+		count++
+	}
+	return nil, batch.flush()
+}
+
+// storeOverlaps 判断消息库的时间范围是否与 [start, end] 相交，时间范围未知时视为相交
+func storeOverlaps(store *msgstore.Store, start, end time.Time) bool {
+	if !store.StartTime.IsZero() && store.StartTime.After(end) {
+		return false
+	}
+	if !store.EndTime.IsZero() && store.EndTime.Before(start) {
+		return false
+	}
+	return true
+}
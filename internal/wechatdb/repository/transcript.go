@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// attachTranscript 将已保存的转写文本补充到语音消息中，供 PlainTextContent 输出和全文索引使用
+func (r *Repository) attachTranscript(msg *model.Message) {
+	if r.transcripts == nil || msg == nil || msg.Type != model.MessageTypeVoice {
+		return
+	}
+	key, ok := msg.Contents["voice"]
+	if !ok {
+		return
+	}
+	if t := r.transcripts.Get(fmt.Sprint(key)); t != nil && t.Text != "" {
+		msg.SetContent("transcript", t.Text)
+	}
+}
+
+// ListTalkers 返回数据源中的全部会话 ID
+func (r *Repository) ListTalkers(ctx context.Context) ([]string, error) {
+	indexable, ok := r.ds.(ftsIndexable)
+	if !ok {
+		return nil, fmt.Errorf("datasource does not support listing talkers")
+	}
+	return indexable.ListTalkers(ctx)
+}
+
+// SaveTranscripts 保存语音转写结果，并将对应消息重新写入全文索引
+func (r *Repository) SaveTranscripts(ctx context.Context, messages []*model.Message, transcripts []*model.Transcript) error {
+	if r.transcripts == nil {
+		return fmt.Errorf("transcript store not initialized")
+	}
+	if err := r.transcripts.Put(transcripts...); err != nil {
+		return err
+	}
+	return r.IndexMessages(ctx, messages)
+}
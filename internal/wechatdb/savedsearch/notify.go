@@ -0,0 +1,43 @@
+package savedsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Send 将新命中以 JSON POST 到通知目标
+func Send(target *model.SearchNotify, alert *model.SavedSearchAlert) error {
+	if target == nil || target.URL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notify %s: status code %d", target.URL, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package savedsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+// ErrNotFound 表示常用搜索不存在
+var ErrNotFound = errors.New("saved search not found")
+
+// Store 以 JSON 文件持久化常用搜索，每次修改后整体写回
+type Store struct {
+	mu    sync.RWMutex
+	path  string
+	items []*model.SavedSearch
+}
+
+// Open 加载 path 处的常用搜索，文件不存在时视为空
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, items: []*model.SavedSearch{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read saved searches: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.items); err != nil {
+			return nil, fmt.Errorf("decode saved searches: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// List 返回全部常用搜索的副本
+func (s *Store) List() []*model.SavedSearch {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*model.SavedSearch, 0, len(s.items))
+	for _, item := range s.items {
+		list = append(list, clone(item))
+	}
+	return list
+}
+
+// Get 返回指定 ID 的常用搜索副本
+func (s *Store) Get(id string) (*model.SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, item := range s.items {
+		if item.ID == id {
+			return clone(item), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Create 新建常用搜索；LastSeen 为空时从当前时间开始提醒，避免对历史消息批量通知
+func (s *Store) Create(item *model.SavedSearch) (*model.SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := clone(item)
+	created.ID = uuid.New().String()
+	created.CreatedAt = time.Now()
+	created.UpdatedAt = created.CreatedAt
+	if created.LastSeen == (model.MessageMarker{}) {
+		created.LastSeen = model.MessageMarker{Unix: created.CreatedAt.Unix()}
+	}
+
+	s.items = append(s.items, created)
+	if err := s.saveLocked(); err != nil {
+		s.items = s.items[:len(s.items)-1]
+		return nil, err
+	}
+	return clone(created), nil
+}
+
+// Update 在锁内修改指定常用搜索并写回文件
+func (s *Store) Update(id string, fn func(item *model.SavedSearch)) (*model.SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for idx, item := range s.items {
+		if item.ID != id {
+			continue
+		}
+		updated := clone(item)
+		fn(updated)
+		updated.ID = item.ID
+		updated.CreatedAt = item.CreatedAt
+		updated.UpdatedAt = time.Now()
+
+		s.items[idx] = updated
+		if err := s.saveLocked(); err != nil {
+			s.items[idx] = item
+			return nil, err
+		}
+		return clone(updated), nil
+	}
+	return nil, ErrNotFound
+}
+
+// Delete 删除指定常用搜索
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for idx, item := range s.items {
+		if item.ID != id {
+			continue
+		}
+		prev := s.items
+		s.items = append(append([]*model.SavedSearch{}, s.items[:idx]...), s.items[idx+1:]...)
+		if err := s.saveLocked(); err != nil {
+			s.items = prev
+			return err
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (s *Store) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func clone(item *model.SavedSearch) *model.SavedSearch {
+	copied := *item
+	copied.Request = item.Request.Clone()
+	if item.Seen != nil {
+		copied.Seen = make(map[string]model.IndexMark, len(item.Seen))
+		for k, v := range item.Seen {
+			copied.Seen[k] = v
+		}
+	}
+	if item.Notify != nil {
+		notify := *item.Notify
+		if item.Notify.Headers != nil {
+			notify.Headers = make(map[string]string, len(item.Notify.Headers))
+			for k, v := range item.Notify.Headers {
+				notify.Headers[k] = v
+			}
+		}
+		copied.Notify = &notify
+	}
+	return &copied
+}
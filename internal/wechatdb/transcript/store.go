@@ -0,0 +1,232 @@
+package transcript
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/ysy950803/chatlog/internal/model"
+)
+
+const checkpointKey = "transcribe"
+
+// Store 持久化语音转写结果，以语音 key 为主键
+// 转写文本整体缓存在内存中，供补充消息信息和建立索引时按 key 快速查询
+type Store struct {
+	mu    sync.RWMutex
+	db    *sql.DB
+	cache map[string]*model.Transcript
+}
+
+// Open 打开（或创建）path 处的转写库并加载已有结果
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create transcript dir: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal=WAL&_synchronous=NORMAL", filepath.ToSlash(path))
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open transcript store: %w", err)
+	}
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS transcripts (
+key        TEXT PRIMARY KEY,
+talker     TEXT NOT NULL,
+seq        INTEGER NOT NULL,
+text       TEXT NOT NULL,
+language   TEXT NOT NULL DEFAULT '',
+duration   REAL NOT NULL DEFAULT 0,
+created_at INTEGER NOT NULL
+);`,
+		`CREATE TABLE IF NOT EXISTS state (
+key   TEXT PRIMARY KEY,
+value TEXT NOT NULL
+);`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("init transcript schema: %w", err)
+		}
+	}
+
+	s := &Store{db: db, cache: make(map[string]*model.Transcript)}
+	if err := s.load(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	rows, err := s.db.Query(`SELECT key, talker, seq, text, language, duration, created_at FROM transcripts`)
+	if err != nil {
+		return fmt.Errorf("load transcripts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t model.Transcript
+		var createdAt int64
+		if err := rows.Scan(&t.Key, &t.Talker, &t.Seq, &t.Text, &t.Language, &t.Duration, &createdAt); err != nil {
+			return fmt.Errorf("scan transcript: %w", err)
+		}
+		t.CreatedAt = time.Unix(createdAt, 0)
+		s.cache[t.Key] = &t
+	}
+	return rows.Err()
+}
+
+// Close 关闭转写库
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// Get 返回语音 key 对应的转写结果，不存在时返回 nil
+func (s *Store) Get(key string) *model.Transcript {
+	if s == nil || key == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cache[key]
+}
+
+// Count 返回已保存的转写条数
+func (s *Store) Count() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.cache)
+}
+
+// Put 保存（覆盖）一批转写结果
+func (s *Store) Put(items ...*model.Transcript) error {
+	if s == nil || len(items) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return errors.New("transcript store closed")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+INSERT INTO transcripts (key, talker, seq, text, language, duration, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(key) DO UPDATE SET
+talker = excluded.talker,
+seq = excluded.seq,
+text = excluded.text,
+language = excluded.language,
+duration = excluded.duration,
+created_at = excluded.created_at
+`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, t := range items {
+		if t == nil || strings.TrimSpace(t.Key) == "" {
+			continue
+		}
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = time.Now()
+		}
+		if _, err := stmt.Exec(t.Key, t.Talker, t.Seq, t.Text, t.Language, t.Duration, t.CreatedAt.Unix()); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("save transcript %s: %w", t.Key, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, t := range items {
+		if t == nil || strings.TrimSpace(t.Key) == "" {
+			continue
+		}
+		copied := *t
+		s.cache[t.Key] = &copied
+	}
+	return nil
+}
+
+// LoadCheckpoint 读取转写任务的断点，不存在时返回 nil
+func (s *Store) LoadCheckpoint() (*model.TranscribeCheckpoint, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return nil, errors.New("transcript store closed")
+	}
+
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM state WHERE key = ?`, checkpointKey).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp model.TranscribeCheckpoint
+	if err := json.Unmarshal([]byte(value), &cp); err != nil {
+		return nil, fmt.Errorf("decode transcribe checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// SaveCheckpoint 保存转写任务的断点，cp 为 nil 时清除
+func (s *Store) SaveCheckpoint(cp *model.TranscribeCheckpoint) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return errors.New("transcript store closed")
+	}
+
+	if cp == nil {
+		_, err := s.db.Exec(`DELETE FROM state WHERE key = ?`, checkpointKey)
+		return err
+	}
+	cp.UpdatedAt = time.Now()
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO state (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`, checkpointKey, string(data))
+	return err
+}
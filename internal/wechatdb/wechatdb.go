@@ -10,25 +10,44 @@ import (
 	"github.com/fsnotify/fsnotify"
 	_ "github.com/mattn/go-sqlite3"
 
+	"github.com/ysy950803/chatlog/internal/embedding"
 	"github.com/ysy950803/chatlog/internal/model"
 	"github.com/ysy950803/chatlog/internal/wechatdb/datasource"
 	"github.com/ysy950803/chatlog/internal/wechatdb/repository"
+	"github.com/ysy950803/chatlog/internal/wechatdb/savedsearch"
+	"github.com/ysy950803/chatlog/internal/wechatdb/transcript"
 )
 
 type DB struct {
-	path     string
-	platform string
-	version  int
-	ds       datasource.DataSource
-	repo     *repository.Repository
+	path        string
+	platform    string
+	version     int
+	ds          datasource.DataSource
+	repo        *repository.Repository
+	transcripts *transcript.Store
+	searches    *savedsearch.Store
+	opts        Options
+}
+
+// Options 为打开 DB 的可选项
+type Options struct {
+	// DeferIndex 为 true 时不在后台自动构建全文索引，供索引维护命令使用
+	DeferIndex bool
+	// Embedder 为语义检索的向量模型，为空时不生成向量
+	Embedder embedding.Provider
 }
 
 func New(path string, platform string, version int) (*DB, error) {
+	return NewWithOptions(path, platform, version, Options{})
+}
+
+func NewWithOptions(path string, platform string, version int, opts Options) (*DB, error) {
 
 	w := &DB{
 		path:     path,
 		platform: platform,
 		version:  version,
+		opts:     opts,
 	}
 
 	// 初始化，加载数据库文件信息
@@ -57,7 +76,21 @@ func (w *DB) Initialize() error {
 	if err := os.MkdirAll(indexPath, 0o755); err != nil {
 		return fmt.Errorf("prepare index directory: %w", err)
 	}
-	w.repo, err = repository.New(w.ds, indexPath)
+	w.transcripts, err = transcript.Open(filepath.Join(w.path, "indexes", "transcripts.db"))
+	if err != nil {
+		return fmt.Errorf("open transcript store: %w", err)
+	}
+	w.searches, err = savedsearch.Open(filepath.Join(w.path, "saved_searches.json"))
+	if err != nil {
+		return err
+	}
+	w.repo, err = repository.New(w.ds, repository.Options{
+		IndexPath:     indexPath,
+		DeferIndex:    w.opts.DeferIndex,
+		Transcripts:   w.transcripts,
+		SavedSearches: w.searches,
+		Embedder:      w.opts.Embedder,
+	})
 	if err != nil {
 		return err
 	}
@@ -65,11 +98,11 @@ func (w *DB) Initialize() error {
 	return nil
 }
 
-func (w *DB) GetMessages(start, end time.Time, talker string, sender string, keyword string, limit, offset int) ([]*model.Message, error) {
+func (w *DB) GetMessages(start, end time.Time, talker string, sender string, keyword string, msgType string, limit, offset int) ([]*model.Message, error) {
 	ctx := context.Background()
 
 	// 使用 repository 获取消息
-	messages, err := w.repo.GetMessages(ctx, start, end, talker, sender, keyword, limit, offset)
+	messages, err := w.repo.GetMessages(ctx, start, end, talker, sender, keyword, msgType, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +110,16 @@ func (w *DB) GetMessages(start, end time.Time, talker string, sender string, key
 	return messages, nil
 }
 
+// StreamMessages 按序号顺序逐条读取游标之后的消息，ctx 取消时停止读取
+func (w *DB) StreamMessages(ctx context.Context, start, end time.Time, talker, sender, keyword, msgType string, cursor model.MessageCursor, limit int, handler func(*model.Message) error) (*model.MessageCursor, error) {
+	return w.repo.StreamMessages(ctx, start, end, talker, sender, keyword, msgType, cursor, limit, handler)
+}
+
+// GetReplyThread 返回消息所在的引用回复链，回复只在该消息之后 window 内查找
+func (w *DB) GetReplyThread(talker string, seq int64, window time.Duration) (*model.ReplyThread, error) {
+	return w.repo.GetReplyThread(context.Background(), talker, seq, window)
+}
+
 func (w *DB) SearchMessages(req *model.SearchRequest) (*model.SearchResponse, error) {
 	ctx := context.Background()
 	return w.repo.SearchMessages(ctx, req)
@@ -89,6 +132,67 @@ func (w *DB) IndexMessages(messages []*model.Message) error {
 	return w.repo.IndexMessages(context.Background(), messages)
 }
 
+// Transcripts 返回语音转写结果存储
+func (w *DB) Transcripts() *transcript.Store {
+	return w.transcripts
+}
+
+// SavedSearches 返回常用搜索存储
+func (w *DB) SavedSearches() *savedsearch.Store {
+	return w.searches
+}
+
+// SaveTranscripts 保存语音转写结果并更新全文索引
+func (w *DB) SaveTranscripts(messages []*model.Message, transcripts []*model.Transcript) error {
+	if w.repo == nil {
+		return fmt.Errorf("repository not initialized")
+	}
+	return w.repo.SaveTranscripts(context.Background(), messages, transcripts)
+}
+
+// ListTalkers 返回全部会话 ID
+func (w *DB) ListTalkers(ctx context.Context) ([]string, error) {
+	if w.repo == nil {
+		return nil, fmt.Errorf("repository not initialized")
+	}
+	return w.repo.ListTalkers(ctx)
+}
+
+// IndexStatus 返回全文索引构建进度
+func (w *DB) IndexStatus() *model.SearchIndexStatus {
+	return w.repo.IndexStatus()
+}
+
+// IndexReport 返回全文索引状态
+func (w *DB) IndexReport() (*model.IndexReport, error) {
+	return w.repo.IndexReport(context.Background())
+}
+
+// RebuildIndex 重建全文索引，storeID 非空时只重建该 store
+func (w *DB) RebuildIndex(storeID string) error {
+	return w.repo.RebuildIndex(context.Background(), storeID)
+}
+
+// VerifyIndex 校验全文索引完整性
+func (w *DB) VerifyIndex(storeID string) ([]*model.IndexCheckResult, error) {
+	return w.repo.VerifyIndex(context.Background(), storeID)
+}
+
+// OptimizeIndex 合并全文索引的段
+func (w *DB) OptimizeIndex(storeID string) ([]*model.IndexCheckResult, error) {
+	return w.repo.OptimizeIndex(context.Background(), storeID)
+}
+
+// VacuumIndex 压缩全文索引文件
+func (w *DB) VacuumIndex(storeID string) ([]*model.IndexCheckResult, error) {
+	return w.repo.VacuumIndex(context.Background(), storeID)
+}
+
+// GetMessageContext 按会话与序号读取消息及其前后各若干条消息
+func (w *DB) GetMessageContext(talker string, seq int64, before, after int) (*model.MessageContext, error) {
+	return w.repo.GetMessageContext(context.Background(), talker, seq, before, after)
+}
+
 type GetContactsResp struct {
 	Items []*model.Contact `json:"items"`
 }
@@ -106,6 +210,11 @@ func (w *DB) GetContacts(key string, limit, offset int) (*GetContactsResp, error
 	}, nil
 }
 
+// GetContact 从联系人缓存中查找联系人，key 可以是 userName、别名、备注或昵称
+func (w *DB) GetContact(key string) (*model.Contact, error) {
+	return w.repo.GetContact(context.Background(), key)
+}
+
 type GetChatRoomsResp struct {
 	Items []*model.ChatRoom `json:"items"`
 }
@@ -123,6 +232,21 @@ func (w *DB) GetChatRooms(key string, limit, offset int) (*GetChatRoomsResp, err
 	}, nil
 }
 
+// GetChatRoom 从群聊缓存中查找群聊，key 可以是群 ID、备注或名称
+func (w *DB) GetChatRoom(key string) (*model.ChatRoom, error) {
+	return w.repo.GetChatRoom(context.Background(), key)
+}
+
+// ResolveTalkerAndSender 将逗号分隔的 talker/sender 名称解析为 userName
+func (w *DB) ResolveTalkerAndSender(talker, sender string) (string, string, error) {
+	return w.repo.ResolveTalkerAndSender(context.Background(), talker, sender)
+}
+
+// ResolveTalker 解析会话名称，返回候选项及最近活跃时间，能唯一确定时给出 userName；all 为 true 时只列出候选项
+func (w *DB) ResolveTalker(name string, limit int, all bool) (*model.TalkerResolution, error) {
+	return w.repo.ResolveTalker(context.Background(), name, limit, all)
+}
+
 type GetSessionsResp struct {
 	Items []*model.Session `json:"items"`
 }
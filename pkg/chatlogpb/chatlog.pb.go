@@ -0,0 +1,1319 @@
+// chatlog gRPC 接口，与 HTTP 数据接口（/api/v1/contact、chatroom、session、chatlog、search 及媒体接口）一一对应
+// 修改后重新生成：protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative chatlog.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        (unknown)
+// source: chatlog.proto
+
+package chatlogpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ListRequest 为联系人、群聊、会话列表的查询条件，keyword 为空时返回全部
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keyword       string                 `protobuf:"bytes,1,opt,name=keyword,proto3" json:"keyword,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_chatlog_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListRequest) GetKeyword() string {
+	if x != nil {
+		return x.Keyword
+	}
+	return ""
+}
+
+func (x *ListRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type Contact struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserName      string                 `protobuf:"bytes,1,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	Alias         string                 `protobuf:"bytes,2,opt,name=alias,proto3" json:"alias,omitempty"`
+	Remark        string                 `protobuf:"bytes,3,opt,name=remark,proto3" json:"remark,omitempty"`
+	NickName      string                 `protobuf:"bytes,4,opt,name=nick_name,json=nickName,proto3" json:"nick_name,omitempty"`
+	IsFriend      bool                   `protobuf:"varint,5,opt,name=is_friend,json=isFriend,proto3" json:"is_friend,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Contact) Reset() {
+	*x = Contact{}
+	mi := &file_chatlog_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Contact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contact) ProtoMessage() {}
+
+func (x *Contact) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contact.ProtoReflect.Descriptor instead.
+func (*Contact) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Contact) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *Contact) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *Contact) GetRemark() string {
+	if x != nil {
+		return x.Remark
+	}
+	return ""
+}
+
+func (x *Contact) GetNickName() string {
+	if x != nil {
+		return x.NickName
+	}
+	return ""
+}
+
+func (x *Contact) GetIsFriend() bool {
+	if x != nil {
+		return x.IsFriend
+	}
+	return false
+}
+
+type ContactList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Contact             `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContactList) Reset() {
+	*x = ContactList{}
+	mi := &file_chatlog_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContactList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContactList) ProtoMessage() {}
+
+func (x *ContactList) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContactList.ProtoReflect.Descriptor instead.
+func (*ContactList) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ContactList) GetItems() []*Contact {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type ChatRoomUser struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserName      string                 `protobuf:"bytes,1,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	DisplayName   string                 `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatRoomUser) Reset() {
+	*x = ChatRoomUser{}
+	mi := &file_chatlog_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatRoomUser) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatRoomUser) ProtoMessage() {}
+
+func (x *ChatRoomUser) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatRoomUser.ProtoReflect.Descriptor instead.
+func (*ChatRoomUser) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ChatRoomUser) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *ChatRoomUser) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+type ChatRoom struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	Users         []*ChatRoomUser        `protobuf:"bytes,3,rep,name=users,proto3" json:"users,omitempty"`
+	Remark        string                 `protobuf:"bytes,4,opt,name=remark,proto3" json:"remark,omitempty"`
+	NickName      string                 `protobuf:"bytes,5,opt,name=nick_name,json=nickName,proto3" json:"nick_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatRoom) Reset() {
+	*x = ChatRoom{}
+	mi := &file_chatlog_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatRoom) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatRoom) ProtoMessage() {}
+
+func (x *ChatRoom) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatRoom.ProtoReflect.Descriptor instead.
+func (*ChatRoom) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ChatRoom) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ChatRoom) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ChatRoom) GetUsers() []*ChatRoomUser {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+func (x *ChatRoom) GetRemark() string {
+	if x != nil {
+		return x.Remark
+	}
+	return ""
+}
+
+func (x *ChatRoom) GetNickName() string {
+	if x != nil {
+		return x.NickName
+	}
+	return ""
+}
+
+type ChatRoomList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*ChatRoom            `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatRoomList) Reset() {
+	*x = ChatRoomList{}
+	mi := &file_chatlog_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatRoomList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatRoomList) ProtoMessage() {}
+
+func (x *ChatRoomList) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatRoomList.ProtoReflect.Descriptor instead.
+func (*ChatRoomList) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ChatRoomList) GetItems() []*ChatRoom {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type Session struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserName      string                 `protobuf:"bytes,1,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	NOrder        int32                  `protobuf:"varint,2,opt,name=n_order,json=nOrder,proto3" json:"n_order,omitempty"`
+	NickName      string                 `protobuf:"bytes,3,opt,name=nick_name,json=nickName,proto3" json:"nick_name,omitempty"`
+	Content       string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	NTime         int64                  `protobuf:"varint,5,opt,name=n_time,json=nTime,proto3" json:"n_time,omitempty"` // unix 秒
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Session) Reset() {
+	*x = Session{}
+	mi := &file_chatlog_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Session) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Session) ProtoMessage() {}
+
+func (x *Session) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Session.ProtoReflect.Descriptor instead.
+func (*Session) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Session) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *Session) GetNOrder() int32 {
+	if x != nil {
+		return x.NOrder
+	}
+	return 0
+}
+
+func (x *Session) GetNickName() string {
+	if x != nil {
+		return x.NickName
+	}
+	return ""
+}
+
+func (x *Session) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Session) GetNTime() int64 {
+	if x != nil {
+		return x.NTime
+	}
+	return 0
+}
+
+type SessionList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Session             `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionList) Reset() {
+	*x = SessionList{}
+	mi := &file_chatlog_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionList) ProtoMessage() {}
+
+func (x *SessionList) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionList.ProtoReflect.Descriptor instead.
+func (*SessionList) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SessionList) GetItems() []*Session {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// MessagesRequest 与 GET /api/v1/chatlog 的参数一致，time 支持相同的时间范围写法，为空时不限时间；talker 必填
+type MessagesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          string                 `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Talker        string                 `protobuf:"bytes,2,opt,name=talker,proto3" json:"talker,omitempty"`
+	Sender        string                 `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`
+	Keyword       string                 `protobuf:"bytes,4,opt,name=keyword,proto3" json:"keyword,omitempty"`
+	Type          string                 `protobuf:"bytes,5,opt,name=type,proto3" json:"type,omitempty"`
+	Cursor        string                 `protobuf:"bytes,6,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32                  `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessagesRequest) Reset() {
+	*x = MessagesRequest{}
+	mi := &file_chatlog_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessagesRequest) ProtoMessage() {}
+
+func (x *MessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessagesRequest.ProtoReflect.Descriptor instead.
+func (*MessagesRequest) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MessagesRequest) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *MessagesRequest) GetTalker() string {
+	if x != nil {
+		return x.Talker
+	}
+	return ""
+}
+
+func (x *MessagesRequest) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *MessagesRequest) GetKeyword() string {
+	if x != nil {
+		return x.Keyword
+	}
+	return ""
+}
+
+func (x *MessagesRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *MessagesRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *MessagesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type Message struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           int64                  `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Time          int64                  `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"` // unix 秒
+	Talker        string                 `protobuf:"bytes,3,opt,name=talker,proto3" json:"talker,omitempty"`
+	TalkerName    string                 `protobuf:"bytes,4,opt,name=talker_name,json=talkerName,proto3" json:"talker_name,omitempty"`
+	IsChatRoom    bool                   `protobuf:"varint,5,opt,name=is_chat_room,json=isChatRoom,proto3" json:"is_chat_room,omitempty"`
+	Sender        string                 `protobuf:"bytes,6,opt,name=sender,proto3" json:"sender,omitempty"`
+	SenderName    string                 `protobuf:"bytes,7,opt,name=sender_name,json=senderName,proto3" json:"sender_name,omitempty"`
+	IsSelf        bool                   `protobuf:"varint,8,opt,name=is_self,json=isSelf,proto3" json:"is_self,omitempty"`
+	Type          int64                  `protobuf:"varint,9,opt,name=type,proto3" json:"type,omitempty"`
+	SubType       int64                  `protobuf:"varint,10,opt,name=sub_type,json=subType,proto3" json:"sub_type,omitempty"`
+	Content       string                 `protobuf:"bytes,11,opt,name=content,proto3" json:"content,omitempty"`
+	ContentsJson  string                 `protobuf:"bytes,12,opt,name=contents_json,json=contentsJson,proto3" json:"contents_json,omitempty"` // 多媒体、引用、合并转发等结构化内容，与 HTTP 接口中的 contents 相同的 JSON
+	Permalink     string                 `protobuf:"bytes,13,opt,name=permalink,proto3" json:"permalink,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_chatlog_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Message) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Message) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Message) GetTalker() string {
+	if x != nil {
+		return x.Talker
+	}
+	return ""
+}
+
+func (x *Message) GetTalkerName() string {
+	if x != nil {
+		return x.TalkerName
+	}
+	return ""
+}
+
+func (x *Message) GetIsChatRoom() bool {
+	if x != nil {
+		return x.IsChatRoom
+	}
+	return false
+}
+
+func (x *Message) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *Message) GetSenderName() string {
+	if x != nil {
+		return x.SenderName
+	}
+	return ""
+}
+
+func (x *Message) GetIsSelf() bool {
+	if x != nil {
+		return x.IsSelf
+	}
+	return false
+}
+
+func (x *Message) GetType() int64 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *Message) GetSubType() int64 {
+	if x != nil {
+		return x.SubType
+	}
+	return 0
+}
+
+func (x *Message) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Message) GetContentsJson() string {
+	if x != nil {
+		return x.ContentsJson
+	}
+	return ""
+}
+
+func (x *Message) GetPermalink() string {
+	if x != nil {
+		return x.Permalink
+	}
+	return ""
+}
+
+// SearchRequest 与 GET /api/v1/search 的参数一致
+type SearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Talker        string                 `protobuf:"bytes,2,opt,name=talker,proto3" json:"talker,omitempty"`
+	Sender        string                 `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`
+	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Time          string                 `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	Limit         int32                  `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,7,opt,name=offset,proto3" json:"offset,omitempty"`
+	Context       int32                  `protobuf:"varint,8,opt,name=context,proto3" json:"context,omitempty"`
+	Cursor        string                 `protobuf:"bytes,9,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Mode          string                 `protobuf:"bytes,10,opt,name=mode,proto3" json:"mode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_chatlog_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetTalker() string {
+	if x != nil {
+		return x.Talker
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetContext() int32 {
+	if x != nil {
+		return x.Context
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+type SearchHit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Snippet       string                 `protobuf:"bytes,2,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	Score         float64                `protobuf:"fixed64,3,opt,name=score,proto3" json:"score,omitempty"`
+	Before        []*Message             `protobuf:"bytes,4,rep,name=before,proto3" json:"before,omitempty"`
+	After         []*Message             `protobuf:"bytes,5,rep,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchHit) Reset() {
+	*x = SearchHit{}
+	mi := &file_chatlog_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchHit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchHit) ProtoMessage() {}
+
+func (x *SearchHit) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchHit.ProtoReflect.Descriptor instead.
+func (*SearchHit) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SearchHit) GetMessage() *Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *SearchHit) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
+func (x *SearchHit) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *SearchHit) GetBefore() []*Message {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *SearchHit) GetAfter() []*Message {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         int32                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Hits          []*SearchHit           `protobuf:"bytes,2,rep,name=hits,proto3" json:"hits,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,3,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,4,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_chatlog_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SearchResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *SearchResponse) GetHits() []*SearchHit {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *SearchResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+// MediaRequest 的 type 为 image、video、file、voice，key 与 HTTP 媒体接口相同
+type MediaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MediaRequest) Reset() {
+	*x = MediaRequest{}
+	mi := &file_chatlog_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MediaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MediaRequest) ProtoMessage() {}
+
+func (x *MediaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MediaRequest.ProtoReflect.Descriptor instead.
+func (*MediaRequest) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MediaRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *MediaRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type MediaChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 以下字段只在第一块中出现
+	Type          string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Key           string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Name          string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	ContentType   string `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Size          int64  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Data          []byte `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MediaChunk) Reset() {
+	*x = MediaChunk{}
+	mi := &file_chatlog_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MediaChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MediaChunk) ProtoMessage() {}
+
+func (x *MediaChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_chatlog_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MediaChunk.ProtoReflect.Descriptor instead.
+func (*MediaChunk) Descriptor() ([]byte, []int) {
+	return file_chatlog_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *MediaChunk) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *MediaChunk) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *MediaChunk) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MediaChunk) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *MediaChunk) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *MediaChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_chatlog_proto protoreflect.FileDescriptor
+
+const file_chatlog_proto_rawDesc = "" +
+	"\n" +
+	"\rchatlog.proto\x12\n" +
+	"chatlog.v1\"U\n" +
+	"\vListRequest\x12\x18\n" +
+	"\akeyword\x18\x01 \x01(\tR\akeyword\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"\x8e\x01\n" +
+	"\aContact\x12\x1b\n" +
+	"\tuser_name\x18\x01 \x01(\tR\buserName\x12\x14\n" +
+	"\x05alias\x18\x02 \x01(\tR\x05alias\x12\x16\n" +
+	"\x06remark\x18\x03 \x01(\tR\x06remark\x12\x1b\n" +
+	"\tnick_name\x18\x04 \x01(\tR\bnickName\x12\x1b\n" +
+	"\tis_friend\x18\x05 \x01(\bR\bisFriend\"8\n" +
+	"\vContactList\x12)\n" +
+	"\x05items\x18\x01 \x03(\v2\x13.chatlog.v1.ContactR\x05items\"N\n" +
+	"\fChatRoomUser\x12\x1b\n" +
+	"\tuser_name\x18\x01 \x01(\tR\buserName\x12!\n" +
+	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\"\x99\x01\n" +
+	"\bChatRoom\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12.\n" +
+	"\x05users\x18\x03 \x03(\v2\x18.chatlog.v1.ChatRoomUserR\x05users\x12\x16\n" +
+	"\x06remark\x18\x04 \x01(\tR\x06remark\x12\x1b\n" +
+	"\tnick_name\x18\x05 \x01(\tR\bnickName\":\n" +
+	"\fChatRoomList\x12*\n" +
+	"\x05items\x18\x01 \x03(\v2\x14.chatlog.v1.ChatRoomR\x05items\"\x8d\x01\n" +
+	"\aSession\x12\x1b\n" +
+	"\tuser_name\x18\x01 \x01(\tR\buserName\x12\x17\n" +
+	"\an_order\x18\x02 \x01(\x05R\x06nOrder\x12\x1b\n" +
+	"\tnick_name\x18\x03 \x01(\tR\bnickName\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\x12\x15\n" +
+	"\x06n_time\x18\x05 \x01(\x03R\x05nTime\"8\n" +
+	"\vSessionList\x12)\n" +
+	"\x05items\x18\x01 \x03(\v2\x13.chatlog.v1.SessionR\x05items\"\xb1\x01\n" +
+	"\x0fMessagesRequest\x12\x12\n" +
+	"\x04time\x18\x01 \x01(\tR\x04time\x12\x16\n" +
+	"\x06talker\x18\x02 \x01(\tR\x06talker\x12\x16\n" +
+	"\x06sender\x18\x03 \x01(\tR\x06sender\x12\x18\n" +
+	"\akeyword\x18\x04 \x01(\tR\akeyword\x12\x12\n" +
+	"\x04type\x18\x05 \x01(\tR\x04type\x12\x16\n" +
+	"\x06cursor\x18\x06 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\a \x01(\x05R\x05limit\"\xe8\x02\n" +
+	"\aMessage\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x03R\x03seq\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\x03R\x04time\x12\x16\n" +
+	"\x06talker\x18\x03 \x01(\tR\x06talker\x12\x1f\n" +
+	"\vtalker_name\x18\x04 \x01(\tR\n" +
+	"talkerName\x12 \n" +
+	"\fis_chat_room\x18\x05 \x01(\bR\n" +
+	"isChatRoom\x12\x16\n" +
+	"\x06sender\x18\x06 \x01(\tR\x06sender\x12\x1f\n" +
+	"\vsender_name\x18\a \x01(\tR\n" +
+	"senderName\x12\x17\n" +
+	"\ais_self\x18\b \x01(\bR\x06isSelf\x12\x12\n" +
+	"\x04type\x18\t \x01(\x03R\x04type\x12\x19\n" +
+	"\bsub_type\x18\n" +
+	" \x01(\x03R\asubType\x12\x18\n" +
+	"\acontent\x18\v \x01(\tR\acontent\x12#\n" +
+	"\rcontents_json\x18\f \x01(\tR\fcontentsJson\x12\x1c\n" +
+	"\tpermalink\x18\r \x01(\tR\tpermalink\"\xf1\x01\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x16\n" +
+	"\x06talker\x18\x02 \x01(\tR\x06talker\x12\x16\n" +
+	"\x06sender\x18\x03 \x01(\tR\x06sender\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x12\n" +
+	"\x04time\x18\x05 \x01(\tR\x04time\x12\x14\n" +
+	"\x05limit\x18\x06 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\a \x01(\x05R\x06offset\x12\x18\n" +
+	"\acontext\x18\b \x01(\x05R\acontext\x12\x16\n" +
+	"\x06cursor\x18\t \x01(\tR\x06cursor\x12\x12\n" +
+	"\x04mode\x18\n" +
+	" \x01(\tR\x04mode\"\xc2\x01\n" +
+	"\tSearchHit\x12-\n" +
+	"\amessage\x18\x01 \x01(\v2\x13.chatlog.v1.MessageR\amessage\x12\x18\n" +
+	"\asnippet\x18\x02 \x01(\tR\asnippet\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x01R\x05score\x12+\n" +
+	"\x06before\x18\x04 \x03(\v2\x13.chatlog.v1.MessageR\x06before\x12)\n" +
+	"\x05after\x18\x05 \x03(\v2\x13.chatlog.v1.MessageR\x05after\"\x93\x01\n" +
+	"\x0eSearchResponse\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x05R\x05total\x12)\n" +
+	"\x04hits\x18\x02 \x03(\v2\x15.chatlog.v1.SearchHitR\x04hits\x12\x1f\n" +
+	"\vduration_ms\x18\x03 \x01(\x03R\n" +
+	"durationMs\x12\x1f\n" +
+	"\vnext_cursor\x18\x04 \x01(\tR\n" +
+	"nextCursor\"4\n" +
+	"\fMediaRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"\x91\x01\n" +
+	"\n" +
+	"MediaChunk\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x03R\x04size\x12\x12\n" +
+	"\x04data\x18\x06 \x01(\fR\x04data2\x9a\x03\n" +
+	"\aChatlog\x12?\n" +
+	"\vGetContacts\x12\x17.chatlog.v1.ListRequest\x1a\x17.chatlog.v1.ContactList\x12A\n" +
+	"\fGetChatRooms\x12\x17.chatlog.v1.ListRequest\x1a\x18.chatlog.v1.ChatRoomList\x12?\n" +
+	"\vGetSessions\x12\x17.chatlog.v1.ListRequest\x1a\x17.chatlog.v1.SessionList\x12A\n" +
+	"\vGetMessages\x12\x1b.chatlog.v1.MessagesRequest\x1a\x13.chatlog.v1.Message0\x01\x12G\n" +
+	"\x0eSearchMessages\x12\x19.chatlog.v1.SearchRequest\x1a\x1a.chatlog.v1.SearchResponse\x12>\n" +
+	"\bGetMedia\x12\x18.chatlog.v1.MediaRequest\x1a\x16.chatlog.v1.MediaChunk0\x01B6Z4github.com/ysy950803/chatlog/pkg/chatlogpb;chatlogpbb\x06proto3"
+
+var (
+	file_chatlog_proto_rawDescOnce sync.Once
+	file_chatlog_proto_rawDescData []byte
+)
+
+func file_chatlog_proto_rawDescGZIP() []byte {
+	file_chatlog_proto_rawDescOnce.Do(func() {
+		file_chatlog_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_chatlog_proto_rawDesc), len(file_chatlog_proto_rawDesc)))
+	})
+	return file_chatlog_proto_rawDescData
+}
+
+var file_chatlog_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_chatlog_proto_goTypes = []any{
+	(*ListRequest)(nil),     // 0: chatlog.v1.ListRequest
+	(*Contact)(nil),         // 1: chatlog.v1.Contact
+	(*ContactList)(nil),     // 2: chatlog.v1.ContactList
+	(*ChatRoomUser)(nil),    // 3: chatlog.v1.ChatRoomUser
+	(*ChatRoom)(nil),        // 4: chatlog.v1.ChatRoom
+	(*ChatRoomList)(nil),    // 5: chatlog.v1.ChatRoomList
+	(*Session)(nil),         // 6: chatlog.v1.Session
+	(*SessionList)(nil),     // 7: chatlog.v1.SessionList
+	(*MessagesRequest)(nil), // 8: chatlog.v1.MessagesRequest
+	(*Message)(nil),         // 9: chatlog.v1.Message
+	(*SearchRequest)(nil),   // 10: chatlog.v1.SearchRequest
+	(*SearchHit)(nil),       // 11: chatlog.v1.SearchHit
+	(*SearchResponse)(nil),  // 12: chatlog.v1.SearchResponse
+	(*MediaRequest)(nil),    // 13: chatlog.v1.MediaRequest
+	(*MediaChunk)(nil),      // 14: chatlog.v1.MediaChunk
+}
+var file_chatlog_proto_depIdxs = []int32{
+	1,  // 0: chatlog.v1.ContactList.items:type_name -> chatlog.v1.Contact
+	3,  // 1: chatlog.v1.ChatRoom.users:type_name -> chatlog.v1.ChatRoomUser
+	4,  // 2: chatlog.v1.ChatRoomList.items:type_name -> chatlog.v1.ChatRoom
+	6,  // 3: chatlog.v1.SessionList.items:type_name -> chatlog.v1.Session
+	9,  // 4: chatlog.v1.SearchHit.message:type_name -> chatlog.v1.Message
+	9,  // 5: chatlog.v1.SearchHit.before:type_name -> chatlog.v1.Message
+	9,  // 6: chatlog.v1.SearchHit.after:type_name -> chatlog.v1.Message
+	11, // 7: chatlog.v1.SearchResponse.hits:type_name -> chatlog.v1.SearchHit
+	0,  // 8: chatlog.v1.Chatlog.GetContacts:input_type -> chatlog.v1.ListRequest
+	0,  // 9: chatlog.v1.Chatlog.GetChatRooms:input_type -> chatlog.v1.ListRequest
+	0,  // 10: chatlog.v1.Chatlog.GetSessions:input_type -> chatlog.v1.ListRequest
+	8,  // 11: chatlog.v1.Chatlog.GetMessages:input_type -> chatlog.v1.MessagesRequest
+	10, // 12: chatlog.v1.Chatlog.SearchMessages:input_type -> chatlog.v1.SearchRequest
+	13, // 13: chatlog.v1.Chatlog.GetMedia:input_type -> chatlog.v1.MediaRequest
+	2,  // 14: chatlog.v1.Chatlog.GetContacts:output_type -> chatlog.v1.ContactList
+	5,  // 15: chatlog.v1.Chatlog.GetChatRooms:output_type -> chatlog.v1.ChatRoomList
+	7,  // 16: chatlog.v1.Chatlog.GetSessions:output_type -> chatlog.v1.SessionList
+	9,  // 17: chatlog.v1.Chatlog.GetMessages:output_type -> chatlog.v1.Message
+	12, // 18: chatlog.v1.Chatlog.SearchMessages:output_type -> chatlog.v1.SearchResponse
+	14, // 19: chatlog.v1.Chatlog.GetMedia:output_type -> chatlog.v1.MediaChunk
+	14, // [14:20] is the sub-list for method output_type
+	8,  // [8:14] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_chatlog_proto_init() }
+func file_chatlog_proto_init() {
+	if File_chatlog_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_chatlog_proto_rawDesc), len(file_chatlog_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_chatlog_proto_goTypes,
+		DependencyIndexes: file_chatlog_proto_depIdxs,
+		MessageInfos:      file_chatlog_proto_msgTypes,
+	}.Build()
+	File_chatlog_proto = out.File
+	file_chatlog_proto_goTypes = nil
+	file_chatlog_proto_depIdxs = nil
+}
@@ -0,0 +1,327 @@
+// chatlog gRPC 接口，与 HTTP 数据接口（/api/v1/contact、chatroom、session、chatlog、search 及媒体接口）一一对应
+// 修改后重新生成：protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative chatlog.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: chatlog.proto
+
+package chatlogpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Chatlog_GetContacts_FullMethodName    = "/chatlog.v1.Chatlog/GetContacts"
+	Chatlog_GetChatRooms_FullMethodName   = "/chatlog.v1.Chatlog/GetChatRooms"
+	Chatlog_GetSessions_FullMethodName    = "/chatlog.v1.Chatlog/GetSessions"
+	Chatlog_GetMessages_FullMethodName    = "/chatlog.v1.Chatlog/GetMessages"
+	Chatlog_SearchMessages_FullMethodName = "/chatlog.v1.Chatlog/SearchMessages"
+	Chatlog_GetMedia_FullMethodName       = "/chatlog.v1.Chatlog/GetMedia"
+)
+
+// ChatlogClient is the client API for Chatlog service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ChatlogClient interface {
+	GetContacts(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ContactList, error)
+	GetChatRooms(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ChatRoomList, error)
+	GetSessions(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*SessionList, error)
+	// GetMessages 按序号顺序逐条返回消息，limit 为 0 时返回全部
+	// 因 limit 截断时 trailer 中的 next-cursor 为下一页的 cursor，与 HTTP 接口的 next_cursor 相同
+	GetMessages(ctx context.Context, in *MessagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error)
+	SearchMessages(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// GetMedia 分块返回媒体文件，第一块带有媒体信息；图片解码为原始格式，语音转为 mp3
+	GetMedia(ctx context.Context, in *MediaRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MediaChunk], error)
+}
+
+type chatlogClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChatlogClient(cc grpc.ClientConnInterface) ChatlogClient {
+	return &chatlogClient{cc}
+}
+
+func (c *chatlogClient) GetContacts(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ContactList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ContactList)
+	err := c.cc.Invoke(ctx, Chatlog_GetContacts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatlogClient) GetChatRooms(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ChatRoomList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChatRoomList)
+	err := c.cc.Invoke(ctx, Chatlog_GetChatRooms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatlogClient) GetSessions(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*SessionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SessionList)
+	err := c.cc.Invoke(ctx, Chatlog_GetSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatlogClient) GetMessages(ctx context.Context, in *MessagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Chatlog_ServiceDesc.Streams[0], Chatlog_GetMessages_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MessagesRequest, Message]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Chatlog_GetMessagesClient = grpc.ServerStreamingClient[Message]
+
+func (c *chatlogClient) SearchMessages(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, Chatlog_SearchMessages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatlogClient) GetMedia(ctx context.Context, in *MediaRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MediaChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Chatlog_ServiceDesc.Streams[1], Chatlog_GetMedia_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MediaRequest, MediaChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Chatlog_GetMediaClient = grpc.ServerStreamingClient[MediaChunk]
+
+// ChatlogServer is the server API for Chatlog service.
+// All implementations must embed UnimplementedChatlogServer
+// for forward compatibility.
+type ChatlogServer interface {
+	GetContacts(context.Context, *ListRequest) (*ContactList, error)
+	GetChatRooms(context.Context, *ListRequest) (*ChatRoomList, error)
+	GetSessions(context.Context, *ListRequest) (*SessionList, error)
+	// GetMessages 按序号顺序逐条返回消息，limit 为 0 时返回全部
+	// 因 limit 截断时 trailer 中的 next-cursor 为下一页的 cursor，与 HTTP 接口的 next_cursor 相同
+	GetMessages(*MessagesRequest, grpc.ServerStreamingServer[Message]) error
+	SearchMessages(context.Context, *SearchRequest) (*SearchResponse, error)
+	// GetMedia 分块返回媒体文件，第一块带有媒体信息；图片解码为原始格式，语音转为 mp3
+	GetMedia(*MediaRequest, grpc.ServerStreamingServer[MediaChunk]) error
+	mustEmbedUnimplementedChatlogServer()
+}
+
+// UnimplementedChatlogServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedChatlogServer struct{}
+
+func (UnimplementedChatlogServer) GetContacts(context.Context, *ListRequest) (*ContactList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetContacts not implemented")
+}
+func (UnimplementedChatlogServer) GetChatRooms(context.Context, *ListRequest) (*ChatRoomList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChatRooms not implemented")
+}
+func (UnimplementedChatlogServer) GetSessions(context.Context, *ListRequest) (*SessionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSessions not implemented")
+}
+func (UnimplementedChatlogServer) GetMessages(*MessagesRequest, grpc.ServerStreamingServer[Message]) error {
+	return status.Errorf(codes.Unimplemented, "method GetMessages not implemented")
+}
+func (UnimplementedChatlogServer) SearchMessages(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMessages not implemented")
+}
+func (UnimplementedChatlogServer) GetMedia(*MediaRequest, grpc.ServerStreamingServer[MediaChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method GetMedia not implemented")
+}
+func (UnimplementedChatlogServer) mustEmbedUnimplementedChatlogServer() {}
+func (UnimplementedChatlogServer) testEmbeddedByValue()                 {}
+
+// UnsafeChatlogServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChatlogServer will
+// result in compilation errors.
+type UnsafeChatlogServer interface {
+	mustEmbedUnimplementedChatlogServer()
+}
+
+func RegisterChatlogServer(s grpc.ServiceRegistrar, srv ChatlogServer) {
+	// If the following call pancis, it indicates UnimplementedChatlogServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Chatlog_ServiceDesc, srv)
+}
+
+func _Chatlog_GetContacts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatlogServer).GetContacts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chatlog_GetContacts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatlogServer).GetContacts(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chatlog_GetChatRooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatlogServer).GetChatRooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chatlog_GetChatRooms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatlogServer).GetChatRooms(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chatlog_GetSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatlogServer).GetSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chatlog_GetSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatlogServer).GetSessions(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chatlog_GetMessages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MessagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatlogServer).GetMessages(m, &grpc.GenericServerStream[MessagesRequest, Message]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Chatlog_GetMessagesServer = grpc.ServerStreamingServer[Message]
+
+func _Chatlog_SearchMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatlogServer).SearchMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chatlog_SearchMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatlogServer).SearchMessages(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chatlog_GetMedia_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MediaRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatlogServer).GetMedia(m, &grpc.GenericServerStream[MediaRequest, MediaChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Chatlog_GetMediaServer = grpc.ServerStreamingServer[MediaChunk]
+
+// Chatlog_ServiceDesc is the grpc.ServiceDesc for Chatlog service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Chatlog_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "chatlog.v1.Chatlog",
+	HandlerType: (*ChatlogServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetContacts",
+			Handler:    _Chatlog_GetContacts_Handler,
+		},
+		{
+			MethodName: "GetChatRooms",
+			Handler:    _Chatlog_GetChatRooms_Handler,
+		},
+		{
+			MethodName: "GetSessions",
+			Handler:    _Chatlog_GetSessions_Handler,
+		},
+		{
+			MethodName: "SearchMessages",
+			Handler:    _Chatlog_SearchMessages_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetMessages",
+			Handler:       _Chatlog_GetMessages_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetMedia",
+			Handler:       _Chatlog_GetMedia_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "chatlog.proto",
+}
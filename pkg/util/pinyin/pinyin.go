@@ -0,0 +1,82 @@
+// Package pinyin 将汉字转换为不带声调的拼音，用于按全拼或首字母查找联系人与群聊
+//
+// pinyin.txt 每行为一个音节及其对应的汉字，覆盖 CJK 基本区（U+4E00–U+9FFF），
+// 由 ICU 的 Han-Latin 转写生成；多音字只取最常用的读音，ü 记为 v（如 lv、nve）
+package pinyin
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed pinyin.txt
+var data string
+
+var (
+	once  sync.Once
+	table map[rune]string
+)
+
+func load() {
+	table = make(map[rune]string, 21000)
+	for _, line := range strings.Split(data, "\n") {
+		syllable, chars, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		for _, r := range chars {
+			table[r] = syllable
+		}
+	}
+}
+
+// Syllables 将 s 切分为音节：汉字转为拼音，连续的字母数字转小写后作为一个音节，其余字符忽略
+func Syllables(s string) []string {
+	once.Do(load)
+	var out []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			out = append(out, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range s {
+		if py, ok := table[r]; ok {
+			flush()
+			out = append(out, py)
+			continue
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			word.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		flush()
+	}
+	flush()
+	return out
+}
+
+// Convert 返回 s 的全拼与首字母，如 "张三" 返回 "zhangsan" 与 "zs"
+func Convert(s string) (full, initials string) {
+	syllables := Syllables(s)
+	var f, i strings.Builder
+	for _, syllable := range syllables {
+		f.WriteString(syllable)
+		i.WriteByte(syllable[0])
+	}
+	return f.String(), i.String()
+}
+
+// HasHan 判断 s 中是否包含可转换为拼音的汉字
+func HasHan(s string) bool {
+	once.Do(load)
+	for _, r := range s {
+		if _, ok := table[r]; ok {
+			return true
+		}
+	}
+	return false
+}
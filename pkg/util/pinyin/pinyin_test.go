@@ -0,0 +1,21 @@
+package pinyin
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		in, full, initials string
+	}{
+		{"张三", "zhangsan", "zs"},
+		{"产品讨论组", "chanpintaolunzu", "cptlz"},
+		{"吕布", "lvbu", "lb"},
+		{"Tom 李", "tomli", "tl"},
+		{"🙂", "", ""},
+	}
+	for _, c := range cases {
+		full, initials := Convert(c.in)
+		if full != c.full || initials != c.initials {
+			t.Errorf("Convert(%q) = %q, %q, want %q, %q", c.in, full, initials, c.full, c.initials)
+		}
+	}
+}